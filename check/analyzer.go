@@ -0,0 +1,114 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// analyzerRegexp matches a diagnostic like "path/to/file.go:10:2: message"
+var analyzerRegexp = regexp.MustCompile(`^(.+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// runInDir runs the named command on the packages in dir, with the go
+// environment of the repo, or in each module of a repo with several.
+// Exit status diagStatus means the command found issues, and is not an
+// error. The combined output is returned, as many commands write their
+// issues to stderr.
+func runInDir(ctx context.Context, dir string, diagStatus int, name string, args ...string) ([]byte, error) {
+	var all []byte
+	for _, modDir := range moduleDirs(ctx, dir) {
+		out, err := runInModule(ctx, modDir, diagStatus, name, args...)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, out...)
+	}
+	return all, nil
+}
+
+// runInModule runs the named command like runInDir in the directory of a
+// single module
+func runInModule(ctx context.Context, dir string, diagStatus int, name string, args ...string) ([]byte, error) {
+	env, err := goEnv(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := commandContext(ctx, dir, env, name, append(args, "./...")...)
+	out, err := combinedOutput(ctx, cmd)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if exitErr.ExitCode() == diagStatus {
+			err = nil
+		}
+	}
+	if _, ok := err.(*LimitError); ok {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// runAnalyzer runs a command built on golang.org/x/tools/go/analysis,
+// such as exhaustive or shadow, on the packages in dir and returns the
+// reported diagnostics. These commands exit 3 if there are diagnostics,
+// and 1 if the packages could not be loaded.
+func runAnalyzer(ctx context.Context, dir string, name string, args ...string) ([]FileSummary, error) {
+	out, err := runInDir(ctx, dir, 3, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseAnalyzer(out, dir, name)
+}
+
+// parseAnalyzer parses the diagnostics printed by a go/analysis command
+// into file summaries, sorted by filename. The errors have the given rule.
+func parseAnalyzer(out []byte, dir, rule string) ([]FileSummary, error) {
+	fsMap := make(map[string]FileSummary)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := analyzerRegexp.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		// paths are absolute, as the command ran in dir
+		filename := reportedFilename(m[1])
+		if skipReported(filename) {
+			continue
+		}
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			return nil, err
+		}
+
+		fs := fsMap[filename]
+		if fs.Filename == "" {
+			fs.Filename = makeFilename(filename)
+			fs.FileURL = fileURL(dir, filename)
+		}
+		// the column is optional
+		col, _ := strconv.Atoi(m[3])
+		fs.Errors = append(fs.Errors, Error{LineNumber: line, Column: col, ErrorString: m[4], RuleID: rule})
+		fsMap[filename] = fs
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var failed = []FileSummary{}
+	for _, v := range fsMap {
+		failed = append(failed, v)
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Filename < failed[j].Filename })
+
+	return failed, nil
+}
@@ -0,0 +1,33 @@
+package check
+
+import "testing"
+
+func TestParseAnalyzer(t *testing.T) {
+	out := `/home/grc/repos/src/github.com/foo/bar/a.go:10:2: missing cases in switch of type bar.Color: bar.Blue, bar.Green
+/home/grc/repos/src/github.com/foo/bar/sub/b.go:3:9: missing cases in switch of type bar.Color: bar.Red
+/home/grc/repos/src/github.com/foo/bar/a.go:20:2: missing cases in switch of type bar.Size: bar.Large
+/home/grc/repos/src/github.com/foo/bar/a.pb.go:1:1: skipped
+-: # github.com/foo/bar/broken
+`
+	failed, err := parseAnalyzer([]byte(out), "repos/src/github.com/foo/bar", "exhaustive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("parseAnalyzer returned %d files, want 2: %v", len(failed), failed)
+	}
+
+	a := failed[0]
+	if a.Filename != "bar/a.go" || a.FileURL != "https://github.com/foo/bar/blob/master/a.go" {
+		t.Errorf("parseAnalyzer file = %q (%q), want bar/a.go", a.Filename, a.FileURL)
+	}
+	if len(a.Errors) != 2 {
+		t.Fatalf("parseAnalyzer a.go errors = %v, want 2", a.Errors)
+	}
+	if e := a.Errors[0]; e.LineNumber != 10 || e.Column != 2 || e.RuleID != "exhaustive" || e.ErrorString != "missing cases in switch of type bar.Color: bar.Blue, bar.Green" {
+		t.Errorf("parseAnalyzer error = %+v", e)
+	}
+	if b := failed[1]; b.Filename != "bar/sub/b.go" || b.Errors[0].LineNumber != 3 {
+		t.Errorf("parseAnalyzer file = %+v, want bar/sub/b.go line 3", b)
+	}
+}
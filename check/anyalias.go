@@ -0,0 +1,67 @@
+package check
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// AnyAlias flags uses of the empty interface literal interface{} where the
+// any alias could be used instead. Only modules whose go.mod targets Go 1.18
+// or newer are checked, since any does not exist before that.
+// The percentage is the fraction of empty interface uses that use any.
+func AnyAlias(dir string, filenames []string) (float64, []FileSummary, error) {
+	if !targetsGo(dir, 18) {
+		return 1, []FileSummary{}, nil
+	}
+
+	var (
+		failed     = []FileSummary{}
+		fset       = token.NewFileSet()
+		uses, anys int
+	)
+	for _, f := range filenames {
+		file, err := parser.ParseFile(fset, f, nil, 0)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+
+		fs := newFileSummary(dir, f)
+		var visit func(n ast.Node) bool
+		visit = func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				// x.any is a field or method, not the alias
+				ast.Inspect(n.X, visit)
+				return false
+			case *ast.Ident:
+				// identifiers resolved by the parser are declared in
+				// the file, so only unresolved ones can be the builtin
+				if n.Name == "any" && n.Obj == nil {
+					uses++
+					anys++
+				}
+			case *ast.InterfaceType:
+				if n.Methods == nil || len(n.Methods.List) == 0 {
+					uses++
+					fs.Errors = append(fs.Errors, Error{
+						LineNumber:  fset.Position(n.Pos()).Line,
+						ErrorString: "interface{} can be replaced by any",
+					})
+				}
+			}
+			return true
+		}
+		ast.Inspect(file, visit)
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	if uses == 0 {
+		return 1, failed, nil
+	}
+
+	return float64(anys) / float64(uses), failed, nil
+}
@@ -0,0 +1,62 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeModule writes the files, whose names may contain slashes, to a
+// temporary directory, with a go.mod targeting goVersion unless it is empty
+func writeModule(t *testing.T, goVersion string, files map[string]string) string {
+	dir := t.TempDir()
+	if goVersion != "" {
+		files["go.mod"] = "module example.com/m\n\ngo " + goVersion + "\n"
+	}
+	for name, src := range files {
+		fp := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var anyAliasTests = []struct {
+	goVersion string
+	src       string
+	percent   float64
+	lines     []int
+}{
+	{"1.20", "package m\n\nfunc f(x interface{}) {}\n", 0, []int{3}},
+	{"1.17", "package m\n\nfunc f(x interface{}) {}\n", 1, nil},
+	{"1.20", "package m\n\nfunc f(x any, y interface{}) {}\n", 0.5, []int{3}},
+	{"1.20", "package m\n\nfunc f(x any) {}\n", 1, nil},
+}
+
+func TestAnyAlias(t *testing.T) {
+	for _, tt := range anyAliasTests {
+		dir := writeModule(t, tt.goVersion, map[string]string{"a.go": tt.src})
+		p, fs, err := AnyAlias(dir, []string{filepath.Join(dir, "a.go")})
+		os.RemoveAll(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != tt.percent {
+			t.Errorf("[go %s] AnyAlias(%q) percent = %f, want %f", tt.goVersion, tt.src, p, tt.percent)
+		}
+		var lines []int
+		for _, s := range fs {
+			for _, e := range s.Errors {
+				lines = append(lines, e.LineNumber)
+			}
+		}
+		if len(lines) != len(tt.lines) || (len(lines) > 0 && lines[0] != tt.lines[0]) {
+			t.Errorf("[go %s] AnyAlias(%q) lines = %v, want %v", tt.goVersion, tt.src, lines, tt.lines)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package check
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// isPkgCall reports whether call is a call to pkg.name, for example
+// fmt.Errorf. Renamed imports are not taken into account.
+func isPkgCall(call *ast.CallExpr, pkg, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == pkg
+}
+
+// stringLit returns the value of a string literal expression
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// formatVerbs returns the verbs of a printf-style format string that
+// consume an argument, in order. A * width or precision consumes an
+// argument too, and is returned as '*'. ok is false for format strings
+// using explicit argument indexes, which are not supported.
+func formatVerbs(format string) (verbs []rune, ok bool) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		// skip flags, width and precision
+		for ; i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) != -1; i++ {
+			switch format[i] {
+			case '*':
+				verbs = append(verbs, '*')
+			case '[':
+				return nil, false
+			}
+		}
+		if i >= len(format) {
+			break
+		}
+		if format[i] == '%' {
+			// %% is a literal percent sign
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(format[i:])
+		verbs = append(verbs, r)
+		i += size - 1
+	}
+	return verbs, true
+}
+
+// errorType is the built-in error interface
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+// isError reports whether expr is an error value according to info.
+// Expressions without a type, because info is nil or their type could
+// not be resolved, fall back to looksLikeError.
+func isError(info *types.Info, expr ast.Expr) bool {
+	if info != nil {
+		if t := info.TypeOf(expr); t != nil && t != types.Typ[types.Invalid] {
+			return types.Implements(t, errorType)
+		}
+	}
+	return looksLikeError(expr)
+}
+
+// looksLikeError reports whether expr is named like an error value,
+// for example err, readErr or ErrNotFound
+func looksLikeError(expr ast.Expr) bool {
+	var name string
+	switch e := expr.(type) {
+	case *ast.Ident:
+		name = e.Name
+	case *ast.SelectorExpr:
+		name = e.Sel.Name
+	default:
+		return false
+	}
+
+	if name == "err" || strings.HasSuffix(name, "Err") {
+		return true
+	}
+	if strings.HasPrefix(name, "Err") && len(name) > 3 {
+		r, _ := utf8.DecodeRuneInString(name[3:])
+		return unicode.IsUpper(r)
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package check
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BaselineFile is the name of the file in the repo root that lists the
+// issues that existed when a repo adopted Go Report Card. These issues do
+// not count towards the grade.
+const BaselineFile = ".goreportcard-baseline.json"
+
+// Baseline is the content of a BaselineFile
+type Baseline struct {
+	Version int `json:"version"`
+	// Issues are the hashes of the issues, see issueHash. An issue that
+	// is reported several times is listed as many times.
+	Issues []string `json:"issues"`
+}
+
+// baselineVersion is the version of the BaselineFile format
+const baselineVersion = 1
+
+// issueHash returns the hash of an issue reported by the named check in
+// the file at rel, relative to the repo root. The line number is left
+// out, so issues stay in the baseline when the code around them changes.
+func issueHash(name, rel string, e Error) string {
+	sum := sha256.Sum256([]byte(name + "\x00" + rel + "\x00" + e.ErrorString))
+	return hex.EncodeToString(sum[:])
+}
+
+// RelFilename returns the name of the file in a file summary of the repo
+// in dir relative to the repo root, which does not depend on where the
+// repo was checked out
+func RelFilename(dir, filename string) string {
+	root := newFileSummary(dir, dir).Filename
+	return strings.TrimPrefix(filename, strings.TrimSuffix(root, "/")+"/")
+}
+
+// NewBaseline returns a baseline of all issues in the results of the
+// checks run on dir
+func NewBaseline(dir string, results []CheckResult) Baseline {
+	b := Baseline{Version: baselineVersion, Issues: []string{}}
+	for _, r := range results {
+		for _, fs := range r.FileSummaries {
+			rel := RelFilename(dir, fs.Filename)
+			for _, e := range fs.Errors {
+				b.Issues = append(b.Issues, issueHash(r.Name, rel, e))
+			}
+		}
+	}
+	sort.Strings(b.Issues)
+	return b
+}
+
+// WriteBaseline writes b to the BaselineFile in dir
+func WriteBaseline(dir string, b Baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, BaselineFile), append(data, '\n'), 0644)
+}
+
+// baselineSet counts the issues in a baseline by hash
+type baselineSet map[string]int
+
+// loadBaseline reads the BaselineFile in dir. A repo without one has an
+// empty baseline.
+func loadBaseline(dir string) (baselineSet, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, BaselineFile))
+	if os.IsNotExist(err) {
+		return baselineSet{}, nil
+	} else if err != nil {
+		return baselineSet{}, err
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return baselineSet{}, fmt.Errorf("%s: %v", BaselineFile, err)
+	}
+	if b.Version != baselineVersion {
+		return baselineSet{}, fmt.Errorf("%s: unsupported version %d", BaselineFile, b.Version)
+	}
+	set := make(baselineSet)
+	for _, h := range b.Issues {
+		set[h]++
+	}
+	return set, nil
+}
+
+// filter drops the issues of the named check that are in the baseline.
+// Like nolintIndex.filter, it returns the remaining file summaries, the
+// number of files that no longer have issues and the number of dropped
+// issues.
+func (set baselineSet) filter(dir, name string, summaries []FileSummary) (kept []FileSummary, cleared, dropped int) {
+	if len(set) == 0 {
+		return summaries, 0, 0
+	}
+
+	// checks are filtered concurrently, so count what was used here
+	// instead of changing the set
+	used := make(map[string]int)
+	kept = []FileSummary{}
+	for _, fs := range summaries {
+		rel := RelFilename(dir, fs.Filename)
+		var errs []Error
+		for _, e := range fs.Errors {
+			h := issueHash(name, rel, e)
+			if used[h] < set[h] {
+				used[h]++
+				dropped++
+				continue
+			}
+			errs = append(errs, e)
+		}
+		switch {
+		case len(errs) == len(fs.Errors):
+		case len(errs) == 0:
+			cleared++
+			continue
+		default:
+			fs.Errors = errs
+		}
+		kept = append(kept, fs)
+	}
+	return kept, cleared, dropped
+}
@@ -0,0 +1,110 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelFilename(t *testing.T) {
+	cases := []struct {
+		dir, path, want string
+	}{
+		{"repos/src/github.com/foo/bar", "repos/src/github.com/foo/bar/sub/a.go", "sub/a.go"},
+		{"repos/src/gitlab.com/foo/bar", "repos/src/gitlab.com/foo/bar/a.go", "a.go"},
+		{"/home/grc/bar", "/home/grc/bar/a.go", "a.go"},
+	}
+	for _, tt := range cases {
+		if got := RelFilename(tt.dir, newFileSummary(tt.dir, tt.path).Filename); got != tt.want {
+			t.Errorf("[%q] RelFilename(%q) = %q, want %q", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBaselineFilter(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	fs := newFileSummary(dir, dir+"/a.go")
+	fs.Errors = []Error{
+		{LineNumber: 3, ErrorString: "old"},
+		{LineNumber: 5, ErrorString: "old"},
+	}
+	b := NewBaseline(dir, []CheckResult{{Name: "vet", FileSummaries: []FileSummary{fs}}})
+	if len(b.Issues) != 2 {
+		t.Fatalf("NewBaseline has %d issues, want 2", len(b.Issues))
+	}
+	set := make(baselineSet)
+	for _, h := range b.Issues {
+		set[h]++
+	}
+
+	// the code moved, and a third copy of the issue and a new issue
+	// were added
+	fs.Errors = []Error{
+		{LineNumber: 4, ErrorString: "old"},
+		{LineNumber: 6, ErrorString: "old"},
+		{LineNumber: 8, ErrorString: "old"},
+		{LineNumber: 9, ErrorString: "new"},
+	}
+	kept, cleared, dropped := set.filter(dir, "vet", []FileSummary{fs})
+	if dropped != 2 || cleared != 0 {
+		t.Errorf("filter dropped %d issues and cleared %d files, want 2 and 0", dropped, cleared)
+	}
+	if len(kept) != 1 || len(kept[0].Errors) != 2 || kept[0].Errors[0].LineNumber != 8 {
+		t.Errorf("filter kept %+v, want lines 8 and 9", kept)
+	}
+
+	// the baseline only applies to the check it was made for
+	if _, _, dropped := set.filter(dir, "golint", []FileSummary{fs}); dropped != 0 {
+		t.Errorf("filter of another check dropped %d issues, want 0", dropped)
+	}
+}
+
+func TestRunBaseline(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{"a.go": "package a\n"})
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a.go")
+	ctx := WithFilenames(context.Background(), []string{a})
+
+	fs := newFileSummary(dir, a)
+	fs.Errors = []Error{{LineNumber: 1, ErrorString: "legacy"}}
+	ck := fileScoredCheck{fixedCheck{"fixed", 0, []FileSummary{fs}}}
+
+	results := Checker{}.run(ctx, dir, []Check{ck})
+	if err := WriteBaseline(dir, NewBaseline(dir, results)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Checker{}.run(ctx, dir, []Check{ck})[0]
+	if r.Baselined != 1 || r.Percentage != 1 || len(r.FileSummaries) != 0 {
+		t.Errorf("run with baseline = %+v, want the issue baselined", r)
+	}
+	r = Checker{NoBaseline: true}.run(ctx, dir, []Check{ck})[0]
+	if r.Baselined != 0 || r.Percentage != 0 {
+		t.Errorf("run with NoBaseline = %+v, want the issue reported", r)
+	}
+}
+
+func TestRunBaselineKeepsPercentage(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{"a.go": "package a\n", "b.go": "package a\n"})
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	ctx := WithFilenames(context.Background(), []string{a, b})
+
+	// like the secrets check, any issue grades the whole repo 0
+	fsA, fsB := newFileSummary(dir, a), newFileSummary(dir, b)
+	fsA.Errors = []Error{{LineNumber: 1, ErrorString: "secret"}}
+	fsB.Errors = []Error{{LineNumber: 1, ErrorString: "secret"}}
+	old := fixedCheck{"fixed", 0, []FileSummary{fsA}}
+	if err := WriteBaseline(dir, NewBaseline(dir, Checker{}.run(ctx, dir, []Check{old}))); err != nil {
+		t.Fatal(err)
+	}
+
+	ck := fixedCheck{"fixed", 0, []FileSummary{fsA, fsB}}
+	r := Checker{}.run(ctx, dir, []Check{ck})[0]
+	if r.Baselined != 1 || len(r.FileSummaries) != 1 {
+		t.Errorf("run with baseline = %+v, want the issue of a.go baselined", r)
+	}
+	if r.Percentage != 0 {
+		t.Errorf("run percentage = %v, want the percentage of the check, 0", r.Percentage)
+	}
+}
@@ -0,0 +1,172 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gojp/goreportcard/download"
+)
+
+// BloatMaxModules is the number of required modules above which a
+// dependency tree is considered heavy
+var BloatMaxModules = 100
+
+// BloatMaxDownloadSize is the total size in bytes of the module zips
+// above which a dependency tree is considered heavy
+var BloatMaxDownloadSize int64 = 100 << 20
+
+// zipSizes caches the size of module zips by module@version, as a
+// published version never changes
+var zipSizes = struct {
+	sync.Mutex
+	sizes map[string]int64
+}{sizes: make(map[string]int64)}
+
+// DependencyStats describes the module graph of a repo
+type DependencyStats struct {
+	Direct   int `json:"direct"`
+	Indirect int `json:"indirect"`
+	// DownloadSize is the total size in bytes of the zips of all
+	// required modules on the module proxy
+	DownloadSize int64 `json:"download_size"`
+}
+
+// Modules returns the number of required modules
+func (s DependencyStats) Modules() int {
+	return s.Direct + s.Indirect
+}
+
+// zipSize returns the size of the zip of path at version on the proxy
+func zipSize(ctx context.Context, path, version string) (int64, error) {
+	key := path + "@" + version
+	zipSizes.Lock()
+	size, ok := zipSizes.sizes[key]
+	zipSizes.Unlock()
+	if ok {
+		return size, nil
+	}
+
+	resp, err := proxyRequest(ctx, "HEAD", download.EscapeModulePath(path)+"/@v/"+download.EscapeModulePath(version)+".zip")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s: proxy returned %s", key, resp.Status)
+	}
+
+	zipSizes.Lock()
+	zipSizes.sizes[key] = resp.ContentLength
+	zipSizes.Unlock()
+	return resp.ContentLength, nil
+}
+
+// DependencyStats returns the size of the module graph of the module in
+// dir. Since Go 1.17 go.mod lists every module needed to build the main
+// module, so the requirements are the whole graph. A dir without go.mod
+// has no dependencies.
+func (c Checker) DependencyStats(ctx context.Context, dir string) (DependencyStats, error) {
+	var stats DependencyStats
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if os.IsNotExist(err) {
+		return stats, nil
+	} else if err != nil {
+		return stats, err
+	}
+
+	reqs := parseRequires(data)
+	var paths []string
+	for path, req := range reqs {
+		if req.indirect {
+			stats.Indirect++
+		} else {
+			stats.Direct++
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var (
+		sizes = make([]int64, len(paths))
+		errs  = make([]error, len(paths))
+		wg    sync.WaitGroup
+		sem   = make(chan bool, outdatedMaxRequests)
+	)
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- true
+			defer func() { <-sem }()
+			sizes[i], errs[i] = zipSize(ctx, path, reqs[path].version)
+		}(i, path)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return stats, ctx.Err()
+	}
+	for i := range paths {
+		if errs[i] != nil {
+			// a missing zip only makes the total less accurate
+			c.logger().Log("could not get module size", "module", paths[i], "error", errs[i])
+			continue
+		}
+		stats.DownloadSize += sizes[i]
+	}
+	return stats, nil
+}
+
+// Bloat is the check for unusually heavy dependency trees
+type Bloat struct{}
+
+// Name returns the name of the display name of the command
+func (g Bloat) Name() string {
+	return "dependency_bloat"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Bloat) Weight() float64 {
+	return 0
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Bloat) Severity() string {
+	return SeverityInfo
+}
+
+// Run returns 1 if the number of required modules and their
+// download size are below BloatMaxModules and BloatMaxDownloadSize, and
+// 0.5 for each limit that is exceeded otherwise
+func (g Bloat) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	stats, err := Checker{}.DependencyStats(ctx, dir)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	var errs []Error
+	if stats.Modules() > BloatMaxModules {
+		errs = append(errs, Error{ErrorString: fmt.Sprintf("%d required modules (%d direct, %d indirect), more than %d", stats.Modules(), stats.Direct, stats.Indirect, BloatMaxModules)})
+	}
+	if stats.DownloadSize > BloatMaxDownloadSize {
+		errs = append(errs, Error{ErrorString: fmt.Sprintf("required modules are %d MB to download, more than %d MB", stats.DownloadSize>>20, BloatMaxDownloadSize>>20)})
+	}
+	if len(errs) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	fs := newFileSummary(dir, filepath.Join(dir, "go.mod"))
+	fs.Errors = errs
+	return 1 - .5*float64(len(errs)), []FileSummary{fs}, nil
+}
+
+// Description returns the description of Bloat
+func (g Bloat) Description() string {
+	return fmt.Sprintf("Counts the modules required by <code>go.mod</code> and the size of their downloads from the Go module proxy, and warns about dependency trees with more than %d modules or more than %d MB. This check does not count towards the grade.", BloatMaxModules, BloatMaxDownloadSize>>20)
+}
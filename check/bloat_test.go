@@ -0,0 +1,55 @@
+package check
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBloat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ".zip") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", "3145728")
+	}))
+	defer srv.Close()
+	defer func(p string) { ModuleProxy = p }(ModuleProxy)
+	ModuleProxy = srv.URL
+	defer func(m int) { BloatMaxModules = m }(BloatMaxModules)
+	BloatMaxModules = 2
+
+	dir := writeModule(t, "", map[string]string{"go.mod": `module example.com/m
+
+require (
+	github.com/a/a v1.0.0
+	github.com/b/b v1.0.0 // indirect
+	github.com/c/c v1.0.0 // indirect
+)
+`})
+	defer os.RemoveAll(dir)
+
+	stats, err := Checker{}.DependencyStats(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DependencyStats{Direct: 1, Indirect: 2, DownloadSize: 3 * 3 << 20}
+	if stats != want {
+		t.Errorf("DependencyStats = %+v, want %+v", stats, want)
+	}
+
+	p, failed, err := Bloat{}.Run(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != .5 || len(failed) != 1 {
+		t.Fatalf("Bloat = %v, %v, want 0.5 with 1 error", p, failed)
+	}
+	if e := failed[0].Errors[0].ErrorString; e != "3 required modules (1 direct, 2 indirect), more than 2" {
+		t.Errorf("Bloat error = %q", e)
+	}
+}
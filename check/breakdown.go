@@ -0,0 +1,90 @@
+package check
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// PackageScore is the score of a package of a repo, computed like the
+// score of the repo from the checks whose issues are in its files
+type PackageScore struct {
+	// Path is the import path of the package, or its directory relative
+	// to the repo if the go command could not list it
+	Path string `json:"path"`
+	// Dir is the directory of the package relative to the repo, with
+	// forward slashes
+	Dir   string `json:"dir"`
+	Files int    `json:"files"`
+	// Issues is the number of issues found in the files of the package
+	Issues     int     `json:"issues"`
+	Percentage float64 `json:"percentage"`
+}
+
+// PackageScores returns the scores of pkgs in the results of the checks
+// run on dir, lowest first. For every check, a package gets the share of
+// its files in which the check found no issues. Checks that failed, and
+// checks that reported issues outside of the Go files of pkgs, such as
+// in go.mod or the license, grade the repo as a whole and are left out.
+func PackageScores(dir string, pkgs []Package, results []CheckResult) []PackageScore {
+	// the package of each file, by its name in file summaries
+	pkgOf := make(map[string]int)
+	scores := make([]PackageScore, len(pkgs))
+	for i, p := range pkgs {
+		files := p.Files()
+		rel, err := filepath.Rel(dir, p.Dir)
+		if err != nil {
+			rel = p.Dir
+		}
+		rel = filepath.ToSlash(rel)
+		scores[i] = PackageScore{Path: p.PkgPath, Dir: rel, Files: len(files)}
+		if scores[i].Path == "" {
+			scores[i].Path = rel
+		}
+		for _, f := range files {
+			pkgOf[newFileSummary(dir, f).Filename] = i
+		}
+	}
+
+	total := make([]float64, len(pkgs))
+	var totalWeight float64
+	for _, r := range results {
+		if r.Error != "" || !inPackages(pkgOf, r.FileSummaries) {
+			continue
+		}
+		failed := make([]int, len(pkgs))
+		for _, fs := range r.FileSummaries {
+			i := pkgOf[fs.Filename]
+			failed[i]++
+			scores[i].Issues += len(fs.Errors)
+		}
+		for i, s := range scores {
+			if s.Files > 0 {
+				total[i] += r.Weight * float64(s.Files-failed[i]) / float64(s.Files)
+			}
+		}
+		totalWeight += r.Weight
+	}
+	for i := range scores {
+		if totalWeight > 0 {
+			scores[i].Percentage = total[i] / totalWeight
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Percentage != scores[j].Percentage {
+			return scores[i].Percentage < scores[j].Percentage
+		}
+		return scores[i].Path < scores[j].Path
+	})
+	return scores
+}
+
+// inPackages reports whether all summaries are of files in pkgOf
+func inPackages(pkgOf map[string]int, summaries []FileSummary) bool {
+	for _, fs := range summaries {
+		if _, ok := pkgOf[fs.Filename]; !ok {
+			return false
+		}
+	}
+	return true
+}
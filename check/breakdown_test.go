@@ -0,0 +1,39 @@
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageScores(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	pkgs := []Package{
+		{PkgPath: "github.com/foo/bar", Dir: dir, GoFiles: []string{dir + "/a.go", dir + "/b.go"}},
+		{PkgPath: "github.com/foo/bar/sub", Dir: dir + "/sub", GoFiles: []string{dir + "/sub/c.go"}},
+		{Dir: dir + "/broken", GoFiles: []string{dir + "/broken/d.go"}},
+	}
+	issue := func(f string, n int) FileSummary {
+		fs := newFileSummary(dir, dir+"/"+f)
+		for i := 0; i < n; i++ {
+			fs.Errors = append(fs.Errors, Error{LineNumber: i + 1})
+		}
+		return fs
+	}
+	results := []CheckResult{
+		{Name: "gofmt", Weight: 0.75, FileSummaries: []FileSummary{issue("a.go", 2), issue("broken/d.go", 1)}},
+		{Name: "go_vet", Weight: 0.25, FileSummaries: []FileSummary{issue("sub/c.go", 1)}},
+		// grades the repo as a whole
+		{Name: "license", Weight: 0.1, FileSummaries: []FileSummary{{Filename: "LICENSE"}}},
+		{Name: "errcheck", Weight: 0.5, Error: "failed", FileSummaries: []FileSummary{}},
+	}
+
+	got := PackageScores(dir, pkgs, results)
+	want := []PackageScore{
+		{Path: "broken", Dir: "broken", Files: 1, Issues: 1, Percentage: 0.25},
+		{Path: "github.com/foo/bar", Dir: ".", Files: 2, Issues: 2, Percentage: 0.625},
+		{Path: "github.com/foo/bar/sub", Dir: "sub", Files: 1, Issues: 1, Percentage: 0.75},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageScores = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package check
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// Cache stores the issues that checks found in files, keyed by a hash
+// of the contents of the file, so that only the files that changed are
+// checked again when a repo is graded again. Implementations must be
+// safe for concurrent use.
+type Cache interface {
+	// Load returns the values stored for those of keys that are in the
+	// cache
+	Load(keys []string) (map[string][]byte, error)
+	// Store stores values by key, evicting other values if the cache
+	// is full
+	Store(values map[string][]byte) error
+}
+
+// cacheVersion is part of every cache key, so that issues cached by
+// older versions of the checks are not used
+const cacheVersion = "1"
+
+// fileCacher is implemented by checks whose issues in a file only depend
+// on the contents of that file, so that they can be cached per file.
+// CacheKey identifies the check and its settings, such as thresholds.
+type fileCacher interface {
+	CacheKey() string
+}
+
+// cachedFile are the issues that a check found in a file
+type cachedFile struct {
+	Errors []Error `json:"errors,omitempty"`
+	// Suppressed is the number of issues dropped because of //nolint
+	// comments
+	Suppressed int64 `json:"suppressed,omitempty"`
+}
+
+// hashFiles returns the sha256 hashes of the contents of the files,
+// leaving out files that could not be read
+func hashFiles(filenames []string) map[string]string {
+	hashes := make(map[string]string, len(filenames))
+	for _, fp := range filenames {
+		f, err := os.Open(fp)
+		if err != nil {
+			continue
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err == nil {
+			hashes[fp] = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+	return hashes
+}
+
+// cacheKey returns the key of the issues that the check with the given
+// key found in the file at rel, relative to the repo root, with the
+// given hash
+func cacheKey(checkKey, rel, hash string) string {
+	sum := sha256.Sum256([]byte(cacheVersion + "\x00" + checkKey + "\x00" + rel + "\x00" + hash))
+	return hex.EncodeToString(sum[:])
+}
+
+// runCached runs ck, which must be a fileCacher, on the files whose issues
+// are not in the cache, one file at a time, and stores their issues. The
+// percentage of files without issues is returned together with the number
+// of files that were not checked again. Single files are graded by lines,
+// so they are not cached.
+func (c Checker) runCached(ctx context.Context, dir string, ck Check, hashes map[string]string) (float64, []FileSummary, int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, []FileSummary{}, 0, err
+	}
+	filenames := Filenames(ctx)
+	if len(filenames) < 2 {
+		p, summaries, err := c.runWithTimeout(ctx, dir, ck)
+		return p, summaries, 0, err
+	}
+
+	checkKey := ck.(fileCacher).CacheKey()
+	keys := make(map[string]string, len(filenames))
+	var lookup []string
+	for _, fp := range filenames {
+		if hash, ok := hashes[fp]; ok {
+			keys[fp] = cacheKey(checkKey, RelFilename(dir, newFileSummary(dir, fp).Filename), hash)
+			lookup = append(lookup, keys[fp])
+		}
+	}
+	stored, err := c.Cache.Load(lookup)
+	if err != nil {
+		c.logger().Log("could not load cached issues", "check", ck.Name(), "dir", dir, "error", err)
+	}
+
+	var (
+		failed  = []FileSummary{}
+		found   = make(map[string][]byte)
+		hits    int
+		timeout = c.timeout(ck)
+	)
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for _, fp := range filenames {
+		var cf cachedFile
+		if data, ok := stored[keys[fp]]; ok && json.Unmarshal(data, &cf) == nil {
+			hits++
+		} else {
+			fileCtx, suppressed := withSuppressed(WithFilenames(checkCtx, []string{fp}))
+			_, summaries, err := ck.Run(fileCtx, dir)
+			if checkCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				return 0, []FileSummary{}, hits, timeoutError{timeout}
+			}
+			if err != nil {
+				return 0, []FileSummary{}, hits, err
+			}
+			for _, fs := range summaries {
+				cf.Errors = append(cf.Errors, fs.Errors...)
+			}
+			cf.Suppressed = *suppressed
+			if key, ok := keys[fp]; ok {
+				if data, err := json.Marshal(cf); err == nil {
+					found[key] = data
+				}
+			}
+		}
+
+		suppressN(ctx, cf.Suppressed)
+		if len(cf.Errors) > 0 {
+			fs := newFileSummary(dir, fp)
+			fs.Errors = cf.Errors
+			failed = append(failed, fs)
+		}
+	}
+
+	if len(found) > 0 {
+		if err := c.Cache.Store(found); err != nil {
+			c.logger().Log("could not store cached issues", "check", ck.Name(), "dir", dir, "error", err)
+		}
+	}
+	p, failed, err := toolPercentage(filenames, failed)
+	return p, failed, hits, err
+}
@@ -0,0 +1,104 @@
+package check
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// mapCache is a Cache in memory
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func (c *mapCache) Load(keys []string) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	found := make(map[string][]byte)
+	for _, k := range keys {
+		if v, ok := c.values[k]; ok {
+			found[k] = v
+		}
+	}
+	return found, nil
+}
+
+func (c *mapCache) Store(values map[string][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = make(map[string][]byte)
+	}
+	for k, v := range values {
+		c.values[k] = v
+	}
+	return nil
+}
+
+// countingGodox is the godox check counting the files it was run on
+type countingGodox struct {
+	Godox
+	mu    *sync.Mutex
+	files *int
+}
+
+func (g countingGodox) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	g.mu.Lock()
+	*g.files += len(Filenames(ctx))
+	g.mu.Unlock()
+	return g.Godox.Run(ctx, dir)
+}
+
+func TestCheckerCache(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": "package m\n\n// TODO: a\nfunc a() {}\n",
+		"b.go": "package m\n\nfunc b() {}\n",
+		"c.go": "package m\n\n// FIXME: c\n// TODO: c\nfunc c() {}\n",
+	})
+	defer os.RemoveAll(dir)
+	files := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go"), filepath.Join(dir, "c.go")}
+
+	var n int
+	ck := countingGodox{mu: &sync.Mutex{}, files: &n}
+	c := Checker{Cache: &mapCache{}}
+	grade := func() CheckResult {
+		n = 0
+		return c.run(WithFilenames(context.Background(), files), dir, []Check{ck})[0]
+	}
+
+	cases := []struct {
+		name    string
+		change  string
+		checked int
+		issues  int
+	}{
+		{"first run", "", 3, 3},
+		{"unchanged", "", 0, 3},
+		{"changed file", "package m\n\nfunc b() {} // HACK\n", 1, 4},
+	}
+	for _, tt := range cases {
+		if tt.change != "" {
+			if err := ioutil.WriteFile(files[1], []byte(tt.change), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r := grade()
+		if r.Error != "" {
+			t.Fatalf("[%s] check failed: %s", tt.name, r.Error)
+		}
+		if n != tt.checked {
+			t.Errorf("[%s] checked %d files, want %d", tt.name, n, tt.checked)
+		}
+		var issues int
+		for _, fs := range r.FileSummaries {
+			issues += len(fs.Errors)
+		}
+		if issues != tt.issues {
+			t.Errorf("[%s] got %d issues, want %d", tt.name, issues, tt.issues)
+		}
+	}
+}
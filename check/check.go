@@ -1,12 +1,52 @@
 package check
 
+import "context"
+
 // Check describes what methods various checks (gofmt, go lint, etc.)
 // should implement
 type Check interface {
 	Name() string
 	Description() string
 	Weight() float64
-	// Percentage returns the passing percentage of the check,
-	// as well as a map of filename to output
-	Percentage() (float64, []FileSummary, error)
+	// Run runs the check on the repo in dir, and returns the passing
+	// percentage of the check, as well as the files with issues. The
+	// Go files to check are in ctx, see WithFilenames.
+	Run(ctx context.Context, dir string) (float64, []FileSummary, error)
+}
+
+type filenamesKey struct{}
+
+// WithFilenames returns a copy of ctx that carries the Go files that
+// checks are run on, as returned by GoFiles
+func WithFilenames(ctx context.Context, filenames []string) context.Context {
+	return context.WithValue(ctx, filenamesKey{}, filenames)
+}
+
+// Filenames returns the Go files to check carried by ctx
+func Filenames(ctx context.Context) []string {
+	filenames, _ := ctx.Value(filenamesKey{}).([]string)
+	return filenames
+}
+
+// CategoryPerformance is the category of checks that give performance
+// hints. They are shown in their own section of the report.
+const CategoryPerformance = "performance"
+
+// CategorySecurity is the category of checks that find security issues.
+// They are shown in their own section of the report.
+const CategorySecurity = "security"
+
+// Categorizer is implemented by checks that belong in a section of the
+// report other than the main results
+type Categorizer interface {
+	Category() string
+}
+
+// category returns the category of ck, or an empty string for the
+// main results
+func category(ck Check) string {
+	if c, ok := ck.(Categorizer); ok {
+		return c.Category()
+	}
+	return ""
 }
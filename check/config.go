@@ -0,0 +1,252 @@
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigFile is the name of the per repo config file in the repo root
+const ConfigFile = ".goreportcard.yml"
+
+// RepoConfig is the per repo configuration read from ConfigFile
+type RepoConfig struct {
+	// Enable lists the opt-in checks to run, by name
+	Enable []string
+	// Disable lists the checks not to run, by name
+	Disable []string
+	// Thresholds sets the limits of checks that have one, such as the
+	// complexity above which gocyclo reports a function, by check name
+	Thresholds map[string]int
+	// Weights sets the weights of checks in the overall average, by
+	// check name
+	Weights map[string]float64
+	// Skip lists globs of files that are not checked, relative to the
+	// repo root, in addition to the vendored and generated files. A
+	// pattern without a slash matches the file name in any directory,
+	// and a pattern ending in /** matches everything below a directory.
+	Skip []string
+	// ExcludeExported leaves exported identifiers out of the unused
+	// check, for libraries whose API is used by other modules
+	ExcludeExported bool
+}
+
+// thresholder is implemented by checks with a limit that repos can
+// change in their ConfigFile
+type thresholder interface {
+	WithThreshold(n int) Check
+}
+
+// enabled reports whether the opt-in check name is enabled
+func (c RepoConfig) enabled(name string) bool {
+	for _, n := range c.Enable {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// disabled reports whether the check name is disabled
+func (c RepoConfig) disabled(name string) bool {
+	for _, n := range c.Disable {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// skips reports whether the file at path in the repo in dir matches one
+// of the skip globs
+func (c RepoConfig) skips(dir, fp string) bool {
+	rel, err := filepath.Rel(dir, fp)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range c.Skip {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the slash separated path rel matches pattern,
+// as described for RepoConfig.Skip
+func matchGlob(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if strings.HasSuffix(pattern, "/**") {
+		prefix := strings.TrimSuffix(pattern, "/**")
+		dir := rel
+		for dir != "." && dir != "/" {
+			dir = path.Dir(dir)
+			if ok, _ := path.Match(prefix, dir); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(pattern, rel)
+	return ok
+}
+
+// Settings describes the settings in c, for showing on the report
+func (c RepoConfig) Settings() []string {
+	var settings []string
+	for _, name := range c.Enable {
+		settings = append(settings, "enabled "+name)
+	}
+	for _, name := range c.Disable {
+		settings = append(settings, "disabled "+name)
+	}
+	var names []string
+	for name := range c.Thresholds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		settings = append(settings, fmt.Sprintf("%s threshold %d", name, c.Thresholds[name]))
+	}
+	names = nil
+	for name := range c.Weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		settings = append(settings, fmt.Sprintf("%s weight %v", name, c.Weights[name]))
+	}
+	for _, pattern := range c.Skip {
+		settings = append(settings, "skipped "+pattern)
+	}
+	if c.ExcludeExported {
+		settings = append(settings, "unused excluding exported identifiers")
+	}
+	return settings
+}
+
+// stringList returns the value of key in the parsed config as a list.
+// A single string is a list with one element.
+func stringList(doc map[string]interface{}, key string) ([]string, error) {
+	switch v := doc[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s: %s must be a list", ConfigFile, key)
+	}
+}
+
+// LoadRepoConfig reads the ConfigFile in dir. A repo without one has the
+// zero config.
+func LoadRepoConfig(dir string) (RepoConfig, error) {
+	var cfg RepoConfig
+	data, err := ioutil.ReadFile(filepath.Join(dir, ConfigFile))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, err
+	}
+
+	doc, err := parseYAML(data)
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %v", ConfigFile, err)
+	}
+	if cfg.Enable, err = stringList(doc, "enable"); err != nil {
+		return cfg, err
+	}
+	if cfg.Disable, err = stringList(doc, "disable"); err != nil {
+		return cfg, err
+	}
+	if cfg.Skip, err = stringList(doc, "skip"); err != nil {
+		return cfg, err
+	}
+	for _, pattern := range cfg.Skip {
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+			return cfg, fmt.Errorf("%s: invalid skip pattern %q", ConfigFile, pattern)
+		}
+	}
+	if cfg.Thresholds, err = thresholds(doc); err != nil {
+		return cfg, err
+	}
+	if cfg.Weights, err = weights(doc); err != nil {
+		return cfg, err
+	}
+	if cfg.ExcludeExported, err = excludeExported(doc); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// excludeExported returns the exclude_exported setting of the unused
+// mapping of the parsed config
+func excludeExported(doc map[string]interface{}) (bool, error) {
+	var m map[string]string
+	switch v := doc["unused"].(type) {
+	case nil:
+		return false, nil
+	case map[string]string:
+		m = v
+	default:
+		return false, fmt.Errorf("%s: unused must be a mapping of settings", ConfigFile)
+	}
+
+	var exclude bool
+	for k, v := range m {
+		if k != "exclude_exported" {
+			return false, fmt.Errorf("%s: unused has no setting %s", ConfigFile, k)
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("%s: exclude_exported of unused must be true or false", ConfigFile)
+		}
+		exclude = b
+	}
+	return exclude, nil
+}
+
+// thresholds returns the thresholds mapping of the parsed config, which
+// may only name checks that have a threshold
+func thresholds(doc map[string]interface{}) (map[string]int, error) {
+	var m map[string]string
+	switch v := doc["thresholds"].(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		m = v
+	default:
+		return nil, fmt.Errorf("%s: thresholds must be a mapping of check names to numbers", ConfigFile)
+	}
+
+	limited := make(map[string]bool)
+	for _, ck := range append(Checks(), OptionalChecks()...) {
+		if _, ok := ck.(thresholder); ok {
+			limited[ck.Name()] = true
+		}
+	}
+
+	result := make(map[string]int)
+	for name, v := range m {
+		if !limited[name] {
+			return nil, fmt.Errorf("%s: check %s has no threshold", ConfigFile, name)
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%s: threshold of %s must be a positive number", ConfigFile, name)
+		}
+		result[name] = n
+	}
+	return result, nil
+}
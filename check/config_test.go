@@ -0,0 +1,204 @@
+package check
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var parseYAMLTests = []struct {
+	src  string
+	want map[string]interface{}
+}{
+	{"", map[string]interface{}{}},
+	{"enable: [library_exits, 'foo']\n", map[string]interface{}{"enable": []string{"library_exits", "foo"}}},
+	{"# comment\nenable:\n  - library_exits # why\n  - \"foo\"\n", map[string]interface{}{"enable": []string{"library_exits", "foo"}}},
+	{"name: \"a # b\"\n", map[string]interface{}{"name": "a # b"}},
+	{"thresholds:\n  gocyclo: 15\n  dupl: 100\n", map[string]interface{}{"thresholds": map[string]string{"gocyclo": "15", "dupl": "100"}}},
+	{"enable: []\n", map[string]interface{}{"enable": []string{}}},
+}
+
+func TestParseYAML(t *testing.T) {
+	for _, tt := range parseYAMLTests {
+		got, err := parseYAML([]byte(tt.src))
+		if err != nil {
+			t.Errorf("[%q] parseYAML error: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%q] parseYAML = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, src := range []string{
+		"  - indented first\n",
+		"enable: [a, b\n",
+		"no colon\n",
+		"enable:\n  - a\n  b: c\n",
+	} {
+		if _, err := parseYAML([]byte(src)); err == nil {
+			t.Errorf("[%q] parseYAML error = nil, want error", src)
+		}
+	}
+}
+
+func TestConfiguredChecks(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{ConfigFile: "enable: [library_exits]\n"})
+	defer os.RemoveAll(dir)
+
+	cfg, err := LoadRepoConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := ConfiguredChecks(cfg)
+	if n := len(checks) - len(Checks()); n != 1 {
+		t.Fatalf("ConfiguredChecks added %d checks, want 1", n)
+	}
+	if name := checks[len(checks)-1].Name(); name != "library_exits" {
+		t.Errorf("ConfiguredChecks last check = %q, want library_exits", name)
+	}
+
+	if checks := ConfiguredChecks(RepoConfig{}); len(checks) != len(Checks()) {
+		t.Errorf("ConfiguredChecks with zero config added checks")
+	}
+}
+
+func TestConfiguredChecksReplace(t *testing.T) {
+	checks := ConfiguredChecks(RepoConfig{Enable: []string{"gofumpt"}})
+	if len(checks) != len(Checks()) {
+		t.Fatalf("ConfiguredChecks with gofumpt returned %d checks, want %d", len(checks), len(Checks()))
+	}
+	for i, ck := range Checks() {
+		want := ck.Name()
+		if want == "gofmt" {
+			want = "gofumpt"
+		}
+		if got := checks[i].Name(); got != want {
+			t.Errorf("ConfiguredChecks check %d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register of an existing check did not panic")
+		}
+	}()
+	Register(GoFmt{})
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{ConfigFile: `disable: [gocyclo]
+thresholds:
+  dupl: 100
+  nakedret: 10
+weights:
+  gofmt: 0.5
+skip:
+  - "internal/gen/**"
+  - "*_mock.go"
+unused:
+  exclude_exported: true
+`})
+	defer os.RemoveAll(dir)
+
+	cfg, err := LoadRepoConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RepoConfig{
+		Disable:         []string{"gocyclo"},
+		Thresholds:      map[string]int{"dupl": 100, "nakedret": 10},
+		Weights:         map[string]float64{"gofmt": 0.5},
+		Skip:            []string{"internal/gen/**", "*_mock.go"},
+		ExcludeExported: true,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadRepoConfig = %#v, want %#v", cfg, want)
+	}
+
+	wantSettings := []string{"disabled gocyclo", "dupl threshold 100", "nakedret threshold 10", "gofmt weight 0.5", "skipped internal/gen/**", "skipped *_mock.go", "unused excluding exported identifiers"}
+	if got := cfg.Settings(); !reflect.DeepEqual(got, wantSettings) {
+		t.Errorf("Settings = %q, want %q", got, wantSettings)
+	}
+
+	checks := ConfiguredChecks(cfg)
+	if len(checks) != len(Checks())-1 {
+		t.Errorf("ConfiguredChecks returned %d checks, want %d", len(checks), len(Checks())-1)
+	}
+	for _, ck := range checks {
+		switch c := ck.(type) {
+		case GoCyclo:
+			t.Errorf("ConfiguredChecks did not disable gocyclo")
+		case Dupl:
+			if c.Threshold != 100 {
+				t.Errorf("ConfiguredChecks dupl threshold = %d, want 100", c.Threshold)
+			}
+		case NakedRet:
+			if c.MaxLength != 10 {
+				t.Errorf("ConfiguredChecks nakedret threshold = %d, want 10", c.MaxLength)
+			}
+		}
+	}
+}
+
+func TestLoadRepoConfigErrors(t *testing.T) {
+	for _, src := range []string{
+		"thresholds:\n  license: 3\n",
+		"thresholds:\n  dupl: many\n",
+		"thresholds: [dupl]\n",
+		"weights:\n  nosuchcheck: 1\n",
+		"weights:\n  gofmt: -1\n",
+		"skip: ['[']\n",
+		"unused:\n  exclude_exported: maybe\n",
+		"unused:\n  exclude_unexported: true\n",
+	} {
+		dir := writeModule(t, "", map[string]string{ConfigFile: src})
+		if _, err := LoadRepoConfig(dir); err == nil {
+			t.Errorf("[%q] LoadRepoConfig error = nil, want error", src)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*_mock.go", "a_mock.go", true},
+		{"*_mock.go", "sub/dir/a_mock.go", true},
+		{"*_mock.go", "a.go", false},
+		{"internal/gen/**", "internal/gen/a.go", true},
+		{"internal/gen/**", "internal/gen/sub/a.go", true},
+		{"internal/gen/**", "internal/a.go", false},
+		{"./legacy/*.go", "legacy/a.go", true},
+		{"legacy/*.go", "legacy/sub/a.go", false},
+	}
+	for _, tt := range cases {
+		if got := matchGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("[%q] matchGlob(%q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestGoFilesSkip(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		ConfigFile:  "skip: [\"*_mock.go\"]\n",
+		"a.go":      "package a\n",
+		"a_mock.go": "package a\n",
+	})
+	defer os.RemoveAll(dir)
+
+	files, skipped, err := GoFiles(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || len(skipped) != 1 {
+		t.Errorf("GoFiles = %v, skipped %v, want only a.go", files, skipped)
+	}
+}
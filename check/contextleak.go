@@ -0,0 +1,163 @@
+package check
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// contextFuncs are the functions in the context package that return
+// a cancel function which must be called
+var contextFuncs = []string{
+	"WithCancel",
+	"WithTimeout",
+	"WithDeadline",
+	"WithCancelCause",
+	"WithTimeoutCause",
+	"WithDeadlineCause",
+}
+
+// contextCall returns the name of the context function called by expr,
+// if it is one of contextFuncs
+func contextCall(expr ast.Expr) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", false
+	}
+	for _, name := range contextFuncs {
+		if isPkgCall(call, "context", name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// contextLeak is a context creation site whose cancel function is
+// not used
+type contextLeak struct {
+	pos token.Pos
+	fn  string
+}
+
+// findContextLeaks returns the number of context creation sites in the
+// function body, and those that leak. A cancel function that is called,
+// deferred, returned, stored or passed on anywhere in the function after
+// its creation counts as used. Function literals inside body are not
+// searched for creation sites, as they are handled as functions of
+// their own, but uses of the cancel function inside them count.
+func findContextLeaks(body *ast.BlockStmt) (sites int, leaks []contextLeak) {
+	if body == nil {
+		return 0, nil
+	}
+
+	// used reports whether the variable id is referenced in body after
+	// its declaration
+	used := func(id *ast.Ident) bool {
+		var found bool
+		ast.Inspect(body, func(n ast.Node) bool {
+			ref, ok := n.(*ast.Ident)
+			if found || !ok {
+				return !found
+			}
+			if ref.Pos() > id.End() && ref.Name == id.Name && (id.Obj == nil || ref.Obj == id.Obj) {
+				found = true
+			}
+			return true
+		})
+		return found
+	}
+
+	check := func(pos token.Pos, cancel ast.Expr, fn string) {
+		sites++
+		id, ok := cancel.(*ast.Ident)
+		if !ok {
+			// assigned to a field or similar, so it is stored for later
+			return
+		}
+		if id.Name == "_" || !used(id) {
+			leaks = append(leaks, contextLeak{pos, fn})
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 2 && len(n.Rhs) == 1 {
+				if fn, ok := contextCall(n.Rhs[0]); ok {
+					check(n.Pos(), n.Lhs[1], fn)
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == 2 && len(n.Values) == 1 {
+				if fn, ok := contextCall(n.Values[0]); ok {
+					check(n.Pos(), n.Names[1], fn)
+				}
+			}
+		case *ast.ExprStmt:
+			// the result, including the cancel function, is discarded
+			if fn, ok := contextCall(n.X); ok {
+				sites++
+				leaks = append(leaks, contextLeak{n.Pos(), fn})
+			}
+		}
+		return true
+	})
+
+	return sites, leaks
+}
+
+// ContextLeak flags calls to context.WithCancel, WithTimeout and
+// WithDeadline whose returned cancel function is never called or
+// deferred in the same function, similar to the lostcancel analyzer of
+// go vet. The percentage is the fraction of context creation sites
+// that cancel.
+func ContextLeak(dir string, filenames []string) (float64, []FileSummary, error) {
+	var (
+		failed       = []FileSummary{}
+		fset         = token.NewFileSet()
+		total, leaks int
+	)
+	for _, f := range filenames {
+		file, err := parser.ParseFile(fset, f, nil, 0)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+
+		fs := newFileSummary(dir, f)
+		ast.Inspect(file, func(n ast.Node) bool {
+			var body *ast.BlockStmt
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				body = n.Body
+			case *ast.FuncLit:
+				body = n.Body
+			default:
+				return true
+			}
+
+			sites, found := findContextLeaks(body)
+			total += sites
+			leaks += len(found)
+			for _, l := range found {
+				fs.Errors = append(fs.Errors, Error{
+					LineNumber:  fset.Position(l.pos).Line,
+					ErrorString: fmt.Sprintf("the cancel function returned by context.%s is not called, so the context leaks", l.fn),
+				})
+			}
+			return true
+		})
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	if total == 0 {
+		return 1, failed, nil
+	}
+
+	return float64(total-leaks) / float64(total), failed, nil
+}
@@ -0,0 +1,45 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var contextLeakTests = []struct {
+	name    string
+	body    string
+	percent float64
+	lines   []int
+}{
+	{"dropped", "ctx, _ = context.WithCancel(ctx)\n\t_ = ctx", 0, []int{11}},
+	{"deferred", "ctx, cancel := context.WithCancel(ctx)\n\tdefer cancel()\n\t_ = ctx", 1, nil},
+	{"called", "ctx, cancel := context.WithTimeout(ctx, time.Second)\n\t_ = ctx\n\tcancel()", 1, nil},
+	{"closure", "ctx, cancel := context.WithCancel(ctx)\n\tgo func() { cancel() }()\n\t_ = ctx", 1, nil},
+	{"discarded", "context.WithDeadline(ctx, time.Now())", 0, []int{11}},
+	{"mixed", "ctx, cancel := context.WithCancel(ctx)\n\tdefer cancel()\n\tctx, _ = context.WithTimeout(ctx, time.Second)\n\t_ = ctx", 0.5, []int{13}},
+}
+
+func TestContextLeak(t *testing.T) {
+	for _, tt := range contextLeakTests {
+		src := "package m\n\nimport (\n\t\"context\"\n\t\"time\"\n)\n\nvar _ = time.Second\n\nfunc f(ctx context.Context) {\n\t" + tt.body + "\n}\n"
+		dir := writeModule(t, "", map[string]string{"a.go": src})
+		p, fs, err := ContextLeak(dir, []string{filepath.Join(dir, "a.go")})
+		os.RemoveAll(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != tt.percent {
+			t.Errorf("[%s] ContextLeak percent = %f, want %f", tt.name, p, tt.percent)
+		}
+		var lines []int
+		for _, s := range fs {
+			for _, e := range s.Errors {
+				lines = append(lines, e.LineNumber)
+			}
+		}
+		if len(lines) != len(tt.lines) || (len(lines) > 0 && lines[0] != tt.lines[0]) {
+			t.Errorf("[%s] ContextLeak lines = %v, want %v", tt.name, lines, tt.lines)
+		}
+	}
+}
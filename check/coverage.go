@@ -0,0 +1,274 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CoverageTimeout is the maximum time the tests of a repo may take
+var CoverageTimeout = 5 * time.Minute
+
+// Coverage is the check for the test coverage of each package
+type Coverage struct{}
+
+// Name returns the name of the display name of the command
+func (g Coverage) Name() string {
+	return "coverage"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Coverage) Weight() float64 {
+	return .05
+}
+
+// RunsCode reports that the check runs the tests of the repo, so it is
+// skipped unless the Checker runs it in an isolated sandbox
+func (g Coverage) RunsCode() bool {
+	return true
+}
+
+// Timeout returns the maximum time the check may take. The -timeout of
+// go test only applies to running the tests, so this also leaves time
+// to build them.
+func (g Coverage) Timeout() time.Duration {
+	return CoverageTimeout + time.Minute
+}
+
+// CoverageProfile is the name of the coverage profile of the repo, which
+// the coverage check writes to the Artifacts directory of the Checker
+const CoverageProfile = "coverage.out"
+
+// package statuses reported by the coverage check
+const (
+	coverageOK          = "ok"
+	coverageNoTests     = "no test files"
+	coverageBuildFailed = "build failed"
+	coverageTestsFailed = "tests failed"
+)
+
+// pkgCoverage is the test result of a single package
+type pkgCoverage struct {
+	pkg    string
+	status string
+	// percent is the statement coverage, or -1 if the package has
+	// no statements
+	percent float64
+}
+
+// goEnv returns the environment to run the go command in for the repo in
+// dir. A module, or a directory of a module in ReposDir, is built in
+// module-aware mode, and other repos in GOPATH mode, with the GOPATH of
+// ReposDir if they are in it.
+func goEnv(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		return []string{"GO111MODULE=on"}, nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := filepath.Abs(filepath.FromSlash(ReposDir))
+	if err != nil {
+		return nil, err
+	}
+	for d := filepath.Dir(abs); strings.HasPrefix(d, repos+string(filepath.Separator)); d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return []string{"GO111MODULE=on"}, nil
+		}
+	}
+	var env []string
+	if filepath.Base(repos) == "src" && strings.HasPrefix(abs, repos+string(filepath.Separator)) {
+		env = append(env, "GOPATH="+filepath.Dir(repos))
+	}
+	return append(env, "GO111MODULE=off"), nil
+}
+
+// Run returns the average test coverage of the packages that
+// build and pass their tests. Packages without tests count as 0%.
+func (g Coverage) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	// the test events of every module of the repo
+	var stdout bytes.Buffer
+	var profiles []string
+	for i, modDir := range moduleDirs(ctx, dir) {
+		env, err := goEnv(modDir)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+
+		args := []string{"test", "-cover", "-json", "-timeout", CoverageTimeout.String()}
+		if artifacts := artifactsDir(ctx); artifacts != "" {
+			profile := filepath.Join(artifacts, fmt.Sprintf("coverage-%d.out", i))
+			profiles = append(profiles, profile)
+			args = append(args, "-coverprofile", profile)
+		}
+		cmd := commandContext(ctx, modDir, env, "go", append(args, "./...")...)
+		l := newOutputLimiter(ctx)
+		cmd.Stdout = l.writer(&stdout)
+		err = cmd.Run()
+		if ctx.Err() != nil {
+			return 0, []FileSummary{}, ctx.Err()
+		}
+		if limitErr := limitExceeded(err, l); limitErr != nil {
+			return 0, []FileSummary{}, limitErr
+		}
+		if _, ok := err.(*exec.ExitError); !ok && err != nil {
+			// failing tests are reported per package
+			return 0, []FileSummary{}, err
+		}
+	}
+
+	if len(profiles) > 0 {
+		if err := mergeCoverProfiles(filepath.Join(artifactsDir(ctx), CoverageProfile), profiles); err != nil {
+			logFrom(ctx).Log("could not write coverage profile", "dir", dir, "error", err)
+		}
+	}
+
+	pkgs, err := parseCoverage(&stdout)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	var failed = []FileSummary{}
+	var total float64
+	var counted int
+	for _, p := range pkgs {
+		if p.status == coverageOK && p.percent >= 0 {
+			total += p.percent
+			counted++
+		} else if p.status == coverageNoTests {
+			counted++
+		}
+		if p.status == coverageOK && (p.percent < 0 || p.percent == 100) {
+			continue
+		}
+
+		msg := p.status
+		if p.status == coverageOK {
+			msg = fmt.Sprintf("coverage: %.1f%% of statements", p.percent)
+		}
+		failed = append(failed, FileSummary{
+			Filename: p.pkg,
+			FileURL:  fileURL(dir, "/"+p.pkg),
+			Errors:   []Error{{ErrorString: msg}},
+		})
+	}
+
+	if counted == 0 {
+		return 0, failed, nil
+	}
+	return total / float64(counted) / 100, failed, nil
+}
+
+// mergeCoverProfiles writes the coverage profiles of the modules of a
+// repo to one profile at path, and removes them. The profile of a module
+// whose tests did not build is missing.
+func mergeCoverProfiles(path string, profiles []string) error {
+	var merged bytes.Buffer
+	for _, p := range profiles {
+		b, err := os.ReadFile(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		os.Remove(p)
+		for i, line := range strings.SplitAfter(string(b), "\n") {
+			// every profile starts with the mode, which is the same
+			if i == 0 && strings.HasPrefix(line, "mode:") && merged.Len() > 0 {
+				continue
+			}
+			merged.WriteString(line)
+		}
+	}
+	if merged.Len() == 0 {
+		return nil
+	}
+	return os.WriteFile(path, merged.Bytes(), 0644)
+}
+
+var coverageRegexp = regexp.MustCompile(`coverage: ([0-9.]+)% of statements`)
+
+// testEvent is an event in the output of go test -json
+type testEvent struct {
+	Action      string
+	Package     string
+	Test        string
+	Output      string
+	FailedBuild string
+}
+
+// parseCoverage parses the output of go test -cover -json into the
+// result of each package, sorted by package
+func parseCoverage(r io.Reader) ([]pkgCoverage, error) {
+	pkgs := make(map[string]*pkgCoverage)
+	get := func(pkg string) *pkgCoverage {
+		if pkgs[pkg] == nil {
+			pkgs[pkg] = &pkgCoverage{pkg: pkg, percent: -1}
+		}
+		return pkgs[pkg]
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		var e testEvent
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if e.Package == "" || e.Test != "" {
+			continue
+		}
+
+		p := get(e.Package)
+		switch e.Action {
+		case "output":
+			if m := coverageRegexp.FindStringSubmatch(e.Output); m != nil {
+				p.percent, _ = strconv.ParseFloat(m[1], 64)
+			}
+			if strings.Contains(e.Output, "[build failed]") || strings.Contains(e.Output, "[setup failed]") {
+				p.status = coverageBuildFailed
+			}
+		case "pass":
+			p.status = coverageOK
+		case "skip":
+			p.status = coverageNoTests
+		case "fail":
+			if e.FailedBuild != "" {
+				p.status = coverageBuildFailed
+			} else if p.status != coverageBuildFailed {
+				p.status = coverageTestsFailed
+			}
+		}
+	}
+
+	var result []pkgCoverage
+	for _, p := range pkgs {
+		if p.status == "" {
+			continue
+		}
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].pkg < result[j].pkg })
+	return result, nil
+}
+
+// Description returns the description of Coverage
+func (g Coverage) Description() string {
+	return "Runs <code>go test -cover</code> and reports the statement coverage of each package. Packages that fail to build or whose tests fail are listed, but do not count towards the average."
+}
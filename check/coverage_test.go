@@ -0,0 +1,120 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCoverage(t *testing.T) {
+	out := `{"Action":"start","Package":"github.com/foo/bar"}
+{"Action":"run","Package":"github.com/foo/bar","Test":"TestA"}
+{"Action":"output","Package":"github.com/foo/bar","Test":"TestA","Output":"--- PASS: TestA (0.00s)\n"}
+{"Action":"pass","Package":"github.com/foo/bar","Test":"TestA"}
+{"Action":"output","Package":"github.com/foo/bar","Output":"coverage: 75.0% of statements\n"}
+{"Action":"output","Package":"github.com/foo/bar","Output":"ok  \tgithub.com/foo/bar\t0.01s\tcoverage: 75.0% of statements\n"}
+{"Action":"pass","Package":"github.com/foo/bar"}
+{"Action":"output","Package":"github.com/foo/bar/broken","Output":"FAIL\tgithub.com/foo/bar/broken [build failed]\n"}
+{"Action":"fail","Package":"github.com/foo/bar/broken"}
+{"Action":"fail","Package":"github.com/foo/bar/failing","Test":"TestB"}
+{"Action":"output","Package":"github.com/foo/bar/failing","Output":"coverage: 40.0% of statements\n"}
+{"Action":"fail","Package":"github.com/foo/bar/failing"}
+{"Action":"output","Package":"github.com/foo/bar/newbroken","Output":"FAIL\n"}
+{"Action":"fail","Package":"github.com/foo/bar/newbroken","FailedBuild":"github.com/foo/bar/newbroken [github.com/foo/bar/newbroken.test]"}
+{"Action":"output","Package":"github.com/foo/bar/none","Output":"?   \tgithub.com/foo/bar/none\t[no test files]\n"}
+{"Action":"skip","Package":"github.com/foo/bar/none"}
+`
+	pkgs, err := parseCoverage(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []pkgCoverage{
+		{"github.com/foo/bar", coverageOK, 75},
+		{"github.com/foo/bar/broken", coverageBuildFailed, -1},
+		{"github.com/foo/bar/failing", coverageTestsFailed, 40},
+		{"github.com/foo/bar/newbroken", coverageBuildFailed, -1},
+		{"github.com/foo/bar/none", coverageNoTests, -1},
+	}
+	if len(pkgs) != len(want) {
+		t.Fatalf("parseCoverage returned %d packages, want %d: %+v", len(pkgs), len(want), pkgs)
+	}
+	for i := range want {
+		if pkgs[i] != want[i] {
+			t.Errorf("parseCoverage package %d = %+v, want %+v", i, pkgs[i], want[i])
+		}
+	}
+}
+
+func TestGoEnv(t *testing.T) {
+	defer func(dir string) { ReposDir = dir }(ReposDir)
+	ReposDir = filepath.ToSlash(filepath.Join(t.TempDir(), "src"))
+	module := filepath.Join(ReposDir, "github.com", "foo", "monorepo")
+	legacy := filepath.Join(ReposDir, "github.com", "foo", "legacy")
+	for _, d := range []string{filepath.Join(module, "services", "api"), legacy} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(module, "go.mod"), []byte("module github.com/foo/monorepo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		dir  string
+		want []string
+	}{
+		{module, []string{"GO111MODULE=on"}},
+		{filepath.Join(module, "services", "api"), []string{"GO111MODULE=on"}},
+		{legacy, []string{"GOPATH=" + filepath.Dir(ReposDir), "GO111MODULE=off"}},
+	}
+	for _, c := range cases {
+		got, err := goEnv(c.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%q] goEnv = %q, want %q", c.dir, got, c.want)
+		}
+	}
+}
+
+func TestMergeCoverProfiles(t *testing.T) {
+	dir := t.TempDir()
+	profiles := []string{filepath.Join(dir, "a.out"), filepath.Join(dir, "missing.out"), filepath.Join(dir, "b.out")}
+	if err := os.WriteFile(profiles[0], []byte("mode: set\ngithub.com/foo/bar/a.go:1.1,2.2 1 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(profiles[2], []byte("mode: set\ngithub.com/foo/bar/api/b.go:3.1,4.2 1 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, CoverageProfile)
+	if err := mergeCoverProfiles(path, profiles); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mode: set\ngithub.com/foo/bar/a.go:1.1,2.2 1 1\ngithub.com/foo/bar/api/b.go:3.1,4.2 1 0\n"
+	if string(b) != want {
+		t.Errorf("merged profile = %q, want %q", b, want)
+	}
+	if _, err := os.Stat(profiles[0]); !os.IsNotExist(err) {
+		t.Errorf("profile of a module was not removed")
+	}
+}
+
+func TestCoverageSkippedOutsideSandbox(t *testing.T) {
+	r := Checker{}.run(context.Background(), "testfiles", []Check{Coverage{}})[0]
+	if r.Status != StatusSkipped || r.Error == "" || r.Weight != 0 {
+		t.Errorf("coverage without a sandbox = %+v, want it skipped without weight", r)
+	}
+	if reason := (Checker{Sandbox: DockerSandbox{Image: "golang"}}).skipReason(Coverage{}); reason != "" {
+		t.Errorf("coverage in a docker sandbox is skipped: %s", reason)
+	}
+}
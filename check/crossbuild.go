@@ -0,0 +1,95 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// BuildTargets are the GOOS/GOARCH pairs the cross-platform build check
+// compiles for
+var BuildTargets = []string{
+	"linux/amd64",
+	"linux/arm64",
+	"linux/386",
+	"darwin/arm64",
+	"windows/amd64",
+	"freebsd/amd64",
+}
+
+// CrossBuild is the check for whether the packages compile on all
+// BuildTargets
+type CrossBuild struct{}
+
+// Name returns the name of the display name of the command
+func (g CrossBuild) Name() string {
+	return "cross_platform_build"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g CrossBuild) Weight() float64 {
+	return .05
+}
+
+// buildFailure returns the first compiler error in the output of go
+// build, skipping the "# package" headers
+func buildFailure(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return strings.TrimSpace(reportedFilename(line))
+		}
+	}
+	return "build failed"
+}
+
+// Run returns the percentage of BuildTargets for which all packages
+// compile. Cgo is disabled, as cross-compiling with cgo needs a C
+// toolchain for every target.
+func (g CrossBuild) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(BuildTargets) == 0 || len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+	failures := FileSummary{Errors: []Error{}}
+	for _, target := range BuildTargets {
+		parts := strings.SplitN(target, "/", 2)
+		if len(parts) != 2 {
+			return 0, []FileSummary{}, fmt.Errorf("invalid build target %q", target)
+		}
+
+		// a target fails if any module of the repo does not build
+		for _, modDir := range moduleDirs(ctx, dir) {
+			env, err := goEnv(modDir)
+			if err != nil {
+				return 0, []FileSummary{}, err
+			}
+			// building several packages discards the results
+			targetEnv := append(env, "GOOS="+parts[0], "GOARCH="+parts[1], "CGO_ENABLED=0")
+			cmd := commandContext(ctx, modDir, targetEnv, "go", "build", "./...")
+			out, err := combinedOutput(ctx, cmd)
+			if ctx.Err() != nil {
+				return 0, []FileSummary{}, ctx.Err()
+			}
+			if _, ok := err.(*exec.ExitError); ok {
+				failures.Errors = append(failures.Errors, Error{
+					ErrorString: fmt.Sprintf("%s: %s", target, buildFailure(string(out))),
+				})
+				break
+			} else if err != nil {
+				return 0, []FileSummary{}, err
+			}
+		}
+	}
+
+	if len(failures.Errors) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+	return float64(len(BuildTargets)-len(failures.Errors)) / float64(len(BuildTargets)), []FileSummary{failures}, nil
+}
+
+// Description returns the description of CrossBuild
+func (g CrossBuild) Description() string {
+	return fmt.Sprintf("Builds your packages for %s with cgo disabled, and lists the platforms they do not compile on.", strings.Join(BuildTargets, ", "))
+}
@@ -0,0 +1,34 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrossBuild(t *testing.T) {
+	dir := writeModule(t, "1.16", map[string]string{
+		"a.go":         "package a\n\nfunc A() int { return b() }\n",
+		"b_linux.go":   "package a\n\nfunc b() int { return 1 }\n",
+		"b_windows.go": "package a\n\nfunc b() int { return 2 }\n",
+	})
+	defer os.RemoveAll(dir)
+	defer func(targets []string) { BuildTargets = targets }(BuildTargets)
+	BuildTargets = []string{"linux/amd64", "windows/amd64", "darwin/arm64", "freebsd/amd64"}
+
+	p, failed, err := CrossBuild{}.Run(WithFilenames(context.Background(), []string{filepath.Join(dir, "a.go")}), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != .5 {
+		t.Errorf("CrossBuild percentage = %v, want 0.5", p)
+	}
+	if len(failed) != 1 || len(failed[0].Errors) != 2 {
+		t.Fatalf("CrossBuild = %v, want 2 failing targets", failed)
+	}
+	if e := failed[0].Errors[0].ErrorString; !strings.HasPrefix(e, "darwin/arm64: ") || !strings.Contains(e, "undefined: b") {
+		t.Errorf("CrossBuild error = %q, want darwin/arm64 undefined: b", e)
+	}
+}
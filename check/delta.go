@@ -0,0 +1,57 @@
+package check
+
+// CheckDelta is the change of the result of a check between two grades
+// of a repo
+type CheckDelta struct {
+	Name   string  `json:"name"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	// NewIssues are the issues that were not found before, and
+	// FixedIssues the issues that are no longer found. Issues are told
+	// apart like in a Baseline, so moving code does not change them.
+	NewIssues   int `json:"new_issues"`
+	FixedIssues int `json:"fixed_issues"`
+}
+
+// Deltas returns the changes of the checks run on dir from the results
+// before to the results after, in the order of after. Checks that were
+// not run before are left out.
+func Deltas(dir string, before, after []CheckResult) []CheckDelta {
+	prev := make(map[string]CheckResult, len(before))
+	for _, r := range before {
+		prev[r.Name] = r
+	}
+	deltas := []CheckDelta{}
+	for _, r := range after {
+		b, ok := prev[r.Name]
+		if !ok {
+			continue
+		}
+		d := CheckDelta{Name: r.Name, Before: b.Percentage, After: r.Percentage}
+		old, now := issueCounts(dir, b), issueCounts(dir, r)
+		for h, n := range now {
+			if n > old[h] {
+				d.NewIssues += n - old[h]
+			}
+		}
+		for h, n := range old {
+			if n > now[h] {
+				d.FixedIssues += n - now[h]
+			}
+		}
+		deltas = append(deltas, d)
+	}
+	return deltas
+}
+
+// issueCounts counts the issues in r by issueHash
+func issueCounts(dir string, r CheckResult) map[string]int {
+	counts := make(map[string]int)
+	for _, fs := range r.FileSummaries {
+		rel := RelFilename(dir, fs.Filename)
+		for _, e := range fs.Errors {
+			counts[issueHash(r.Name, rel, e)]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,43 @@
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeltas(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	summary := func(f string, msgs ...string) FileSummary {
+		fs := newFileSummary(dir, dir+"/"+f)
+		for i, msg := range msgs {
+			fs.Errors = append(fs.Errors, Error{LineNumber: i + 1, ErrorString: msg})
+		}
+		return fs
+	}
+	before := []CheckResult{
+		{Name: "gofmt", Percentage: 0.5, FileSummaries: []FileSummary{summary("a.go", "not formatted")}},
+		{Name: "go_vet", Percentage: 0.8, FileSummaries: []FileSummary{summary("a.go", "unreachable code", "bad printf")}},
+		{Name: "misspell", Percentage: 1, FileSummaries: []FileSummary{}},
+		{Name: "removed", Percentage: 1},
+	}
+	after := []CheckResult{
+		{Name: "gofmt", Percentage: 1, FileSummaries: []FileSummary{}},
+		// the unreachable code moved to another line
+		{Name: "go_vet", Percentage: 0.6, FileSummaries: []FileSummary{
+			summary("a.go", "self-assignment", "unreachable code"),
+			summary("b.go", "copies lock", "copies lock"),
+		}},
+		{Name: "misspell", Percentage: 1, FileSummaries: []FileSummary{}},
+		{Name: "added", Percentage: 1},
+	}
+
+	got := Deltas(dir, before, after)
+	want := []CheckDelta{
+		{Name: "gofmt", Before: 0.5, After: 1, FixedIssues: 1},
+		{Name: "go_vet", Before: 0.8, After: 0.6, NewIssues: 3, FixedIssues: 1},
+		{Name: "misspell", Before: 1, After: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Deltas = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package check
+
+// IssueDiff is the issues of a check that changed between two grades of
+// a repo
+type IssueDiff struct {
+	Name string `json:"name"`
+	// Introduced are the issues that were not found before, and Resolved
+	// the issues that are no longer found. Issues are told apart like in
+	// a Baseline, so moving code does not change them.
+	Introduced []FileSummary `json:"introduced"`
+	Resolved   []FileSummary `json:"resolved"`
+}
+
+// DiffIssues returns the issues introduced and resolved by the checks run
+// on dir from the results before to the results after, by check in the
+// order of after and then of before. Checks without changes are left out.
+func DiffIssues(dir string, before, after []CheckResult) []IssueDiff {
+	prev := make(map[string]CheckResult, len(before))
+	for _, r := range before {
+		prev[r.Name] = r
+	}
+	now := make(map[string]bool, len(after))
+	diffs := []IssueDiff{}
+	add := func(d IssueDiff) {
+		if len(d.Introduced) > 0 || len(d.Resolved) > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	for _, r := range after {
+		now[r.Name] = true
+		b := prev[r.Name]
+		add(IssueDiff{
+			Name:       r.Name,
+			Introduced: unmatchedIssues(dir, r, b),
+			Resolved:   unmatchedIssues(dir, b, r),
+		})
+	}
+	for _, r := range before {
+		if !now[r.Name] {
+			add(IssueDiff{Name: r.Name, Introduced: []FileSummary{}, Resolved: unmatchedIssues(dir, r, CheckResult{})})
+		}
+	}
+	return diffs
+}
+
+// unmatchedIssues returns the issues in r that are not in other, by file.
+// An issue that is in r more often than in other is unmatched as often
+// as it is in r in excess.
+func unmatchedIssues(dir string, r, other CheckResult) []FileSummary {
+	counts := issueCounts(dir, other)
+	unmatched := []FileSummary{}
+	for _, fs := range r.FileSummaries {
+		rel := RelFilename(dir, fs.Filename)
+		var errs []Error
+		for _, e := range fs.Errors {
+			h := issueHash(r.Name, rel, e)
+			if counts[h] > 0 {
+				counts[h]--
+				continue
+			}
+			errs = append(errs, e)
+		}
+		if len(errs) > 0 {
+			unmatched = append(unmatched, FileSummary{Filename: fs.Filename, FileURL: fs.FileURL, Errors: errs})
+		}
+	}
+	return unmatched
+}
@@ -0,0 +1,52 @@
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffIssues(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	summary := func(f string, msgs ...string) FileSummary {
+		fs := newFileSummary(dir, dir+"/"+f)
+		for i, msg := range msgs {
+			fs.Errors = append(fs.Errors, Error{LineNumber: i + 1, ErrorString: msg})
+		}
+		return fs
+	}
+	before := []CheckResult{
+		{Name: "gofmt", FileSummaries: []FileSummary{summary("a.go", "not formatted")}},
+		{Name: "go_vet", FileSummaries: []FileSummary{summary("a.go", "unreachable code", "bad printf"), summary("b.go", "copies lock")}},
+		{Name: "misspell", FileSummaries: []FileSummary{}},
+		{Name: "removed", FileSummaries: []FileSummary{summary("c.go", "old issue")}},
+	}
+	after := []CheckResult{
+		{Name: "gofmt", FileSummaries: []FileSummary{}},
+		// the unreachable code moved to another line
+		{Name: "go_vet", FileSummaries: []FileSummary{
+			summary("a.go", "self-assignment", "unreachable code"),
+			summary("b.go", "copies lock", "copies lock"),
+		}},
+		{Name: "misspell", FileSummaries: []FileSummary{}},
+	}
+
+	got := DiffIssues(dir, before, after)
+	a, b, c := summary("a.go"), summary("b.go"), summary("c.go")
+	want := []IssueDiff{
+		{Name: "gofmt", Introduced: []FileSummary{}, Resolved: []FileSummary{
+			{Filename: a.Filename, FileURL: a.FileURL, Errors: []Error{{LineNumber: 1, ErrorString: "not formatted"}}},
+		}},
+		{Name: "go_vet", Introduced: []FileSummary{
+			{Filename: a.Filename, FileURL: a.FileURL, Errors: []Error{{LineNumber: 1, ErrorString: "self-assignment"}}},
+			{Filename: b.Filename, FileURL: b.FileURL, Errors: []Error{{LineNumber: 2, ErrorString: "copies lock"}}},
+		}, Resolved: []FileSummary{
+			{Filename: a.Filename, FileURL: a.FileURL, Errors: []Error{{LineNumber: 2, ErrorString: "bad printf"}}},
+		}},
+		{Name: "removed", Introduced: []FileSummary{}, Resolved: []FileSummary{
+			{Filename: c.Filename, FileURL: c.FileURL, Errors: []Error{{LineNumber: 1, ErrorString: "old issue"}}},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffIssues = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DocCoverage is the check for doc comments on exported identifiers
+type DocCoverage struct{}
+
+// Name returns the name of the display name of the command
+func (g DocCoverage) Name() string {
+	return "doc_coverage"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g DocCoverage) Weight() float64 {
+	return .05
+}
+
+// Severity returns the severity of the issues the check reports
+func (g DocCoverage) Severity() string {
+	return SeverityWarning
+}
+
+// exportedIdent is an exported identifier declared at the top level
+type exportedIdent struct {
+	name       string
+	pos        token.Pos
+	documented bool
+}
+
+// exportedIdents returns the exported top-level identifiers in file.
+// Methods are included if their receiver type is exported. A const, var
+// or type in a group is documented by a comment on the group or on itself.
+func exportedIdents(file *ast.File) []exportedIdent {
+	var idents []exportedIdent
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recv := d.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				id, ok := recv.(*ast.Ident)
+				if !ok || !id.IsExported() {
+					continue
+				}
+				name = id.Name + "." + name
+			}
+			idents = append(idents, exportedIdent{name, d.Pos(), d.Doc != nil})
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						idents = append(idents, exportedIdent{s.Name.Name, s.Pos(), d.Doc != nil || s.Doc != nil})
+					}
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if n.IsExported() {
+							idents = append(idents, exportedIdent{n.Name, n.Pos(), d.Doc != nil || s.Doc != nil})
+						}
+					}
+				}
+			}
+		}
+	}
+	return idents
+}
+
+// Run returns the percentage of exported identifiers that have a
+// doc comment. Packages are listed from worst to best documented, with
+// their undocumented identifiers. Test files and package main are not
+// checked.
+func (g DocCoverage) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	type pkgDocs struct {
+		dir               string
+		total, documented int
+		missing           []Error
+	}
+	var (
+		pkgs = make(map[string]*pkgDocs)
+		fset = token.NewFileSet()
+	)
+	for _, f := range filenames {
+		if err := ctx.Err(); err != nil {
+			return 0, []FileSummary{}, err
+		}
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		if file.Name.Name == "main" {
+			continue
+		}
+
+		pkgDir := filepath.Dir(f)
+		p := pkgs[pkgDir]
+		if p == nil {
+			p = &pkgDocs{dir: pkgDir}
+			pkgs[pkgDir] = p
+		}
+		fs := newFileSummary(dir, f)
+		for _, id := range exportedIdents(file) {
+			p.total++
+			if id.documented {
+				p.documented++
+				continue
+			}
+			line := fset.Position(id.pos).Line
+			p.missing = append(p.missing, Error{
+				ErrorString: fmt.Sprintf("%s has no doc comment", id.name),
+				Related:     []Location{{Filename: fs.Filename, FileURL: fs.FileURL, StartLine: line, EndLine: line}},
+			})
+		}
+	}
+
+	var sorted []*pkgDocs
+	var total, documented int
+	for _, p := range pkgs {
+		total += p.total
+		documented += p.documented
+		if len(p.missing) > 0 {
+			sorted = append(sorted, p)
+		}
+	}
+	// worst documented first
+	sort.Slice(sorted, func(i, j int) bool {
+		ci := float64(sorted[i].documented) / float64(sorted[i].total)
+		cj := float64(sorted[j].documented) / float64(sorted[j].total)
+		if ci != cj {
+			return ci < cj
+		}
+		return sorted[i].dir < sorted[j].dir
+	})
+
+	var failed = []FileSummary{}
+	for _, p := range sorted {
+		fs := newFileSummary(dir, p.dir)
+		fs.Errors = append([]Error{{
+			ErrorString: fmt.Sprintf("%d%% of exported identifiers are documented (%d of %d)", 100*p.documented/p.total, p.documented, p.total),
+		}}, p.missing...)
+		failed = append(failed, fs)
+	}
+
+	if total == 0 {
+		return 1, failed, nil
+	}
+	return float64(documented) / float64(total), failed, nil
+}
+
+// Description returns the description of DocCoverage
+func (g DocCoverage) Description() string {
+	return "Computes the percentage of exported identifiers with a doc comment, and lists the packages from worst to best documented. Test files and commands are not checked."
+}
@@ -0,0 +1,78 @@
+package check
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocCoverage(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": `package a
+
+// A is documented
+func A() {}
+
+func B() {}
+
+type T struct{}
+
+// M is documented
+func (T) M() {}
+
+func (*T) N() {}
+
+type unexported struct{}
+
+func (unexported) Exported() {}
+
+// Limits are documented as a group
+const (
+	Max = 1
+	Min = 0
+)
+
+var (
+	// X is documented
+	X int
+	Y int
+)
+`,
+		"a_test.go": "package a\n\nfunc Helper() {}\n",
+	})
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "a_test.go"), filepath.Join(dir, "b", "b.go")}
+	if err := ioutil.WriteFile(files[2], []byte("package b\n\nfunc C() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, failed, err := DocCoverage{}.Run(WithFilenames(context.Background(), files), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// documented: A, M, Max, Min, X; undocumented: B, T, T.N, Y, C
+	if p != .5 {
+		t.Errorf("DocCoverage percentage = %v, want 0.5", p)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("DocCoverage reported %d packages, want 2", len(failed))
+	}
+	if e := failed[0].Errors[0].ErrorString; e != "0% of exported identifiers are documented (0 of 1)" {
+		t.Errorf("DocCoverage worst package = %q", e)
+	}
+	want := []string{"B", "T", "T.N", "Y"}
+	errs := failed[1].Errors[1:]
+	if len(errs) != len(want) {
+		t.Fatalf("DocCoverage undocumented = %v, want %v", errs, want)
+	}
+	for i, name := range want {
+		if errs[i].ErrorString != name+" has no doc comment" {
+			t.Errorf("DocCoverage undocumented %d = %q, want %s", i, errs[i].ErrorString, name)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package check
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultDuplThreshold is the minimum size in tokens of a duplicated
+// block of code that is reported
+const DefaultDuplThreshold = 150
+
+// Dupl is the check for the dupl command
+type Dupl struct {
+	// Threshold is the minimum clone size in tokens, or
+	// DefaultDuplThreshold if zero
+	Threshold int
+}
+
+// Name returns the name of the display name of the command
+func (g Dupl) Name() string {
+	return "dupl"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Dupl) Weight() float64 {
+	return .05
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Dupl) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Dupl) Severity() string {
+	return SeverityWarning
+}
+
+// WithThreshold returns the check with the minimum clone size set to
+// n tokens
+func (g Dupl) WithThreshold(n int) Check {
+	g.Threshold = n
+	return g
+}
+
+// Run returns the percentage of .go files without duplicated code
+func (g Dupl) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	threshold := g.Threshold
+	if threshold == 0 {
+		threshold = DefaultDuplThreshold
+	}
+
+	// pass the files on stdin, so the files skipped by GoFiles
+	// are not checked
+	cmd := commandContext(ctx, "", nil, "dupl", "-plumbing", "-t", strconv.Itoa(threshold), "-files")
+	cmd.Stdin = strings.NewReader(strings.Join(filenames, "\n"))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	l := newOutputLimiter(ctx)
+	failed, err := parseDupl(l.reader(stdout), dir)
+	if err != nil {
+		cmd.Wait()
+		return 0, []FileSummary{}, err
+	}
+	if err := cmd.Wait(); ctx.Err() != nil {
+		return 0, []FileSummary{}, ctx.Err()
+	} else if limitErr := limitExceeded(err, l); limitErr != nil {
+		return 0, []FileSummary{}, limitErr
+	} else if err != nil {
+		return 0, failed, err
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// parseLocation parses a location like path/to/a.go:10-20
+func parseLocation(dir, s string) (Location, error) {
+	i := strings.LastIndex(s, ":")
+	if i == -1 {
+		return Location{}, fmt.Errorf("invalid location %q", s)
+	}
+	lines := strings.SplitN(s[i+1:], "-", 2)
+	if len(lines) != 2 {
+		return Location{}, fmt.Errorf("invalid line range in %q", s)
+	}
+	start, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return Location{}, err
+	}
+	end, err := strconv.Atoi(lines[1])
+	if err != nil {
+		return Location{}, err
+	}
+
+	filename := repoPath(s[:i])
+	return Location{
+		Filename:  makeFilename(filename),
+		FileURL:   fileURL(dir, filename),
+		StartLine: start,
+		EndLine:   end,
+	}, nil
+}
+
+// parseDupl parses the plumbing output of dupl, which has a line like
+// "a.go:10-20: duplicate of b.go:30-40" for every pair of clones. Pairs
+// that share a clone are merged into one group, and every clone is
+// reported once, as an error with the other clones of its group as
+// related locations.
+func parseDupl(r io.Reader, dir string) ([]FileSummary, error) {
+	// the pairs are merged into groups with a union-find: parent links
+	// every location towards the root location of its group
+	parent := make(map[Location]Location)
+	var order []Location
+	root := func(l Location) Location {
+		for parent[l] != l {
+			parent[l] = parent[parent[l]]
+			l = parent[l]
+		}
+		return l
+	}
+	add := func(l Location) {
+		if _, ok := parent[l]; !ok {
+			parent[l] = l
+			order = append(order, l)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ": duplicate of ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		a, err := parseLocation(dir, parts[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := parseLocation(dir, parts[1])
+		if err != nil {
+			return nil, err
+		}
+		add(a)
+		add(b)
+		if ra, rb := root(a), root(b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// groups has the locations of each group, in the order they were
+	// first reported
+	var groups [][]Location
+	index := make(map[Location]int)
+	for _, l := range order {
+		r := root(l)
+		g, ok := index[r]
+		if !ok {
+			g = len(groups)
+			index[r] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], l)
+	}
+
+	fsMap := make(map[string]FileSummary)
+	for _, locs := range groups {
+		for i, l := range locs {
+			var related []Location
+			related = append(related, locs[:i]...)
+			related = append(related, locs[i+1:]...)
+
+			fs := fsMap[l.Filename]
+			if fs.Filename == "" {
+				fs.Filename = l.Filename
+				fs.FileURL = l.FileURL
+			}
+			fs.Errors = append(fs.Errors, Error{
+				LineNumber:  l.StartLine,
+				ErrorString: fmt.Sprintf("lines %d-%d are duplicated in %d other place(s)", l.StartLine, l.EndLine, len(related)),
+				Related:     related,
+			})
+			fsMap[l.Filename] = fs
+		}
+	}
+
+	var failed = []FileSummary{}
+	for _, v := range fsMap {
+		sort.Slice(v.Errors, func(i, j int) bool { return v.Errors[i].LineNumber < v.Errors[j].LineNumber })
+		failed = append(failed, v)
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Filename < failed[j].Filename })
+
+	return failed, nil
+}
+
+// Description returns the description of Dupl
+func (g Dupl) Description() string {
+	threshold := g.Threshold
+	if threshold == 0 {
+		threshold = DefaultDuplThreshold
+	}
+	return fmt.Sprintf(`<a href="https://github.com/mibk/dupl">dupl</a> finds blocks of duplicated code. Go Report Card reports clones of at least %d tokens.`, threshold)
+}
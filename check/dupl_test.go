@@ -0,0 +1,64 @@
+package check
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDupl(t *testing.T) {
+	out := `repos/src/github.com/foo/bar/a.go:10-20: duplicate of repos/src/github.com/foo/bar/b.go:30-40
+repos/src/github.com/foo/bar/a.go:10-20: duplicate of repos/src/github.com/foo/bar/c.go:1-11
+repos/src/github.com/foo/bar/b.go:30-40: duplicate of repos/src/github.com/foo/bar/a.go:10-20
+repos/src/github.com/foo/bar/b.go:30-40: duplicate of repos/src/github.com/foo/bar/c.go:1-11
+repos/src/github.com/foo/bar/c.go:1-11: duplicate of repos/src/github.com/foo/bar/a.go:10-20
+repos/src/github.com/foo/bar/c.go:1-11: duplicate of repos/src/github.com/foo/bar/b.go:30-40
+Found total 6 clone groups.
+`
+	failed, err := parseDupl(strings.NewReader(out), "repos/src/github.com/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 3 {
+		t.Fatalf("parseDupl returned %d files, want 3", len(failed))
+	}
+
+	a := failed[0]
+	if a.Filename != "bar/a.go" || a.FileURL != "https://github.com/foo/bar/blob/master/a.go" {
+		t.Errorf("parseDupl file = %q (%q), want bar/a.go", a.Filename, a.FileURL)
+	}
+	if len(a.Errors) != 1 {
+		t.Fatalf("parseDupl a.go errors = %v, want 1 error", a.Errors)
+	}
+	e := a.Errors[0]
+	if e.LineNumber != 10 || len(e.Related) != 2 {
+		t.Fatalf("parseDupl a.go error = %+v, want line 10 with 2 related locations", e)
+	}
+	if r := e.Related[0]; r.Filename != "bar/b.go" || r.StartLine != 30 || r.EndLine != 40 {
+		t.Errorf("parseDupl related = %+v, want bar/b.go:30-40", r)
+	}
+}
+
+func TestParseDuplMergesGroups(t *testing.T) {
+	// the first two pairs start two groups that the third pair joins
+	out := `repos/src/github.com/foo/bar/a.go:1-10: duplicate of repos/src/github.com/foo/bar/b.go:1-10
+repos/src/github.com/foo/bar/c.go:1-10: duplicate of repos/src/github.com/foo/bar/d.go:1-10
+repos/src/github.com/foo/bar/b.go:1-10: duplicate of repos/src/github.com/foo/bar/c.go:1-10
+repos/src/github.com/foo/bar/e.go:5-9: duplicate of repos/src/github.com/foo/bar/a.go:20-24
+`
+	failed, err := parseDupl(strings.NewReader(out), "repos/src/github.com/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	related := make(map[string]int)
+	for _, fs := range failed {
+		for _, e := range fs.Errors {
+			related[fmt.Sprintf("%s:%d", fs.Filename, e.LineNumber)] = len(e.Related)
+		}
+	}
+	want := map[string]int{"bar/a.go:1": 3, "bar/b.go:1": 3, "bar/c.go:1": 3, "bar/d.go:1": 3, "bar/a.go:20": 1, "bar/e.go:5": 1}
+	if !reflect.DeepEqual(related, want) {
+		t.Errorf("parseDupl related locations = %v, want %v", related, want)
+	}
+}
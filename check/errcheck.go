@@ -1,10 +1,13 @@
 package check
 
+import "context"
+
+// ErrCheckWeight is the weight errcheck has in the overall average.
+// It can be changed before any checks are run.
+var ErrCheckWeight = .15
+
 // ErrCheck is the check for the errcheck command
-type ErrCheck struct {
-	Dir       string
-	Filenames []string
-}
+type ErrCheck struct{}
 
 // Name returns the name of the display name of the command
 func (c ErrCheck) Name() string {
@@ -13,15 +16,21 @@ func (c ErrCheck) Name() string {
 
 // Weight returns the weight this check has in the overall average
 func (c ErrCheck) Weight() float64 {
-	return .15
+	return ErrCheckWeight
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (c ErrCheck) FileScored() bool {
+	return true
 }
 
-// Percentage returns the percentage of .go files that pass gofmt
-func (c ErrCheck) Percentage() (float64, []FileSummary, error) {
-	return GoTool(c.Dir, c.Filenames, []string{"gometalinter", "--deadline=180s", "--disable-all", "--linter='errch:errcheck {path}:PATH:LINE:MESSAGE'", "--enable=errch"})
+// Run returns the percentage of .go files that pass errcheck
+func (c ErrCheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoTool(ctx, dir, Filenames(ctx), []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=errcheck"})
 }
 
-// Description returns the description of gofmt
+// Description returns the description of errcheck
 func (c ErrCheck) Description() string {
 	return `<a href="https://github.com/kisielk/errcheck">errcheck</a> finds unchecked errors in go programs`
 }
@@ -0,0 +1,103 @@
+package check
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+// ErrorWrap flags fmt.Errorf calls that format an error with %v or %s
+// instead of wrapping it with %w, which loses the chain for errors.Is and
+// errors.As. Only modules whose go.mod targets Go 1.13 or newer are checked.
+// Arguments are errors if their type implements error; only arguments
+// whose type is unknown, for example because the package does not
+// type-check, are recognized by their name. Calls that intentionally
+// flatten the error can be annotated with //nolint:errorwrap on any of
+// their lines. The percentage is the fraction of fmt.Errorf calls
+// formatting an error that use %w.
+func ErrorWrap(dir string, filenames []string) (float64, []FileSummary, error) {
+	if !targetsGo(dir, 13) {
+		return 1, []FileSummary{}, nil
+	}
+
+	var (
+		failed         = []FileSummary{}
+		fset           = token.NewFileSet()
+		typed          = loadTypes(fset, dir)
+		total, wrapped int
+	)
+	for _, f := range filenames {
+		var info *types.Info
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		tf, ok := typed[abs]
+		file := tf.file
+		if ok {
+			info = tf.info
+		} else if file, err = parser.ParseFile(fset, f, nil, parser.ParseComments); err != nil {
+			return 0, []FileSummary{}, err
+		}
+		nolint := nolintLines(fset, file, "errorwrap")
+
+		fs := newFileSummary(dir, f)
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !isPkgCall(call, "fmt", "Errorf") || len(call.Args) == 0 {
+				return true
+			}
+			format, ok := stringLit(call.Args[0])
+			if !ok {
+				return true
+			}
+			verbs, ok := formatVerbs(format)
+			if !ok {
+				return true
+			}
+
+			var formatsErr bool
+			var flattened rune
+			for i, v := range verbs {
+				if i+1 >= len(call.Args) {
+					break
+				}
+				if !isError(info, call.Args[i+1]) {
+					continue
+				}
+				formatsErr = true
+				if v == 'v' || v == 's' {
+					flattened = v
+				}
+			}
+			if !formatsErr {
+				return true
+			}
+
+			total++
+			line := fset.Position(call.Pos()).Line
+			if flattened == 0 || nolintBetween(nolint, line, fset.Position(call.End()).Line) {
+				wrapped++
+				return true
+			}
+			fs.Errors = append(fs.Errors, Error{
+				LineNumber:  line,
+				ErrorString: fmt.Sprintf("use %%w instead of %%%c to wrap errors in fmt.Errorf", flattened),
+			})
+			return true
+		})
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	if total == 0 {
+		return 1, failed, nil
+	}
+
+	return float64(wrapped) / float64(total), failed, nil
+}
@@ -0,0 +1,87 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var errorWrapTests = []struct {
+	goVersion string
+	call      string
+	// formatsErr is whether the call formats an error
+	formatsErr bool
+	flagged    bool
+}{
+	{"1.13", `fmt.Errorf("x: %v", err)`, true, true},
+	{"1.20", `fmt.Errorf("x: %s", err)`, true, true},
+	{"1.20", `fmt.Errorf("x: %w", err)`, true, false},
+	{"1.20", `fmt.Errorf("x: %d %v", 1, err)`, true, true},
+	{"1.20", `fmt.Errorf("x: %v", err) //nolint:errorwrap`, true, false},
+	{"1.20", `fmt.Errorf("x: %v", err) //nolint`, true, false},
+	{"1.20", `fmt.Errorf("x: %v", err) //nolint:golint`, true, true},
+	{"1.20", `fmt.Errorf("x: %v", name)`, false, false},
+	{"1.20", `fmt.Errorf("x: %v", e)`, true, true},
+	{"1.20", `fmt.Errorf("x: %v", countErr)`, false, false},
+	{"1.20", "fmt.Errorf(\"x: %v\",\n\t\terr) //nolint:errorwrap", true, false},
+	{"1.20", "fmt.Errorf(\"x: %v\",\n\t\terr)", true, true},
+	{"1.12", `fmt.Errorf("x: %v", err)`, true, false},
+	{"", `fmt.Errorf("x: %v", err)`, true, false},
+}
+
+func TestErrorWrap(t *testing.T) {
+	// the calls of a Go version are graded together, one file each, as
+	// loading the types of a module takes a while
+	var versions []string
+	files := make(map[string]map[string]string)
+	for i, tt := range errorWrapTests {
+		if files[tt.goVersion] == nil {
+			versions = append(versions, tt.goVersion)
+			files[tt.goVersion] = make(map[string]string)
+		}
+		name := fmt.Sprintf("f%d.go", i)
+		files[tt.goVersion][name] = fmt.Sprintf("package m\n\nimport \"fmt\"\n\nfunc f%d(name string, err, e error, countErr int) error {\n\treturn %s\n}\n", i, tt.call)
+	}
+
+	for _, v := range versions {
+		dir := writeModule(t, v, files[v])
+		var filenames []string
+		for i, tt := range errorWrapTests {
+			if tt.goVersion == v {
+				filenames = append(filenames, filepath.Join(dir, fmt.Sprintf("f%d.go", i)))
+			}
+		}
+		p, fs, err := ErrorWrap(dir, filenames)
+		os.RemoveAll(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		flagged := make(map[string]bool)
+		for _, s := range fs {
+			flagged[filepath.Base(s.Filename)] = true
+			if s.Errors[0].LineNumber != 6 {
+				t.Errorf("[go %s] ErrorWrap(%s) line = %d, want 6", v, s.Filename, s.Errors[0].LineNumber)
+			}
+		}
+		var total, wrapped float64
+		for i, tt := range errorWrapTests {
+			if tt.goVersion != v {
+				continue
+			}
+			if got := flagged[fmt.Sprintf("f%d.go", i)]; got != tt.flagged {
+				t.Errorf("[go %s] ErrorWrap(%s) flagged = %t, want %t", v, tt.call, got, tt.flagged)
+			}
+			if tt.formatsErr {
+				total++
+				if !tt.flagged {
+					wrapped++
+				}
+			}
+		}
+		if want := wrapped / total; p != want {
+			t.Errorf("[go %s] ErrorWrap percent = %f, want %f", v, p, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package check
+
+import "context"
+
+// Exhaustive is the check for switch statements on enums that are
+// missing cases
+type Exhaustive struct{}
+
+// Name returns the name of the display name of the command
+func (g Exhaustive) Name() string {
+	return "exhaustive"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Exhaustive) Weight() float64 {
+	return .05
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Exhaustive) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Exhaustive) Severity() string {
+	return SeverityWarning
+}
+
+// Run returns the percentage of .go files that pass exhaustive
+func (g Exhaustive) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	failed, err := runAnalyzer(ctx, dir, "exhaustive")
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// Description returns the description of Exhaustive
+func (g Exhaustive) Description() string {
+	return `<a href="https://github.com/nishanths/exhaustive">Exhaustive</a> finds switch statements on enum-like types that do not have a case for every constant of the type, and lists the missing constants.`
+}
@@ -0,0 +1,76 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// FieldAlignment is the check for structs whose field order wastes memory
+type FieldAlignment struct{}
+
+// Name returns the name of the display name of the command
+func (g FieldAlignment) Name() string {
+	return "fieldalignment"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g FieldAlignment) Weight() float64 {
+	return 0
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g FieldAlignment) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g FieldAlignment) Severity() string {
+	return SeverityInfo
+}
+
+// Category returns the report section of the check
+func (g FieldAlignment) Category() string {
+	return CategoryPerformance
+}
+
+var structSizeRegexp = regexp.MustCompile(`^struct (?:of size|with) (\d+) (?:pointer bytes )?could be (\d+)`)
+
+// addSavings adds the number of bytes that reordering would save to
+// fieldalignment messages such as "struct of size 24 could be 16"
+func addSavings(msg string) string {
+	m := structSizeRegexp.FindStringSubmatch(msg)
+	if m == nil {
+		return msg
+	}
+	size, _ := strconv.Atoi(m[1])
+	optimal, _ := strconv.Atoi(m[2])
+	return fmt.Sprintf("%s (saves %d bytes)", msg, size-optimal)
+}
+
+// Run returns the percentage of .go files that pass fieldalignment
+func (g FieldAlignment) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	failed, err := runAnalyzer(ctx, dir, "fieldalignment")
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	for _, fs := range failed {
+		for i := range fs.Errors {
+			fs.Errors[i].ErrorString = addSavings(fs.Errors[i].ErrorString)
+		}
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// Description returns the description of FieldAlignment
+func (g FieldAlignment) Description() string {
+	return `<a href="https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/fieldalignment">Fieldalignment</a> finds structs that would use less memory, or less pointer bytes to scan for the garbage collector, if their fields were sorted. This is a performance hint and does not count towards the grade.`
+}
@@ -0,0 +1,20 @@
+package check
+
+import "testing"
+
+var addSavingsTests = []struct {
+	msg  string
+	want string
+}{
+	{"struct of size 24 could be 16", "struct of size 24 could be 16 (saves 8 bytes)"},
+	{"struct with 16 pointer bytes could be 8", "struct with 16 pointer bytes could be 8 (saves 8 bytes)"},
+	{"something else", "something else"},
+}
+
+func TestAddSavings(t *testing.T) {
+	for _, tt := range addSavingsTests {
+		if got := addSavings(tt.msg); got != tt.want {
+			t.Errorf("[%q] addSavings = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
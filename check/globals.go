@@ -0,0 +1,121 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// Globals is the check for mutable package-level variables
+type Globals struct{}
+
+// Name returns the name of the display name of the command
+func (g Globals) Name() string {
+	return "globals"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Globals) Weight() float64 {
+	return .05
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Globals) Severity() string {
+	return SeverityWarning
+}
+
+// allowedGlobal reports whether the package-level variable name with the
+// given type and value is an error or a registered flag, which are not
+// intended to be changed
+func allowedGlobal(name *ast.Ident, typ, value ast.Expr) bool {
+	if name.Name == "_" || looksLikeError(name) {
+		return true
+	}
+	if id, ok := typ.(*ast.Ident); ok && id.Name == "error" {
+		return true
+	}
+	call, ok := value.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	if isPkgCall(call, "errors", "New") || isPkgCall(call, "fmt", "Errorf") {
+		return true
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && (id.Name == "flag" || id.Name == "pflag")
+}
+
+// Run returns the percentage of .go files without mutable
+// package-level variables. Test files are not checked.
+func (g Globals) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	var (
+		failed  = []FileSummary{}
+		fset    = token.NewFileSet()
+		checked int
+	)
+	for _, f := range filenames {
+		if err := ctx.Err(); err != nil {
+			return 0, []FileSummary{}, err
+		}
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		checked++
+		file, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		nolint := nolintLines(fset, file, "globals")
+
+		fs := newFileSummary(dir, f)
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					var value ast.Expr
+					if i < len(vs.Values) {
+						value = vs.Values[i]
+					}
+					line := fset.Position(name.Pos()).Line
+					if allowedGlobal(name, vs.Type, value) {
+						continue
+					}
+					if nolint[line] {
+						suppress(ctx)
+						continue
+					}
+					fs.Errors = append(fs.Errors, Error{
+						LineNumber:  line,
+						ErrorString: fmt.Sprintf("%s is a mutable global variable", name.Name),
+					})
+				}
+			}
+		}
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	if checked == 0 {
+		return 1, failed, nil
+	}
+	return float64(checked-len(failed)) / float64(checked), failed, nil
+}
+
+// Description returns the description of Globals
+func (g Globals) Description() string {
+	return "Finds package-level variables, which can be changed by any code in the package and make testing and concurrency harder. Errors and registered flags are allowed."
+}
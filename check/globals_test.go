@@ -0,0 +1,62 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobals(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": `package a
+
+import (
+	"errors"
+	"flag"
+)
+
+var ErrNotFound = errors.New("not found")
+
+var (
+	addr    = flag.String("http", ":8000", "address")
+	counter int
+	_       = counter
+)
+
+var cache, size = map[string]int{}, 0
+
+var verbose bool //nolint:globals
+
+const limit = 10
+`,
+		"a_test.go": "package a\n\nvar testCounter int\n",
+	})
+	defer os.RemoveAll(dir)
+
+	ctx := WithFilenames(context.Background(), []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "a_test.go")})
+	_, failed, err := Globals{}.Run(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("Globals reported %d files, want 1", len(failed))
+	}
+	want := []string{
+		"counter is a mutable global variable",
+		"cache is a mutable global variable",
+		"size is a mutable global variable",
+	}
+	errs := failed[0].Errors
+	if len(errs) != len(want) {
+		t.Fatalf("Globals errors = %v, want %v", errs, want)
+	}
+	for i := range want {
+		if errs[i].ErrorString != want[i] {
+			t.Errorf("Globals error %d = %q, want %q", i, errs[i].ErrorString, want[i])
+		}
+	}
+	if errs[0].LineNumber != 12 {
+		t.Errorf("Globals line = %d, want 12", errs[0].LineNumber)
+	}
+}
@@ -1,10 +1,9 @@
 package check
 
+import "context"
+
 // GoVet is the check for the go vet command
-type GoVet struct {
-	Dir       string
-	Filenames []string
-}
+type GoVet struct{}
 
 // Name returns the name of the display name of the command
 func (g GoVet) Name() string {
@@ -16,9 +15,15 @@ func (g GoVet) Weight() float64 {
 	return .25
 }
 
-// Percentage returns the percentage of .go files that pass go vet
-func (g GoVet) Percentage() (float64, []FileSummary, error) {
-	return GoTool(g.Dir, g.Filenames, []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=vet"})
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g GoVet) FileScored() bool {
+	return true
+}
+
+// Run returns the percentage of .go files that pass go vet
+func (g GoVet) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoTool(ctx, dir, Filenames(ctx), []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=vet"})
 }
 
 // Description returns the description of go lint
@@ -0,0 +1,136 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultGoCognitOver is the cognitive complexity above which
+// functions are reported
+const DefaultGoCognitOver = 15
+
+// GoCognit is the check for the gocognit command
+type GoCognit struct {
+	// Over is the complexity above which functions are reported,
+	// or DefaultGoCognitOver if zero
+	Over int
+}
+
+func (g GoCognit) over() int {
+	if g.Over == 0 {
+		return DefaultGoCognitOver
+	}
+	return g.Over
+}
+
+// Name returns the name of the display name of the command
+func (g GoCognit) Name() string {
+	return "gocognit"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g GoCognit) Weight() float64 {
+	return .05
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g GoCognit) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g GoCognit) Severity() string {
+	return SeverityWarning
+}
+
+// WithThreshold returns the check with the complexity above which
+// functions are reported set to n
+func (g GoCognit) WithThreshold(n int) Check {
+	g.Over = n
+	return g
+}
+
+// Run returns the percentage of .go files that pass gocognit
+func (g GoCognit) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	args := append([]string{"-over", strconv.Itoa(g.over())}, filenames...)
+	out, err := runTool(ctx, "gocognit", args...)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	failed, err := parseGoCognit(out, dir, g.over())
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// parseGoCognit parses gocognit output, which has a line like
+// "21 mypkg (*T).Method path/to/file.go:10:1" for every function
+// over the complexity limit
+func parseGoCognit(out []byte, dir string, over int) ([]FileSummary, error) {
+	fsMap := make(map[string]FileSummary)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		complexity, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		fn := strings.Join(fields[2:len(fields)-1], " ")
+
+		pos := splitPosition(fields[len(fields)-1], -1)
+		if len(pos) < 2 {
+			return nil, fmt.Errorf("invalid gocognit position %q", fields[len(fields)-1])
+		}
+		line, err := strconv.Atoi(pos[1])
+		if err != nil {
+			return nil, err
+		}
+
+		filename := repoPath(pos[0])
+		fs := fsMap[filename]
+		if fs.Filename == "" {
+			fs.Filename = makeFilename(filename)
+			fs.FileURL = fileURL(dir, filename)
+		}
+		fs.Errors = append(fs.Errors, Error{
+			LineNumber:  line,
+			ErrorString: fmt.Sprintf("cognitive complexity %d of func %s is high (> %d)", complexity, fn, over),
+		})
+		fsMap[filename] = fs
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var failed = []FileSummary{}
+	for _, v := range fsMap {
+		failed = append(failed, v)
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Filename < failed[j].Filename })
+
+	return failed, nil
+}
+
+// Description returns the description of GoCognit
+func (g GoCognit) Description() string {
+	return fmt.Sprintf(`<a href="https://github.com/uudashr/gocognit">Gocognit</a> calculates the cognitive complexity of functions in Go source code, a measure of how hard the code is to understand. Unlike cyclomatic complexity, nesting increases the cost of control flow.
+
+Go Report Card warns on functions with cognitive complexity > %d.`, g.over())
+}
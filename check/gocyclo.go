@@ -1,9 +1,27 @@
 package check
 
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// DefaultGoCycloOver is the cyclomatic complexity above which functions
+// are reported
+const DefaultGoCycloOver = 15
+
 // GoCyclo is the check for the go cyclo command
 type GoCyclo struct {
-	Dir       string
-	Filenames []string
+	// Over is the complexity above which functions are reported,
+	// or DefaultGoCycloOver if zero
+	Over int
+}
+
+func (g GoCyclo) over() int {
+	if g.Over == 0 {
+		return DefaultGoCycloOver
+	}
+	return g.Over
 }
 
 // Name returns the name of the display name of the command
@@ -16,19 +34,42 @@ func (g GoCyclo) Weight() float64 {
 	return .10
 }
 
-// Percentage returns the percentage of .go files that pass gofmt
-func (g GoCyclo) Percentage() (float64, []FileSummary, error) {
-	return GoTool(g.Dir, g.Filenames, []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=gocyclo", "--cyclo-over=15"})
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g GoCyclo) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g GoCyclo) Severity() string {
+	return SeverityWarning
+}
+
+// FileScoped returns true, as gocyclo measures one function at a time
+func (g GoCyclo) FileScoped() bool {
+	return true
+}
+
+// WithThreshold returns the check with the complexity above which
+// functions are reported set to n
+func (g GoCyclo) WithThreshold(n int) Check {
+	g.Over = n
+	return g
+}
+
+// Run returns the percentage of .go files that pass gofmt
+func (g GoCyclo) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoTool(ctx, dir, Filenames(ctx), []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=gocyclo", "--cyclo-over=" + strconv.Itoa(g.over())})
 }
 
 // Description returns the description of GoCyclo
 func (g GoCyclo) Description() string {
-	return `<a href="https://github.com/fzipp/gocyclo">Gocyclo</a> calculates cyclomatic complexities of functions in Go source code.
+	return fmt.Sprintf(`<a href="https://github.com/fzipp/gocyclo">Gocyclo</a> calculates cyclomatic complexities of functions in Go source code.
 
 The cyclomatic complexity of a function is calculated according to the following rules:
 
 1 is the base complexity of a function
 +1 for each 'if', 'for', 'case', '&&' or '||'
 
-Go Report Card warns on functions with cyclomatic complexity > 15.`
+Go Report Card warns on functions with cyclomatic complexity > %d.`, g.over())
 }
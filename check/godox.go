@@ -0,0 +1,116 @@
+package check
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// GodoxWeight is the weight the godox check has in the overall average.
+// Tech debt comments are only reported by default.
+var GodoxWeight = 0.0
+
+// godoxKeywords are the comment prefixes that mark tech debt
+var godoxKeywords = []string{"TODO", "FIXME", "HACK"}
+
+// Godox is the check for TODO, FIXME and HACK comments
+type Godox struct{}
+
+// Name returns the name of the display name of the command
+func (g Godox) Name() string {
+	return "godox"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Godox) Weight() float64 {
+	return GodoxWeight
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Godox) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Godox) Severity() string {
+	return SeverityInfo
+}
+
+// CacheKey returns the key of the issues the check finds in a file
+func (g Godox) CacheKey() string {
+	return g.Name() + ":" + strings.Join(godoxKeywords, ",")
+}
+
+// debtKeyword returns the keyword the comment line starts with, or an
+// empty string if it does not mark tech debt
+func debtKeyword(line string) string {
+	line = strings.TrimLeft(line, " \t*")
+	for _, kw := range godoxKeywords {
+		if !strings.HasPrefix(line, kw) {
+			continue
+		}
+		// TODOS or HACKER are not keywords
+		rest := strings.TrimPrefix(line, kw)
+		if rest == "" || !unicode.IsLetter(rune(rest[0])) && !unicode.IsDigit(rune(rest[0])) {
+			return kw
+		}
+	}
+	return ""
+}
+
+// Run returns the percentage of .go files without tech debt
+// comments. Every comment is listed, so the number of errors is the
+// amount of tech debt.
+func (g Godox) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	var (
+		failed = []FileSummary{}
+		fset   = token.NewFileSet()
+	)
+	for _, f := range filenames {
+		if err := ctx.Err(); err != nil {
+			return 0, []FileSummary{}, err
+		}
+		file, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		nolint := nolintLines(fset, file, "godox")
+
+		fs := newFileSummary(dir, f)
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				text := strings.TrimPrefix(c.Text, "//")
+				text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+				start := fset.Position(c.Slash).Line
+				for i, line := range strings.Split(text, "\n") {
+					if debtKeyword(line) == "" {
+						continue
+					}
+					if nolint[start+i] {
+						suppress(ctx)
+						continue
+					}
+					fs.Errors = append(fs.Errors, Error{
+						LineNumber:  start + i,
+						ErrorString: strings.TrimSpace(strings.TrimLeft(line, " \t*")),
+					})
+				}
+			}
+		}
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// Description returns the description of Godox
+func (g Godox) Description() string {
+	return "Lists the TODO, FIXME and HACK comments in your code. The number of comments is a measure of tech debt, and can be tracked between runs."
+}
@@ -0,0 +1,72 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var debtKeywordTests = []struct {
+	line string
+	want string
+}{
+	{" TODO: fix this", "TODO"},
+	{"TODO(bob) remove", "TODO"},
+	{" * FIXME", "FIXME"},
+	{" HACK around issue 12", "HACK"},
+	{" TODOS are bad", ""},
+	{" todo: lowercase", ""},
+	{" this is not a TODO", ""},
+}
+
+func TestDebtKeyword(t *testing.T) {
+	for _, tt := range debtKeywordTests {
+		if got := debtKeyword(tt.line); got != tt.want {
+			t.Errorf("[%q] debtKeyword = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestGodox(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": `package a
+
+// TODO: document
+func A() {} // FIXME
+
+/*
+Some text
+HACK: block comment
+*/
+func B() {} /* TODO: ignored */ //nolint:godox
+`,
+		"b.go": "package a\n",
+	})
+	defer os.RemoveAll(dir)
+
+	ctx := WithFilenames(context.Background(), []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")})
+	p, failed, err := Godox{}.Run(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != .5 {
+		t.Errorf("Godox percentage = %v, want 0.5", p)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("Godox reported %d files, want 1", len(failed))
+	}
+	want := []int{3, 4, 8}
+	errs := failed[0].Errors
+	if len(errs) != len(want) {
+		t.Fatalf("Godox errors = %v, want lines %v", errs, want)
+	}
+	for i, line := range want {
+		if errs[i].LineNumber != line {
+			t.Errorf("Godox error %d line = %d, want %d", i, errs[i].LineNumber, line)
+		}
+	}
+	if errs[0].ErrorString != "TODO: document" {
+		t.Errorf("Godox error = %q, want %q", errs[0].ErrorString, "TODO: document")
+	}
+}
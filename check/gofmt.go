@@ -1,10 +1,9 @@
 package check
 
+import "context"
+
 // GoFmt is the check for the go fmt command
-type GoFmt struct {
-	Dir       string
-	Filenames []string
-}
+type GoFmt struct{}
 
 // Name returns the name of the display name of the command
 func (g GoFmt) Name() string {
@@ -16,10 +15,26 @@ func (g GoFmt) Weight() float64 {
 	return .30
 }
 
-// Percentage returns the percentage of .go files that pass gofmt
-func (g GoFmt) Percentage() (float64, []FileSummary, error) {
-	return GoTool(g.Dir, g.Filenames, []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=gofmt"})
-	// return GoFmtNative(g.Dir, g.Filenames)
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g GoFmt) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g GoFmt) Severity() string {
+	return SeverityWarning
+}
+
+// FileScoped returns true, as gofmt formats one file at a time
+func (g GoFmt) FileScoped() bool {
+	return true
+}
+
+// Run returns the percentage of .go files that pass gofmt
+func (g GoFmt) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoTool(ctx, dir, Filenames(ctx), []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=gofmt"})
+	// return GoFmtNative(dir, filenames)
 }
 
 // Description returns the description of gofmt
@@ -0,0 +1,81 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	gofumpt "mvdan.cc/gofumpt/format"
+)
+
+// GoFumpt is the check for the stricter formatting of gofumpt. It is
+// opt-in, and replaces the gofmt check when enabled.
+type GoFumpt struct{}
+
+// Name returns the name of the display name of the command
+func (g GoFumpt) Name() string {
+	return "gofumpt"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g GoFumpt) Weight() float64 {
+	return GoFmt{}.Weight()
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g GoFumpt) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g GoFumpt) Severity() string {
+	return SeverityWarning
+}
+
+// Replaces returns the name of the check that GoFumpt replaces
+func (g GoFumpt) Replaces() string {
+	return GoFmt{}.Name()
+}
+
+// Run returns the percentage of .go files that are formatted
+// according to gofumpt
+func (g GoFumpt) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	mods := Modules(ctx)
+	if mods == nil {
+		var err error
+		if mods, err = FindModules(dir); err != nil {
+			return 0, []FileSummary{}, err
+		}
+	}
+	return GoFumptNative(dir, Filenames(ctx), mods)
+}
+
+// GoFumptNative runs gofumpt via its format pkg. The rules of gofumpt
+// depend on the Go version and the path of the module of a file, which
+// are read from the go.mod of the innermost of mods that it is in.
+func GoFumptNative(dir string, filenames []string, mods []Module) (float64, []FileSummary, error) {
+	opts := make([]gofumpt.Options, len(mods))
+	for i, m := range mods {
+		opts[i].ModulePath = m.Path
+		if major, minor, ok := goDirective(m.Dir); ok {
+			opts[i].LangVersion = fmt.Sprintf("go%d.%d", major, minor)
+		}
+	}
+	return formatNative(dir, filenames, "file is not gofumpt formatted", func(f string, b []byte) (bool, error) {
+		var opt gofumpt.Options
+		if i := moduleOf(mods, f); i >= 0 {
+			opt = opts[i]
+		}
+		g, err := gofumpt.Source(b, opt)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(b, g), nil
+	})
+}
+
+// Description returns the description of GoFumpt
+func (g GoFumpt) Description() string {
+	return `<a href="https://github.com/mvdan/gofumpt">Gofumpt</a> is a stricter gofmt, which for example removes empty lines at the start and end of blocks. Enable it with <code>enable: [gofumpt]</code> in <code>` + ConfigFile + `</code>, where it replaces the gofmt check.`
+}
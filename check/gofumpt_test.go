@@ -0,0 +1,29 @@
+package check
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoFumpt(t *testing.T) {
+	dir := writeModule(t, "1.20", map[string]string{
+		"ok.go": "package m\n\nfunc f() {\n\tprintln()\n}\n",
+		// gofmt keeps the empty line at the start of the block
+		"bad.go":     "package m\n\nfunc g() {\n\n\tprintln()\n}\n",
+		"sub/go.mod": "module example.com/sub\n\ngo 1.20\n",
+		"sub/ok.go":  "package sub\n\nvar x = 1\n",
+	})
+	filenames := []string{filepath.Join(dir, "bad.go"), filepath.Join(dir, "ok.go"), filepath.Join(dir, "sub", "ok.go")}
+
+	p, failed, err := GoFumpt{}.Run(WithFilenames(context.Background(), filenames), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || filepath.Base(failed[0].Filename) != "bad.go" {
+		t.Errorf("GoFumpt failed = %+v, want bad.go", failed)
+	}
+	if p >= 1 {
+		t.Errorf("GoFumpt percentage = %v, want less than 1", p)
+	}
+}
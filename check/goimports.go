@@ -0,0 +1,42 @@
+package check
+
+import "context"
+
+// GoImports is the check for the goimports command
+type GoImports struct{}
+
+// Name returns the name of the display name of the command
+func (g GoImports) Name() string {
+	return "goimports"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g GoImports) Weight() float64 {
+	return .10
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g GoImports) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g GoImports) Severity() string {
+	return SeverityWarning
+}
+
+// FileScoped returns true, as goimports formats one file at a time
+func (g GoImports) FileScoped() bool {
+	return true
+}
+
+// Run returns the percentage of .go files that pass goimports
+func (g GoImports) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoImportsNative(dir, Filenames(ctx))
+}
+
+// Description returns the description of goimports
+func (g GoImports) Description() string {
+	return `<a href="https://godoc.org/golang.org/x/tools/cmd/goimports">Goimports</a> checks that imports are grouped and sorted, and that no imports are missing or unused. Formatting issues are reported by gofmt.`
+}
@@ -0,0 +1,154 @@
+package check
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// goDirective returns the major and minor version from the go directive
+// in the go.mod file at the root of dir. ok is false if there is no go.mod,
+// or it has no (parseable) go directive.
+func goDirective(dir string) (major, minor int, ok bool) {
+	file, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return 0, 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "go" {
+			continue
+		}
+		// the go directive looks like "go 1.20" or "go 1.21.3"
+		parts := strings.Split(fields[1], ".")
+		if len(parts) < 2 {
+			return 0, 0, false
+		}
+		major, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, 0, false
+		}
+		minor, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, false
+		}
+		return major, minor, true
+	}
+
+	return 0, 0, false
+}
+
+// targetsGo reports whether the module in dir declares a go directive
+// of at least 1.minor. Directories without a go.mod are assumed not to.
+func targetsGo(dir string, minor int) bool {
+	major, m, ok := goDirective(dir)
+	if !ok {
+		return false
+	}
+	return major > 1 || (major == 1 && m >= minor)
+}
+
+// requirement is a module requirement in a go.mod file
+type requirement struct {
+	version  string
+	line     int
+	indirect bool
+}
+
+// parseRequires returns the requirements in the go.mod file data,
+// keyed by module path
+func parseRequires(data []byte) map[string]requirement {
+	reqs := make(map[string]requirement)
+	var inBlock bool
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "//"):
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+			continue
+		case fields[0] == "require":
+			fields = fields[1:]
+		case !inBlock:
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		reqs[fields[0]] = requirement{
+			version:  fields[1],
+			line:     i + 1,
+			indirect: strings.Contains(line, "// indirect"),
+		}
+	}
+	return reqs
+}
+
+// compareSemver compares two semantic versions like v1.2.3 or
+// v0.0.0-20190101000000-abcdef123456, returning -1, 0 or 1. Build
+// metadata is ignored, and a prerelease sorts before its release.
+func compareSemver(a, b string) int {
+	split := func(v string) (nums []int, pre string) {
+		v = strings.TrimPrefix(v, "v")
+		if i := strings.Index(v, "+"); i != -1 {
+			v = v[:i]
+		}
+		if i := strings.Index(v, "-"); i != -1 {
+			v, pre = v[:i], v[i+1:]
+		}
+		for _, p := range strings.Split(v, ".") {
+			n, _ := strconv.Atoi(p)
+			nums = append(nums, n)
+		}
+		for len(nums) < 3 {
+			nums = append(nums, 0)
+		}
+		return nums, pre
+	}
+
+	an, apre := split(a)
+	bn, bpre := split(b)
+	for i := 0; i < 3; i++ {
+		switch {
+		case an[i] < bn[i]:
+			return -1
+		case an[i] > bn[i]:
+			return 1
+		}
+	}
+	switch {
+	case apre == bpre:
+		return 0
+	case apre == "":
+		return 1
+	case bpre == "":
+		return -1
+	case apre < bpre:
+		return -1
+	}
+	return 1
+}
+
+// modulePathMajor splits a module path like example.com/foo/v3 into its
+// path without the major version suffix and the major version. Paths
+// without a suffix are major version 1.
+func modulePathMajor(path string) (base string, major int) {
+	i := strings.LastIndex(path, "/v")
+	if i == -1 {
+		return path, 1
+	}
+	n, err := strconv.Atoi(path[i+2:])
+	if err != nil || n < 2 {
+		return path, 1
+	}
+	return path[:i], n
+}
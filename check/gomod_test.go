@@ -0,0 +1,51 @@
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTidyDiff(t *testing.T) {
+	before := []byte(`module example.com/m
+
+go 1.20
+
+require (
+	example.com/unused v1.0.0
+	example.com/old v1.0.0
+	example.com/indirect v1.0.0
+)
+`)
+	after := []byte(`module example.com/m
+
+go 1.20
+
+require (
+	example.com/missing v1.1.0
+	example.com/old v1.2.0
+	example.com/indirect v1.0.0 // indirect
+)
+`)
+	want := []string{
+		"requirement example.com/indirect should be marked // indirect",
+		"missing requirement example.com/missing v1.1.0",
+		"requirement example.com/old v1.0.0 would be changed to v1.2.0",
+		"unused requirement example.com/unused v1.0.0",
+	}
+
+	errs := tidyDiff(before, after)
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.ErrorString)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("tidyDiff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if errs[3].LineNumber != 6 {
+		t.Errorf("tidyDiff unused line = %d, want 6", errs[3].LineNumber)
+	}
+
+	if errs := tidyDiff(before, before); len(errs) != 0 {
+		t.Errorf("tidyDiff(same) = %v, want no errors", errs)
+	}
+}
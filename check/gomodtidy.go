@@ -0,0 +1,176 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GoModTidy is the check for whether go.mod and go.sum are tidy
+type GoModTidy struct{}
+
+// Name returns the name of the display name of the command
+func (g GoModTidy) Name() string {
+	return "go_mod_tidy"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g GoModTidy) Weight() float64 {
+	return .05
+}
+
+// UsesNetwork returns true, as go mod tidy downloads the modules that
+// are not in the module cache
+func (g GoModTidy) UsesNetwork() bool {
+	return true
+}
+
+// copyDir copies the files in src to dst, skipping the .git directory
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case fi.IsDir() && fi.Name() == ".git":
+			return filepath.SkipDir
+		case fi.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !fi.Mode().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// Run returns 1 if go.mod and go.sum are tidy and 0 if running
+// go mod tidy would change them. Repos without a go.mod are not checked.
+func (g GoModTidy) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	gomod := filepath.Join(dir, "go.mod")
+	before, err := ioutil.ReadFile(gomod)
+	if os.IsNotExist(err) {
+		return 1, []FileSummary{}, nil
+	} else if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	sumBefore, err := ioutil.ReadFile(filepath.Join(dir, "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, []FileSummary{}, err
+	}
+
+	// tidy a throwaway copy, so the clone used by the other checks
+	// is not modified
+	tmp, err := ioutil.TempDir("", "goreportcard-tidy")
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := copyDir(dir, tmp); err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	cmd := commandContext(ctx, tmp, []string{"GO111MODULE=on", "GOFLAGS=-mod=mod"}, "go", "mod", "tidy")
+	if out, err := combinedOutput(ctx, cmd); ctx.Err() != nil {
+		return 0, []FileSummary{}, ctx.Err()
+	} else if _, ok := err.(*LimitError); ok {
+		return 0, []FileSummary{}, err
+	} else if err != nil {
+		return 0, []FileSummary{}, fmt.Errorf("go mod tidy: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	after, err := ioutil.ReadFile(filepath.Join(tmp, "go.mod"))
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	sumAfter, err := ioutil.ReadFile(filepath.Join(tmp, "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, []FileSummary{}, err
+	}
+
+	errs := tidyDiff(before, after)
+	if !bytes.Equal(sumBefore, sumAfter) {
+		errs = append(errs, Error{ErrorString: "go.sum is not up to date"})
+	}
+	if len(errs) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	fs := newFileSummary(dir, gomod)
+	fs.Errors = errs
+	return 0, []FileSummary{fs}, nil
+}
+
+// tidyDiff compares the go.mod file before and after go mod tidy, and
+// returns an error for every requirement that is missing, unused or
+// would change
+func tidyDiff(before, after []byte) []Error {
+	var errs []Error
+	if bytes.Equal(before, after) {
+		return errs
+	}
+
+	old, tidy := parseRequires(before), parseRequires(after)
+	var paths []string
+	for path := range old {
+		paths = append(paths, path)
+	}
+	for path := range tidy {
+		if _, ok := old[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		o, inOld := old[path]
+		t, inTidy := tidy[path]
+		switch {
+		case !inTidy:
+			errs = append(errs, Error{LineNumber: o.line, ErrorString: fmt.Sprintf("unused requirement %s %s", path, o.version)})
+		case !inOld:
+			errs = append(errs, Error{ErrorString: fmt.Sprintf("missing requirement %s %s", path, t.version)})
+		case o.version != t.version:
+			errs = append(errs, Error{LineNumber: o.line, ErrorString: fmt.Sprintf("requirement %s %s would be changed to %s", path, o.version, t.version)})
+		case o.indirect != t.indirect && t.indirect:
+			errs = append(errs, Error{LineNumber: o.line, ErrorString: fmt.Sprintf("requirement %s should be marked // indirect", path)})
+		case o.indirect != t.indirect:
+			errs = append(errs, Error{LineNumber: o.line, ErrorString: fmt.Sprintf("requirement %s is not indirect", path)})
+		}
+	}
+
+	if len(errs) == 0 {
+		// for example the go directive or formatting changed
+		errs = append(errs, Error{ErrorString: "go.mod would be changed by go mod tidy"})
+	}
+	return errs
+}
+
+// Description returns the description of GoModTidy
+func (g GoModTidy) Description() string {
+	return "Checks whether <code>go mod tidy</code> would change your <code>go.mod</code> or <code>go.sum</code>, for example because requirements are missing or unused."
+}
@@ -0,0 +1,114 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Gosec is the check for the gosec security scanner
+type Gosec struct{}
+
+// Name returns the name of the display name of the command
+func (g Gosec) Name() string {
+	return "gosec"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Gosec) Weight() float64 {
+	return .05
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Gosec) FileScored() bool {
+	return true
+}
+
+// Category returns the report section of the check
+func (g Gosec) Category() string {
+	return CategorySecurity
+}
+
+// gosecIssue is a single finding in the JSON output of gosec
+type gosecIssue struct {
+	Severity string `json:"severity"`
+	RuleID   string `json:"rule_id"`
+	Details  string `json:"details"`
+	File     string `json:"file"`
+	// Line is either a single line, or a range like "12-14"
+	Line string `json:"line"`
+}
+
+type gosecReport struct {
+	Issues []gosecIssue `json:"Issues"`
+}
+
+// Run returns the percentage of .go files without security issues. Repos
+// with several modules are scanned one module at a time.
+func (g Gosec) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	params := []string{"-fmt=json"}
+	for _, skip := range skipDirs {
+		params = append(params, "-exclude-dir="+skip)
+	}
+	params = append(params, "./...")
+
+	var report gosecReport
+	for _, modDir := range moduleDirs(ctx, dir) {
+		out, err := runRepoTool(ctx, modDir, "gosec", params...)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		if len(out) > 0 {
+			var modReport gosecReport
+			if err := json.Unmarshal(out, &modReport); err != nil {
+				return 0, []FileSummary{}, err
+			}
+			report.Issues = append(report.Issues, modReport.Issues...)
+		}
+	}
+
+	fsMap := make(map[string]FileSummary)
+	for _, issue := range report.Issues {
+		filename := reportedFilename(issue.File)
+		if skipReported(filename) {
+			continue
+		}
+
+		line, err := strconv.Atoi(strings.SplitN(issue.Line, "-", 2)[0])
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+
+		fs := fsMap[filename]
+		if fs.Filename == "" {
+			fs.Filename = makeFilename(filename)
+			fs.FileURL = fileURL(dir, filename)
+		}
+		fs.Errors = append(fs.Errors, Error{
+			LineNumber:  line,
+			ErrorString: issue.Details,
+			RuleID:      issue.RuleID,
+			Severity:    normalizeSeverity(issue.Severity),
+		})
+		fsMap[filename] = fs
+	}
+
+	var failed = []FileSummary{}
+	for _, v := range fsMap {
+		failed = append(failed, v)
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// Description returns the description of Gosec
+func (g Gosec) Description() string {
+	return `<a href="https://github.com/securego/gosec">gosec</a> inspects source code for security problems, such as hardcoded credentials, weak cryptography and unsafe command execution.`
+}
@@ -0,0 +1,245 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VulnCacheTTL is how long govulncheck results are reused for a
+// module whose dependencies have not changed. Results expire so that
+// newly published vulnerabilities are picked up.
+var VulnCacheTTL = 24 * time.Hour
+
+type vulnCacheEntry struct {
+	failed  []FileSummary
+	percent float64
+	created time.Time
+}
+
+var vulnCache = struct {
+	sync.Mutex
+	entries map[string]vulnCacheEntry
+}{entries: make(map[string]vulnCacheEntry)}
+
+// GoVulnCheck is the check for known vulnerabilities in the
+// dependencies of a module, using govulncheck
+type GoVulnCheck struct{}
+
+// Name returns the name of the display name of the command
+func (g GoVulnCheck) Name() string {
+	return "vulnerabilities"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g GoVulnCheck) Weight() float64 {
+	return 0.0
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g GoVulnCheck) FileScored() bool {
+	return true
+}
+
+// Category returns the report section of the check
+func (g GoVulnCheck) Category() string {
+	return CategorySecurity
+}
+
+// UsesNetwork returns true, as govulncheck fetches the vulnerability
+// database and the modules of the repo
+func (g GoVulnCheck) UsesNetwork() bool {
+	return true
+}
+
+// vulnCacheKey returns the cache key for the module in dir, which
+// changes whenever its dependencies change
+func vulnCacheKey(dir string) (string, error) {
+	h := sha256.New()
+	for _, name := range []string{"go.mod", "go.sum"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil && name == "go.mod" {
+			return "", err
+		}
+		h.Write(b)
+	}
+	return dir + "@" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Run returns the percentage of .go files that do not call
+// vulnerable code. Repos without a go.mod are not checked.
+func (g GoVulnCheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+	key, err := vulnCacheKey(dir)
+	if err != nil {
+		// govulncheck only supports modules
+		return 1, []FileSummary{}, nil
+	}
+
+	vulnCache.Lock()
+	e, ok := vulnCache.entries[key]
+	vulnCache.Unlock()
+	if ok && time.Since(e.created) < VulnCacheTTL {
+		return e.percent, e.failed, nil
+	}
+
+	cmd := commandContext(ctx, dir, nil, "govulncheck", "-json", "./...")
+	out, err := output(ctx, cmd)
+	if ctx.Err() != nil {
+		return 0, []FileSummary{}, ctx.Err()
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		// govulncheck exits 3 when vulnerabilities are found
+		if exitErr.ExitCode() != 3 {
+			return 0, []FileSummary{}, err
+		}
+	} else if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	failed, err := parseGoVulnCheck(bytes.NewReader(out), dir)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	percent, failed, err := toolPercentage(filenames, failed)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	vulnCache.Lock()
+	vulnCache.entries[key] = vulnCacheEntry{failed, percent, time.Now()}
+	vulnCache.Unlock()
+
+	return percent, failed, nil
+}
+
+type vulnFrame struct {
+	Module   string `json:"module"`
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	Receiver string `json:"receiver"`
+	Position *struct {
+		Filename string `json:"filename"`
+		Line     int    `json:"line"`
+	} `json:"position"`
+}
+
+func (f vulnFrame) String() string {
+	name := f.Package
+	if f.Receiver != "" {
+		name += "." + strings.TrimPrefix(f.Receiver, "*")
+	}
+	if f.Function != "" {
+		name += "." + f.Function
+	}
+	return name
+}
+
+type vulnMessage struct {
+	OSV *struct {
+		ID      string   `json:"id"`
+		Aliases []string `json:"aliases"`
+		Summary string   `json:"summary"`
+	} `json:"osv"`
+	Finding *struct {
+		OSV   string      `json:"osv"`
+		Trace []vulnFrame `json:"trace"`
+	} `json:"finding"`
+}
+
+// parseGoVulnCheck parses the stream of JSON messages written by
+// govulncheck -json. Only findings where vulnerable code is called are
+// reported, at the position in the repo where the call path starts.
+func parseGoVulnCheck(r io.Reader, dir string) ([]FileSummary, error) {
+	type osv struct {
+		aliases []string
+		summary string
+	}
+	osvs := make(map[string]osv)
+	seen := make(map[string]bool)
+	fsMap := make(map[string]FileSummary)
+
+	dec := json.NewDecoder(r)
+	for {
+		var msg vulnMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.OSV != nil {
+			osvs[msg.OSV.ID] = osv{msg.OSV.Aliases, msg.OSV.Summary}
+		}
+		f := msg.Finding
+		// the first frame is the vulnerable symbol, and the last one is
+		// the entry point in the repo; a trace without a function only
+		// means the vulnerable module or package is imported
+		if f == nil || len(f.Trace) == 0 || f.Trace[0].Function == "" {
+			continue
+		}
+		entry := f.Trace[len(f.Trace)-1]
+		if entry.Position == nil {
+			continue
+		}
+
+		filename := reportedFilename(entry.Position.Filename)
+		key := fmt.Sprintf("%s:%s:%d", f.OSV, filename, entry.Position.Line)
+		if seen[key] || skipReported(filename) {
+			continue
+		}
+		seen[key] = true
+
+		var path []string
+		for i := len(f.Trace) - 1; i >= 0; i-- {
+			path = append(path, f.Trace[i].String())
+		}
+		id := f.OSV
+		if aliases := osvs[f.OSV].aliases; len(aliases) > 0 {
+			id += " (" + strings.Join(aliases, ", ") + ")"
+		}
+
+		fs := fsMap[filename]
+		if fs.Filename == "" {
+			fs.Filename = makeFilename(filename)
+			fs.FileURL = fileURL(dir, filename)
+		}
+		fs.Errors = append(fs.Errors, Error{
+			LineNumber:  entry.Position.Line,
+			ErrorString: fmt.Sprintf("%s: %s, called via %s", id, osvs[f.OSV].summary, strings.Join(path, " -> ")),
+			RuleID:      f.OSV,
+			Severity:    SeverityError,
+		})
+		fsMap[filename] = fs
+	}
+
+	var failed = []FileSummary{}
+	for _, v := range fsMap {
+		failed = append(failed, v)
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Filename < failed[j].Filename })
+
+	return failed, nil
+}
+
+// Description returns the description of GoVulnCheck
+func (g GoVulnCheck) Description() string {
+	return `<a href="https://go.dev/security/vuln">govulncheck</a> reports known vulnerabilities (CVEs and GO IDs) in the dependencies of your module that your code actually calls, with the call path that reaches them. It does not affect the grade.`
+}
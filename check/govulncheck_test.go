@@ -0,0 +1,30 @@
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGoVulnCheck(t *testing.T) {
+	out := `{"config":{"protocol_version":"v1.0.0"}}
+{"osv":{"id":"GO-2023-1571","aliases":["CVE-2022-41723"],"summary":"Denial of service via crafted HTTP/2 stream"}}
+{"finding":{"osv":"GO-2023-1571","trace":[{"module":"golang.org/x/net","package":"golang.org/x/net/http2"}]}}
+{"finding":{"osv":"GO-2023-1571","trace":[{"module":"golang.org/x/net","package":"golang.org/x/net/http2","function":"ReadFrame","receiver":"*Framer"},{"module":"github.com/foo/bar","package":"github.com/foo/bar","function":"serve","position":{"filename":"/srv/repos/src/github.com/foo/bar/main.go","line":12}}]}}
+`
+	failed, err := parseGoVulnCheck(strings.NewReader(out), "repos/src/github.com/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || len(failed[0].Errors) != 1 {
+		t.Fatalf("parseGoVulnCheck = %v, want a single error", failed)
+	}
+	e := failed[0].Errors[0]
+	if e.LineNumber != 12 || e.RuleID != "GO-2023-1571" {
+		t.Errorf("parseGoVulnCheck error = %+v, want GO-2023-1571 at line 12", e)
+	}
+	for _, want := range []string{"CVE-2022-41723", "github.com/foo/bar.serve -> golang.org/x/net/http2.Framer.ReadFrame"} {
+		if !strings.Contains(e.ErrorString, want) {
+			t.Errorf("parseGoVulnCheck message %q does not contain %q", e.ErrorString, want)
+		}
+	}
+}
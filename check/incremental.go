@@ -0,0 +1,183 @@
+package check
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Previous is an earlier grade of a repo, which the checks that look at
+// one file at a time reuse for the files that did not change since
+type Previous struct {
+	// Results are the results of the checks at the earlier commit
+	Results []CheckResult
+	// Changed are the files that changed since the earlier commit,
+	// relative to the repo root and with forward slashes, as returned
+	// by ChangedFiles
+	Changed []string
+}
+
+// fileScoped is implemented by checks whose issues in a file only depend
+// on the files in its directory. When a repo is graded incrementally, they
+// are only run on the directories in which files changed.
+type fileScoped interface {
+	FileScoped() bool
+}
+
+// regradeFiles are the files whose changes can change the issues in any
+// Go file, so that a repo is graded from scratch when they change
+var regradeFiles = []string{ConfigFile, BaselineFile, "go.mod", "revive.toml", ".revive.toml"}
+
+// HeadCommit returns the hash of the commit checked out in the git
+// repository at dir
+func HeadCommit(dir string) (string, error) {
+	out, err := gitStdout(dir, "rev-parse", "HEAD")
+	return strings.TrimSpace(out), err
+}
+
+// ChangedFiles returns the files that differ between two commits of the
+// git repository at dir, relative to dir. If dir is a directory of the
+// repository, only the files in it are returned.
+func ChangedFiles(dir, from, to string) ([]string, error) {
+	out, err := gitStdout(dir, "diff", "--name-only", "--relative", "-z", from, to)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" {
+			changed = append(changed, f)
+		}
+	}
+	return changed, nil
+}
+
+// incremental returns the result of ck in p, if ck can be graded
+// incrementally, along with the changed files. ok is false if the repo
+// must be graded from scratch.
+func (p *Previous) incremental(ck Check) (prev CheckResult, changed map[string]bool, ok bool) {
+	if p == nil {
+		return CheckResult{}, nil, false
+	}
+	if fs, isFileScoped := ck.(fileScoped); !isFileScoped || !fs.FileScoped() {
+		return CheckResult{}, nil, false
+	}
+	changed = make(map[string]bool, len(p.Changed))
+	for _, f := range p.Changed {
+		if contains(regradeFiles, f) {
+			return CheckResult{}, nil, false
+		}
+		changed[f] = true
+	}
+	for _, r := range p.Results {
+		if r.Name == ck.Name() {
+			// checks that failed, or found issues in files through
+			// other files, are run again
+			return r, changed, r.Error == "" && r.Status == ""
+		}
+	}
+	return CheckResult{}, nil, false
+}
+
+type incrementalKey struct{}
+
+// withIncremental returns a copy of ctx that tells checks that the files
+// it carries are the files that changed, rather than all files of the repo
+func withIncremental(ctx context.Context) context.Context {
+	return context.WithValue(ctx, incrementalKey{}, true)
+}
+
+// toolTargets returns the arguments that make a tool check the repo in
+// dir: all of it, or the directories of the files carried by ctx when
+// the repo is graded incrementally
+func toolTargets(ctx context.Context, dir string) []string {
+	if incremental, _ := ctx.Value(incrementalKey{}).(bool); !incremental {
+		return []string{dir + "/..."}
+	}
+	var dirs []string
+	for _, fp := range Filenames(ctx) {
+		if d := filepath.Dir(fp); !contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// relativeTargets returns the targets of a tool, which are dir/... or
+// directories in dir, relative to dir, for running the tool in dir
+func relativeTargets(dir string, targets []string) []string {
+	rel := make([]string, len(targets))
+	for i, t := range targets {
+		if t == dir+"/..." {
+			rel[i] = "./..."
+			continue
+		}
+		r, err := filepath.Rel(dir, t)
+		if err != nil {
+			rel[i] = t
+			continue
+		}
+		rel[i] = "./" + filepath.ToSlash(r)
+	}
+	return rel
+}
+
+// targetSummaries drops the summaries of files that a tool reported on
+// although ctx does not carry them, such as generated files
+func targetSummaries(ctx context.Context, dir string, summaries []FileSummary) []FileSummary {
+	if incremental, _ := ctx.Value(incrementalKey{}).(bool); !incremental {
+		return summaries
+	}
+	names := make(map[string]bool)
+	for _, fp := range Filenames(ctx) {
+		names[newFileSummary(dir, fp).Filename] = true
+	}
+	kept := []FileSummary{}
+	for _, fs := range summaries {
+		if names[fs.Filename] {
+			kept = append(kept, fs)
+		}
+	}
+	return kept
+}
+
+// runIncremental runs ck only on the files in the directories in which
+// files changed since prev, and takes the issues of the other files from
+// prev
+func (c Checker) runIncremental(ctx context.Context, dir string, ck Check, prev CheckResult, changed map[string]bool) (float64, []FileSummary, error) {
+	changedDirs := make(map[string]bool)
+	for f := range changed {
+		changedDirs[path.Dir(f)] = true
+	}
+	inChangedDir := func(name string) bool {
+		return changedDirs[path.Dir(RelFilename(dir, name))]
+	}
+
+	filenames := Filenames(ctx)
+	present := make(map[string]bool, len(filenames))
+	var run []string
+	for _, fp := range filenames {
+		name := newFileSummary(dir, fp).Filename
+		present[name] = true
+		if inChangedDir(name) {
+			run = append(run, fp)
+		}
+	}
+
+	// files that were deleted since are left out
+	summaries := []FileSummary{}
+	for _, fs := range prev.FileSummaries {
+		if present[fs.Filename] && !inChangedDir(fs.Filename) {
+			summaries = append(summaries, fs)
+		}
+	}
+	if len(run) > 0 {
+		_, found, err := c.runWithTimeout(withIncremental(WithFilenames(ctx, run)), dir, ck)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		summaries = append(summaries, found...)
+	}
+	return toolPercentage(filenames, summaries)
+}
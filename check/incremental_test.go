@@ -0,0 +1,110 @@
+package check
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// badCheck reports the files that contain "bad", and records the files
+// it was run on
+type badCheck struct {
+	mu  *sync.Mutex
+	ran *[]string
+}
+
+func (g badCheck) Name() string        { return "bad" }
+func (g badCheck) Description() string { return "" }
+func (g badCheck) Weight() float64     { return 1 }
+func (g badCheck) FileScoped() bool    { return true }
+
+func (g badCheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	failed := []FileSummary{}
+	for _, f := range filenames {
+		g.mu.Lock()
+		*g.ran = append(*g.ran, strings.TrimPrefix(filepath.ToSlash(f), filepath.ToSlash(dir)+"/"))
+		g.mu.Unlock()
+		src, err := ioutil.ReadFile(f)
+		if err != nil {
+			return 0, nil, err
+		}
+		if strings.Contains(string(src), "bad") {
+			fs := newFileSummary(dir, f)
+			fs.Errors = append(fs.Errors, Error{LineNumber: 1, ErrorString: "bad"})
+			failed = append(failed, fs)
+		}
+	}
+	return toolPercentage(filenames, failed)
+}
+
+func TestCheckerPrevious(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goreportcard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/a.go", "package a // bad\n")
+	write("a/b.go", "package a\n")
+	write("b/c.go", "package b // bad\n")
+	write("b/d.go", "package b\n")
+	gitOutput(t, dir, "init", "-q")
+	gitOutput(t, dir, "add", ".")
+	gitOutput(t, dir, "commit", "-q", "-m", "first")
+	first, err := HeadCommit(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	ck := badCheck{mu: &sync.Mutex{}, ran: &ran}
+	files := []string{filepath.Join(dir, "a/a.go"), filepath.Join(dir, "a/b.go"), filepath.Join(dir, "b/c.go"), filepath.Join(dir, "b/d.go")}
+	results := Checker{}.run(WithFilenames(context.Background(), files), dir, []Check{ck})
+
+	write("b/c.go", "package b\n")
+	write("b/d.go", "package b // bad\n")
+	gitOutput(t, dir, "commit", "-q", "-a", "-m", "second")
+	second, err := HeadCommit(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := ChangedFiles(dir, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b/c.go b/d.go"; strings.Join(changed, " ") != want {
+		t.Fatalf("ChangedFiles = %v, want %s", changed, want)
+	}
+
+	ran = nil
+	c := Checker{Previous: &Previous{Results: results, Changed: changed}}
+	r := c.run(WithFilenames(context.Background(), files), dir, []Check{ck})[0]
+	if want := "b/c.go b/d.go"; strings.Join(ran, " ") != want {
+		t.Errorf("ran on %v, want %s", ran, want)
+	}
+	var failed []string
+	for _, fs := range r.FileSummaries {
+		failed = append(failed, filepath.Base(fs.Filename))
+	}
+	sort.Strings(failed)
+	if want := "a.go d.go"; strings.Join(failed, " ") != want {
+		t.Errorf("files with issues = %v, want %s", failed, want)
+	}
+	if r.Percentage != 0.5 {
+		t.Errorf("percentage = %v, want 0.5", r.Percentage)
+	}
+}
@@ -1,10 +1,9 @@
 package check
 
+import "context"
+
 // IneffAssign is the check for the ineffassign command
-type IneffAssign struct {
-	Dir       string
-	Filenames []string
-}
+type IneffAssign struct{}
 
 // Name returns the name of the display name of the command
 func (g IneffAssign) Name() string {
@@ -16,9 +15,15 @@ func (g IneffAssign) Weight() float64 {
 	return 0.05
 }
 
-// Percentage returns the percentage of .go files that pass gofmt
-func (g IneffAssign) Percentage() (float64, []FileSummary, error) {
-	return GoTool(g.Dir, g.Filenames, []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=ineffassign"})
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g IneffAssign) FileScored() bool {
+	return true
+}
+
+// Run returns the percentage of .go files that pass ineffassign
+func (g IneffAssign) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoTool(ctx, dir, Filenames(ctx), []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=ineffassign"})
 }
 
 // Description returns the description of IneffAssign
@@ -0,0 +1,73 @@
+package check
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// InfraMarker is a file that indicates operational maturity of a repo,
+// such as a CI configuration. The marker is present if any of its glob
+// patterns, relative to the repo root, matches a file.
+type InfraMarker struct {
+	Name     string
+	Patterns []string
+}
+
+// DefaultInfraMarkers are the markers checked by Infrastructure
+var DefaultInfraMarkers = []InfraMarker{
+	{"CI configuration", []string{
+		".github/workflows/*.yml",
+		".github/workflows/*.yaml",
+		".gitlab-ci.yml",
+		".circleci/config.yml",
+		".travis.yml",
+	}},
+	{"go.mod", []string{"go.mod"}},
+	{"Makefile or Taskfile", []string{
+		"Makefile",
+		"makefile",
+		"GNUmakefile",
+		"Taskfile.yml",
+		"Taskfile.yaml",
+	}},
+}
+
+// Infrastructure checks dir for the DefaultInfraMarkers
+func Infrastructure(dir string) (float64, []FileSummary, error) {
+	return InfrastructureMarkers(dir, DefaultInfraMarkers)
+}
+
+// InfrastructureMarkers checks dir for the presence of the given markers.
+// The percentage is the fraction of markers present, and the missing
+// markers are listed as errors of a single FileSummary.
+func InfrastructureMarkers(dir string, markers []InfraMarker) (float64, []FileSummary, error) {
+	if len(markers) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	missing := FileSummary{Errors: []Error{}}
+	var found int
+outer:
+	for _, m := range markers {
+		for _, pattern := range m.Patterns {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern))
+			if err != nil {
+				return 0, []FileSummary{}, err
+			}
+			if len(matches) > 0 {
+				found++
+				continue outer
+			}
+		}
+		missing.Errors = append(missing.Errors, Error{
+			ErrorString: fmt.Sprintf("missing %s (%s)", m.Name, strings.Join(m.Patterns, ", ")),
+		})
+	}
+
+	if len(missing.Errors) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	return float64(found) / float64(len(markers)), []FileSummary{missing}, nil
+}
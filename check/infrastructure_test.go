@@ -0,0 +1,43 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInfrastructure(t *testing.T) {
+	dir := writeModule(t, "1.20", map[string]string{"a.go": "package m\n"})
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, ".github", "workflows"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, ".github", "workflows", "ci.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	p, fs, err := Infrastructure(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2.0 / 3.0; p != want {
+		t.Errorf("Infrastructure percent = %f, want %f", p, want)
+	}
+	if len(fs) != 1 || len(fs[0].Errors) != 1 {
+		t.Fatalf("Infrastructure summaries = %v, want one missing marker", fs)
+	}
+	if msg := fs[0].Errors[0].ErrorString; !strings.Contains(msg, "Makefile") {
+		t.Errorf("Infrastructure missing = %q, want Makefile", msg)
+	}
+
+	p, fs, err = InfrastructureMarkers(dir, []InfraMarker{{"go.mod", []string{"go.mod"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != 1 || len(fs) != 0 {
+		t.Errorf("InfrastructureMarkers(go.mod) = %f, %v, want 1 and no summaries", p, fs)
+	}
+}
@@ -0,0 +1,110 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// LibraryExits is the check for calls that stop the program in packages
+// other than main. It is opt-in, as not every repo is a library.
+type LibraryExits struct{}
+
+// Name returns the name of the display name of the command
+func (g LibraryExits) Name() string {
+	return "library_exits"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g LibraryExits) Weight() float64 {
+	return .05
+}
+
+// Severity returns the severity of the issues the check reports
+func (g LibraryExits) Severity() string {
+	return SeverityWarning
+}
+
+// exitCall returns the name of the function if call panics or exits
+// the program, or an empty string otherwise
+func exitCall(call *ast.CallExpr) string {
+	if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "panic" && id.Obj == nil {
+		return "panic"
+	}
+	for _, name := range []string{"Fatal", "Fatalf", "Fatalln"} {
+		if isPkgCall(call, "log", name) {
+			return "log." + name
+		}
+	}
+	if isPkgCall(call, "os", "Exit") {
+		return "os.Exit"
+	}
+	return ""
+}
+
+// Run returns the percentage of .go files in non-main packages that
+// do not call panic, log.Fatal or os.Exit. Test files are not checked.
+func (g LibraryExits) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	var (
+		failed  = []FileSummary{}
+		fset    = token.NewFileSet()
+		checked int
+	)
+	for _, f := range filenames {
+		if err := ctx.Err(); err != nil {
+			return 0, []FileSummary{}, err
+		}
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		if file.Name.Name == "main" {
+			continue
+		}
+		checked++
+		nolint := nolintLines(fset, file, "library_exits")
+
+		fs := newFileSummary(dir, f)
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			name := exitCall(call)
+			line := fset.Position(call.Pos()).Line
+			if name == "" {
+				return true
+			}
+			if nolint[line] {
+				suppress(ctx)
+				return true
+			}
+			fs.Errors = append(fs.Errors, Error{
+				LineNumber:  line,
+				ErrorString: fmt.Sprintf("%s in a library package, return an error instead", name),
+			})
+			return true
+		})
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	if checked == 0 {
+		return 1, failed, nil
+	}
+	return float64(checked-len(failed)) / float64(checked), failed, nil
+}
+
+// Description returns the description of LibraryExits
+func (g LibraryExits) Description() string {
+	return "Finds calls to <code>panic</code>, <code>log.Fatal</code> and <code>os.Exit</code> outside of package main. Libraries should return errors and leave it to the program to decide whether to exit. Enable this check with <code>enable: [library_exits]</code> in <code>" + ConfigFile + "</code>."
+}
@@ -0,0 +1,49 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryExits(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": `package a
+
+import (
+	"log"
+	"os"
+)
+
+func a() {
+	if len(os.Args) == 0 {
+		panic("no args")
+	}
+	log.Fatalf("x")
+	os.Exit(1) //nolint:library_exits
+}
+`,
+		"main.go":   "package main\n\nimport \"os\"\n\nfunc main() { os.Exit(1) }\n",
+		"a_test.go": "package a\n\nfunc init() { panic(1) }\n",
+	})
+	defer os.RemoveAll(dir)
+
+	var filenames []string
+	for _, f := range []string{"a.go", "main.go", "a_test.go"} {
+		filenames = append(filenames, filepath.Join(dir, f))
+	}
+	p, failed, err := LibraryExits{}.Run(WithFilenames(context.Background(), filenames), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != 0 {
+		t.Errorf("LibraryExits percentage = %v, want 0", p)
+	}
+	if len(failed) != 1 || len(failed[0].Errors) != 2 {
+		t.Fatalf("LibraryExits = %v, want 2 errors in a.go", failed)
+	}
+	if e := failed[0].Errors[1]; e.LineNumber != 12 || e.ErrorString != "log.Fatalf in a library package, return an error instead" {
+		t.Errorf("LibraryExits error = %+v", e)
+	}
+}
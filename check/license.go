@@ -1,16 +1,23 @@
 package check
 
 import (
+	"context"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
 )
 
+// LicenseWeight is the weight the license check has in the overall
+// average. It can be changed before any checks are run.
+var LicenseWeight = .05
+
+// UnrecognizedLicenseScore is the percentage given to repos with a
+// license file that could not be identified as a known license
+var UnrecognizedLicenseScore = .5
+
 // License is the check for the existence of a license file
-type License struct {
-	Dir       string
-	Filenames []string
-}
+type License struct{}
 
 // Name returns the name of the display name of the command
 func (g License) Name() string {
@@ -19,7 +26,7 @@ func (g License) Name() string {
 
 // Weight returns the weight this check has in the overall average
 func (g License) Weight() float64 {
-	return .05
+	return LicenseWeight
 }
 
 // thank you https://github.com/ryanuber/go-license and client9
@@ -32,31 +39,105 @@ var licenses = []string{
 	"copyleft",
 }
 
-// Percentage returns 0 if no LICENSE, 1 if LICENSE
-func (g License) Percentage() (float64, []FileSummary, error) {
-	files, err := ioutil.ReadDir(g.Dir)
+// spdxLicenses are the licenses that can be identified, with phrases
+// that must all appear in the license text. More specific licenses
+// come before the licenses they would otherwise be mistaken for.
+var spdxLicenses = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+}
+
+// identifyLicense returns the SPDX identifier of the license text,
+// or an empty string if it is not a known license
+func identifyLicense(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "SPDX-License-Identifier:"); i != -1 {
+			return strings.TrimSpace(line[i+len("SPDX-License-Identifier:"):])
+		}
+	}
+
+	// normalize case and whitespace, as license texts are wrapped
+	// and capitalized in different ways
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+outer:
+	for _, l := range spdxLicenses {
+		for _, p := range l.phrases {
+			if !strings.Contains(text, p) {
+				continue outer
+			}
+		}
+		return l.id
+	}
+	return ""
+}
+
+// DetectLicense finds the license file in the root of dir and returns
+// its name and the SPDX identifier of the license. The filename is empty
+// if there is no license file, and the identifier is empty if the
+// license is not recognized.
+func DetectLicense(dir string) (id, filename string, err error) {
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return 0.0, []FileSummary{}, err
+		return "", "", err
 	}
 
 	for _, file := range files {
 		name := strings.ToLower(file.Name())
 
-		if filepath.Ext(name) == "go" {
+		if file.IsDir() || filepath.Ext(name) == ".go" {
 			continue
 		}
 
 		for i := range licenses {
 			if strings.HasPrefix(name, licenses[i]) {
-				return 1.0, []FileSummary{}, nil
+				b, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+				if err != nil {
+					return "", file.Name(), err
+				}
+				return identifyLicense(string(b)), file.Name(), nil
 			}
 		}
 	}
 
-	return 0.0, []FileSummary{{"", "http://choosealicense.com/", []Error{}}}, nil
+	return "", "", nil
+}
+
+// Run returns 0 if no LICENSE, 1 if LICENSE, and
+// UnrecognizedLicenseScore if the LICENSE is not a known license
+func (g License) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	id, filename, err := DetectLicense(dir)
+	if err != nil {
+		return 0.0, []FileSummary{}, err
+	}
+
+	if filename == "" {
+		return 0.0, []FileSummary{{"", "http://choosealicense.com/", []Error{}}}, nil
+	}
+
+	if id == "" {
+		fs := newFileSummary(dir, filepath.Join(dir, filename))
+		fs.Errors = []Error{{ErrorString: fmt.Sprintf("%s does not contain a recognized license", filename)}}
+		return UnrecognizedLicenseScore, []FileSummary{fs}, nil
+	}
+
+	return 1.0, []FileSummary{}, nil
 }
 
 // Description returns the description of License
 func (g License) Description() string {
-	return "Checks whether your project has a LICENSE file."
+	return "Checks whether your project has a LICENSE file, and whether it is a known open source license."
 }
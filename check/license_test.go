@@ -1,10 +1,13 @@
 package check
 
-import "testing"
+import (
+	"context"
+	"os"
+	"testing"
+)
 
 func TestPercentage(t *testing.T) {
-	g := License{"testfiles", []string{}}
-	p, _, err := g.Percentage()
+	p, _, err := License{}.Run(context.Background(), "testfiles")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -12,3 +15,38 @@ func TestPercentage(t *testing.T) {
 		t.Errorf("License check failed")
 	}
 }
+
+var identifyLicenseTests = []struct {
+	text string
+	want string
+}{
+	{"Permission is hereby granted, free of charge, to any person", "MIT"},
+	{"Apache License\n  Version 2.0, January 2004", "Apache-2.0"},
+	{"Redistribution and use in source and binary forms, with or without\nmodification... Neither the name of", "BSD-3-Clause"},
+	{"Redistribution and use in source and binary forms, with or without", "BSD-2-Clause"},
+	{"GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", "GPL-3.0"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3", "LGPL-3.0"},
+	{"// SPDX-License-Identifier: EPL-2.0\n", "EPL-2.0"},
+	{"All rights reserved.", ""},
+}
+
+func TestIdentifyLicense(t *testing.T) {
+	for _, tt := range identifyLicenseTests {
+		if got := identifyLicense(tt.text); got != tt.want {
+			t.Errorf("identifyLicense(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestUnrecognizedLicense(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{"LICENSE": "All rights reserved."})
+	defer os.RemoveAll(dir)
+
+	p, fs, err := License{}.Run(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != UnrecognizedLicenseScore || len(fs) != 1 {
+		t.Errorf("License(unrecognized) = %f, %v, want %f with one summary", p, fs, UnrecognizedLicenseScore)
+	}
+}
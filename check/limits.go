@@ -0,0 +1,202 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Limits are the resources that a single command run by a check may use.
+// Zero values are not limited.
+type Limits struct {
+	// CPUTime is the processor time of each process of the command
+	CPUTime time.Duration
+	// Memory is the memory of the command in bytes. On the host, it
+	// limits the address space of each process.
+	Memory int64
+	// Output is the size of the output that is read from the command
+	// in bytes
+	Output int64
+}
+
+// DefaultLimits are the limits of a Checker without any
+var DefaultLimits = Limits{
+	CPUTime: 10 * time.Minute,
+	Memory:  4 << 30,
+	Output:  32 << 20,
+}
+
+// the resources that can be limited, as reported in a LimitError
+const (
+	LimitCPUTime = "cpu time"
+	LimitMemory  = "memory"
+	LimitOutput  = "output"
+)
+
+// LimitError is the error of a check that stopped one of its commands
+// because it exceeded its Limits
+type LimitError struct {
+	// Limit is the resource that was exceeded, such as LimitMemory
+	Limit  string
+	Limits Limits
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitCPUTime:
+		return fmt.Sprintf("exceeded the cpu time limit of %v", e.Limits.CPUTime)
+	case LimitMemory:
+		return fmt.Sprintf("exceeded the memory limit of %d MB", e.Limits.Memory>>20)
+	case LimitOutput:
+		return fmt.Sprintf("exceeded the output limit of %d MB", e.Limits.Output>>20)
+	}
+	return "exceeded the " + e.Limit + " limit"
+}
+
+// limitsFrom returns the limits carried by ctx
+func limitsFrom(ctx context.Context) Limits {
+	sv, ok := ctx.Value(sandboxKey{}).(sandboxValue)
+	if !ok {
+		return DefaultLimits
+	}
+	return sv.limits
+}
+
+// outputLimiter counts the output that is read from a command, and fails
+// reads and writes once there is more than the limit
+type outputLimiter struct {
+	limits Limits
+
+	mu sync.Mutex
+	n  int64
+}
+
+func newOutputLimiter(ctx context.Context) *outputLimiter {
+	return &outputLimiter{limits: limitsFrom(ctx)}
+}
+
+// add counts n bytes of output, and returns a LimitError if that is
+// more than the limit
+func (l *outputLimiter) add(n int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.n += int64(n)
+	if l.limits.Output > 0 && l.n > l.limits.Output {
+		return &LimitError{Limit: LimitOutput, Limits: l.limits}
+	}
+	return nil
+}
+
+// exceeded reports whether there was more output than the limit
+func (l *outputLimiter) exceeded() bool {
+	return l.add(0) != nil
+}
+
+func (l *outputLimiter) writer(w io.Writer) io.Writer {
+	return limitedWriter{l, w}
+}
+
+func (l *outputLimiter) reader(r io.Reader) io.Reader {
+	return limitedReader{l, r}
+}
+
+type limitedWriter struct {
+	l *outputLimiter
+	w io.Writer
+}
+
+func (w limitedWriter) Write(p []byte) (int, error) {
+	if err := w.l.add(len(p)); err != nil {
+		// the command gets a broken pipe when it writes more
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+type limitedReader struct {
+	l *outputLimiter
+	r io.Reader
+}
+
+func (r limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if limitErr := r.l.add(n); limitErr != nil {
+		return n, limitErr
+	}
+	return n, err
+}
+
+// exit codes of the docker client for containers that were killed by
+// the kernel for running out of memory, and that exceeded their cpu time
+const (
+	exitOOMKilled = 128 + 9
+	exitCPUTime   = 128 + 24
+)
+
+// limitExceeded returns a LimitError if a command that exited with err
+// was stopped because it exceeded one of the limits of l, and nil
+// otherwise. Only the way the command exited is looked at, as its output
+// may mention running out of memory for other reasons.
+func limitExceeded(err error, l *outputLimiter) error {
+	limits := l.limits
+	if l.exceeded() {
+		return &LimitError{Limit: LimitOutput, Limits: limits}
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return nil
+	}
+
+	if limits.CPUTime > 0 {
+		// processes are killed by a signal at the hard limit, which has
+		// no exit code
+		state := exitErr.ProcessState
+		cpu := state.UserTime() + state.SystemTime()
+		if state.ExitCode() == exitCPUTime || cpuSignaled(state) || state.ExitCode() == -1 && cpu >= limits.CPUTime {
+			return &LimitError{Limit: LimitCPUTime, Limits: limits}
+		}
+	}
+	if limits.Memory > 0 {
+		if exitErr.ExitCode() == exitOOMKilled {
+			return &LimitError{Limit: LimitMemory, Limits: limits}
+		}
+	}
+	return nil
+}
+
+// output runs cmd and returns its standard output, like cmd.Output, or a
+// LimitError if cmd exceeded the limits carried by ctx
+func output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	l := newOutputLimiter(ctx)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = l.writer(&stdout)
+	cmd.Stderr = l.writer(&stderr)
+	err := cmd.Run()
+	if limitErr := limitExceeded(err, l); limitErr != nil {
+		return nil, limitErr
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+// combinedOutput runs cmd and returns its standard output and standard
+// error, like cmd.CombinedOutput, or a LimitError if cmd exceeded the
+// limits carried by ctx
+func combinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	l := newOutputLimiter(ctx)
+	var out bytes.Buffer
+	w := l.writer(&out)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	if limitErr := limitExceeded(err, l); limitErr != nil {
+		return nil, limitErr
+	}
+	return out.Bytes(), err
+}
@@ -0,0 +1,72 @@
+package check
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+var limitsTests = []struct {
+	name    string
+	limits  Limits
+	command toolCheck
+	err     string
+}{
+	{"output", Limits{Output: 1 << 20}, toolCheck{"yes"}, "exceeded the output limit of 1 MB"},
+	{"cpu time", Limits{CPUTime: time.Second}, toolCheck{"sh", "-c", "while :; do :; done"}, "exceeded the cpu time limit of 1s"},
+	{"within limits", Limits{Output: 1 << 20, CPUTime: time.Minute}, toolCheck{"echo", "hello"}, ""},
+}
+
+func TestLimits(t *testing.T) {
+	for _, tt := range limitsTests {
+		c := Checker{Limits: tt.limits, Timeout: 30 * time.Second}
+		results := c.run(context.Background(), "testfiles", []Check{tt.command})
+		if results[0].Error != tt.err {
+			t.Errorf("[%s] run error = %q, want %q", tt.name, results[0].Error, tt.err)
+		}
+		wantStatus := ""
+		if tt.err != "" {
+			wantStatus = StatusExceededLimits
+		}
+		if results[0].Status != wantStatus {
+			t.Errorf("[%s] run status = %q, want %q", tt.name, results[0].Status, wantStatus)
+		}
+	}
+}
+
+func TestWithRlimits(t *testing.T) {
+	name, args := withRlimits(Limits{CPUTime: 1500 * time.Millisecond, Memory: 1 << 30}, "go", []string{"vet", "./..."})
+	want := []string{"-c", `ulimit -t 3 && ulimit -S -t 2 && ulimit -v 1048576 && exec "$0" "$@"`, "go", "vet", "./..."}
+	if name != "/bin/sh" || len(args) != len(want) {
+		t.Fatalf("withRlimits = %q %q, want /bin/sh %q", name, args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("withRlimits arg %d = %q, want %q", i, args[i], want[i])
+		}
+	}
+	if name, _ := withRlimits(Limits{Output: 1}, "go", nil); name != "go" {
+		t.Errorf("withRlimits without cpu and memory limits runs %q, want go", name)
+	}
+}
+
+func TestLimitExceededMemory(t *testing.T) {
+	l := &outputLimiter{limits: Limits{Memory: 1 << 30}}
+	cases := []struct {
+		script string
+		limit  bool
+	}{
+		// the output of a failing command is not looked at
+		{"echo 'fatal error: out of memory' >&2; exit 1", false},
+		{"exit 137", true},
+	}
+	for _, tt := range cases {
+		// Output keeps the standard error in the exit error
+		_, err := exec.Command("sh", "-c", tt.script).Output()
+		err = limitExceeded(err, l)
+		if limitErr, ok := err.(*LimitError); ok != tt.limit || ok && limitErr.Limit != LimitMemory {
+			t.Errorf("[%q] limitExceeded = %v, want a memory limit error %v", tt.script, err, tt.limit)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Logger receives structured log events from a Checker. The message is
+// followed by alternating keys and values, such as "check", "gofmt".
+// Checks run concurrently, so implementations must be safe for
+// concurrent use.
+type Logger interface {
+	Log(msg string, keyvals ...interface{})
+}
+
+// nopLogger discards all log events
+type nopLogger struct{}
+
+func (nopLogger) Log(string, ...interface{}) {}
+
+// stdLogger writes log events to the standard library logger
+type stdLogger struct{}
+
+func (stdLogger) Log(msg string, keyvals ...interface{}) {
+	log.Println(formatKeyvals(msg, keyvals))
+}
+
+// StdLogger returns a Logger that writes events to the standard
+// library logger as msg key=value pairs
+func StdLogger() Logger {
+	return stdLogger{}
+}
+
+func formatKeyvals(msg string, keyvals []interface{}) string {
+	parts := []string{msg}
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		parts = append(parts, fmt.Sprintf("%v=%v", keyvals[i], v))
+	}
+	return strings.Join(parts, " ")
+}
+
+// slogLogger writes log events to a structured logger
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (l slogLogger) Log(msg string, keyvals ...interface{}) {
+	level := slog.LevelInfo
+	for i := 0; i < len(keyvals); i += 2 {
+		if keyvals[i] == "error" {
+			level = slog.LevelWarn
+		}
+	}
+	if msg == "check started" {
+		level = slog.LevelDebug
+	}
+	l.l.Log(context.Background(), level, msg, keyvals...)
+}
+
+// SlogLogger returns a Logger that writes events to l. Events with an
+// error are logged as warnings, and the start of every check at the debug
+// level.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type loggerKey struct{}
+
+// withLogger returns a copy of ctx that carries the logger of the Checker
+// running the checks
+func withLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// logFrom returns the logger carried by ctx, for checks to log with
+func logFrom(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return l
+	}
+	return nopLogger{}
+}
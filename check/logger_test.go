@@ -0,0 +1,129 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type logEvent struct {
+	msg     string
+	keyvals []interface{}
+}
+
+// value returns the value logged for key, or nil
+func (e logEvent) value(key string) interface{} {
+	for i := 0; i+1 < len(e.keyvals); i += 2 {
+		if e.keyvals[i] == key {
+			return e.keyvals[i+1]
+		}
+	}
+	return nil
+}
+
+type captureLogger struct {
+	mu     sync.Mutex
+	events []logEvent
+}
+
+func (l *captureLogger) Log(msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, logEvent{msg, keyvals})
+}
+
+func (l *captureLogger) find(msg string) []logEvent {
+	var found []logEvent
+	for _, e := range l.events {
+		if e.msg == msg {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// captureStdout returns everything written to os.Stdout while running f
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	f()
+	os.Stdout = stdout
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestCheckerLogsWalkErrors(t *testing.T) {
+	l := &captureLogger{}
+	c := Checker{Logger: l}
+	out := captureStdout(t, func() {
+		c.GoFiles(context.Background(), "testfiles/does-not-exist")
+	})
+	if out != "" {
+		t.Errorf("GoFiles printed to stdout: %q", out)
+	}
+	events := l.find("could not walk path")
+	if len(events) != 1 {
+		t.Fatalf("got %d walk error events, want 1", len(events))
+	}
+	if p := events[0].value("path"); p != "testfiles/does-not-exist" {
+		t.Errorf("walk error path = %v, want %q", p, "testfiles/does-not-exist")
+	}
+	if events[0].value("error") == nil {
+		t.Error("walk error event has no error")
+	}
+}
+
+func TestCheckerLogsTimings(t *testing.T) {
+	l := &captureLogger{}
+	c := Checker{Logger: l}
+	files := []string{"testfiles/a.go", "testfiles/b.go", "testfiles/c.go"}
+	results := c.RunAll(context.Background(), "testfiles", files)
+
+	started, finished := l.find("check started"), l.find("check finished")
+	// the coverage check is skipped outside of a sandbox
+	run := len(results) - len(l.find("check skipped"))
+	if run != len(results)-1 || len(started) != run || len(finished) != run {
+		t.Fatalf("got %d start and %d finish events, want %d of each", len(started), len(finished), len(results)-1)
+	}
+	for _, e := range finished {
+		if _, ok := e.value("duration").(time.Duration); !ok {
+			t.Errorf("finish event for %v has no duration", e.value("check"))
+		}
+	}
+}
+
+func TestSlogLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l := SlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	cases := []struct {
+		msg     string
+		keyvals []interface{}
+		want    string
+	}{
+		{"check started", []interface{}{"check", "gofmt"}, "level=DEBUG"},
+		{"check finished", []interface{}{"check", "gofmt"}, "level=INFO"},
+		{"could not walk path", []interface{}{"path", "a", "error", "boom"}, "level=WARN"},
+	}
+	for _, tt := range cases {
+		buf.Reset()
+		l.Log(tt.msg, tt.keyvals...)
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("[%q] logged %q, want %s", tt.msg, buf.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package check
+
+import (
+	"context"
+	"sort"
+)
+
+// MissingTests is the check for packages without any test files
+type MissingTests struct{}
+
+// Name returns the name of the display name of the command
+func (g MissingTests) Name() string {
+	return "missing_tests"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g MissingTests) Weight() float64 {
+	return 0
+}
+
+// Severity returns the severity of the issues the check reports
+func (g MissingTests) Severity() string {
+	return SeverityWarning
+}
+
+// Run returns the fraction of packages that have at least one
+// _test.go file, and lists the packages that have none
+func (g MissingTests) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	tested := make(map[string]bool)
+	for _, p := range Packages(ctx) {
+		tested[p.Dir] = len(p.TestGoFiles) > 0
+	}
+	if len(tested) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	var pkgs []string
+	for pkg := range tested {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	var failed = []FileSummary{}
+	for _, pkg := range pkgs {
+		if tested[pkg] {
+			continue
+		}
+		fs := newFileSummary(dir, pkg)
+		fs.Errors = []Error{{ErrorString: "package has no test files"}}
+		failed = append(failed, fs)
+	}
+
+	return float64(len(tested)-len(failed)) / float64(len(tested)), failed, nil
+}
+
+// Description returns the description of MissingTests
+func (g MissingTests) Description() string {
+	return "Lists the packages that do not have a single <code>_test.go</code> file. This check does not count towards the grade."
+}
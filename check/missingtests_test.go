@@ -0,0 +1,38 @@
+package check
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMissingTests(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	ctx := WithFilenames(context.Background(), []string{
+		dir + "/a.go",
+		dir + "/a_test.go",
+		dir + "/sub/b.go",
+		dir + "/sub/deeper/c.go",
+		dir + "/sub/deeper/c_test.go",
+		dir + "/other/d.go",
+	})
+
+	p, failed, err := MissingTests{}.Run(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != .5 {
+		t.Errorf("MissingTests percentage = %v, want 0.5", p)
+	}
+	want := []string{"bar/other", "bar/sub"}
+	if len(failed) != len(want) {
+		t.Fatalf("MissingTests reported %d packages, want %d: %v", len(failed), len(want), failed)
+	}
+	for i, fs := range failed {
+		if fs.Filename != want[i] {
+			t.Errorf("MissingTests package %d = %q, want %q", i, fs.Filename, want[i])
+		}
+	}
+	if u := failed[0].FileURL; u != "https://github.com/foo/bar/blob/master/other" {
+		t.Errorf("MissingTests URL = %q", u)
+	}
+}
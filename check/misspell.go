@@ -1,10 +1,9 @@
 package check
 
+import "context"
+
 // Misspell is the check for the misspell command
-type Misspell struct {
-	Dir       string
-	Filenames []string
-}
+type Misspell struct{}
 
 // Name returns the name of the display name of the command
 func (g Misspell) Name() string {
@@ -16,9 +15,30 @@ func (g Misspell) Weight() float64 {
 	return 0.0
 }
 
-// Percentage returns the percentage of .go files that pass gofmt
-func (g Misspell) Percentage() (float64, []FileSummary, error) {
-	return GoTool(g.Dir, g.Filenames, []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=misspell"})
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Misspell) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Misspell) Severity() string {
+	return SeverityInfo
+}
+
+// misspellMaxFiles is the number of files above which misspell is
+// skipped, as it is the slowest check
+const misspellMaxFiles = 1000
+
+// Run returns the percentage of .go files that pass misspell.
+// Comments, string literals and identifiers are all checked.
+func (g Misspell) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) > misspellMaxFiles {
+		logFrom(ctx).Log("skipping misspell on large repo", "check", g.Name(), "files", len(filenames))
+		return 1, []FileSummary{}, nil
+	}
+	return GoTool(ctx, dir, filenames, []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=misspell"})
 }
 
 // Description returns the description of Misspell
@@ -0,0 +1,189 @@
+package check
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module is a Go module in a repo
+type Module struct {
+	// Path is the module path declared in its go.mod
+	Path string
+	Dir  string
+}
+
+// FindModules returns the modules of the repo in dir, sorted by
+// directory: the modules used by its go.work file, or else the modules of
+// all go.mod files outside of skipped directories. A repo that is not a
+// module has none.
+func FindModules(dir string) ([]Module, error) {
+	var dirs []string
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "go.work")); err == nil {
+		for _, use := range parseWorkUses(string(data)) {
+			dirs = append(dirs, filepath.Join(dir, filepath.FromSlash(use)))
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	} else {
+		err := filepath.Walk(dir, func(fp string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if fi.IsDir() && fp != dir && skipModuleDir(fi.Name()) {
+				return filepath.SkipDir
+			}
+			if !fi.IsDir() && fi.Name() == "go.mod" {
+				dirs = append(dirs, filepath.Dir(fp))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var mods []Module
+	for _, d := range dirs {
+		data, err := ioutil.ReadFile(filepath.Join(d, "go.mod"))
+		if err != nil {
+			// a go.work may use a module that is not in the repo
+			continue
+		}
+		mods = append(mods, Module{Path: parseModulePath(string(data)), Dir: d})
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].Dir < mods[j].Dir })
+	return mods, nil
+}
+
+// skipModuleDir reports whether modules in the directory with the given
+// name are not part of the repo, like the go command does for ./...
+func skipModuleDir(name string) bool {
+	return contains(skipDirs, name) || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// parseWorkUses returns the directories in the use directives of the
+// go.work file data
+func parseWorkUses(data string) []string {
+	var uses []string
+	var inBlock bool
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case fields[0] == "use" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+			continue
+		case fields[0] == "use" && len(fields) > 1:
+			fields = fields[1:]
+		case !inBlock:
+			continue
+		}
+		uses = append(uses, strings.Trim(fields[0], `"`))
+	}
+	return uses
+}
+
+// parseModulePath returns the module path in the go.mod file data
+func parseModulePath(data string) string {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+type modulesKey struct{}
+
+// WithModules returns a copy of ctx that carries the modules of the repo
+// that checks are run on, as returned by FindModules
+func WithModules(ctx context.Context, mods []Module) context.Context {
+	return context.WithValue(ctx, modulesKey{}, mods)
+}
+
+// Modules returns the modules carried by ctx
+func Modules(ctx context.Context) []Module {
+	mods, _ := ctx.Value(modulesKey{}).([]Module)
+	return mods
+}
+
+// moduleDirs returns the directories that go tools are run in to check
+// the repo in dir: the directories of the modules carried by ctx if there
+// are several, as the go command only sees one module in a directory, or
+// else dir
+func moduleDirs(ctx context.Context, dir string) []string {
+	mods := Modules(ctx)
+	if len(mods) < 2 {
+		return []string{dir}
+	}
+	dirs := make([]string, len(mods))
+	for i, m := range mods {
+		dirs[i] = m.Dir
+	}
+	return dirs
+}
+
+// moduleOf returns the index of the innermost module in mods that the
+// file or directory at fp is in, or -1
+func moduleOf(mods []Module, fp string) int {
+	found := -1
+	for i, m := range mods {
+		if within(m.Dir, fp) && (found < 0 || len(m.Dir) > len(mods[found].Dir)) {
+			found = i
+		}
+	}
+	return found
+}
+
+// within reports whether the path fp is dir or in it
+func within(dir, fp string) bool {
+	rel, err := filepath.Rel(dir, fp)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// moduleTargets returns the targets of a go tool run in the module
+// directory modDir, out of the targets of the repo in dir
+func moduleTargets(ctx context.Context, dir, modDir string, targets []string) []string {
+	mods := Modules(ctx)
+	if len(mods) < 2 {
+		return targets
+	}
+	var kept []string
+	for _, t := range targets {
+		if t == dir+"/..." {
+			kept = append(kept, modDir+"/...")
+		} else if i := moduleOf(mods, t); i >= 0 && mods[i].Dir == modDir {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// ModuleScores returns the scores of mods in the results of the checks
+// run on dir, computed like PackageScores from the files of pkgs in each
+// module. The Path of a score is the module path, and its Dir the
+// directory of the module.
+func ModuleScores(dir string, mods []Module, pkgs []Package, results []CheckResult) []PackageScore {
+	merged := make([]Package, len(mods))
+	for i, m := range mods {
+		merged[i] = Package{PkgPath: m.Path, Dir: m.Dir}
+	}
+	for _, p := range pkgs {
+		if i := moduleOf(mods, p.Dir); i >= 0 {
+			merged[i].GoFiles = append(merged[i].GoFiles, p.Files()...)
+		}
+	}
+	return PackageScores(dir, merged, results)
+}
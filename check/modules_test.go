@@ -0,0 +1,153 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var parseWorkUsesTests = []struct {
+	data string
+	want []string
+}{
+	{"go 1.21\n\nuse ./api\n", []string{"./api"}},
+	{"go 1.21\n\nuse (\n\t.\n\t./tools // linters\n\t\"./cmd/x\"\n)\n", []string{".", "./tools", "./cmd/x"}},
+	{"go 1.21\n\n// use ./old\nreplace example.com/a => ./a\n", nil},
+}
+
+func TestParseWorkUses(t *testing.T) {
+	for _, tt := range parseWorkUsesTests {
+		if got := parseWorkUses(tt.data); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWorkUses(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestFindModules(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"go.mod":                 "module example.com/m\n",
+		"api/go.mod":             "module example.com/m/api\n",
+		"vendor/x/go.mod":        "module example.com/x\n",
+		"testdata/y/go.mod":      "module example.com/y\n",
+		".github/z/go.mod":       "module example.com/z\n",
+		"api/internal/a/a.go":    "package a\n",
+		"tools/cmd/lint/go.mod":  "module example.com/m/tools/cmd/lint\n",
+		"tools/cmd/lint/main.go": "package main\n",
+	})
+	mods, err := FindModules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Module{
+		{"example.com/m", dir},
+		{"example.com/m/api", filepath.Join(dir, "api")},
+		{"example.com/m/tools/cmd/lint", filepath.Join(dir, "tools", "cmd", "lint")},
+	}
+	if !reflect.DeepEqual(mods, want) {
+		t.Errorf("FindModules = %v, want %v", mods, want)
+	}
+
+	// a workspace only uses the modules in its go.work
+	if err := os.WriteFile(filepath.Join(dir, "go.work"), []byte("go 1.21\n\nuse (\n\t./api\n\t../outside\n)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mods, err = FindModules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Module{{"example.com/m/api", filepath.Join(dir, "api")}}; !reflect.DeepEqual(mods, want) {
+		t.Errorf("FindModules with go.work = %v, want %v", mods, want)
+	}
+}
+
+func TestModuleTargets(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	ctx := WithModules(context.Background(), []Module{
+		{"github.com/foo/bar", dir},
+		{"github.com/foo/bar/api", dir + "/api"},
+	})
+	targets := []string{dir, dir + "/sub", dir + "/api", dir + "/api/v1", dir + "/apis"}
+	if got, want := moduleTargets(ctx, dir, dir, targets), []string{dir, dir + "/sub", dir + "/apis"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("moduleTargets of root = %q, want %q", got, want)
+	}
+	if got, want := moduleTargets(ctx, dir, dir+"/api", targets), []string{dir + "/api", dir + "/api/v1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("moduleTargets of api = %q, want %q", got, want)
+	}
+	if got, want := moduleTargets(ctx, dir, dir+"/api", []string{dir + "/..."}), []string{dir + "/api/..."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("moduleTargets of api = %q, want %q", got, want)
+	}
+}
+
+func TestLoadPackagesModules(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"go.mod":        "module example.com/m\n\ngo 1.20\n",
+		"m.go":          "package m\n",
+		"api/go.mod":    "module example.com/api\n\ngo 1.20\n",
+		"api/api.go":    "package api\n",
+		"api/v1/v1.go":  "package v1\n",
+		"api/v1/x_test": "not a go file\n",
+	})
+	// nested modules are only listed on their own in module-aware mode
+	t.Setenv("GO111MODULE", "on")
+	pkgs, _, err := Checker{}.LoadPackages(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, p := range pkgs {
+		paths = append(paths, p.PkgPath)
+	}
+	if want := []string{"example.com/m", "example.com/api", "example.com/api/v1"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("LoadPackages = %q, want %q", paths, want)
+	}
+}
+
+func TestGoToolModules(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.20\n",
+		"m.go":       "package m\n\nimport \"fmt\"\n\nfunc f() { fmt.Printf(\"%d\", \"m\") }\n",
+		"api/go.mod": "module example.com/api\n\ngo 1.20\n",
+		"api/api.go": "package api\n\nimport \"fmt\"\n\nfunc f() { fmt.Printf(\"%d\", \"api\") }\n",
+	})
+	mods, err := FindModules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// go vet run in the root alone does not see the api module
+	vet := `go vet $(for a; do case $a in --skip=*) ;; *) echo $a;; esac; done) 2>&1`
+	filenames := []string{filepath.Join(dir, "api", "api.go"), filepath.Join(dir, "m.go")}
+	p, failed, err := GoTool(WithModules(context.Background(), mods), dir, filenames, []string{"sh", "-c", vet, "sh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != 0 || len(failed) != 2 {
+		t.Fatalf("GoTool = %v, %v, want issues in both modules", p, failed)
+	}
+}
+
+func TestModuleScores(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	mods := []Module{
+		{"github.com/foo/bar", dir},
+		{"github.com/foo/bar/api", dir + "/api"},
+	}
+	pkgs := []Package{
+		{PkgPath: "github.com/foo/bar", Dir: dir, GoFiles: []string{dir + "/a.go"}},
+		{PkgPath: "github.com/foo/bar/sub", Dir: dir + "/sub", GoFiles: []string{dir + "/sub/b.go"}},
+		{PkgPath: "github.com/foo/bar/api", Dir: dir + "/api", GoFiles: []string{dir + "/api/c.go"}},
+	}
+	results := []CheckResult{
+		{Name: "gofmt", Weight: 1, FileSummaries: []FileSummary{newFileSummary(dir, dir+"/a.go")}},
+	}
+	got := ModuleScores(dir, mods, pkgs, results)
+	want := []PackageScore{
+		{Path: "github.com/foo/bar", Dir: ".", Files: 2, Percentage: 0.5},
+		{Path: "github.com/foo/bar/api", Dir: "api", Files: 1, Percentage: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ModuleScores = %+v, want %+v", got, want)
+	}
+}
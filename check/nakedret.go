@@ -0,0 +1,141 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+)
+
+// DefaultNakedRetMaxLength is the function length in lines above which
+// naked returns are reported
+const DefaultNakedRetMaxLength = 5
+
+// NakedRet is the check for naked returns in long functions
+type NakedRet struct {
+	// MaxLength is the function length in lines above which naked
+	// returns are reported, or DefaultNakedRetMaxLength if zero
+	MaxLength int
+}
+
+func (g NakedRet) maxLength() int {
+	if g.MaxLength == 0 {
+		return DefaultNakedRetMaxLength
+	}
+	return g.MaxLength
+}
+
+// Name returns the name of the display name of the command
+func (g NakedRet) Name() string {
+	return "nakedret"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g NakedRet) Weight() float64 {
+	return .05
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g NakedRet) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g NakedRet) Severity() string {
+	return SeverityWarning
+}
+
+// CacheKey returns the key of the issues the check finds in a file
+func (g NakedRet) CacheKey() string {
+	return g.Name() + ":" + strconv.Itoa(g.maxLength())
+}
+
+// WithThreshold returns the check with the function length above which
+// naked returns are reported set to n lines
+func (g NakedRet) WithThreshold(n int) Check {
+	g.MaxLength = n
+	return g
+}
+
+// Run returns the percentage of .go files without naked returns
+// in functions longer than the maximum length
+func (g NakedRet) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	var (
+		failed = []FileSummary{}
+		fset   = token.NewFileSet()
+	)
+	for _, f := range filenames {
+		if err := ctx.Err(); err != nil {
+			return 0, []FileSummary{}, err
+		}
+		file, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		nolint := nolintLines(fset, file, "nakedret")
+
+		fs := newFileSummary(dir, f)
+		ast.Inspect(file, func(n ast.Node) bool {
+			var (
+				name = "function literal"
+				typ  *ast.FuncType
+				body *ast.BlockStmt
+			)
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				name, typ, body = n.Name.Name, n.Type, n.Body
+			case *ast.FuncLit:
+				typ, body = n.Type, n.Body
+			default:
+				return true
+			}
+			if body == nil || typ.Results == nil || len(typ.Results.List[0].Names) == 0 {
+				return true
+			}
+			length := fset.Position(n.End()).Line - fset.Position(n.Pos()).Line + 1
+			if length <= g.maxLength() {
+				return true
+			}
+
+			ast.Inspect(body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					// checked on its own by the outer inspection
+					return false
+				case *ast.ReturnStmt:
+					line := fset.Position(n.Pos()).Line
+					switch {
+					case len(n.Results) != 0:
+					case nolint[line]:
+						suppress(ctx)
+					default:
+						fs.Errors = append(fs.Errors, Error{
+							LineNumber:  line,
+							ErrorString: fmt.Sprintf("naked return in %s with %d lines", name, length),
+						})
+					}
+				}
+				return true
+			})
+			return true
+		})
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	if len(filenames) == 0 {
+		return 1, failed, nil
+	}
+	return toolPercentage(filenames, failed)
+}
+
+// Description returns the description of NakedRet
+func (g NakedRet) Description() string {
+	return `<a href="https://github.com/alexkohler/nakedret">Nakedret</a> finds naked returns in functions that are longer than a few lines, where it is hard to see what is returned.`
+}
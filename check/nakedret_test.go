@@ -0,0 +1,54 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNakedRet(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": `package a
+
+func short() (n int) {
+	return
+}
+
+func long() (n int, err error) {
+	n = 1
+	f := func() (m int) { return }
+	_ = f
+	if n > 0 {
+		return
+	}
+	return n, nil
+}
+
+func unnamed() int {
+	x := 1
+	x++
+	x++
+	x++
+	return x
+}
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	ctx := WithFilenames(context.Background(), []string{filepath.Join(dir, "a.go")})
+	_, failed, err := NakedRet{}.Run(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || len(failed[0].Errors) != 1 {
+		t.Fatalf("NakedRet = %v, want 1 error", failed)
+	}
+	if e := failed[0].Errors[0]; e.LineNumber != 12 || e.ErrorString != "naked return in long with 9 lines" {
+		t.Errorf("NakedRet error = %+v, want line 12 in long", e)
+	}
+
+	if _, failed, _ := (NakedRet{MaxLength: 10}).Run(ctx, dir); len(failed) != 0 {
+		t.Errorf("NakedRet with MaxLength 10 = %v, want no errors", failed)
+	}
+}
@@ -0,0 +1,190 @@
+package check
+
+import (
+	"context"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// nolintLines returns the set of lines in file that carry a //nolint
+// comment applying to the named check. A bare //nolint applies to every
+// check, while //nolint:a,b only applies to the checks a and b.
+func nolintLines(fset *token.FileSet, file *ast.File, name string) map[int]bool {
+	lines := make(map[int]bool)
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			if nolintApplies(c.Text, name) {
+				lines[fset.Position(c.Slash).Line] = true
+			}
+		}
+	}
+	return lines
+}
+
+// nolintBetween reports whether one of the lines from first to last, as
+// returned by nolintLines, carries a //nolint comment, so that a node
+// spanning several lines can be annotated on any of them
+func nolintBetween(lines map[int]bool, first, last int) bool {
+	for l := first; l <= last; l++ {
+		if lines[l] {
+			return true
+		}
+	}
+	return false
+}
+
+// nolintApplies reports whether the comment text is a //nolint directive
+// that applies to the named check
+func nolintApplies(text, name string) bool {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "//"))
+	if !strings.HasPrefix(text, "nolint") {
+		return false
+	}
+	text = strings.TrimPrefix(text, "nolint")
+	if text == "" || text[0] == ' ' {
+		return true
+	}
+	if text[0] != ':' {
+		return false
+	}
+	// the list of checks ends at the first space, after which
+	// an explanation may follow
+	list := strings.Fields(text[1:])
+	if len(list) == 0 {
+		return true
+	}
+	for _, n := range strings.Split(list[0], ",") {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressedKey is the context key of the number of issues a check
+// dropped because of //nolint comments
+type suppressedKey struct{}
+
+// withSuppressed returns a copy of ctx that counts the issues recorded
+// with suppress
+func withSuppressed(ctx context.Context) (context.Context, *int64) {
+	n := new(int64)
+	return context.WithValue(ctx, suppressedKey{}, n), n
+}
+
+// suppress records that a check dropped an issue because of a //nolint
+// comment, so it can be shown on the report
+func suppress(ctx context.Context) {
+	suppressN(ctx, 1)
+}
+
+// suppressN records that a check dropped n issues because of //nolint
+// comments
+func suppressN(ctx context.Context, n int64) {
+	if p, ok := ctx.Value(suppressedKey{}).(*int64); ok {
+		atomic.AddInt64(p, n)
+	}
+}
+
+// nolintIndex finds the //nolint comments in the Go files of a repo, for
+// dropping the issues reported by external tools. Files are only read
+// when a check reports an issue in them.
+type nolintIndex struct {
+	sync.Mutex
+	// paths maps the filenames used in file summaries to paths
+	paths map[string]string
+	// comments maps paths to the comments on each line
+	comments map[string]map[int][]string
+}
+
+func newNolintIndex(dir string, filenames []string) *nolintIndex {
+	idx := &nolintIndex{
+		paths:    make(map[string]string),
+		comments: make(map[string]map[int][]string),
+	}
+	for _, f := range filenames {
+		idx.paths[newFileSummary(dir, f).Filename] = f
+	}
+	return idx
+}
+
+// lineComments returns the comments in the file at path by line
+func (idx *nolintIndex) lineComments(path string) map[int][]string {
+	idx.Lock()
+	defer idx.Unlock()
+	if lines, ok := idx.comments[path]; ok {
+		return lines
+	}
+
+	lines := make(map[int][]string)
+	src, err := ioutil.ReadFile(path)
+	if err == nil {
+		fset := token.NewFileSet()
+		var s scanner.Scanner
+		// errors are ignored, the comments before them are still found
+		s.Init(fset.AddFile(path, -1, len(src)), src, nil, scanner.ScanComments)
+		for {
+			pos, tok, lit := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			if tok == token.COMMENT {
+				line := fset.Position(pos).Line
+				lines[line] = append(lines[line], lit)
+			}
+		}
+	}
+	idx.comments[path] = lines
+	return lines
+}
+
+// filter drops the issues of the named check on lines with a //nolint
+// comment that applies to it. It returns the remaining file summaries,
+// the number of files that no longer have issues and the number of
+// dropped issues.
+func (idx *nolintIndex) filter(name string, summaries []FileSummary) (kept []FileSummary, cleared, suppressed int) {
+	kept = []FileSummary{}
+	for _, fs := range summaries {
+		path, ok := idx.paths[fs.Filename]
+		if !ok {
+			kept = append(kept, fs)
+			continue
+		}
+		lines := idx.lineComments(path)
+
+		var errs []Error
+		for _, e := range fs.Errors {
+			if e.LineNumber > 0 && anyNolintApplies(lines[e.LineNumber], name) {
+				suppressed++
+				continue
+			}
+			errs = append(errs, e)
+		}
+		switch {
+		case len(errs) == len(fs.Errors):
+		case len(errs) == 0:
+			cleared++
+			continue
+		default:
+			fs.Errors = errs
+		}
+		kept = append(kept, fs)
+	}
+	return kept, cleared, suppressed
+}
+
+// anyNolintApplies reports whether one of the comments is a //nolint
+// directive that applies to the named check
+func anyNolintApplies(comments []string, name string) bool {
+	for _, c := range comments {
+		if nolintApplies(c, name) {
+			return true
+		}
+	}
+	return false
+}
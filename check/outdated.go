@@ -0,0 +1,183 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojp/goreportcard/download"
+)
+
+// ModuleProxy is the Go module proxy that is asked for the latest
+// versions of dependencies
+var ModuleProxy = "https://proxy.golang.org"
+
+// outdatedMaxRequests is the number of concurrent requests to the proxy
+const outdatedMaxRequests = 8
+
+var proxyClient = &http.Client{Timeout: 10 * time.Second}
+
+// Outdated is the check for direct dependencies that are behind their
+// latest release
+type Outdated struct{}
+
+// Name returns the name of the display name of the command
+func (g Outdated) Name() string {
+	return "outdated_dependencies"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Outdated) Weight() float64 {
+	return 0
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Outdated) Severity() string {
+	return SeverityInfo
+}
+
+// proxyRequest sends a request for the path on the module proxy, which
+// is cancelled when ctx is done
+func proxyRequest(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, ModuleProxy+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return proxyClient.Do(req.WithContext(ctx))
+}
+
+// latestVersion returns the latest version of the module path known to
+// the proxy, or an empty string if the module does not exist
+func latestVersion(ctx context.Context, path string) (string, error) {
+	resp, err := proxyRequest(ctx, "GET", download.EscapeModulePath(path)+"/@latest")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return "", nil
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%s: proxy returned %s", path, resp.Status)
+	}
+
+	var info struct{ Version string }
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	return info.Version, nil
+}
+
+// newerMajor returns the newest major version of path after its own, and
+// its latest version. The version is empty if there is no newer major
+// version.
+func newerMajor(ctx context.Context, path string) (major int, version string, err error) {
+	base, major := modulePathMajor(path)
+	if strings.HasPrefix(base, "gopkg.in/") {
+		// gopkg.in paths encode the major version as .vN
+		return major, "", nil
+	}
+	for {
+		v, err := latestVersion(ctx, fmt.Sprintf("%s/v%d", base, major+1))
+		if err != nil || v == "" {
+			return major, version, err
+		}
+		major, version = major+1, v
+	}
+}
+
+// Run returns the percentage of direct dependencies in go.mod that
+// are at their latest release. Dependencies that are a major version
+// behind are reported too, but do not change the percentage, as upgrading
+// them can need code changes.
+func (g Outdated) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	gomod := filepath.Join(dir, "go.mod")
+	data, err := ioutil.ReadFile(gomod)
+	if os.IsNotExist(err) {
+		return 1, []FileSummary{}, nil
+	} else if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	var paths []string
+	reqs := parseRequires(data)
+	for path, req := range reqs {
+		if !req.indirect {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+	sort.Strings(paths)
+
+	type result struct {
+		latest, majorVersion string
+		major                int
+		err                  error
+	}
+	var (
+		results = make([]result, len(paths))
+		wg      sync.WaitGroup
+		sem     = make(chan bool, outdatedMaxRequests)
+	)
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- true
+			defer func() { <-sem }()
+			r := &results[i]
+			if r.latest, r.err = latestVersion(ctx, path); r.err == nil {
+				r.major, r.majorVersion, r.err = newerMajor(ctx, path)
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	var errs []Error
+	var behind, majorBehind int
+	for i, path := range paths {
+		r, req := results[i], reqs[path]
+		if r.err != nil {
+			return 0, []FileSummary{}, r.err
+		}
+		if r.latest != "" && compareSemver(req.version, r.latest) < 0 {
+			behind++
+			errs = append(errs, Error{
+				LineNumber:  req.line,
+				ErrorString: fmt.Sprintf("%s %s is behind the latest release %s", path, req.version, r.latest),
+			})
+		}
+		if r.majorVersion != "" {
+			majorBehind++
+			base, _ := modulePathMajor(path)
+			errs = append(errs, Error{
+				LineNumber:  req.line,
+				ErrorString: fmt.Sprintf("%s is a major version behind, the latest is %s/v%d %s", path, base, r.major, r.majorVersion),
+			})
+		}
+	}
+	if len(errs) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	fs := newFileSummary(dir, gomod)
+	fs.Errors = append([]Error{{
+		ErrorString: fmt.Sprintf("%d of %d direct dependencies are behind their latest release, %d are a major version behind", behind, len(paths), majorBehind),
+	}}, errs...)
+	return float64(len(paths)-behind) / float64(len(paths)), []FileSummary{fs}, nil
+}
+
+// Description returns the description of Outdated
+func (g Outdated) Description() string {
+	return "Compares the direct dependencies in <code>go.mod</code> with their latest versions on the Go module proxy, including newer major versions. This check does not count towards the grade."
+}
@@ -0,0 +1,83 @@
+package check
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+var compareSemverTests = []struct {
+	a, b string
+	want int
+}{
+	{"v1.2.3", "v1.2.3", 0},
+	{"v1.2.3", "v1.10.0", -1},
+	{"v2.0.0", "v1.9.9", 1},
+	{"v1.0.0-rc.1", "v1.0.0", -1},
+	{"v0.0.0-20190101000000-abcdef123456", "v0.1.0", -1},
+	{"v1.2.3+incompatible", "v1.2.3", 0},
+}
+
+func TestCompareSemver(t *testing.T) {
+	for _, tt := range compareSemverTests {
+		if got := compareSemver(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareSemver(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestOutdated(t *testing.T) {
+	latest := map[string]string{
+		"/github.com/!foo/bar/@latest":    `{"Version":"v1.5.0"}`,
+		"/github.com/!foo/bar/v2/@latest": `{"Version":"v2.1.0"}`,
+		"/github.com/baz/qux/@latest":     `{"Version":"v0.3.0"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := latest[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(v))
+	}))
+	defer srv.Close()
+	defer func(p string) { ModuleProxy = p }(ModuleProxy)
+	ModuleProxy = srv.URL
+
+	dir := writeModule(t, "", map[string]string{"go.mod": `module example.com/m
+
+require (
+	github.com/Foo/bar v1.2.0
+	github.com/baz/qux v0.3.0
+	github.com/ind/irect v0.1.0 // indirect
+)
+`})
+	defer os.RemoveAll(dir)
+
+	p, failed, err := Outdated{}.Run(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != .5 {
+		t.Errorf("Outdated percentage = %v, want 0.5", p)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("Outdated reported %d files, want 1", len(failed))
+	}
+	want := []Error{
+		{ErrorString: "1 of 2 direct dependencies are behind their latest release, 1 are a major version behind"},
+		{LineNumber: 4, ErrorString: "github.com/Foo/bar v1.2.0 is behind the latest release v1.5.0"},
+		{LineNumber: 4, ErrorString: "github.com/Foo/bar is a major version behind, the latest is github.com/Foo/bar/v2 v2.1.0"},
+	}
+	errs := failed[0].Errors
+	if len(errs) != len(want) {
+		t.Fatalf("Outdated errors = %v, want %v", errs, want)
+	}
+	for i := range want {
+		if errs[i].LineNumber != want[i].LineNumber || errs[i].ErrorString != want[i].ErrorString {
+			t.Errorf("Outdated error %d = %+v, want %+v", i, errs[i], want[i])
+		}
+	}
+}
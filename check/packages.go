@@ -0,0 +1,292 @@
+package check
+
+import (
+	"context"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Package is a Go package in a repo, with the files that checks are run
+// on. Files that GoFiles skips are left out.
+type Package struct {
+	// PkgPath and Name are empty if the go command could not list the
+	// package
+	PkgPath string
+	Name    string
+	Dir     string
+	// GoFiles are the files that are built on this platform
+	GoFiles []string
+	// TestGoFiles are the _test.go files of the package and of its
+	// external test package
+	TestGoFiles []string
+	// IgnoredGoFiles are the files excluded by build constraints, such as
+	// files for other platforms
+	IgnoredGoFiles []string
+}
+
+// Files returns all Go files of p
+func (p Package) Files() []string {
+	files := append(append(append([]string(nil), p.GoFiles...), p.TestGoFiles...), p.IgnoredGoFiles...)
+	sort.Strings(files)
+	return files
+}
+
+// PackageFiles returns all Go files of pkgs, sorted
+func PackageFiles(pkgs []Package) []string {
+	var files []string
+	for _, p := range pkgs {
+		files = append(files, p.Files()...)
+	}
+	sort.Strings(files)
+	return files
+}
+
+type packagesKey struct{}
+
+// WithPackages returns a copy of ctx that carries the packages that
+// checks are run on, as returned by LoadPackages
+func WithPackages(ctx context.Context, pkgs []Package) context.Context {
+	return context.WithValue(ctx, packagesKey{}, pkgs)
+}
+
+// Packages returns the packages to check carried by ctx. Without them,
+// the Go files carried by ctx are grouped into packages by directory.
+func Packages(ctx context.Context) []Package {
+	if pkgs, ok := ctx.Value(packagesKey{}).([]Package); ok {
+		return pkgs
+	}
+	return groupFiles(Filenames(ctx))
+}
+
+// LoadPackages returns the packages in a given directory, as listed by
+// the go command, so that build constraints and test files are taken into
+// account. Files matching the skip rules are returned as skipped, see
+// GoFiles. Repos the go command cannot list, such as repos laid out for
+// GOPATH, are walked instead. The packages of a repo with several
+// modules are listed in each module.
+func (c Checker) LoadPackages(ctx context.Context, dir string) (pkgs []Package, skipped []string, err error) {
+	logger := c.logger()
+	cfg, cfgErr := LoadRepoConfig(dir)
+	if cfgErr != nil {
+		logger.Log("could not load repo config", "dir", dir, "error", cfgErr)
+	}
+
+	mods, err := FindModules(dir)
+	if err != nil {
+		logger.Log("could not find modules", "dir", dir, "error", err)
+	}
+	seen := make(map[string]bool)
+	for _, modDir := range moduleDirs(WithModules(ctx, mods), dir) {
+		modPkgs, err := listPackages(ctx, modDir)
+		if err != nil {
+			logger.Log("could not list packages", "dir", modDir, "error", err)
+		}
+		for _, p := range modPkgs {
+			// the packages of a workspace can be listed in every module
+			if !seen[p.Dir] {
+				seen[p.Dir] = true
+				pkgs = append(pkgs, p)
+			}
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Dir < pkgs[j].Dir })
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(pkgs) == 0 {
+		filenames, err := c.walkGoFiles(ctx, dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		pkgs = groupFiles(filenames)
+	}
+
+	skip := func(files []string) []string {
+		var kept []string
+		for _, fp := range files {
+			if skipFile(dir, fp, cfg, logger) {
+				skipped = append(skipped, fp)
+				continue
+			}
+			kept = append(kept, fp)
+		}
+		return kept
+	}
+	var kept []Package
+	for _, p := range pkgs {
+		p.GoFiles = skip(p.GoFiles)
+		p.TestGoFiles = skip(p.TestGoFiles)
+		p.IgnoredGoFiles = skip(p.IgnoredGoFiles)
+		if len(p.Files()) > 0 {
+			kept = append(kept, p)
+		}
+	}
+	sort.Strings(skipped)
+	return kept, skipped, nil
+}
+
+// listPackages lists the packages in dir and their tests with go/packages.
+// The network is not used, so modules that are not in the module cache
+// only cause errors on the packages that import them, which still list
+// their files.
+func listPackages(ctx context.Context, dir string) ([]Package, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	list, err := packages.Load(&packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedFiles,
+		Dir:     abs,
+		Env:     append(os.Environ(), "GOPROXY=off"),
+		Tests:   true,
+	}, "./...")
+	if err != nil {
+		return nil, err
+	}
+
+	// with tests, a package is listed up to three times: on its own, with
+	// its _test.go files and as its external test package
+	byDir := make(map[string]*Package)
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, lp := range list {
+		files := append(append([]string(nil), lp.GoFiles...), lp.IgnoredFiles...)
+		for _, f := range files {
+			rel, err := filepath.Rel(abs, f)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				// generated test mains are in the build cache
+				continue
+			}
+			if filepath.Ext(f) != ".go" || seen[f] || inSkipDir(filepath.Join(dir, rel)) {
+				continue
+			}
+			seen[f] = true
+
+			fp := filepath.Join(dir, rel)
+			pkgDir := filepath.Dir(fp)
+			p := byDir[pkgDir]
+			if p == nil {
+				p = &Package{Dir: pkgDir}
+				byDir[pkgDir] = p
+				dirs = append(dirs, pkgDir)
+			}
+			if p.Name == "" && !strings.HasSuffix(lp.Name, "_test") {
+				p.PkgPath, p.Name = lp.PkgPath, lp.Name
+			}
+			switch {
+			case strings.HasSuffix(f, "_test.go"):
+				p.TestGoFiles = append(p.TestGoFiles, fp)
+			case contains(lp.IgnoredFiles, f):
+				p.IgnoredGoFiles = append(p.IgnoredGoFiles, fp)
+			default:
+				p.GoFiles = append(p.GoFiles, fp)
+			}
+		}
+	}
+
+	sort.Strings(dirs)
+	pkgs := make([]Package, len(dirs))
+	for i, d := range dirs {
+		p := byDir[d]
+		sort.Strings(p.GoFiles)
+		sort.Strings(p.TestGoFiles)
+		sort.Strings(p.IgnoredGoFiles)
+		pkgs[i] = *p
+	}
+	return pkgs, nil
+}
+
+// typedFile is a parsed Go file with the type information of its package
+type typedFile struct {
+	file *ast.File
+	info *types.Info
+}
+
+// loadTypes type-checks the packages in dir and their tests with
+// go/packages, and returns their files by absolute path, parsed into
+// fset. Dependencies are type-checked from source too, rather than read
+// from export data, which the go command on the PATH may write in a
+// format this build cannot read. Like listPackages, the network is not
+// used; expressions whose type depends on a module that is not in the
+// module cache are left without a type. Files of packages that cannot be
+// loaded are missing.
+func loadTypes(fset *token.FileSet, dir string) map[string]typedFile {
+	files := make(map[string]typedFile)
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return files
+	}
+	list, err := packages.Load(&packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:   abs,
+		Env:   append(os.Environ(), "GOPROXY=off"),
+		Fset:  fset,
+		Tests: true,
+	}, "./...")
+	if err != nil {
+		return files
+	}
+	for _, lp := range list {
+		if lp.TypesInfo == nil {
+			continue
+		}
+		for _, f := range lp.Syntax {
+			path := fset.Position(f.Package).Filename
+			// a package and its test variant share files
+			if _, ok := files[path]; !ok {
+				files[path] = typedFile{file: f, info: lp.TypesInfo}
+			}
+		}
+	}
+	return files
+}
+
+// groupFiles groups Go files into packages by directory, using the build
+// constraints of the default build context
+func groupFiles(filenames []string) []Package {
+	byDir := make(map[string]*Package)
+	var dirs []string
+	for _, fp := range filenames {
+		pkgDir := filepath.Dir(fp)
+		p := byDir[pkgDir]
+		if p == nil {
+			p = &Package{Dir: pkgDir}
+			byDir[pkgDir] = p
+			dirs = append(dirs, pkgDir)
+		}
+		match, err := build.Default.MatchFile(pkgDir, filepath.Base(fp))
+		switch {
+		case strings.HasSuffix(fp, "_test.go"):
+			p.TestGoFiles = append(p.TestGoFiles, fp)
+		case err == nil && !match:
+			p.IgnoredGoFiles = append(p.IgnoredGoFiles, fp)
+		default:
+			p.GoFiles = append(p.GoFiles, fp)
+		}
+	}
+
+	sort.Strings(dirs)
+	pkgs := make([]Package, len(dirs))
+	for i, d := range dirs {
+		pkgs[i] = *byDir[d]
+	}
+	return pkgs
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
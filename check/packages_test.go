@@ -0,0 +1,64 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPackages(t *testing.T) {
+	dir := writeModule(t, "1.21", map[string]string{
+		"a.go":      "package m\n",
+		"a_test.go": "package m\n",
+		"x_test.go": "package m_test\n",
+		"gen.go":    "//go:build ignore\n\npackage main\n",
+		"a.pb.go":   "package m\n",
+	})
+	defer os.RemoveAll(dir)
+
+	pkgs, skipped, err := Checker{}.LoadPackages(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("LoadPackages returned %d packages, want 1: %v", len(pkgs), pkgs)
+	}
+	p := pkgs[0]
+	if p.Name != "m" || p.Dir != filepath.Clean(dir) {
+		t.Errorf("LoadPackages package %q in %q, want %q in %q", p.Name, p.Dir, "m", dir)
+	}
+	files := func(names ...string) []string {
+		var paths []string
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+		return paths
+	}
+	for _, tt := range []struct {
+		kind      string
+		got, want []string
+	}{
+		{"GoFiles", p.GoFiles, files("a.go")},
+		{"TestGoFiles", p.TestGoFiles, files("a_test.go", "x_test.go")},
+		{"IgnoredGoFiles", p.IgnoredGoFiles, files("gen.go")},
+		{"skipped", skipped, files("a.pb.go")},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("LoadPackages %s = %v, want %v", tt.kind, tt.got, tt.want)
+		}
+	}
+}
+
+func TestPackagesFromFilenames(t *testing.T) {
+	ctx := WithFilenames(context.Background(), []string{"a/a.go", "a/a_test.go", "b/b.go"})
+	pkgs := Packages(ctx)
+	want := []Package{
+		{Dir: "a", GoFiles: []string{"a/a.go"}, TestGoFiles: []string{"a/a_test.go"}},
+		{Dir: "b", GoFiles: []string{"b/b.go"}},
+	}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("Packages = %+v, want %+v", pkgs, want)
+	}
+}
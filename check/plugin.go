@@ -0,0 +1,309 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// output formats of plugin commands
+const (
+	// FormatVet is one "file.go:line[:column]: message" line per issue
+	FormatVet = "vet"
+	// FormatJSON is a JSON array of objects with file, line, message
+	// and the optional rule and severity keys
+	FormatJSON = "json"
+	// FormatCheckstyle is a checkstyle XML report
+	FormatCheckstyle = "checkstyle"
+)
+
+// PluginConfig configures an external command as a check. Plugins are
+// set up by the operator of the server, not by the repos being checked.
+type PluginConfig struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Weight      float64 `json:"weight"`
+	Category    string  `json:"category"`
+	// Severity is the severity of the issues that the command does
+	// not give one for, SeverityError if empty
+	Severity string `json:"severity"`
+	// Optional plugins are only run on repos that enable them in
+	// their ConfigFile
+	Optional bool `json:"optional"`
+	// Command is the command and its arguments, run in the root of
+	// the repo with the go environment of the repo
+	Command []string `json:"command"`
+	// Format is the output format of the command: FormatVet,
+	// FormatJSON or FormatCheckstyle
+	Format string `json:"format"`
+}
+
+// plugin is a check that runs an external command
+type plugin struct {
+	cfg PluginConfig
+}
+
+// Name returns the name of the display name of the command
+func (p plugin) Name() string {
+	return p.cfg.Name
+}
+
+// Weight returns the weight this check has in the overall average
+func (p plugin) Weight() float64 {
+	return p.cfg.Weight
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (p plugin) FileScored() bool {
+	return true
+}
+
+// Category returns the report section of the check
+func (p plugin) Category() string {
+	return p.cfg.Category
+}
+
+// Severity returns the severity of the issues the check reports
+func (p plugin) Severity() string {
+	if p.cfg.Severity == "" {
+		return SeverityError
+	}
+	return p.cfg.Severity
+}
+
+// Description returns the description of the plugin
+func (p plugin) Description() string {
+	return p.cfg.Description
+}
+
+// Run returns the percentage of .go files without issues reported by
+// the command. A non-zero exit status is only an error if the command
+// did not report any issues, as most linters exit 1 when they find some.
+func (p plugin) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	env, err := goEnv(dir)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	cmd := commandContext(ctx, dir, env, p.cfg.Command[0], p.cfg.Command[1:]...)
+	l := newOutputLimiter(ctx)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = l.writer(&stdout)
+	if p.cfg.Format == FormatVet {
+		// vet-style commands often report on stderr
+		cmd.Stderr = cmd.Stdout
+	} else {
+		cmd.Stderr = l.writer(&stderr)
+	}
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return 0, []FileSummary{}, ctx.Err()
+	}
+	if limitErr := limitExceeded(runErr, l); limitErr != nil {
+		return 0, []FileSummary{}, limitErr
+	}
+	if _, ok := runErr.(*exec.ExitError); !ok && runErr != nil {
+		return 0, []FileSummary{}, fmt.Errorf("%s: %v", p.cfg.Name, runErr)
+	}
+
+	issues, err := parsePluginOutput(p.cfg.Format, stdout.Bytes())
+	if err != nil {
+		return 0, []FileSummary{}, fmt.Errorf("%s: %v", p.cfg.Name, err)
+	}
+	if runErr != nil && len(issues) == 0 {
+		out := stderr.String()
+		if p.cfg.Format == FormatVet {
+			out = stdout.String()
+		}
+		return 0, []FileSummary{}, fmt.Errorf("%s: %v: %s", p.cfg.Name, runErr, strings.TrimSpace(out))
+	}
+
+	return toolPercentage(filenames, pluginSummaries(dir, issues))
+}
+
+// pluginIssue is a single issue reported by a plugin command
+type pluginIssue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+}
+
+// parsePluginOutput parses the output of a plugin command in format
+func parsePluginOutput(format string, out []byte) ([]pluginIssue, error) {
+	switch format {
+	case FormatVet:
+		return parseVetOutput(out)
+	case FormatJSON:
+		if len(bytes.TrimSpace(out)) == 0 {
+			return nil, nil
+		}
+		var issues []pluginIssue
+		if err := json.Unmarshal(out, &issues); err != nil {
+			return nil, err
+		}
+		return issues, nil
+	case FormatCheckstyle:
+		return parseCheckstyle(out)
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// vetRegexp matches an issue like "path/to/file.go:10:2: message"
+var vetRegexp = regexp.MustCompile(`^(.+\.go):(\d+)(?::\d+)?: (.*)$`)
+
+func parseVetOutput(out []byte) ([]pluginIssue, error) {
+	var issues []pluginIssue
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := vetRegexp.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, pluginIssue{File: m[1], Line: line, Message: m[3]})
+	}
+	return issues, scanner.Err()
+}
+
+// checkstyleReport is the checkstyle XML format, as written by
+// golangci-lint and many other linters
+type checkstyleReport struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+func parseCheckstyle(out []byte) ([]pluginIssue, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var report checkstyleReport
+	if err := xml.Unmarshal(out, &report); err != nil {
+		return nil, err
+	}
+
+	var issues []pluginIssue
+	for _, f := range report.Files {
+		for _, e := range f.Errors {
+			issues = append(issues, pluginIssue{
+				File:     f.Name,
+				Line:     e.Line,
+				Message:  e.Message,
+				Rule:     e.Source,
+				Severity: e.Severity,
+			})
+		}
+	}
+	return issues, nil
+}
+
+// pluginSummaries groups the issues by file, sorted by filename. The
+// files can be absolute or relative to dir, where the command ran.
+func pluginSummaries(dir string, issues []pluginIssue) []FileSummary {
+	fsMap := make(map[string]FileSummary)
+	for _, issue := range issues {
+		path := issue.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		filename := reportedFilename(filepath.ToSlash(path))
+		if skipReported(filename) {
+			continue
+		}
+
+		fs := fsMap[filename]
+		if fs.Filename == "" {
+			fs.Filename = makeFilename(filename)
+			fs.FileURL = fileURL(dir, filename)
+		}
+		fs.Errors = append(fs.Errors, Error{
+			LineNumber:  issue.Line,
+			ErrorString: issue.Message,
+			RuleID:      issue.Rule,
+			Severity:    normalizeSeverity(issue.Severity),
+		})
+		fsMap[filename] = fs
+	}
+
+	var failed = []FileSummary{}
+	for _, v := range fsMap {
+		failed = append(failed, v)
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Filename < failed[j].Filename })
+	return failed
+}
+
+// LoadPlugins reads a JSON array of PluginConfig from the file at path,
+// and registers a check for each plugin
+func LoadPlugins(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfgs []PluginConfig
+	if err := json.Unmarshal(data, &cfgs); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for _, cfg := range cfgs {
+		if err := RegisterPlugin(cfg); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// RegisterPlugin registers a check that runs the command of cfg
+func RegisterPlugin(cfg PluginConfig) error {
+	switch {
+	case cfg.Name == "":
+		return fmt.Errorf("plugin without a name")
+	case len(cfg.Command) == 0:
+		return fmt.Errorf("plugin %s: no command", cfg.Name)
+	case cfg.Weight < 0:
+		return fmt.Errorf("plugin %s: negative weight", cfg.Name)
+	}
+	switch cfg.Format {
+	case FormatVet, FormatJSON, FormatCheckstyle:
+	default:
+		return fmt.Errorf("plugin %s: unknown output format %q", cfg.Name, cfg.Format)
+	}
+	if cfg.Severity != "" && severityRank(cfg.Severity) == len(Severities) {
+		return fmt.Errorf("plugin %s: unknown severity %q", cfg.Name, cfg.Severity)
+	}
+	if cfg.Description == "" {
+		cfg.Description = fmt.Sprintf("Runs <code>%s</code>.", html.EscapeString(cfg.Command[0]))
+	}
+
+	if cfg.Optional {
+		return register(&registry.optional, plugin{cfg})
+	}
+	return register(&registry.checks, plugin{cfg})
+}
@@ -0,0 +1,99 @@
+package check
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParsePluginOutput(t *testing.T) {
+	cases := []struct {
+		format string
+		out    string
+		want   []pluginIssue
+	}{
+		{FormatVet, `# github.com/foo/bar
+a.go:10:2: do not do this
+/abs/sub/b.go:3: nor this
+`, []pluginIssue{
+			{File: "a.go", Line: 10, Message: "do not do this"},
+			{File: "/abs/sub/b.go", Line: 3, Message: "nor this"},
+		}},
+		{FormatJSON, `[{"file": "a.go", "line": 4, "message": "bad", "rule": "R1", "severity": "warning"}]`, []pluginIssue{
+			{File: "a.go", Line: 4, Message: "bad", Rule: "R1", Severity: "warning"},
+		}},
+		{FormatJSON, "", nil},
+		{FormatCheckstyle, `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0">
+  <file name="a.go">
+    <error column="2" line="7" message="unused variable" severity="error" source="unused"></error>
+  </file>
+  <file name="b.go"></file>
+</checkstyle>`, []pluginIssue{
+			{File: "a.go", Line: 7, Message: "unused variable", Rule: "unused", Severity: "error"},
+		}},
+	}
+
+	for _, tt := range cases {
+		got, err := parsePluginOutput(tt.format, []byte(tt.out))
+		if err != nil {
+			t.Errorf("[%s] parsePluginOutput returned error: %v", tt.format, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("[%s] parsePluginOutput = %v, want %v", tt.format, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("[%s] parsePluginOutput[%d] = %+v, want %+v", tt.format, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	if _, err := parsePluginOutput(FormatJSON, []byte("not json")); err == nil {
+		t.Errorf("parsePluginOutput of invalid JSON did not return an error")
+	}
+}
+
+func TestPluginRun(t *testing.T) {
+	dir := writeModule(t, "1.20", map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package a\n",
+	})
+	ctx := WithFilenames(context.Background(), []string{dir + "/a.go", dir + "/b.go"})
+
+	p := plugin{PluginConfig{
+		Name:    "echo",
+		Command: []string{"sh", "-c", `echo "a.go:1:1: found something" >&2; exit 1`},
+		Format:  FormatVet,
+	}}
+	pct, failed, err := p.Run(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pct != 0.5 {
+		t.Errorf("Run percentage = %v, want 0.5", pct)
+	}
+	if len(failed) != 1 || len(failed[0].Errors) != 1 || failed[0].Errors[0].ErrorString != "found something" {
+		t.Errorf("Run failed = %+v, want one error in a.go", failed)
+	}
+
+	p.cfg.Command = []string{"sh", "-c", "echo broken >&2; exit 2"}
+	if _, _, err := p.Run(ctx, dir); err == nil {
+		t.Errorf("Run of a failing command without issues did not return an error")
+	}
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	cases := []PluginConfig{
+		{Command: []string{"true"}, Format: FormatVet},
+		{Name: "nocommand", Format: FormatVet},
+		{Name: "noformat", Command: []string{"true"}},
+		{Name: "gofmt", Command: []string{"true"}, Format: FormatVet},
+	}
+	for _, cfg := range cases {
+		if err := RegisterPlugin(cfg); err == nil {
+			t.Errorf("[%q] RegisterPlugin did not return an error", cfg.Name)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// Prealloc is the check for slices that could be preallocated
+type Prealloc struct{}
+
+// Name returns the name of the display name of the command
+func (g Prealloc) Name() string {
+	return "prealloc"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Prealloc) Weight() float64 {
+	return 0
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Prealloc) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Prealloc) Severity() string {
+	return SeverityInfo
+}
+
+// CacheKey returns the key of the issues the check finds in a file
+func (g Prealloc) CacheKey() string {
+	return g.Name()
+}
+
+// Category returns the report section of the check
+func (g Prealloc) Category() string {
+	return CategoryPerformance
+}
+
+// emptySliceDecl returns the names of the slices declared empty by stmt,
+// as in var s []T or s := []T{}, keyed by name
+func emptySliceDecl(stmt ast.Stmt) map[string]ast.Node {
+	names := make(map[string]ast.Node)
+	switch s := stmt.(type) {
+	case *ast.DeclStmt:
+		gen, ok := s.Decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			break
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if arr, ok := vs.Type.(*ast.ArrayType); !ok || arr.Len != nil || len(vs.Values) != 0 {
+				continue
+			}
+			for _, n := range vs.Names {
+				names[n.Name] = n
+			}
+		}
+	case *ast.AssignStmt:
+		if s.Tok != token.DEFINE {
+			break
+		}
+		for i, rhs := range s.Rhs {
+			lit, ok := rhs.(*ast.CompositeLit)
+			if !ok || len(lit.Elts) != 0 || i >= len(s.Lhs) {
+				continue
+			}
+			if arr, ok := lit.Type.(*ast.ArrayType); !ok || arr.Len != nil {
+				continue
+			}
+			if id, ok := s.Lhs[i].(*ast.Ident); ok {
+				names[id.Name] = id
+			}
+		}
+	}
+	return names
+}
+
+// simpleLoopAppends returns the names of the slices that the body of a
+// range loop appends to, as in s = append(s, x). Loops that return, break,
+// continue or goto are not simple, as they may append fewer elements.
+func simpleLoopAppends(body *ast.BlockStmt) map[string]bool {
+	appends := make(map[string]bool)
+	simple := true
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt, *ast.BranchStmt:
+			simple = false
+		}
+		return true
+	})
+	if !simple {
+		return appends
+	}
+
+	for _, stmt := range body.List {
+		as, ok := stmt.(*ast.AssignStmt)
+		if !ok || as.Tok != token.ASSIGN || len(as.Lhs) != 1 || len(as.Rhs) != 1 {
+			continue
+		}
+		lhs, ok := as.Lhs[0].(*ast.Ident)
+		if !ok {
+			continue
+		}
+		call, ok := as.Rhs[0].(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			continue
+		}
+		if fn, ok := call.Fun.(*ast.Ident); !ok || fn.Name != "append" {
+			continue
+		}
+		if arg, ok := call.Args[0].(*ast.Ident); ok && arg.Name == lhs.Name {
+			appends[lhs.Name] = true
+		}
+	}
+	return appends
+}
+
+// Run returns the percentage of .go files in which all slices that
+// are appended to in range loops are preallocated
+func (g Prealloc) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	var (
+		failed = []FileSummary{}
+		fset   = token.NewFileSet()
+	)
+	for _, f := range filenames {
+		if err := ctx.Err(); err != nil {
+			return 0, []FileSummary{}, err
+		}
+		file, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		nolint := nolintLines(fset, file, "prealloc")
+
+		fs := newFileSummary(dir, f)
+		ast.Inspect(file, func(n ast.Node) bool {
+			block, ok := n.(*ast.BlockStmt)
+			if !ok {
+				return true
+			}
+			declared := make(map[string]ast.Node)
+			for _, stmt := range block.List {
+				for name, decl := range emptySliceDecl(stmt) {
+					declared[name] = decl
+				}
+				loop, ok := stmt.(*ast.RangeStmt)
+				if !ok {
+					continue
+				}
+				for name := range simpleLoopAppends(loop.Body) {
+					decl, ok := declared[name]
+					if !ok {
+						continue
+					}
+					delete(declared, name)
+					line := fset.Position(decl.Pos()).Line
+					if nolint[line] {
+						suppress(ctx)
+						continue
+					}
+					fs.Errors = append(fs.Errors, Error{
+						LineNumber:  line,
+						ErrorString: fmt.Sprintf("consider preallocating %s", name),
+					})
+				}
+			}
+			return true
+		})
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// Description returns the description of Prealloc
+func (g Prealloc) Description() string {
+	return `Based on <a href="https://github.com/alexkohler/prealloc">prealloc</a>, finds slices that are appended to in a range loop and could be created with <code>make</code> and the length of the loop instead. This is a performance hint and does not count towards the grade.`
+}
@@ -0,0 +1,76 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrealloc(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": `package a
+
+func a(in []int) []int {
+	var out []int
+	for _, x := range in {
+		out = append(out, x)
+	}
+	return out
+}
+
+func b(in []int) []int {
+	out := []int{}
+	for _, x := range in {
+		if x == 0 {
+			continue
+		}
+		out = append(out, x)
+	}
+	return out
+}
+
+func c(in []int) []string {
+	names := []string{}
+	for range in {
+		names = append(names, "x")
+	}
+	return names
+}
+
+func d(in []int) []int {
+	out := make([]int, 0, len(in))
+	for _, x := range in {
+		out = append(out, x)
+	}
+	return out
+}
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	ctx := WithFilenames(context.Background(), []string{filepath.Join(dir, "a.go")})
+	_, failed, err := Prealloc{}.Run(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("Prealloc reported %d files, want 1", len(failed))
+	}
+	want := []struct {
+		line int
+		msg  string
+	}{
+		{4, "consider preallocating out"},
+		{23, "consider preallocating names"},
+	}
+	errs := failed[0].Errors
+	if len(errs) != len(want) {
+		t.Fatalf("Prealloc errors = %v, want %d", errs, len(want))
+	}
+	for i, w := range want {
+		if errs[i].LineNumber != w.line || errs[i].ErrorString != w.msg {
+			t.Errorf("Prealloc error %d = %+v, want line %d %q", i, errs[i], w.line, w.msg)
+		}
+	}
+}
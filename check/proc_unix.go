@@ -0,0 +1,50 @@
+//go:build !windows
+// +build !windows
+
+package check
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// killProcessGroup starts cmd in a process group of its own, and makes
+// cancelling cmd kill the whole group, so tools like gometalinter do not
+// leave the linters they started running
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// cpuSignaled reports whether the process was killed for exceeding its
+// soft cpu time limit
+func cpuSignaled(state *os.ProcessState) bool {
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGXCPU
+}
+
+// withRlimits returns the command line that runs name with args with the
+// cpu time and memory of its processes limited by the shell
+func withRlimits(limits Limits, name string, args []string) (string, []string) {
+	var ulimits []string
+	if limits.CPUTime > 0 {
+		// the soft limit sends SIGXCPU, which tells the limit apart from
+		// other kills
+		secs := int64(math.Ceil(limits.CPUTime.Seconds()))
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -t %d && ulimit -S -t %d", secs+1, secs))
+	}
+	if limits.Memory > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -v %d", limits.Memory>>10))
+	}
+	if len(ulimits) == 0 {
+		return name, args
+	}
+	script := strings.Join(ulimits, " && ") + ` && exec "$0" "$@"`
+	return "/bin/sh", append([]string{"-c", script, name}, args...)
+}
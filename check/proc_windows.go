@@ -0,0 +1,21 @@
+package check
+
+import (
+	"os"
+	"os/exec"
+)
+
+// killProcessGroup does nothing on Windows, where cancelling cmd only
+// kills cmd itself
+func killProcessGroup(cmd *exec.Cmd) {}
+
+// cpuSignaled returns false, as Windows has no cpu time limit
+func cpuSignaled(state *os.ProcessState) bool {
+	return false
+}
+
+// withRlimits returns name and args unchanged, as Windows has no rlimits.
+// Only the output of commands is limited.
+func withRlimits(limits Limits, name string, args []string) (string, []string) {
+	return name, args
+}
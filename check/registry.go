@@ -0,0 +1,99 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+)
+
+var registry = struct {
+	sync.Mutex
+	checks   []Check
+	optional []Check
+	names    map[string]bool
+}{names: make(map[string]bool)}
+
+func register(list *[]Check, ck Check) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.names[ck.Name()] {
+		return fmt.Errorf("check: Register called twice for check %s", ck.Name())
+	}
+	registry.names[ck.Name()] = true
+	*list = append(*list, ck)
+	return nil
+}
+
+// Register adds a check that is run on every repo, after the checks
+// registered before it. It panics if a check with the same name is
+// already registered.
+func Register(ck Check) {
+	if err := register(&registry.checks, ck); err != nil {
+		panic(err.Error())
+	}
+}
+
+// RegisterOptional adds a check that repos can enable in their
+// ConfigFile. If it implements Replaces, it is run in place of the named
+// check. It panics if a check with the same name is already registered.
+func RegisterOptional(ck Check) {
+	if err := register(&registry.optional, ck); err != nil {
+		panic(err.Error())
+	}
+}
+
+// Checks returns the checks that are run on every repo, in the order
+// they were registered
+func Checks() []Check {
+	registry.Lock()
+	defer registry.Unlock()
+	return append([]Check(nil), registry.checks...)
+}
+
+// OptionalChecks returns the checks that repos can enable in their
+// ConfigFile, in the order they were registered
+func OptionalChecks() []Check {
+	registry.Lock()
+	defer registry.Unlock()
+	return append([]Check(nil), registry.optional...)
+}
+
+func init() {
+	for _, ck := range []Check{
+		GoFmt{},
+		GoImports{},
+		GoVet{},
+		CrossBuild{},
+		Shadow{},
+		Revive{},
+		GoCyclo{},
+		GoCognit{},
+		NakedRet{},
+		Globals{},
+		License{},
+		Misspell{},
+		IneffAssign{},
+		Unconvert{},
+		Staticcheck{},
+		Unused{},
+		Exhaustive{},
+		ErrCheck{},
+		Gosec{},
+		Secrets{},
+		Dupl{},
+		GoVulnCheck{},
+		GoModTidy{},
+		Outdated{},
+		Bloat{},
+		Coverage{},
+		MissingTests{},
+		DocCoverage{},
+		Prealloc{},
+		FieldAlignment{},
+		Godox{},
+	} {
+		Register(ck)
+	}
+
+	RegisterOptional(LibraryExits{})
+	RegisterOptional(GoFumpt{})
+}
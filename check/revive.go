@@ -0,0 +1,88 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// reviveConfigs are the names of the revive config files that are looked
+// up in the repo root, in order of preference
+var reviveConfigs = []string{"revive.toml", ".revive.toml"}
+
+// Revive is the check for the revive command, which replaces golint. It
+// keeps the golint name, by which stored reports, //nolint comments,
+// baselines and weights refer to it.
+type Revive struct{}
+
+// Name returns the name of the display name of the command
+func (g Revive) Name() string {
+	return "golint"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Revive) Weight() float64 {
+	return .10
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Revive) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Revive) Severity() string {
+	return SeverityWarning
+}
+
+// FileScoped returns true, as the revive rules look at one package at a
+// time
+func (g Revive) FileScoped() bool {
+	return true
+}
+
+// reviveArgs returns the arguments to run revive on dir with, see
+// toolTargets. The repo's own config is used if it has one, otherwise
+// revive's defaults apply, which match the golint rules.
+func reviveArgs(ctx context.Context, dir string) []string {
+	args := []string{"-formatter", "default"}
+	for _, name := range reviveConfigs {
+		cfg := filepath.Join(dir, name)
+		if fi, err := os.Stat(cfg); err == nil && fi.Mode().IsRegular() {
+			args = append(args, "-config", cfg)
+			break
+		}
+	}
+	for _, skip := range skipDirs {
+		args = append(args, "-exclude", filepath.Join(dir, skip)+"/...")
+	}
+	return append(args, toolTargets(ctx, dir)...)
+}
+
+// Run returns the percentage of .go files that pass revive
+func (g Revive) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	out, err := runTool(ctx, "revive", reviveArgs(ctx, dir)...)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	fsMap, err := getFileSummaryMap(bufio.NewScanner(bytes.NewReader(out)), dir, dir)
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	var failed = []FileSummary{}
+	for _, v := range fsMap {
+		failed = append(failed, v)
+	}
+	return toolPercentage(filenames, targetSummaries(ctx, dir, failed))
+}
+
+// Description returns the description of golint
+func (g Revive) Description() string {
+	return `Golint is a linter for Go source code, which is run with <a href="https://github.com/mgechev/revive">revive</a>, the successor of the deprecated golint command. The rules can be configured with a <code>revive.toml</code> or <code>.revive.toml</code> file in the root of your repo.`
+}
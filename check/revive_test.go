@@ -0,0 +1,48 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReviveArgs(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{"a.go": "package a\n"})
+	defer os.RemoveAll(dir)
+
+	args := reviveArgs(context.Background(), dir)
+	for _, a := range args {
+		if a == "-config" {
+			t.Errorf("reviveArgs without config = %v, want no -config", args)
+		}
+	}
+	if last := args[len(args)-1]; last != dir+"/..." {
+		t.Errorf("reviveArgs last argument = %q, want %q", last, dir+"/...")
+	}
+
+	dir = writeModule(t, "", map[string]string{
+		"a.go":         "package a\n",
+		".revive.toml": "confidence = 0.9\n",
+	})
+	defer os.RemoveAll(dir)
+
+	args = reviveArgs(context.Background(), dir)
+	want := filepath.Join(dir, ".revive.toml")
+	var found bool
+	for i, a := range args {
+		if a == "-config" && i+1 < len(args) && args[i+1] == want {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("reviveArgs = %v, want -config %s", args, want)
+	}
+}
+
+func TestReviveName(t *testing.T) {
+	// stored reports, //nolint comments, baselines and weights name it
+	if name := (Revive{}).Name(); name != "golint" {
+		t.Errorf("Revive name = %q, want golint", name)
+	}
+}
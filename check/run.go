@@ -0,0 +1,457 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWorkers is the number of checks a Checker runs at the same time
+// if its Workers field is zero
+var DefaultWorkers = runtime.NumCPU()
+
+// DefaultCheckTimeout is the maximum time a check may take if neither
+// the check nor the Checker sets a timeout
+var DefaultCheckTimeout = 5 * time.Minute
+
+// CheckResult contains the outcome of running a single check
+type CheckResult struct {
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	FileSummaries []FileSummary `json:"file_summaries"`
+	Weight        float64       `json:"weight"`
+	Percentage    float64       `json:"percentage"`
+	Error         string        `json:"error"`
+	// Category is the section of the report the check belongs in,
+	// or empty for the main results
+	Category string `json:"category,omitempty"`
+	// Suppressed is the number of issues dropped because of //nolint
+	// comments
+	Suppressed int `json:"suppressed,omitempty"`
+	// Baselined is the number of issues dropped because they are in
+	// the repo's BaselineFile
+	Baselined int `json:"baselined,omitempty"`
+	// Severities counts the issues by severity
+	Severities map[string]int `json:"severities,omitempty"`
+	// Status is StatusTimedOut or StatusExceededLimits if the check was
+	// stopped, StatusSkipped if it was not run, and empty otherwise
+	Status string `json:"status,omitempty"`
+	// Duration is how long the check took to run
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// the statuses of checks that were stopped or not run
+const (
+	StatusTimedOut       = "timed_out"
+	StatusExceededLimits = "exceeded_limits"
+	// StatusSkipped is the status of a check that the sandbox of the
+	// Checker cannot run safely. Skipped checks have no weight, and
+	// their Error says why they were skipped.
+	StatusSkipped = "skipped"
+)
+
+// timeoutError is the error of a check that took longer than its timeout
+type timeoutError struct {
+	timeout time.Duration
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v", e.timeout)
+}
+
+// status returns the status of a check that failed with err
+func status(err error) string {
+	switch err.(type) {
+	case timeoutError:
+		return StatusTimedOut
+	case *LimitError:
+		return StatusExceededLimits
+	}
+	return ""
+}
+
+// timeouter is implemented by checks that need a different timeout
+// than the Checker's, such as running the tests of a repo
+type timeouter interface {
+	Timeout() time.Duration
+}
+
+// replacer is implemented by optional checks that take the place of
+// one of the default checks when they are enabled
+type replacer interface {
+	Replaces() string
+}
+
+// codeRunner is implemented by checks that run the code of the repo, such
+// as its tests, which is only done in a sandbox that isolates it from the
+// host
+type codeRunner interface {
+	RunsCode() bool
+}
+
+// fileScorer is implemented by checks whose percentage is graded with
+// toolPercentage from the files with issues, so that it can be graded
+// again when //nolint comments or the baseline drop issues. The
+// percentages of other checks are kept.
+type fileScorer interface {
+	FileScored() bool
+}
+
+// networkUser is implemented by checks whose commands need network
+// access, such as to download modules, which they are skipped without
+type networkUser interface {
+	UsesNetwork() bool
+}
+
+// ConfiguredChecks returns the checks that are run on every repo, followed
+// by the optional checks enabled in cfg. An optional check that replaces a
+// default check is run in its place instead. Checks disabled in cfg are
+// left out, and the thresholds and unused settings in cfg are applied.
+func ConfiguredChecks(cfg RepoConfig) []Check {
+	checks := Checks()
+outer:
+	for _, ck := range OptionalChecks() {
+		if !cfg.enabled(ck.Name()) {
+			continue
+		}
+		if r, ok := ck.(replacer); ok {
+			for i := range checks {
+				if checks[i].Name() == r.Replaces() {
+					checks[i] = ck
+					continue outer
+				}
+			}
+		}
+		checks = append(checks, ck)
+	}
+
+	var configured []Check
+	for _, ck := range checks {
+		if cfg.disabled(ck.Name()) {
+			continue
+		}
+		if n, ok := cfg.Thresholds[ck.Name()]; ok {
+			if t, ok := ck.(thresholder); ok {
+				ck = t.WithThreshold(n)
+			}
+		}
+		if u, ok := ck.(Unused); ok {
+			u.ExcludeExported = cfg.ExcludeExported
+			ck = u
+		}
+		configured = append(configured, ck)
+	}
+	return configured
+}
+
+// Checker runs checks on a directory. The zero value is ready to use
+// and discards all log events.
+type Checker struct {
+	// Logger receives events such as walk errors and per check
+	// timings. If nil, events are discarded.
+	Logger Logger
+	// Workers is the maximum number of checks run at the same time.
+	// If zero, DefaultWorkers is used.
+	Workers int
+	// Timeout is the maximum time a single check may take, unless the
+	// check sets its own. If zero, DefaultCheckTimeout is used.
+	Timeout time.Duration
+	// NoBaseline reports the issues in the repo's BaselineFile too,
+	// for example to write a new baseline
+	NoBaseline bool
+	// Sandbox starts the commands of the checks. If nil, DefaultSandbox
+	// is used.
+	Sandbox Sandbox
+	// Limits are the resources each command of a check may use. If
+	// zero, DefaultLimits are used.
+	Limits Limits
+	// Cache stores the issues of checks that look at one file at a time,
+	// so that files that did not change are not checked again. If nil,
+	// all files are checked.
+	Cache Cache
+	// Previous is an earlier grade of the repo. If set, the checks that
+	// look at one file at a time only check the files that changed since.
+	Previous *Previous
+	// Progress is called when a check starts and when it finishes. Checks
+	// run concurrently, so it must be safe for concurrent use. If nil,
+	// progress is not reported.
+	Progress func(Progress)
+	// Snippets attaches the source around the line of every error to the
+	// error, see Snippet
+	Snippets bool
+	// Artifacts is a directory that checks write the files they produce
+	// into, such as the CoverageProfile of the coverage check. A
+	// DockerSandbox mounts it writable. If empty, no files are written.
+	Artifacts string
+}
+
+// Progress is the progress of a Checker running the checks on a repo
+type Progress struct {
+	// Check is the check that started or finished
+	Check string
+	// Finished is false when Check started, and true when it finished
+	Finished bool
+	// Done is the number of checks that finished, and Total the number
+	// of checks that are run
+	Done, Total int
+}
+
+func (c Checker) logger() Logger {
+	if c.Logger == nil {
+		return nopLogger{}
+	}
+	return c.Logger
+}
+
+// RunAll concurrently runs all checks on the given files in dir
+// using a Checker with no logger
+func RunAll(ctx context.Context, dir string, filenames []string) []CheckResult {
+	return Checker{}.RunAll(ctx, dir, filenames)
+}
+
+// RunAll concurrently runs all checks on the given files in dir, with the
+// optional checks enabled in the repo's ConfigFile and the weights of
+// Weights and of the ConfigFile, running at most
+// c.Workers checks at a time. The results are returned in the same order
+// as ConfiguredChecks. A check that fails to run or times out does not
+// stop the others; its error is recorded in the result. If ctx is done,
+// the running checks are stopped and the remaining ones fail with the
+// error of ctx. In a repo with several modules, found by FindModules, the
+// go tools of the checks are run in each module.
+func (c Checker) RunAll(ctx context.Context, dir string, filenames []string) []CheckResult {
+	logger := c.logger()
+	cfg, err := LoadRepoConfig(dir)
+	if err != nil {
+		logger.Log("could not load repo config", "dir", dir, "error", err)
+	}
+	mods, err := FindModules(dir)
+	if err != nil {
+		logger.Log("could not find modules", "dir", dir, "error", err)
+	}
+	ctx = WithModules(WithFilenames(ctx, filenames), mods)
+	results := c.run(ctx, dir, ConfiguredChecks(cfg))
+	for i := range results {
+		if results[i].Status != StatusSkipped {
+			results[i].Weight = cfg.weight(results[i].Name, results[i].Weight)
+		}
+	}
+	return results
+}
+
+// run runs the checks on dir with a pool of workers, and returns their
+// results in the same order as checks
+func (c Checker) run(ctx context.Context, dir string, checks []Check) []CheckResult {
+	workers := c.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+	sandbox := c.Sandbox
+	if sandbox == nil {
+		sandbox = DefaultSandbox
+	}
+	limits := c.Limits
+	if limits == (Limits{}) {
+		limits = DefaultLimits
+	}
+	ctx = withSandbox(ctx, sandbox, dir, limits)
+	ctx = withLogger(ctx, c.logger())
+	if c.Artifacts != "" {
+		ctx = context.WithValue(ctx, artifactsKey{}, c.Artifacts)
+	}
+
+	f := filters{nolints: newNolintIndex(dir, Filenames(ctx))}
+	if c.Snippets {
+		f.sources = newSourceIndex(f.nolints.paths)
+	}
+	if !c.NoBaseline {
+		var err error
+		if f.baseline, err = loadBaseline(dir); err != nil {
+			c.logger().Log("could not load baseline", "dir", dir, "error", err)
+		}
+	}
+
+	var hashes map[string]string
+	if c.Cache != nil {
+		hashes = hashFiles(Filenames(ctx))
+	}
+
+	results := make([]CheckResult, len(checks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var done int64
+	progress := func(name string, finished bool) {
+		if c.Progress == nil {
+			return
+		}
+		n := atomic.LoadInt64(&done)
+		if finished {
+			n = atomic.AddInt64(&done, 1)
+		}
+		c.Progress(Progress{Check: name, Finished: finished, Done: int(n), Total: len(checks)})
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				progress(checks[i].Name(), false)
+				// every worker writes to its own elements of results
+				results[i] = c.runCheck(ctx, dir, checks[i], f, hashes)
+				progress(checks[i].Name(), true)
+			}
+		}()
+	}
+	for i := range checks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+type artifactsKey struct{}
+
+// artifactsDir returns the Artifacts directory of the Checker running the
+// checks, or "" if files are not written
+func artifactsDir(ctx context.Context) string {
+	dir, _ := ctx.Value(artifactsKey{}).(string)
+	return dir
+}
+
+// skipReason returns why ck is not run in the sandbox of c, or "" if it
+// is run
+func (c Checker) skipReason(ck Check) string {
+	sandbox := c.Sandbox
+	if sandbox == nil {
+		sandbox = DefaultSandbox
+	}
+	if r, ok := ck.(codeRunner); ok && r.RunsCode() && !isolates(sandbox) {
+		return "runs the code of the repo, which is only done in an isolated sandbox"
+	}
+	if u, ok := ck.(networkUser); ok && u.UsesNetwork() && isolates(sandbox) {
+		return "needs network access, which the sandbox does not have"
+	}
+	return ""
+}
+
+// timeout returns the maximum time ck may take
+func (c Checker) timeout(ck Check) time.Duration {
+	if t, ok := ck.(timeouter); ok {
+		return t.Timeout()
+	}
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultCheckTimeout
+}
+
+// runWithTimeout runs ck, stopping it when its timeout passes or ctx
+// is done
+func (c Checker) runWithTimeout(ctx context.Context, dir string, ck Check) (float64, []FileSummary, error) {
+	if err := ctx.Err(); err != nil {
+		// the run was cancelled before the check started
+		return 0, []FileSummary{}, err
+	}
+
+	timeout := c.timeout(ck)
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	p, summaries, err := ck.Run(checkCtx, dir)
+	if checkCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return 0, []FileSummary{}, timeoutError{timeout}
+	}
+	return p, summaries, err
+}
+
+// filters drop issues from the results of the checks, and add the
+// source around the remaining ones if sources is set
+type filters struct {
+	nolints  *nolintIndex
+	baseline baselineSet
+	sources  *sourceIndex
+}
+
+// runCheck runs a single check and records its outcome. Issues on lines
+// with a //nolint comment for the check, and issues in the baseline, are
+// dropped. The percentage is then weighted by the severity of the
+// remaining issues. The issues of files with the given hashes are taken
+// from the cache if ck supports it.
+func (c Checker) runCheck(ctx context.Context, dir string, ck Check, f filters, hashes map[string]string) CheckResult {
+	logger := c.logger()
+	if reason := c.skipReason(ck); reason != "" {
+		logger.Log("check skipped", "check", ck.Name(), "dir", dir, "reason", reason)
+		return CheckResult{
+			Name:          ck.Name(),
+			Description:   ck.Description(),
+			FileSummaries: []FileSummary{},
+			Error:         reason,
+			Status:        StatusSkipped,
+			Category:      category(ck),
+		}
+	}
+	logger.Log("check started", "check", ck.Name(), "dir", dir)
+	started := time.Now()
+	ctx, suppressed := withSuppressed(ctx)
+	var (
+		p         float64
+		summaries []FileSummary
+		cached    int
+		err       error
+	)
+	if prev, changed, ok := c.Previous.incremental(ck); ok {
+		p, summaries, err = c.runIncremental(ctx, dir, ck, prev, changed)
+	} else if _, ok := ck.(fileCacher); ok && c.Cache != nil {
+		p, summaries, cached, err = c.runCached(ctx, dir, ck, hashes)
+	} else {
+		p, summaries, err = c.runWithTimeout(ctx, dir, ck)
+	}
+	errMsg := ""
+	if err != nil {
+		logger.Log("check failed", "check", ck.Name(), "dir", dir, "error", err)
+		errMsg = err.Error()
+	}
+	var baselined int
+	var severities map[string]int
+	if err == nil {
+		var n int
+		summaries, _, n = f.nolints.filter(ck.Name(), summaries)
+		*suppressed += int64(n)
+		summaries, _, baselined = f.baseline.filter(dir, ck.Name(), summaries)
+		if fs, ok := ck.(fileScorer); ok && fs.FileScored() && n+baselined > 0 {
+			// the percentage is graded again without the dropped issues
+			if cp, _, err := toolPercentage(Filenames(ctx), summaries); err != nil {
+				logger.Log("could not grade check without cleared issues", "check", ck.Name(), "dir", dir, "error", err)
+			} else {
+				p = cp
+			}
+		}
+		p, summaries, severities = applySeverities(p, severity(ck), summaries)
+		// applySeverities copied the errors, so they can be sorted
+		sortSummaries(summaries)
+		f.sources.attach(summaries)
+	}
+	elapsed := time.Since(started)
+	logger.Log("check finished", "check", ck.Name(), "dir", dir,
+		"duration", elapsed, "percentage", p, "cached", cached)
+	return CheckResult{
+		Name:          ck.Name(),
+		Description:   ck.Description(),
+		FileSummaries: summaries,
+		Weight:        ck.Weight(),
+		Percentage:    p,
+		Error:         errMsg,
+		Status:        status(err),
+		Category:      category(ck),
+		Suppressed:    int(*suppressed),
+		Baselined:     baselined,
+		Severities:    severities,
+		Duration:      elapsed,
+	}
+}
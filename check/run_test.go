@@ -0,0 +1,234 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingCheck records how many checks are running at the same time
+type countingCheck struct {
+	name    string
+	mu      *sync.Mutex
+	running *int
+	max     *int
+}
+
+func (c countingCheck) Name() string        { return c.name }
+func (c countingCheck) Description() string { return "" }
+func (c countingCheck) Weight() float64     { return 1 }
+
+func (c countingCheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	c.mu.Lock()
+	*c.running++
+	if *c.running > *c.max {
+		*c.max = *c.running
+	}
+	c.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	*c.running--
+	c.mu.Unlock()
+	return 1, []FileSummary{}, nil
+}
+
+func TestRunWorkers(t *testing.T) {
+	var mu sync.Mutex
+	var running, max int
+	var checks []Check
+	for i := 0; i < 10; i++ {
+		checks = append(checks, countingCheck{fmt.Sprintf("check%d", i), &mu, &running, &max})
+	}
+
+	for _, workers := range []int{1, 3, 20} {
+		max = 0
+		results := Checker{Workers: workers}.run(context.Background(), "testfiles", checks)
+		if len(results) != len(checks) {
+			t.Fatalf("[%d] run returned %d results, want %d", workers, len(results), len(checks))
+		}
+		for i, r := range results {
+			if r.Name != checks[i].Name() {
+				t.Errorf("[%d] result %d is for %s, want %s", workers, i, r.Name, checks[i].Name())
+			}
+		}
+		want := workers
+		if want > len(checks) {
+			want = len(checks)
+		}
+		if max > want {
+			t.Errorf("[%d] %d checks ran at the same time, want at most %d", workers, max, want)
+		}
+	}
+}
+
+func TestRunProgress(t *testing.T) {
+	var mu sync.Mutex
+	var events []Progress
+	c := Checker{Workers: 2, Progress: func(p Progress) {
+		mu.Lock()
+		events = append(events, p)
+		mu.Unlock()
+	}}
+	checks := []Check{fixedCheck{name: "a", percent: 1}, fixedCheck{name: "b", percent: 1}, fixedCheck{name: "c", percent: 1}}
+	c.run(context.Background(), "testfiles", checks)
+
+	if len(events) != 2*len(checks) {
+		t.Fatalf("got %d progress events, want %d", len(events), 2*len(checks))
+	}
+	var finished []int
+	for _, e := range events {
+		if e.Total != len(checks) {
+			t.Errorf("[%s] total = %d, want %d", e.Check, e.Total, len(checks))
+		}
+		if e.Finished {
+			finished = append(finished, e.Done)
+		}
+	}
+	for i, done := range finished {
+		if done != i+1 {
+			t.Errorf("finish event %d has done = %d, want %d", i, done, i+1)
+		}
+	}
+}
+
+// blockingCheck runs until its context is done
+type blockingCheck struct{}
+
+func (blockingCheck) Name() string        { return "blocking" }
+func (blockingCheck) Description() string { return "" }
+func (blockingCheck) Weight() float64     { return 1 }
+
+func (blockingCheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	<-ctx.Done()
+	return 0, []FileSummary{}, ctx.Err()
+}
+
+func TestRunTimeout(t *testing.T) {
+	results := Checker{Timeout: 10 * time.Millisecond}.run(context.Background(), "testfiles", []Check{blockingCheck{}})
+	if want := "timed out after 10ms"; results[0].Error != want || results[0].Status != StatusTimedOut {
+		t.Errorf("run error = %q with status %q, want %q with status %q", results[0].Error, results[0].Status, want, StatusTimedOut)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var mu sync.Mutex
+	var running, max int
+	results = Checker{}.run(ctx, "testfiles", []Check{countingCheck{"counting", &mu, &running, &max}})
+	if results[0].Error != context.Canceled.Error() || max != 0 {
+		t.Errorf("run after cancel = %+v, ran %d checks, want a cancelled result", results[0], max)
+	}
+}
+
+func TestRunToolKilled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	// the pipe keeps the output open while sleep is running, unless the
+	// whole process group is killed
+	_, err := runTool(ctx, "sh", "-c", "sleep 10 | cat")
+	if err != context.DeadlineExceeded {
+		t.Errorf("runTool error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(started); d > 5*time.Second {
+		t.Errorf("runTool took %v to stop", d)
+	}
+}
+
+// fixedCheck reports the same issues on every run
+type fixedCheck struct {
+	name    string
+	percent float64
+	failed  []FileSummary
+}
+
+func (c fixedCheck) Name() string        { return c.name }
+func (c fixedCheck) Description() string { return "" }
+func (c fixedCheck) Weight() float64     { return 1 }
+
+func (c fixedCheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return c.percent, c.failed, nil
+}
+
+// fileScoredCheck is a fixedCheck whose percentage is graded per file
+type fileScoredCheck struct {
+	fixedCheck
+}
+
+func (c fileScoredCheck) FileScored() bool { return true }
+
+func TestRunNolint(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": "package a\n\nvar A = 1 //nolint:fixed\nvar B = 2 //nolint:other\n",
+		"b.go": "package a\n\nvar C = 3 //nolint\n",
+	})
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	ctx := WithFilenames(context.Background(), []string{a, b})
+
+	fsA, fsB := newFileSummary(dir, a), newFileSummary(dir, b)
+	fsA.Errors = []Error{{LineNumber: 3, ErrorString: "A"}, {LineNumber: 4, ErrorString: "B"}}
+	fsB.Errors = []Error{{LineNumber: 3, ErrorString: "C"}}
+	ck := fileScoredCheck{fixedCheck{"fixed", 0, []FileSummary{fsA, fsB}}}
+
+	results := Checker{}.run(ctx, dir, []Check{ck})
+	r := results[0]
+	if r.Suppressed != 2 {
+		t.Errorf("run suppressed = %d, want 2", r.Suppressed)
+	}
+	if len(r.FileSummaries) != 1 || len(r.FileSummaries[0].Errors) != 1 || r.FileSummaries[0].Errors[0].ErrorString != "B" {
+		t.Errorf("run file summaries = %+v, want only B", r.FileSummaries)
+	}
+	if r.Percentage != 0.5 {
+		t.Errorf("run percentage = %v, want 0.5", r.Percentage)
+	}
+}
+
+func TestRunNolintLongFile(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": "package a\n\nvar A = 1 //nolint:fixed\nvar B = 2\n" + strings.Repeat("\n", 196),
+		"b.go": "package a\n",
+	})
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	filenames := []string{a, b}
+	ctx := WithFilenames(context.Background(), filenames)
+
+	fs := newFileSummary(dir, a)
+	fs.Errors = []Error{{LineNumber: 3, ErrorString: "A"}, {LineNumber: 4, ErrorString: "B"}}
+	p, _, err := toolPercentage(filenames, []FileSummary{fs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := fs
+	remaining.Errors = fs.Errors[1:]
+	want, _, err := toolPercentage(filenames, []FileSummary{remaining})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// clearing one of two issues in a long file only takes back its lines
+	r := Checker{}.run(ctx, dir, []Check{fileScoredCheck{fixedCheck{"fixed", p, []FileSummary{fs}}}})[0]
+	if r.Percentage != want || want >= 1 {
+		t.Errorf("run percentage = %v, want %v", r.Percentage, want)
+	}
+}
+
+func TestRunNativeSuppressed(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": "package a\n\nvar verbose bool //nolint:globals\n",
+	})
+	defer os.RemoveAll(dir)
+	ctx := WithFilenames(context.Background(), []string{filepath.Join(dir, "a.go")})
+
+	r := Checker{}.run(ctx, dir, []Check{Globals{}})[0]
+	if r.Suppressed != 1 || r.Percentage != 1 {
+		t.Errorf("run globals = %+v, want 1 suppressed issue", r)
+	}
+}
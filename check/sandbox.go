@@ -0,0 +1,169 @@
+package check
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SandboxCommand is a command that a check runs
+type SandboxCommand struct {
+	// Root is the repo that is checked, which Dir is usually inside of
+	Root string
+	// Dir is the directory the command runs in, or the current directory
+	// if empty
+	Dir string
+	// Env are variables that are set in addition to the environment of
+	// the sandbox
+	Env  []string
+	Name string
+	Args []string
+	// Limits are the resources the command may use
+	Limits Limits
+	// Artifacts is the directory that the command may write the files
+	// that checks produce to, or "" if there is none
+	Artifacts string
+}
+
+// Sandbox starts the commands that checks run. Linters run on untrusted
+// code, and some of them, like go test, run that code.
+type Sandbox interface {
+	// Command returns a command that runs c within its limits. The
+	// command must be killed, together with the processes it started,
+	// when ctx is done.
+	Command(ctx context.Context, c SandboxCommand) *exec.Cmd
+}
+
+// DefaultSandbox is the sandbox of a Checker without one
+var DefaultSandbox Sandbox = LocalSandbox{}
+
+// LocalSandbox runs commands directly on the host, as the user running
+// Go Report Card
+type LocalSandbox struct{}
+
+// Command returns a command that runs on the host. Where the shell has
+// ulimit, the cpu time and memory of the command are limited with it.
+func (LocalSandbox) Command(ctx context.Context, c SandboxCommand) *exec.Cmd {
+	name, args := withRlimits(c.Limits, c.Name, c.Args)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	killProcessGroup(cmd)
+	// do not wait for output from processes that escaped the kill
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// DockerSandbox runs every command in a new Docker container without
+// network access, so checks that need it are skipped. The repo is
+// mounted read-only at the same path as on the host, so the paths that
+// tools report do not change. The image must have the Go toolchain and
+// the linters installed.
+type DockerSandbox struct {
+	// Image is the image the containers are started from
+	Image string
+	// Mounts are host paths that are mounted read-only at the same path,
+	// such as the module cache of the host
+	Mounts []string
+	// Docker is the docker command. If empty, docker is looked up in PATH.
+	Docker string
+}
+
+// Command returns a command that runs the docker client, which starts a
+// container for the command and removes it when the command exits. The
+// container is killed when ctx is done.
+func (s DockerSandbox) Command(ctx context.Context, c SandboxCommand) *exec.Cmd {
+	docker := s.Docker
+	if docker == "" {
+		docker = "docker"
+	}
+	container := "goreportcard-" + randomHex(8)
+
+	wd, err := filepath.Abs(c.Dir)
+	if err != nil {
+		wd = c.Dir
+	}
+	dockerArgs := []string{"run", "--rm", "-i", "--name", container, "--network", "none", "-w", wd}
+	if c.Limits.CPUTime > 0 {
+		// the soft limit sends SIGXCPU, so the exit code tells it apart
+		// from running out of memory
+		secs := int64(math.Ceil(c.Limits.CPUTime.Seconds()))
+		dockerArgs = append(dockerArgs, "--ulimit", fmt.Sprintf("cpu=%d:%d", secs, secs+1))
+	}
+	if c.Limits.Memory > 0 {
+		mem := strconv.FormatInt(c.Limits.Memory, 10)
+		dockerArgs = append(dockerArgs, "--memory", mem, "--memory-swap", mem)
+	}
+	if c.Root != "" {
+		if abs, err := filepath.Abs(c.Root); err == nil {
+			dockerArgs = append(dockerArgs, "-v", abs+":"+abs+":ro")
+			// commands like go mod tidy run on a temporary copy of the
+			// repo, which they may change
+			if wd != abs && !strings.HasPrefix(wd, abs+string(filepath.Separator)) && c.Dir != "" {
+				dockerArgs = append(dockerArgs, "-v", wd+":"+wd)
+			}
+		}
+	}
+	for _, m := range s.Mounts {
+		dockerArgs = append(dockerArgs, "-v", m+":"+m+":ro")
+	}
+	if c.Artifacts != "" {
+		if abs, err := filepath.Abs(c.Artifacts); err == nil {
+			dockerArgs = append(dockerArgs, "-v", abs+":"+abs)
+		}
+	}
+	for _, e := range c.Env {
+		dockerArgs = append(dockerArgs, "-e", e)
+	}
+	dockerArgs = append(append(dockerArgs, s.Image, c.Name), c.Args...)
+
+	cmd := exec.CommandContext(ctx, docker, dockerArgs...)
+	killProcessGroup(cmd)
+	killClient := cmd.Cancel
+	cmd.Cancel = func() error {
+		// killing the client leaves the container running
+		exec.Command(docker, "kill", container).Run()
+		return killClient()
+	}
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// isolates reports whether s keeps the commands it runs away from the
+// files and network of the host, so that they can run the code of repos
+// but cannot reach the network
+func isolates(s Sandbox) bool {
+	_, ok := s.(DockerSandbox)
+	return ok
+}
+
+// randomHex returns n random bytes in hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type sandboxKey struct{}
+
+type sandboxValue struct {
+	sandbox Sandbox
+	root    string
+	limits  Limits
+}
+
+// withSandbox returns a copy of ctx in which the commands of checks on
+// the repo at root are started by s, within limits
+func withSandbox(ctx context.Context, s Sandbox, root string, limits Limits) context.Context {
+	return context.WithValue(ctx, sandboxKey{}, sandboxValue{s, root, limits})
+}
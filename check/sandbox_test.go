@@ -0,0 +1,99 @@
+package check
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDockerSandboxCommand(t *testing.T) {
+	root, err := filepath.Abs("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := os.TempDir()
+	s := DockerSandbox{Image: "golang:1.22", Mounts: []string{"/go/pkg/mod"}}
+	for i, tt := range []struct {
+		dir  string
+		want string
+	}{
+		{"testfiles", "-w " + root + " -v " + root + ":" + root + ":ro -v /go/pkg/mod:/go/pkg/mod:ro -e GO111MODULE=on golang:1.22 go vet ./..."},
+		{tmp, "-w " + tmp + " -v " + root + ":" + root + ":ro -v " + tmp + ":" + tmp + " -v /go/pkg/mod:/go/pkg/mod:ro -e GO111MODULE=on golang:1.22 go vet ./..."},
+		{"testfiles", "-w " + root + " -v " + root + ":" + root + ":ro -v /go/pkg/mod:/go/pkg/mod:ro -v " + tmp + ":" + tmp + " -e GO111MODULE=on golang:1.22 go vet ./..."},
+	} {
+		var artifacts string
+		if i == 2 {
+			artifacts = tmp
+		}
+		cmd := s.Command(context.Background(), SandboxCommand{
+			Root:      "testfiles",
+			Dir:       tt.dir,
+			Env:       []string{"GO111MODULE=on"},
+			Name:      "go",
+			Args:      []string{"vet", "./..."},
+			Artifacts: artifacts,
+		})
+		args := strings.Join(cmd.Args, " ")
+		if !strings.HasPrefix(args, "docker run --rm -i --name goreportcard-") || !strings.Contains(args, " --network none ") {
+			t.Errorf("[%q] DockerSandbox runs %q, want a container without network", tt.dir, args)
+		}
+		if !strings.HasSuffix(args, tt.want) {
+			t.Errorf("[%q] DockerSandbox runs %q, want it to end with %q", tt.dir, args, tt.want)
+		}
+		if cmd.Dir != "" || cmd.Env != nil {
+			t.Errorf("[%q] DockerSandbox client runs in %q with %v, want the defaults", tt.dir, cmd.Dir, cmd.Env)
+		}
+	}
+}
+
+// recordingSandbox runs commands locally and records them
+type recordingSandbox struct {
+	roots, names []string
+}
+
+func (s *recordingSandbox) Command(ctx context.Context, c SandboxCommand) *exec.Cmd {
+	s.roots = append(s.roots, c.Root)
+	s.names = append(s.names, c.Name)
+	return LocalSandbox{}.Command(ctx, c)
+}
+
+// toolCheck runs a command with runTool
+type toolCheck []string
+
+func (toolCheck) Name() string        { return "tool" }
+func (toolCheck) Description() string { return "" }
+func (toolCheck) Weight() float64     { return 1 }
+func (c toolCheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	_, err := runTool(ctx, c[0], c[1:]...)
+	return 1, []FileSummary{}, err
+}
+
+func TestCheckerSandbox(t *testing.T) {
+	s := &recordingSandbox{}
+	results := Checker{Sandbox: s}.run(context.Background(), "testfiles", []Check{toolCheck{"go", "version"}})
+	if results[0].Error != "" {
+		t.Fatalf("tool check failed: %s", results[0].Error)
+	}
+	if len(s.names) != 1 || s.names[0] != "go" || s.roots[0] != "testfiles" {
+		t.Errorf("sandbox ran %v in %v, want go in %q", s.names, s.roots, "testfiles")
+	}
+}
+
+func TestSkipReasonOffline(t *testing.T) {
+	docker := Checker{Sandbox: DockerSandbox{Image: "golang"}}
+	for _, ck := range []Check{GoModTidy{}, GoVulnCheck{}} {
+		if docker.skipReason(ck) == "" {
+			t.Errorf("%s runs in a sandbox without network", ck.Name())
+		}
+		if reason := (Checker{}).skipReason(ck); reason != "" {
+			t.Errorf("%s is skipped on the host: %s", ck.Name(), reason)
+		}
+	}
+	// the server asks the module proxy, not the sandbox
+	if reason := docker.skipReason(Outdated{}); reason != "" {
+		t.Errorf("outdated_dependencies is skipped in a sandbox: %s", reason)
+	}
+}
@@ -0,0 +1,129 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// secretsMaxFileSize is the size above which files are not scanned for
+// secrets, as they are unlikely to be config or source files
+const secretsMaxFileSize = 1 << 20
+
+// secretRules are the patterns of credentials that should not be committed
+var secretRules = []struct {
+	id      string
+	desc    string
+	pattern *regexp.Regexp
+}{
+	{"aws-access-key-id", "AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-access-key", "AWS secret access key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`)},
+	{"private-key", "private key block", regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{"github-token", "GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack-token", "Slack token", regexp.MustCompile(`\bxox[abprs]-[0-9A-Za-z-]{10,}`)},
+	{"google-api-key", "Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"stripe-secret-key", "Stripe secret key", regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{24,}\b`)},
+}
+
+// Secrets is the check for credentials committed to the repo
+type Secrets struct{}
+
+// Name returns the name of the display name of the command
+func (g Secrets) Name() string {
+	return "secrets"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Secrets) Weight() float64 {
+	return .05
+}
+
+// Category returns the report section of the check
+func (g Secrets) Category() string {
+	return CategorySecurity
+}
+
+// scanSecrets returns the secrets found in data, one error per line. The
+// secrets themselves are not included in the errors.
+func scanSecrets(data []byte) []Error {
+	var errs []Error
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), secretsMaxFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		for _, r := range secretRules {
+			if r.pattern.Match(scanner.Bytes()) {
+				errs = append(errs, Error{
+					LineNumber:  line,
+					ErrorString: "possible " + r.desc + " committed to the repo",
+					RuleID:      r.id,
+					Severity:    SeverityError,
+				})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// Run returns 0 if any file in the repo contains a secret, and 1
+// otherwise. All files are scanned, not only .go files, except for
+// binary files and the skipped directories.
+func (g Secrets) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	var failed = []FileSummary{}
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			for _, skip := range append(skipDirs, ".git") {
+				if fi.Name() == skip {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || fi.Size() > secretsMaxFileSize {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		head := data
+		if len(head) > 8000 {
+			head = head[:8000]
+		}
+		if bytes.IndexByte(head, 0) != -1 {
+			// binary file
+			return nil
+		}
+
+		if errs := scanSecrets(data); len(errs) > 0 {
+			fs := newFileSummary(dir, path)
+			fs.Errors = errs
+			failed = append(failed, fs)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	if len(failed) > 0 {
+		return 0, failed, nil
+	}
+	return 1, failed, nil
+}
+
+// Description returns the description of Secrets
+func (g Secrets) Description() string {
+	return "Scans all files in the repo for committed credentials, such as AWS keys, API tokens and private keys. Anything found should be revoked, as it stays in the git history."
+}
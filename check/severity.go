@@ -0,0 +1,100 @@
+package check
+
+import "strings"
+
+// severities of issues, from the most to the least severe
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Severities lists the severities from the most to the least severe
+var Severities = []string{SeverityError, SeverityWarning, SeverityInfo}
+
+// SeverityWeights is how much a file with issues counts against the
+// percentage of a check, by the most severe issue in the file
+var SeverityWeights = map[string]float64{
+	SeverityError:   1,
+	SeverityWarning: 0.5,
+	SeverityInfo:    0.2,
+}
+
+// Severitier is implemented by checks whose issues are not errors by
+// default. Checks can also set the severity of each Error.
+type Severitier interface {
+	Severity() string
+}
+
+// severity returns the default severity of the issues of ck
+func severity(ck Check) string {
+	if s, ok := ck.(Severitier); ok {
+		return s.Severity()
+	}
+	return SeverityError
+}
+
+// normalizeSeverity maps the severity names used by tools, such as the
+// HIGH, MEDIUM and LOW of gosec, to one of ours. It returns an empty
+// string for unknown names.
+func normalizeSeverity(s string) string {
+	switch strings.ToLower(s) {
+	case "critical", "high", "error":
+		return SeverityError
+	case "medium", "moderate", "warning", "warn":
+		return SeverityWarning
+	case "low", "info", "note", "hint", "ignore":
+		return SeverityInfo
+	}
+	return ""
+}
+
+// severityRank orders severities, with the most severe first
+func severityRank(s string) int {
+	for i, sev := range Severities {
+		if s == sev {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// applySeverities returns a copy of summaries with the severity of every
+// issue set, using def for issues without a known one, and counts the
+// issues by severity. The penalty 1-p of the check is scaled by the
+// average weight of the most severe issue of each file with issues, so a
+// check that only finds minor issues loses less.
+func applySeverities(p float64, def string, summaries []FileSummary) (float64, []FileSummary, map[string]int) {
+	counts := make(map[string]int)
+	var weights float64
+	var files int
+	result := make([]FileSummary, len(summaries))
+	for i, fs := range summaries {
+		result[i] = fs
+		if len(fs.Errors) == 0 {
+			continue
+		}
+		// the summaries may be cached by the check, so are not changed
+		fs.Errors = append([]Error(nil), fs.Errors...)
+		result[i].Errors = fs.Errors
+
+		worst := SeverityInfo
+		for j := range fs.Errors {
+			sev := normalizeSeverity(fs.Errors[j].Severity)
+			if sev == "" {
+				sev = def
+			}
+			fs.Errors[j].Severity = sev
+			counts[sev]++
+			if severityRank(sev) < severityRank(worst) {
+				worst = sev
+			}
+		}
+		weights += SeverityWeights[worst]
+		files++
+	}
+	if files == 0 {
+		return p, result, nil
+	}
+	return 1 - (1-p)*weights/float64(files), result, counts
+}
@@ -0,0 +1,49 @@
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplySeverities(t *testing.T) {
+	summaries := []FileSummary{
+		{Filename: "a.go", Errors: []Error{{ErrorString: "x", Severity: "HIGH"}, {ErrorString: "y"}}},
+		{Filename: "b.go", Errors: []Error{{ErrorString: "z"}}},
+	}
+
+	// 2 of 4 files fail: a.go with an error, and b.go with a warning
+	p, got, counts := applySeverities(0.5, SeverityWarning, summaries)
+	if want := 1 - 0.5*(1+0.5)/2; p != want {
+		t.Errorf("applySeverities percentage = %v, want %v", p, want)
+	}
+	if want := map[string]int{SeverityError: 1, SeverityWarning: 2}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("applySeverities counts = %v, want %v", counts, want)
+	}
+	if got[0].Errors[0].Severity != SeverityError || got[0].Errors[1].Severity != SeverityWarning {
+		t.Errorf("applySeverities errors = %+v, want error and warning", got[0].Errors)
+	}
+	if summaries[0].Errors[1].Severity != "" {
+		t.Errorf("applySeverities changed the summaries it was passed")
+	}
+
+	if p, _, counts := applySeverities(0.25, SeverityInfo, []FileSummary{}); p != 0.25 || counts != nil {
+		t.Errorf("applySeverities without issues = %v, %v, want 0.25 and no counts", p, counts)
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	for in, want := range map[string]string{
+		"HIGH":    SeverityError,
+		"error":   SeverityError,
+		"MEDIUM":  SeverityWarning,
+		"warning": SeverityWarning,
+		"LOW":     SeverityInfo,
+		"note":    SeverityInfo,
+		"":        "",
+		"unknown": "",
+	} {
+		if got := normalizeSeverity(in); got != want {
+			t.Errorf("[%q] normalizeSeverity = %q, want %q", in, got, want)
+		}
+	}
+}
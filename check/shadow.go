@@ -0,0 +1,48 @@
+package check
+
+import "context"
+
+// Shadow is the check for shadowed variables
+type Shadow struct{}
+
+// Name returns the name of the display name of the command
+func (g Shadow) Name() string {
+	return "shadow"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Shadow) Weight() float64 {
+	return .05
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Shadow) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Shadow) Severity() string {
+	return SeverityWarning
+}
+
+// Run returns the percentage of .go files that pass the shadow
+// analyzer
+func (g Shadow) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	failed, err := runAnalyzer(ctx, dir, "shadow")
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	return toolPercentage(filenames, failed)
+}
+
+// Description returns the description of Shadow
+func (g Shadow) Description() string {
+	return `The <a href="https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/shadow">shadow</a> vet analyzer finds variables that shadow a variable of the same name in an outer scope, such as an <code>err</code> declared with <code>:=</code> inside an <code>if</code>. It is not part of the standard <code>go vet</code> run.`
+}
@@ -0,0 +1,97 @@
+package check
+
+import (
+	"bufio"
+	"os"
+	"sync"
+)
+
+// SnippetContext is the number of lines before and after the line of an
+// error that its snippet has
+const SnippetContext = 2
+
+// maxSnippetLine is the number of bytes of a line that are kept in a
+// snippet, so that minified or generated code does not bloat the report
+const maxSnippetLine = 240
+
+// Snippet is the source around the line of an Error
+type Snippet struct {
+	// StartLine is the line number of the first line
+	StartLine int      `json:"start_line"`
+	Lines     []string `json:"lines"`
+}
+
+// sourceIndex reads the lines of the Go files of a repo, for attaching
+// snippets to errors. Files are only read when a check reports an issue
+// in them.
+type sourceIndex struct {
+	mu sync.Mutex
+	// paths maps the filenames used in file summaries to paths
+	paths map[string]string
+	lines map[string][]string
+}
+
+func newSourceIndex(paths map[string]string) *sourceIndex {
+	return &sourceIndex{paths: paths, lines: make(map[string][]string)}
+}
+
+// fileLines returns the lines of the file at path, or nil if it cannot
+// be read
+func (idx *sourceIndex) fileLines(path string) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if lines, ok := idx.lines[path]; ok {
+		return lines
+	}
+
+	var lines []string
+	if f, err := os.Open(path); err == nil {
+		s := bufio.NewScanner(f)
+		s.Buffer(nil, 1<<20)
+		for s.Scan() {
+			line := s.Text()
+			if len(line) > maxSnippetLine {
+				line = line[:maxSnippetLine]
+			}
+			lines = append(lines, line)
+		}
+		f.Close()
+		if s.Err() != nil {
+			lines = nil
+		}
+	}
+	idx.lines[path] = lines
+	return lines
+}
+
+// attach sets the snippet of the errors in summaries that are on a line
+// of their file. The errors are copied, as the summaries may be cached by
+// the check.
+func (idx *sourceIndex) attach(summaries []FileSummary) {
+	if idx == nil {
+		return
+	}
+	for i, fs := range summaries {
+		path, ok := idx.paths[fs.Filename]
+		if !ok {
+			continue
+		}
+		lines := idx.fileLines(path)
+		errs := append([]Error(nil), fs.Errors...)
+		for j, e := range errs {
+			if e.LineNumber < 1 || e.LineNumber > len(lines) {
+				continue
+			}
+			start := e.LineNumber - SnippetContext
+			if start < 1 {
+				start = 1
+			}
+			end := e.LineNumber + SnippetContext
+			if end > len(lines) {
+				end = len(lines)
+			}
+			errs[j].Snippet = &Snippet{StartLine: start, Lines: lines[start-1 : end]}
+		}
+		summaries[i].Errors = errs
+	}
+}
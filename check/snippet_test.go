@@ -0,0 +1,40 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSourceIndexAttach(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"a.go": "package a\n\nfunc f() {\n\tx := 1\n\t_ = x\n}\n",
+	})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.go")
+	name := newFileSummary(dir, path).Filename
+
+	cases := []struct {
+		line int
+		want *Snippet
+	}{
+		{1, &Snippet{StartLine: 1, Lines: []string{"package a", "", "func f() {"}}},
+		{4, &Snippet{StartLine: 2, Lines: []string{"", "func f() {", "\tx := 1", "\t_ = x", "}"}}},
+		{6, &Snippet{StartLine: 4, Lines: []string{"\tx := 1", "\t_ = x", "}"}}},
+		{0, nil},
+		{20, nil},
+	}
+	idx := newSourceIndex(map[string]string{name: path})
+	for _, tt := range cases {
+		errs := []Error{{LineNumber: tt.line, ErrorString: "issue"}}
+		summaries := []FileSummary{{Filename: name, Errors: errs}}
+		idx.attach(summaries)
+		if got := summaries[0].Errors[0].Snippet; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d] snippet = %+v, want %+v", tt.line, got, tt.want)
+		}
+		if errs[0].Snippet != nil {
+			t.Errorf("[%d] attach changed the errors of the check", tt.line)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package check
+
+import "context"
+
+// Staticcheck is the check for the staticcheck command
+type Staticcheck struct{}
+
+// Name returns the name of the display name of the command
+func (g Staticcheck) Name() string {
+	return "staticcheck"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Staticcheck) Weight() float64 {
+	return .10
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Staticcheck) FileScored() bool {
+	return true
+}
+
+// Run returns the percentage of .go files that pass staticcheck
+func (g Staticcheck) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoTool(ctx, dir, Filenames(ctx), []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=staticcheck"})
+}
+
+// Description returns the description of Staticcheck
+func (g Staticcheck) Description() string {
+	return `<a href="https://staticcheck.io">Staticcheck</a> finds bugs and performance issues, offers simplifications, and enforces style rules.`
+}
@@ -0,0 +1,73 @@
+package check
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// StaticErrorf flags fmt.Errorf calls whose format string has no
+// formatting verbs, and so could use errors.New instead. An escaped %%
+// is not a verb. The percentage is the fraction of errors.New and
+// fmt.Errorf calls that use the appropriate function.
+func StaticErrorf(dir string, filenames []string) (float64, []FileSummary, error) {
+	var (
+		failed      = []FileSummary{}
+		fset        = token.NewFileSet()
+		total, good int
+	)
+	for _, f := range filenames {
+		file, err := parser.ParseFile(fset, f, nil, parser.ParseComments)
+		if err != nil {
+			return 0, []FileSummary{}, err
+		}
+		nolint := nolintLines(fset, file, "staticerrorf")
+
+		fs := newFileSummary(dir, f)
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if isPkgCall(call, "errors", "New") {
+				total++
+				good++
+				return true
+			}
+			if !isPkgCall(call, "fmt", "Errorf") || len(call.Args) != 1 {
+				// calls with arguments but no verbs are left to go vet
+				return true
+			}
+			format, ok := stringLit(call.Args[0])
+			if !ok {
+				return true
+			}
+			verbs, ok := formatVerbs(format)
+			if !ok {
+				return true
+			}
+
+			total++
+			line := fset.Position(call.Pos()).Line
+			if len(verbs) > 0 || nolint[line] {
+				good++
+				return true
+			}
+			fs.Errors = append(fs.Errors, Error{
+				LineNumber:  line,
+				ErrorString: "fmt.Errorf has no formatting directives, use errors.New instead",
+			})
+			return true
+		})
+
+		if len(fs.Errors) > 0 {
+			failed = append(failed, fs)
+		}
+	}
+
+	if total == 0 {
+		return 1, failed, nil
+	}
+
+	return float64(good) / float64(total), failed, nil
+}
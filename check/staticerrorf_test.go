@@ -0,0 +1,42 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var staticErrorfTests = []struct {
+	call    string
+	percent float64
+	flagged bool
+}{
+	{`fmt.Errorf("not found")`, 0, true},
+	{`fmt.Errorf("100%% done")`, 0, true},
+	{`fmt.Errorf("id %d", id)`, 1, false},
+	{`fmt.Errorf("id %5.2f", float64(id))`, 1, false},
+	{`errors.New("not found")`, 1, false},
+	{`fmt.Errorf("not found") //nolint:staticerrorf`, 1, false},
+}
+
+func TestStaticErrorf(t *testing.T) {
+	for _, tt := range staticErrorfTests {
+		src := "package m\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n)\n\nvar _ = errors.New\n\nfunc f(id int) error {\n\treturn " + tt.call + "\n}\n"
+		dir := writeModule(t, "", map[string]string{"a.go": src})
+		p, fs, err := StaticErrorf(dir, []string{filepath.Join(dir, "a.go")})
+		os.RemoveAll(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the errors.New reference in the var declaration is not a call,
+		// so only the returned expression is counted
+		if p != tt.percent {
+			t.Errorf("StaticErrorf(%s) percent = %f, want %f", tt.call, p, tt.percent)
+		}
+		if flagged := len(fs) > 0; flagged != tt.flagged {
+			t.Errorf("StaticErrorf(%s) flagged = %t, want %t", tt.call, flagged, tt.flagged)
+		} else if flagged && fs[0].Errors[0].LineNumber != 11 {
+			t.Errorf("StaticErrorf(%s) line = %d, want 11", tt.call, fs[0].Errors[0].LineNumber)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Tool is a command that checks run, which must be installed where they
+// run it, on the host or in the image of the sandbox
+type Tool struct {
+	Name string
+	// VersionArgs are the arguments with which the tool prints its
+	// version, or nil for tools that cannot
+	VersionArgs []string
+}
+
+// Tools are the commands that the checks registered by this package run
+var Tools = []Tool{
+	{Name: "go", VersionArgs: []string{"version"}},
+	{Name: "git", VersionArgs: []string{"--version"}},
+	{Name: "gometalinter", VersionArgs: []string{"--version"}},
+	{Name: "revive", VersionArgs: []string{"-version"}},
+	{Name: "gocognit"},
+	{Name: "gosec", VersionArgs: []string{"-version"}},
+	{Name: "dupl"},
+	{Name: "govulncheck", VersionArgs: []string{"-version"}},
+	{Name: "exhaustive"},
+	{Name: "fieldalignment"},
+	{Name: "shadow"},
+}
+
+// ToolVersion returns the first line that t prints as its version, or an
+// empty string if it cannot print one. It fails if t is not installed.
+// Tools are looked up where DefaultSandbox runs them; on the host, a
+// tool that is installed but fails to print its version is not an error.
+func ToolVersion(ctx context.Context, t Tool) (string, error) {
+	_, local := DefaultSandbox.(LocalSandbox)
+	if local {
+		if _, err := exec.LookPath(t.Name); err != nil {
+			return "", err
+		}
+		if t.VersionArgs == nil {
+			return "", nil
+		}
+	}
+
+	c := SandboxCommand{Name: t.Name, Args: t.VersionArgs}
+	if t.VersionArgs == nil {
+		c = SandboxCommand{Name: "sh", Args: []string{"-c", `command -v "$0"`, t.Name}}
+	}
+	out, err := DefaultSandbox.Command(ctx, c).CombinedOutput()
+	if err != nil {
+		if local {
+			return "", nil
+		}
+		return "", fmt.Errorf("%s: %v: %s", t.Name, err, strings.TrimSpace(string(out)))
+	}
+	if t.VersionArgs == nil {
+		return "", nil
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return version, nil
+}
@@ -0,0 +1,37 @@
+package check
+
+import "context"
+
+// Unconvert is the check for the unconvert command
+type Unconvert struct{}
+
+// Name returns the name of the display name of the command
+func (g Unconvert) Name() string {
+	return "unconvert"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Unconvert) Weight() float64 {
+	return 0.05
+}
+
+// FileScored returns true, as the percentage is graded from the files
+// with issues
+func (g Unconvert) FileScored() bool {
+	return true
+}
+
+// Severity returns the severity of the issues the check reports
+func (g Unconvert) Severity() string {
+	return SeverityWarning
+}
+
+// Run returns the percentage of .go files that pass unconvert
+func (g Unconvert) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	return GoTool(ctx, dir, Filenames(ctx), []string{"gometalinter", "--deadline=180s", "--disable-all", "--enable=unconvert"})
+}
+
+// Description returns the description of Unconvert
+func (g Unconvert) Description() string {
+	return `<a href="https://github.com/mdempsky/unconvert">Unconvert</a> finds unnecessary type conversions, such as converting a value to the type it already has.`
+}
@@ -0,0 +1,114 @@
+package check
+
+import (
+	"context"
+	"go/ast"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Unused is the check for unused functions, types, constants, variables
+// and fields
+type Unused struct {
+	// ExcludeExported leaves out exported identifiers, which libraries
+	// do not use themselves
+	ExcludeExported bool
+}
+
+// Name returns the name of the display name of the command
+func (g Unused) Name() string {
+	return "unused"
+}
+
+// Weight returns the weight this check has in the overall average
+func (g Unused) Weight() float64 {
+	return .05
+}
+
+// unusedIdent returns the name of the identifier in an unused message
+// like "func (*T).foo is unused (U1000)"
+func unusedIdent(msg string) string {
+	fields := strings.Fields(msg)
+	if len(fields) < 2 {
+		return ""
+	}
+	name := fields[1]
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// byPackage regroups the per file summaries into one summary per package.
+// Every error links to its file and line as a related location.
+func byPackage(files []FileSummary, excludeExported bool) []FileSummary {
+	pkgs := make(map[string]*FileSummary)
+	for _, fs := range files {
+		pkg := path.Dir(fs.Filename)
+		if pkgs[pkg] == nil {
+			url := fs.FileURL
+			if i := strings.LastIndex(url, "/"); i != -1 {
+				url = url[:i]
+			}
+			pkgs[pkg] = &FileSummary{Filename: pkg, FileURL: url}
+		}
+		p := pkgs[pkg]
+		for _, e := range fs.Errors {
+			if excludeExported && ast.IsExported(unusedIdent(e.ErrorString)) {
+				continue
+			}
+			p.Errors = append(p.Errors, Error{
+				ErrorString: e.ErrorString,
+				Related: []Location{{
+					Filename:  fs.Filename,
+					FileURL:   fs.FileURL,
+					StartLine: e.LineNumber,
+					EndLine:   e.LineNumber,
+				}},
+			})
+		}
+	}
+
+	var failed = []FileSummary{}
+	for _, p := range pkgs {
+		if len(p.Errors) > 0 {
+			failed = append(failed, *p)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Filename < failed[j].Filename })
+	return failed
+}
+
+// Run returns the percentage of .go files without unused code.
+// The unused code is reported per package.
+func (g Unused) Run(ctx context.Context, dir string) (float64, []FileSummary, error) {
+	filenames := Filenames(ctx)
+	if len(filenames) == 0 {
+		return 1, []FileSummary{}, nil
+	}
+
+	// staticcheck exits 1 if it finds issues
+	out, err := runInDir(ctx, dir, 1, "staticcheck", "-checks", "U1000")
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	files, err := parseAnalyzer(out, dir, "U1000")
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+
+	var withIssues int
+	for _, fs := range files {
+		for _, e := range fs.Errors {
+			if !g.ExcludeExported || !ast.IsExported(unusedIdent(e.ErrorString)) {
+				withIssues++
+				break
+			}
+		}
+	}
+
+	return float64(len(filenames)-withIssues) / float64(len(filenames)), byPackage(files, g.ExcludeExported), nil
+}
+
+// Description returns the description of Unused
+func (g Unused) Description() string {
+	return `Runs the <a href="https://staticcheck.io/docs/checks#U1000">unused</a> check of staticcheck, which finds unused functions, types, constants, variables and fields. The unused code is listed per package.`
+}
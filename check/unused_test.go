@@ -0,0 +1,103 @@
+package check
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestByPackage(t *testing.T) {
+	files := []FileSummary{
+		{
+			Filename: "bar/a.go",
+			FileURL:  "https://github.com/foo/bar/blob/master/a.go",
+			Errors: []Error{
+				{LineNumber: 3, ErrorString: "func foo is unused (U1000)"},
+				{LineNumber: 7, ErrorString: "func (*T).Bar is unused (U1000)"},
+			},
+		},
+		{
+			Filename: "bar/b.go",
+			FileURL:  "https://github.com/foo/bar/blob/master/b.go",
+			Errors:   []Error{{LineNumber: 1, ErrorString: "const c is unused (U1000)"}},
+		},
+		{
+			Filename: "bar/sub/c.go",
+			FileURL:  "https://github.com/foo/bar/blob/master/sub/c.go",
+			Errors:   []Error{{LineNumber: 5, ErrorString: "type Exported is unused (U1000)"}},
+		},
+	}
+
+	pkgs := byPackage(files, false)
+	if len(pkgs) != 2 {
+		t.Fatalf("byPackage returned %d packages, want 2: %v", len(pkgs), pkgs)
+	}
+	bar := pkgs[0]
+	if bar.Filename != "bar" || bar.FileURL != "https://github.com/foo/bar/blob/master" || len(bar.Errors) != 3 {
+		t.Errorf("byPackage package = %+v, want bar with 3 errors", bar)
+	}
+	if r := bar.Errors[2].Related[0]; r.Filename != "bar/b.go" || r.StartLine != 1 {
+		t.Errorf("byPackage related = %+v, want bar/b.go:1", r)
+	}
+
+	pkgs = byPackage(files, true)
+	if len(pkgs) != 1 || len(pkgs[0].Errors) != 2 {
+		t.Errorf("byPackage excluding exported = %v, want 2 errors in bar", pkgs)
+	}
+}
+
+func TestRunAllUnusedExcludeExported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake staticcheck is a shell script")
+	}
+	// a fake staticcheck reports an unexported and an exported function
+	bin := t.TempDir()
+	script := `#!/bin/sh
+echo "$PWD/a.go:3:6: func foo is unused (U1000)"
+echo "$PWD/a.go:5:6: func Bar is unused (U1000)"
+exit 1
+`
+	if err := ioutil.WriteFile(filepath.Join(bin, "staticcheck"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	src := "package m\n\nfunc foo() {}\n\nfunc Bar() {}\n"
+	cases := []struct {
+		config string
+		want   []string
+	}{
+		{"", []string{"func Bar is unused (U1000)", "func foo is unused (U1000)"}},
+		{"unused:\n  exclude_exported: true\n", []string{"func foo is unused (U1000)"}},
+	}
+	for _, tt := range cases {
+		files := map[string]string{"a.go": src}
+		if tt.config != "" {
+			files[ConfigFile] = tt.config
+		}
+		dir := writeModule(t, "1.21", files)
+		results := RunAll(context.Background(), dir, []string{filepath.Join(dir, "a.go")})
+
+		var got []string
+		for _, r := range results {
+			if r.Name != "unused" {
+				continue
+			}
+			if r.Error != "" {
+				t.Fatalf("[%q] unused error = %s", tt.config, r.Error)
+			}
+			for _, fs := range r.FileSummaries {
+				for _, e := range fs.Errors {
+					got = append(got, e.ErrorString)
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%q] unused reported %q, want %q", tt.config, got, tt.want)
+		}
+	}
+}
@@ -3,16 +3,23 @@ package check
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"go/format"
+	"io"
 	"io/ioutil"
-	"log"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
+
+	"github.com/gojp/goreportcard/download"
+	"golang.org/x/tools/imports"
 )
 
 var (
@@ -30,45 +37,74 @@ func addSkipDirs(params []string) []string {
 
 // GoFiles returns a slice of Go filenames
 // in a given directory.
-func GoFiles(dir string) (filenames, skipped []string, err error) {
+func GoFiles(ctx context.Context, dir string) (filenames, skipped []string, err error) {
+	return Checker{}.GoFiles(ctx, dir)
+}
+
+// GoFiles returns a slice of Go filenames in a given directory, the
+// files of the packages returned by LoadPackages. Files matching the skip
+// globs in the repo's ConfigFile are returned as skipped, like generated
+// files. Loading stops with the error of ctx if ctx is done.
+func (c Checker) GoFiles(ctx context.Context, dir string) (filenames, skipped []string, err error) {
+	pkgs, skipped, err := c.LoadPackages(ctx, dir)
+	return PackageFiles(pkgs), skipped, err
+}
+
+// inSkipDir reports whether the file at fp is in a directory that is not
+// checked, such as vendor
+func inSkipDir(fp string) bool {
+	for _, skip := range skipDirs {
+		if strings.Contains(filepath.ToSlash(fp), fmt.Sprintf("/%s/", skip)) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipFile reports whether the Go file at fp is generated or matches a
+// skip glob of cfg
+func skipFile(dir, fp string, cfg RepoConfig, logger Logger) bool {
+	for _, skip := range skipSuffixes {
+		if strings.HasSuffix(fp, skip) {
+			return true
+		}
+	}
+	gen, err := autoGenerated(fp)
+	if err != nil {
+		logger.Log("could not check for generated file", "path", fp, "error", err)
+	}
+	return gen || cfg.skips(dir, fp)
+}
+
+// walkGoFiles returns the Go files in dir that are not in a skipped
+// directory. Paths that cannot be walked are logged and skipped. The walk
+// stops with the error of ctx if ctx is done.
+func (c Checker) walkGoFiles(ctx context.Context, dir string) (filenames []string, err error) {
+	logger := c.logger()
 	visit := func(fp string, fi os.FileInfo, err error) error {
-		for _, skip := range skipDirs {
-			if strings.Contains(fp, fmt.Sprintf("/%s/", skip)) {
-				return nil
-			}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if inSkipDir(fp) {
+			return nil
 		}
 		if err != nil {
-			fmt.Println(err) // can't walk here,
-			return nil       // but continue walking elsewhere
+			logger.Log("could not walk path", "path", fp, "error", err) // can't walk here,
+			return nil                                                  // but continue walking elsewhere
 		}
 		if fi.IsDir() {
 			return nil // not a file.  ignore.
 		}
-		fiName := fi.Name()
-		for _, skip := range skipSuffixes {
-			if strings.HasSuffix(fiName, skip) {
-				skipped = append(skipped, fp)
-				return nil
-			}
-		}
-		ext := filepath.Ext(fiName)
-		if ext != ".go" {
+		if filepath.Ext(fi.Name()) != ".go" {
 			return nil
 		}
-
-		if autoGenerated(fp) {
-			skipped = append(skipped, fp)
-			return nil
-		}
-
 		filenames = append(filenames, fp)
-
 		return nil
 	}
 
 	err = filepath.Walk(dir, visit)
 
-	return filenames, skipped, err
+	return filenames, err
 }
 
 // RenameFiles renames the provided filenames to have a ".grc.bk" extension,
@@ -98,35 +134,62 @@ func RevertFiles(names []string) (err error) {
 	return err
 }
 
-// lineCount returns the number of lines in a given file
+// lineCount returns the number of lines in a given file, counting
+// newlines like wc -l
 func lineCount(filepath string) (int, error) {
-	out, err := exec.Command("wc", "-l", filepath).Output()
-	if err != nil {
-		return 0, err
-	}
-	// wc output is like: 999 filename.go
-	count, err := strconv.Atoi(strings.Split(strings.TrimSpace(string(out)), " ")[0])
+	f, err := os.Open(filepath)
 	if err != nil {
 		return 0, err
 	}
+	defer f.Close()
 
-	return count, nil
+	var count int
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		count += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return 0, err
+		}
+	}
 }
 
+// generatedComment is the comment that marks generated Go files, see
+// https://golang.org/s/generatedcode
+var generatedComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
 // determine whether the Go file was auto-generated
-func autoGenerated(fp string) bool {
+func autoGenerated(fp string) (bool, error) {
 	file, err := os.Open(fp)
 	if err != nil {
-		fmt.Println(err)
-		return false
+		return false, err
 	}
 	defer file.Close()
 
-	// read first line of file and determine if it might
-	// be auto-generated
 	scanner := bufio.NewScanner(file)
-	scanner.Scan()
-	line := strings.ToLower(scanner.Text())
+	for first := true; scanner.Scan(); first = false {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if generatedComment.MatchString(text) {
+			return true, nil
+		}
+		if strings.HasPrefix(text, "package ") {
+			// the comment must come before the package clause
+			break
+		}
+		if first && generatedFirstLine(text) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// generatedFirstLine reports whether the first line of a Go file is a
+// comment that looks like it was written by a generator that does not
+// follow the convention
+func generatedFirstLine(line string) bool {
+	line = strings.ToLower(line)
 	commentStyles := []string{"// ", "//", "/* ", "/*"}
 	for _, skip := range skipFirstLines {
 		for i := range commentStyles {
@@ -141,8 +204,29 @@ func autoGenerated(fp string) bool {
 // Error contains the line number and the reason for
 // an error output from a command
 type Error struct {
-	LineNumber  int    `json:"line_number"`
+	LineNumber int `json:"line_number"`
+	// Column is the column of the error on its line, starting at 1, or
+	// zero if the tool did not report it
+	Column      int    `json:"column,omitempty"`
 	ErrorString string `json:"error_string"`
+	// RuleID identifies what reported the error, such as the linter or
+	// the rule of a linter
+	RuleID   string `json:"rule_id,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	// Related contains other locations involved in the error,
+	// for example the other copies of duplicated code
+	Related []Location `json:"related,omitempty"`
+	// Snippet is the source around the error, if the Checker attaches
+	// snippets
+	Snippet *Snippet `json:"snippet,omitempty"`
+}
+
+// Location is a range of lines in a file
+type Location struct {
+	Filename  string `json:"filename"`
+	FileURL   string `json:"file_url"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
 }
 
 // FileSummary contains the filename, location of the file
@@ -153,9 +237,51 @@ type FileSummary struct {
 	Errors   []Error `json:"errors"`
 }
 
-// AddError adds an Error to FileSummary
+// newFileSummary returns an empty FileSummary for the file at path f,
+// which is expected to be inside dir
+func newFileSummary(dir, f string) FileSummary {
+	filename := repoPath(f)
+	return FileSummary{
+		Filename: makeFilename(filename),
+		FileURL:  fileURL(dir, filename),
+	}
+}
+
+// ReposDir is the directory that repos are downloaded into, at their
+// import paths, with forward slashes. Modules are checked from their root
+// in module-aware mode, and ReposDir is laid out like the src directory
+// of a GOPATH for the repos that are not modules.
+var ReposDir = "repos/src"
+
+// RepoDir returns the directory of the repo with the import path
+func RepoDir(importPath string) string {
+	return ReposDir + "/" + importPath
+}
+
+// repoPath returns a path with forward slashes and without the ReposDir
+// prefix, which is how files are named in file summaries
+func repoPath(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(path), ReposDir)
+}
+
+// splitPosition splits a position like file.go:10:2 at the colons,
+// leaving a Windows volume name like C: in the file name
+func splitPosition(s string, n int) []string {
+	vol := filepath.VolumeName(s)
+	parts := strings.SplitN(s[len(vol):], ":", n)
+	parts[0] = vol + parts[0]
+	return parts
+}
+
+// linterSuffix matches the severity and the linter name that gometalinter
+// adds to messages, as in "warning: message (golint)"
+var linterSuffix = regexp.MustCompile(`^(?:warning|error): .* \(([\w-]+)\)$`)
+
+// AddError adds an Error to FileSummary from a line of tool output like
+// file.go:10:2: message, where the column may be empty. The linter that
+// gometalinter names after the message is the rule of the error.
 func (fs *FileSummary) AddError(out string) error {
-	s := strings.SplitN(out, ":", 2)
+	s := splitPosition(out, 2)
 	msg := strings.SplitAfterN(s[1], ":", 3)[2]
 
 	e := Error{ErrorString: msg}
@@ -165,6 +291,12 @@ func (fs *FileSummary) AddError(out string) error {
 		return err
 	}
 	e.LineNumber = ln
+	if col, err := strconv.Atoi(ls[1]); err == nil {
+		e.Column = col
+	}
+	if m := linterSuffix.FindStringSubmatch(msg); m != nil {
+		e.RuleID = m[1]
+	}
 
 	fs.Errors = append(fs.Errors, e)
 
@@ -214,28 +346,134 @@ func goPkgInToGitHub(name string) string {
 	return "https://github.com/" + user + "/" + pkg + "/blob/" + version + dir
 }
 
+// FileURLTemplate is the URL of the files of repos on hosts that are
+// not known, such as the hosts of repos with vanity import paths. {repo}
+// is replaced with the HTTPS URL of the repo, {branch} with the branch,
+// tag or commit that was graded and {path} with the path of the file in the repo, as in
+// {repo}/src/branch/{branch}/{path} for Gitea. If empty, the files of
+// such repos are not linked.
+var FileURLTemplate = ""
+
+// repoInfo is where the files of a cloned repo are
+type repoInfo struct {
+	// web is the HTTPS URL of the origin of the repo
+	web string
+	// branch is the branch, or the tag or commit, that files link to
+	branch string
+	// subdir is the directory of the repo that is graded, with forward
+	// slashes, or "" for its root
+	subdir string
+}
+
+// repoInfos caches the repoInfo of the repos in dirs, as fileURL is called
+// for every file with issues
+var repoInfos sync.Map
+
+// cloneInfo returns the repoInfo of the repo in dir
+func cloneInfo(dir string) repoInfo {
+	if info, ok := repoInfos.Load(dir); ok {
+		return info.(repoInfo)
+	}
+	info := repoInfo{web: download.WebURL(dir), branch: download.DefaultBranch(dir), subdir: gitSubdir(dir)}
+	repoInfos.Store(dir, info)
+	return info
+}
+
+// SetRef makes the files of the repo cloned in dir link to ref, which is
+// checked out in it, or to its default branch if ref is empty. dir may be
+// a directory of the repo.
+func SetRef(dir, ref string) {
+	if ref == "" {
+		ref = download.DefaultBranch(dir)
+	}
+	repoInfos.Store(dir, repoInfo{web: download.WebURL(dir), branch: ref, subdir: gitSubdir(dir)})
+}
+
+// gitSubdir returns the directory of the git repo that dir is, or "" if
+// dir is its root or is not in a git repo of its own
+func gitSubdir(dir string) string {
+	out, err := gitStdout(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return ""
+	}
+	prefix := strings.Trim(strings.TrimSpace(out), "/")
+	if prefix == "" || !strings.HasSuffix(filepath.ToSlash(dir), "/"+prefix) {
+		// dir is not a repo, but in the working tree of another
+		return ""
+	}
+	return prefix
+}
+
+// hostFileURL returns the URL of the file at path, which starts with a
+// slash, on the default branch of the repo at the HTTPS URL web
+func hostFileURL(web, branch, path string) string {
+	host := strings.SplitN(strings.TrimPrefix(web, "https://"), "/", 2)[0]
+	switch {
+	case host == "github.com":
+		return web + "/blob/" + branch + path
+	case host == "bitbucket.org":
+		return web + "/src/" + branch + path
+	case download.IsGitLab(host):
+		return web + "/-/blob/" + branch + path
+	case FileURLTemplate != "":
+		return strings.NewReplacer("{repo}", web, "{branch}", branch, "{path}", strings.TrimPrefix(path, "/")).Replace(FileURLTemplate)
+	}
+	return ""
+}
+
 func fileURL(dir, filename string) string {
-	var fileURL string
-	base := strings.TrimPrefix(dir, "repos/src/")
+	base := strings.TrimPrefix(filepath.ToSlash(dir), ReposDir+"/")
+	if subdir := cloneInfo(dir).subdir; subdir != "" {
+		// the import path of the repo, as the path of the file is
+		// relative to its root
+		base = strings.TrimSuffix(base, "/"+subdir)
+	}
 	switch {
 	case strings.HasPrefix(base, "golang.org/x/"):
 		var pkg string
 		if len(strings.Split(base, "/")) >= 3 {
 			pkg = strings.Split(base, "/")[2]
 		}
-		return fmt.Sprintf("https://github.com/golang/%s/blob/master%s", pkg, strings.TrimPrefix(filename, "/"+base))
+		return fmt.Sprintf("https://github.com/golang/%s/blob/%s%s", pkg, cloneInfo(dir).branch, strings.TrimPrefix(filename, "/"+base))
 	case strings.HasPrefix(base, "github.com/"):
 		if len(strings.Split(base, "/")) == 4 {
 			base = strings.Join(strings.Split(base, "/")[0:3], "/")
 		}
-		return fmt.Sprintf("https://%s/blob/master%s", base, strings.TrimPrefix(filename, "/"+base))
+		return hostFileURL("https://"+base, cloneInfo(dir).branch, strings.TrimPrefix(filename, "/"+base))
 	case strings.HasPrefix(base, "gopkg.in/"):
-		fmt.Println(goPkgInToGitHub(base))
-		fmt.Println(strings.TrimPrefix(filename, "/"+base))
 		return goPkgInToGitHub(base) + strings.TrimPrefix(filename, "/"+base)
+	case strings.HasPrefix(base, "bitbucket.org/"):
+		if len(strings.Split(base, "/")) == 4 {
+			base = strings.Join(strings.Split(base, "/")[0:3], "/")
+		}
+		return hostFileURL("https://"+base, cloneInfo(dir).branch, strings.TrimPrefix(filename, "/"+base))
+	case download.IsGitLab(base):
+		return hostFileURL("https://"+base, cloneInfo(dir).branch, strings.TrimPrefix(filename, "/"+base))
+	}
+
+	// a vanity import path, or a host that is not known: link to where
+	// the repo was cloned from
+	info := cloneInfo(dir)
+	if info.web == "" {
+		return ""
 	}
+	return hostFileURL(info.web, info.branch, strings.TrimPrefix(filename, "/"+base))
+}
 
-	return fileURL
+// LineURL returns the URL of the lines from start to end of the file at
+// fileURL, or of the line start if end is not after it. Bitbucket links to
+// lines differently from the other hosts.
+func LineURL(fileURL string, start, end int) string {
+	bitbucket := strings.HasPrefix(fileURL, "https://bitbucket.org/")
+	switch {
+	case bitbucket && end > start:
+		return fmt.Sprintf("%s#lines-%d:%d", fileURL, start, end)
+	case bitbucket:
+		return fmt.Sprintf("%s#lines-%d", fileURL, start)
+	case end > start:
+		return fmt.Sprintf("%s#L%d-L%d", fileURL, start, end)
+	}
+	return fmt.Sprintf("%s#L%d", fileURL, start)
 }
 
 func makeFilename(fn string) string {
@@ -253,25 +491,51 @@ func makeFilename(fn string) string {
 		if len(sp) > 3 {
 			return strings.Join(sp[3:], "/")
 		}
+	case strings.HasPrefix(fn, "/bitbucket.org"):
+		if len(sp) > 3 {
+			return strings.Join(sp[3:], "/")
+		}
 	}
 
 	return fn
 }
 
-func getFileSummaryMap(out *bufio.Scanner, dir string) (map[string]FileSummary, error) {
+// skipReported reports whether results for filename, relative to
+// ReposDir, should be ignored because the file is generated
+func skipReported(filename string) bool {
+	for _, skip := range skipSuffixes {
+		if strings.HasSuffix(filename, skip) {
+			return true
+		}
+	}
+
+	gen, _ := autoGenerated(ReposDir + filename)
+	return gen
+}
+
+// reportedFilename returns the path of a file reported by a tool
+// relative to ReposDir, for tools that report absolute paths
+func reportedFilename(path string) string {
+	path = filepath.ToSlash(path)
+	if i := strings.Index(path, ReposDir+"/"); i != -1 {
+		return path[i+len(ReposDir):]
+	}
+	return path
+}
+
+// getFileSummaryMap reads the issues that a tool run on the repo in dir
+// reported in out, by file. Relative paths are relative to cwd, the
+// directory the tool ran in.
+func getFileSummaryMap(out *bufio.Scanner, dir, cwd string) (map[string]FileSummary, error) {
 	fsMap := make(map[string]FileSummary)
-outer:
 	for out.Scan() {
-		filename := strings.Split(out.Text(), ":")[0]
-		filename = strings.TrimPrefix(filename, "repos/src")
-		for _, skip := range skipSuffixes {
-			if strings.HasSuffix(filename, skip) {
-				continue outer
-			}
+		p := splitPosition(out.Text(), 2)[0]
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(cwd, p)
 		}
-
-		if autoGenerated("repos/src" + filename) {
-			continue outer
+		filename := reportedFilename(repoPath(p))
+		if skipReported(filename) {
+			continue
 		}
 
 		fu := fileURL(dir, filename)
@@ -289,44 +553,121 @@ outer:
 	return fsMap, nil
 }
 
+// commandContext returns a command that runs name with args in dir, with
+// the variables in env added to the environment, in the sandbox and
+// within the limits carried by ctx. The command is killed, together with
+// the processes it started, when ctx is done. Its output should be read
+// with output, combinedOutput or an outputLimiter.
+func commandContext(ctx context.Context, dir string, env []string, name string, args ...string) *exec.Cmd {
+	sv, ok := ctx.Value(sandboxKey{}).(sandboxValue)
+	if !ok {
+		sv = sandboxValue{sandbox: DefaultSandbox, limits: DefaultLimits}
+	}
+	return sv.sandbox.Command(ctx, SandboxCommand{
+		Root:      sv.root,
+		Dir:       dir,
+		Env:       env,
+		Name:      name,
+		Args:      args,
+		Limits:    sv.limits,
+		Artifacts: artifactsDir(ctx),
+	})
+}
+
+// runTool runs the named command and returns its output. Like go vet,
+// many linters exit 1 when there are issues, so that is not an error.
+func runTool(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runToolIn(ctx, "", nil, name, args...)
+}
+
+// runRepoTool runs the named command like runTool, in the repo in dir
+// with its go environment, for tools that type check the packages of the
+// repo, so that a module is checked with its go.mod
+func runRepoTool(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	env, err := goEnv(dir)
+	if err != nil {
+		return nil, err
+	}
+	return runToolIn(ctx, dir, env, name, args...)
+}
+
+func runToolIn(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	out, err := output(ctx, commandContext(ctx, dir, env, name, args...))
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if exitErr.ExitCode() == 1 {
+			return out, nil
+		}
+	}
+	return out, err
+}
+
 // GoTool runs a given go command (for example gofmt, go tool vet)
-// on a directory
-func GoTool(dir string, filenames, command []string) (float64, []FileSummary, error) {
+// on a directory. The command is killed if ctx is done, and fails with a
+// LimitError if it exceeds the limits carried by ctx. In a repo with
+// several modules, the command is run in each of them.
+func GoTool(ctx context.Context, dir string, filenames, command []string) (float64, []FileSummary, error) {
 	// started := time.Now()
-	// temporary disabling of misspell as it's the slowest
-	// command right now
-	if strings.Contains(command[len(command)-1], "misspell") && len(filenames) > 1000 {
-		log.Println("disabling misspell on large repo...")
-		return 1, []FileSummary{}, nil
+	var failed = []FileSummary{}
+	targets := toolTargets(ctx, dir)
+	for _, modDir := range moduleDirs(ctx, dir) {
+		modTargets := moduleTargets(ctx, dir, modDir, targets)
+		if len(modTargets) == 0 {
+			continue
+		}
+		summaries, err := runGoTool(ctx, dir, modDir, command, modTargets)
+		failed = append(failed, summaries...)
+		if err != nil {
+			return 0, failed, err
+		}
+	}
+	failed = targetSummaries(ctx, dir, failed)
+	sortSummaries(failed)
+
+	// log.Println("END: ", command, time.Now().Sub(started))
+	return toolPercentage(filenames, failed)
+}
+
+// runGoTool runs a go command on the targets in the module directory
+// modDir of the repo in dir, and returns the files it reported issues in
+func runGoTool(ctx context.Context, dir, modDir string, command, targets []string) ([]FileSummary, error) {
+	env, err := goEnv(modDir)
+	if err != nil {
+		return []FileSummary{}, err
 	}
 	params := command[1:]
 	params = addSkipDirs(params)
-	params = append(params, dir+"/...")
+	params = append(params, relativeTargets(modDir, targets)...)
 
-	cmd := exec.Command(command[0], params...)
+	// the tool runs in the module, so that it is checked with its
+	// go.mod, replace directives included
+	cmd := commandContext(ctx, modDir, env, command[0], params...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return 0, []FileSummary{}, err
+		return []FileSummary{}, err
 	}
 
 	err = cmd.Start()
 	if err != nil {
-		return 0, []FileSummary{}, err
+		return []FileSummary{}, err
 	}
 
-	out := bufio.NewScanner(stdout)
+	l := newOutputLimiter(ctx)
+	out := bufio.NewScanner(l.reader(stdout))
 
 	// the same file can appear multiple times out of order
 	// in the output, so we can't go line by line, have to store
 	// a map of filename to FileSummary
 	var failed = []FileSummary{}
 
-	fsMap, err := getFileSummaryMap(out, dir)
+	fsMap, err := getFileSummaryMap(out, dir, modDir)
 	if err != nil {
-		return 0, []FileSummary{}, err
+		return []FileSummary{}, err
 	}
 	if err := out.Err(); err != nil {
-		return 0, []FileSummary{}, err
+		return []FileSummary{}, err
 	}
 
 	for _, v := range fsMap {
@@ -334,90 +675,165 @@ func GoTool(dir string, filenames, command []string) (float64, []FileSummary, er
 	}
 
 	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return []FileSummary{}, ctx.Err()
+	}
+	if limitErr := limitExceeded(err, l); limitErr != nil {
+		return []FileSummary{}, limitErr
+	}
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		// The program has exited with an exit code != 0
 
-		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-			// some commands exit 1 when files fail to pass (for example go vet)
-			if status.ExitStatus() != 1 {
-				return 0, failed, err
-				// return 0, Error{}, err
-			}
+		// some commands exit 1 when files fail to pass (for example go vet)
+		if exitErr.ExitCode() != 1 {
+			return failed, err
 		}
 	}
+	return failed, nil
+}
 
-	if len(filenames) == 1 {
-		lc, err := lineCount(filenames[0])
+// sortSummaries sorts file summaries by filename, and their errors by
+// position, so that results do not change between runs
+func sortSummaries(summaries []FileSummary) {
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].Filename < summaries[j].Filename })
+	for _, fs := range summaries {
+		errs := fs.Errors
+		sort.SliceStable(errs, func(i, j int) bool {
+			if errs[i].LineNumber != errs[j].LineNumber {
+				return errs[i].LineNumber < errs[j].LineNumber
+			}
+			if errs[i].Column != errs[j].Column {
+				return errs[i].Column < errs[j].Column
+			}
+			return errs[i].ErrorString < errs[j].ErrorString
+		})
+	}
+}
+
+// LinesPerIssue is the number of lines that an issue counts against in
+// the file it is in. A file with issues loses the share of its lines that
+// its issues count against, up to the whole file, so an issue weighs
+// less in a long file than in a short one.
+var LinesPerIssue = 50
+
+// toolPercentage returns the average percentage of filenames without
+// issues, where a file with issues loses LinesPerIssue lines per issue.
+// filenames are the files of the repo that are graded, so issues that a
+// check found in other Go files, such as generated or vendored files it
+// read anyway, are dropped. This way every check grades the same files.
+func toolPercentage(filenames []string, failed []FileSummary) (float64, []FileSummary, error) {
+	paths := make(map[string]string, len(filenames))
+	for _, fp := range filenames {
+		paths[newFileSummary("", fp).Filename] = fp
+	}
+
+	var penalty float64
+	kept := []FileSummary{}
+	for _, fs := range failed {
+		fp, ok := paths[fs.Filename]
+		if !ok && strings.HasSuffix(fs.Filename, ".go") {
+			continue
+		}
+		kept = append(kept, fs)
+		if !ok {
+			// an issue outside of the Go files, such as in go.mod
+			penalty++
+			continue
+		}
+		issues := len(fs.Errors)
+		if issues == 0 {
+			issues = 1
+		}
+		lc, err := lineCount(fp)
 		if err != nil {
-			return 0, failed, err
+			return 0, kept, err
 		}
-
-		var errors int
-		if len(failed) != 0 {
-			errors = len(failed[0].Errors)
+		if lc == 0 {
+			lc = 1
 		}
-
-		return float64(lc-errors) / float64(lc), failed, nil
+		penalty += math.Min(1, float64(issues*LinesPerIssue)/float64(lc))
 	}
-
-	// log.Println("END: ", command, time.Now().Sub(started))
-	return float64(len(filenames)-len(failed)) / float64(len(filenames)), failed, nil
+	if len(filenames) == 0 {
+		return 1, kept, nil
+	}
+	return math.Max(0, 1-penalty/float64(len(filenames))), kept, nil
 }
 
 // GoFmtNative runs gofmt via golang's stdlib format pkg
 func GoFmtNative(dir string, filenames []string) (float64, []FileSummary, error) {
-	fsChan := make(chan FileSummary)
-	errChan := make(chan error)
-	stopChan := make(chan bool)
-	go func(stopChan chan bool) {
-		for _, f := range filenames {
-			for _, skip := range skipSuffixes {
-				if strings.HasSuffix(f, skip) {
-					continue
-				}
-			}
+	return formatNative(dir, filenames, "file is not gofmted", func(f string, b []byte) (bool, error) {
+		g, err := format.Source(b)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(b, g), nil
+	})
+}
 
-			if autoGenerated(f) {
-				continue
-			}
+// GoImportsNative runs goimports via the imports pkg of x/tools. Only
+// the imports are compared, so files that are not gofmted pass as long
+// as their imports are right.
+func GoImportsNative(dir string, filenames []string) (float64, []FileSummary, error) {
+	return formatNative(dir, filenames, "file is not goimported", func(f string, b []byte) (bool, error) {
+		g, err := format.Source(b)
+		if err != nil {
+			return false, err
+		}
+		i, err := imports.Process(f, b, nil)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(g, i), nil
+	})
+}
 
-			go func(c chan FileSummary, errChan chan error, f string) {
-				b, err := ioutil.ReadFile(f)
-				if err != nil {
-					errChan <- err
-				}
-				g, err := format.Source(b)
-				if err != nil {
-					errChan <- err
-				}
-				if !bytes.Equal(b, g) {
-					filename := strings.TrimPrefix(f, "repos/src")
-					fs := FileSummary{}
-					fs.Filename = makeFilename(filename)
-					fu := fileURL(dir, strings.TrimPrefix(f, "repos/src"))
-					fs.FileURL = fu
-					fs.Errors = append(fs.Errors, Error{1, "file is not gofmted"})
-
-					fsChan <- fs
-				}
-			}(fsChan, errChan, f)
-		}
-		stopChan <- true
-	}(stopChan)
+// formatNative reports the files for which ok returns false with the
+// issue, on their first line
+func formatNative(dir string, filenames []string, issue string, ok func(f string, b []byte) (bool, error)) (float64, []FileSummary, error) {
+	type result struct {
+		fs  *FileSummary
+		err error
+	}
+	results := make(chan result)
+	for _, f := range filenames {
+		go func(f string) {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			pass, err := ok(f, b)
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			if pass {
+				results <- result{}
+				return
+			}
+			fs := newFileSummary(dir, f)
+			fs.Errors = append(fs.Errors, Error{LineNumber: 1, ErrorString: issue})
+			results <- result{fs: &fs}
+		}(f)
+	}
 
 	var (
 		failed = []FileSummary{}
-		f      FileSummary
 		err    error
 	)
-	for {
-		select {
-		case fsChan <- f:
-			failed = append(failed, f)
-		case errChan <- err:
-			return 0, []FileSummary{}, err
-		case <-stopChan:
-			return float64(len(filenames)-len(failed)) / float64(len(filenames)), failed, nil
+	// every file is waited for, so no goroutine is left blocked
+	for range filenames {
+		r := <-results
+		if r.err != nil && err == nil {
+			err = r.err
+		}
+		if r.fs != nil {
+			failed = append(failed, *r.fs)
 		}
 	}
+	if err != nil {
+		return 0, []FileSummary{}, err
+	}
+	sortSummaries(failed)
+	return toolPercentage(filenames, failed)
 }
@@ -1,12 +1,20 @@
 package check
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/gojp/goreportcard/download"
 )
 
 func TestGoFiles(t *testing.T) {
-	files, skipped, err := GoFiles("testfiles/")
+	files, skipped, err := GoFiles(context.Background(), "testfiles/")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -35,7 +43,7 @@ var goToolTests = []struct {
 
 func TestGoTool(t *testing.T) {
 	for _, tt := range goToolTests {
-		f, fs, err := GoTool(tt.dir, tt.filenames, tt.tool)
+		f, fs, err := GoTool(context.Background(), tt.dir, tt.filenames, tt.tool)
 		if err != nil && !tt.wantErr {
 			t.Fatal(err)
 		}
@@ -47,3 +55,349 @@ func TestGoTool(t *testing.T) {
 		}
 	}
 }
+
+func TestGoToolModuleWithReplace(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.20\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ./dep\n",
+		"m.go":       "package m\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/dep\"\n)\n\nfunc f() { fmt.Printf(\"%d\", dep.Name) }\n",
+		"dep/go.mod": "module example.com/dep\n\ngo 1.20\n",
+		"dep/dep.go": "package dep\n\nconst Name = \"dep\"\n",
+	}
+	for name, src := range files {
+		fp := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fp, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// go vet only type checks the module with the replaced dependency in
+	// module-aware mode. Like gometalinter, the tool prints the issues to
+	// stdout and takes the directories to skip.
+	vet := `go vet $(for a; do case $a in --skip=*) ;; *) echo $a;; esac; done) 2>&1`
+	_, failed, err := GoTool(context.Background(), dir, []string{filepath.Join(dir, "m.go")}, []string{"sh", "-c", vet, "sh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || len(failed[0].Errors) != 1 || failed[0].Errors[0].LineNumber != 9 {
+		t.Fatalf("GoTool failed = %v, want an issue on line 9 of m.go", failed)
+	}
+	if !strings.HasSuffix(failed[0].Filename, "/m.go") {
+		t.Errorf("GoTool filename = %q, want m.go", failed[0].Filename)
+	}
+}
+
+var autoGeneratedTests = []struct {
+	name string
+	src  string
+	want bool
+}{
+	{"convention", "// Code generated by stringer -type=Pill; DO NOT EDIT.\n\npackage m\n", true},
+	{"after license", "// Copyright 2017 The Authors.\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n// source: a.proto\n\npackage m\n", true},
+	{"after build tag", "//go:build linux\n\n// Code generated by mkerrors.sh; DO NOT EDIT.\n\npackage m\n", true},
+	{"windows line endings", "// Code generated by go-bindata. DO NOT EDIT.\r\n\r\npackage m\r\n", true},
+	{"first line fallback", "// autogenerated by a script\n\npackage m\n", true},
+	{"after package clause", "package m\n\n// Code generated by hand. DO NOT EDIT.\n", false},
+	{"not at line start", "package m // Code generated by hand. DO NOT EDIT.\n", false},
+	{"missing period", "// Copyright 2017 The Authors.\n\n// Code generated by hand. DO NOT EDIT\n\npackage m\n", false},
+	{"no comment", "package m\n\nfunc f() {}\n", false},
+}
+
+func TestAutoGenerated(t *testing.T) {
+	files := make(map[string]string)
+	for i, tt := range autoGeneratedTests {
+		files[fmt.Sprintf("f%d.go", i)] = tt.src
+	}
+	dir := writeModule(t, "", files)
+	defer os.RemoveAll(dir)
+
+	for i, tt := range autoGeneratedTests {
+		got, err := autoGenerated(filepath.Join(dir, fmt.Sprintf("f%d.go", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("[%s] autoGenerated = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+var lineCountTests = []struct {
+	src  string
+	want int
+}{
+	{"", 0},
+	{"package m\n", 1},
+	{"package m\n\nfunc f() {}\n", 3},
+	{"package m\r\n\r\nfunc f() {}", 2},
+}
+
+func TestLineCount(t *testing.T) {
+	for _, tt := range lineCountTests {
+		dir := writeModule(t, "", map[string]string{"a.go": tt.src})
+		got, err := lineCount(filepath.Join(dir, "a.go"))
+		os.RemoveAll(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("[%q] lineCount = %d, want %d", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestToolPercentage(t *testing.T) {
+	defer func(old int) { LinesPerIssue = old }(LinesPerIssue)
+	LinesPerIssue = 10
+
+	dir := writeModule(t, "", map[string]string{
+		"long.go":  strings.Repeat("\n", 200),
+		"short.go": strings.Repeat("\n", 20),
+		"clean.go": "package m\n",
+	})
+	defer os.RemoveAll(dir)
+	var filenames []string
+	for _, f := range []string{"clean.go", "long.go", "short.go"} {
+		filenames = append(filenames, filepath.Join(dir, f))
+	}
+	failed := func(f string, issues int) FileSummary {
+		fs := newFileSummary(dir, filepath.Join(dir, f))
+		for i := 0; i < issues; i++ {
+			fs.Errors = append(fs.Errors, Error{LineNumber: i + 1})
+		}
+		return fs
+	}
+
+	cases := []struct {
+		name   string
+		failed []FileSummary
+		want   float64
+	}{
+		{"none", nil, 1},
+		// 10 of 200 lines
+		{"long", []FileSummary{failed("long.go", 1)}, 1 - 0.05/3},
+		// 10 of 20 lines
+		{"short", []FileSummary{failed("short.go", 1)}, 1 - 0.5/3},
+		// at most the whole file
+		{"short many", []FileSummary{failed("short.go", 5)}, 1 - 1.0/3},
+		// not graded, such as generated files
+		{"other go file", []FileSummary{{Filename: "other.go"}}, 1},
+		{"not go", []FileSummary{{Filename: "go.mod"}}, 1 - 1.0/3},
+	}
+	for _, tt := range cases {
+		got, _, err := toolPercentage(filenames, tt.failed)
+		if err != nil {
+			t.Fatalf("[%s] %v", tt.name, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("[%s] toolPercentage = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGoFmtNative(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"ok.go":  "package m\n",
+		"bad.go": "package m\nfunc  f() {}\n",
+	})
+	defer os.RemoveAll(dir)
+	filenames := []string{filepath.Join(dir, "bad.go"), filepath.Join(dir, "ok.go")}
+
+	p, failed, err := GoFmtNative(dir, filenames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Filename != newFileSummary(dir, filenames[0]).Filename {
+		t.Errorf("GoFmtNative failed = %+v, want bad.go", failed)
+	}
+	// the issue counts against all lines of the short file
+	if p != 0.5 {
+		t.Errorf("GoFmtNative percentage = %v, want 0.5", p)
+	}
+
+	if _, _, err := GoFmtNative(dir, append(filenames, filepath.Join(dir, "missing.go"))); err == nil {
+		t.Errorf("GoFmtNative of a missing file succeeded, want an error")
+	}
+}
+
+func TestGoImportsNative(t *testing.T) {
+	dir := writeModule(t, "", map[string]string{
+		"ok.go":      "package m\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n",
+		"unfmt.go":   "package m\nimport \"strings\"\nvar  _ = strings.Title\n",
+		"unused.go":  "package m\n\nimport \"os\"\n",
+		"missing.go": "package m\n\nvar _ = bytes.Equal\n",
+	})
+	defer os.RemoveAll(dir)
+	var filenames []string
+	for _, f := range []string{"missing.go", "ok.go", "unfmt.go", "unused.go"} {
+		filenames = append(filenames, filepath.Join(dir, f))
+	}
+
+	_, failed, err := GoImportsNative(dir, filenames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, fs := range failed {
+		got = append(got, filepath.Base(fs.Filename))
+	}
+	// formatting issues are left to gofmt
+	if want := []string{"missing.go", "unused.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GoImportsNative failed = %q, want %q", got, want)
+	}
+}
+
+func TestSortSummaries(t *testing.T) {
+	summaries := []FileSummary{
+		{Filename: "b.go", Errors: []Error{{LineNumber: 3, ErrorString: "x"}, {LineNumber: 1, ErrorString: "z"}, {LineNumber: 1, ErrorString: "y"}}},
+		{Filename: "a.go", Errors: []Error{{LineNumber: 2, ErrorString: "w"}}},
+	}
+	sortSummaries(summaries)
+	want := []FileSummary{
+		{Filename: "a.go", Errors: []Error{{LineNumber: 2, ErrorString: "w"}}},
+		{Filename: "b.go", Errors: []Error{{LineNumber: 1, ErrorString: "y"}, {LineNumber: 1, ErrorString: "z"}, {LineNumber: 3, ErrorString: "x"}}},
+	}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("sortSummaries = %v, want %v", summaries, want)
+	}
+}
+
+var addErrorTests = []struct {
+	out    string
+	line   int
+	column int
+	rule   string
+	msg    string
+}{
+	{"a.go:10:2:warning: exported func F should have comment (golint)", 10, 2, "golint", "warning: exported func F should have comment (golint)"},
+	{"a.go:3::warning: file is not gofmted with -s (gofmt)", 3, 0, "gofmt", "warning: file is not gofmted with -s (gofmt)"},
+	{"a.go:7:1: exported function F should have comment or be unexported", 7, 1, "", " exported function F should have comment or be unexported"},
+	{"a.go:7:1: call f (again)", 7, 1, "", " call f (again)"},
+}
+
+func TestAddError(t *testing.T) {
+	for _, tt := range addErrorTests {
+		var fs FileSummary
+		if err := fs.AddError(tt.out); err != nil {
+			t.Fatalf("[%q] AddError: %v", tt.out, err)
+		}
+		e := fs.Errors[0]
+		if e.LineNumber != tt.line || e.Column != tt.column || e.RuleID != tt.rule || e.ErrorString != tt.msg {
+			t.Errorf("[%q] AddError = %+v, want line %d, column %d, rule %q, message %q", tt.out, e, tt.line, tt.column, tt.rule, tt.msg)
+		}
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	defer func(hosts []string) { download.GitLabHosts = hosts }(download.GitLabHosts)
+	download.GitLabHosts = append(download.GitLabHosts, "git.example.com")
+
+	cases := []struct {
+		dir, filename, want string
+	}{
+		{"repos/src/github.com/foo/bar", "/github.com/foo/bar/a/b.go", "https://github.com/foo/bar/blob/master/a/b.go"},
+		{"repos/src/golang.org/x/tools", "/golang.org/x/tools/go/vcs/vcs.go", "https://github.com/golang/tools/blob/master/go/vcs/vcs.go"},
+		// repos that were not cloned are on master
+		{"repos/src/gitlab.com/foo/bar", "/gitlab.com/foo/bar/a/b.go", "https://gitlab.com/foo/bar/-/blob/master/a/b.go"},
+		{"repos/src/gitlab.com/foo/group/bar", "/gitlab.com/foo/group/bar/b.go", "https://gitlab.com/foo/group/bar/-/blob/master/b.go"},
+		{"repos/src/git.example.com/foo/bar", "/git.example.com/foo/bar/b.go", "https://git.example.com/foo/bar/-/blob/master/b.go"},
+		{"repos/src/bitbucket.org/foo/bar", "/bitbucket.org/foo/bar/a/b.go", "https://bitbucket.org/foo/bar/src/master/a/b.go"},
+		{"repos/src/example.com/foo/bar", "/example.com/foo/bar/b.go", ""},
+	}
+	for _, c := range cases {
+		if got := fileURL(c.dir, c.filename); got != c.want {
+			t.Errorf("[%q] fileURL = %q, want %q", c.filename, got, c.want)
+		}
+	}
+}
+
+func TestSetRef(t *testing.T) {
+	dir := "repos/src/github.com/foo/tagged"
+	defer repoInfos.Delete(dir)
+
+	SetRef(dir, "v1.2.3")
+	if got, want := fileURL(dir, "/github.com/foo/tagged/a.go"), "https://github.com/foo/tagged/blob/v1.2.3/a.go"; got != want {
+		t.Errorf("fileURL at a tag = %q, want %q", got, want)
+	}
+	SetRef(dir, "")
+	if got, want := fileURL(dir, "/github.com/foo/tagged/a.go"), "https://github.com/foo/tagged/blob/master/a.go"; got != want {
+		t.Errorf("fileURL at the default branch = %q, want %q", got, want)
+	}
+}
+
+func TestFileURLSubdir(t *testing.T) {
+	defer func(dir string) { ReposDir = dir }(ReposDir)
+	ReposDir = filepath.ToSlash(t.TempDir())
+	repo := filepath.Join(ReposDir, "gitlab.com", "foo", "group", "bar")
+	dir := filepath.Join(repo, "services", "api")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "--quiet"}, {"remote", "add", "origin", "https://gitlab.com/foo/group/bar.git"}} {
+		if err := git(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer repoInfos.Delete(dir)
+
+	// the group of the repo cannot be told apart from the directory
+	SetRef(dir, "main")
+	if got, want := fileURL(dir, "/gitlab.com/foo/group/bar/services/api/a.go"), "https://gitlab.com/foo/group/bar/-/blob/main/services/api/a.go"; got != want {
+		t.Errorf("fileURL in a directory of the repo = %q, want %q", got, want)
+	}
+}
+
+func TestHostFileURL(t *testing.T) {
+	defer func(tmpl string) { FileURLTemplate = tmpl }(FileURLTemplate)
+
+	cases := []struct {
+		web, tmpl, want string
+	}{
+		{"https://github.com/uber-go/zap", "", "https://github.com/uber-go/zap/blob/main/a/b.go"},
+		{"https://gitlab.com/foo/bar", "", "https://gitlab.com/foo/bar/-/blob/main/a/b.go"},
+		{"https://bitbucket.org/foo/bar", "", "https://bitbucket.org/foo/bar/src/main/a/b.go"},
+		{"https://git.example.com/foo/bar", "", ""},
+		{"https://git.example.com/foo/bar", "{repo}/src/branch/{branch}/{path}", "https://git.example.com/foo/bar/src/branch/main/a/b.go"},
+	}
+	for _, c := range cases {
+		FileURLTemplate = c.tmpl
+		if got := hostFileURL(c.web, "main", "/a/b.go"); got != c.want {
+			t.Errorf("[%q] hostFileURL with template %q = %q, want %q", c.web, c.tmpl, got, c.want)
+		}
+	}
+}
+
+func TestLineURL(t *testing.T) {
+	cases := []struct {
+		fileURL    string
+		start, end int
+		want       string
+	}{
+		{"https://github.com/foo/bar/blob/master/a.go", 3, 0, "https://github.com/foo/bar/blob/master/a.go#L3"},
+		{"https://github.com/foo/bar/blob/master/a.go", 3, 7, "https://github.com/foo/bar/blob/master/a.go#L3-L7"},
+		{"https://gitlab.com/foo/bar/-/blob/main/a.go", 3, 3, "https://gitlab.com/foo/bar/-/blob/main/a.go#L3"},
+		{"https://bitbucket.org/foo/bar/src/main/a.go", 3, 0, "https://bitbucket.org/foo/bar/src/main/a.go#lines-3"},
+		{"https://bitbucket.org/foo/bar/src/main/a.go", 3, 7, "https://bitbucket.org/foo/bar/src/main/a.go#lines-3:7"},
+	}
+	for _, c := range cases {
+		if got := LineURL(c.fileURL, c.start, c.end); got != c.want {
+			t.Errorf("[%q] LineURL(%d, %d) = %q, want %q", c.fileURL, c.start, c.end, got, c.want)
+		}
+	}
+}
+
+func TestMakeFilename(t *testing.T) {
+	cases := []struct{ fn, want string }{
+		{"/github.com/foo/bar/a/b.go", "bar/a/b.go"},
+		{"/bitbucket.org/foo/bar/a/b.go", "bar/a/b.go"},
+		{"/example.com/foo/bar/b.go", "/example.com/foo/bar/b.go"},
+	}
+	for _, c := range cases {
+		if got := makeFilename(c.fn); got != c.want {
+			t.Errorf("[%q] makeFilename = %q, want %q", c.fn, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package check
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Weights overrides the weights that checks have in the overall average,
+// by check name. Repos can override them again in their ConfigFile.
+var Weights map[string]float64
+
+// ParseWeights parses a comma separated list of weights of checks, such
+// as "gofmt=0.3,go_vet=0.25"
+func ParseWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, kv := range strings.Split(s, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("weight %q is not name=weight", kv)
+		}
+		name := strings.TrimSpace(parts[0])
+		w, err := parseWeight(name, parts[1])
+		if err != nil {
+			return nil, err
+		}
+		weights[name] = w
+	}
+	return weights, nil
+}
+
+// parseWeight parses the weight of the named check, which may not be
+// negative
+func parseWeight(name, s string) (float64, error) {
+	w, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || w < 0 {
+		return 0, fmt.Errorf("weight of %s must be a number of at least 0", name)
+	}
+	return w, nil
+}
+
+// weight returns the weight of the named check with the default weight
+// def, after the overrides of the server and of the repo
+func (c RepoConfig) weight(name string, def float64) float64 {
+	if w, ok := c.Weights[name]; ok {
+		return w
+	}
+	if w, ok := Weights[name]; ok {
+		return w
+	}
+	return def
+}
+
+// weights returns the weights mapping of the parsed config, which may
+// only name known checks
+func weights(doc map[string]interface{}) (map[string]float64, error) {
+	var m map[string]string
+	switch v := doc["weights"].(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		m = v
+	default:
+		return nil, fmt.Errorf("%s: weights must be a mapping of check names to numbers", ConfigFile)
+	}
+
+	known := make(map[string]bool)
+	for _, ck := range append(Checks(), OptionalChecks()...) {
+		known[ck.Name()] = true
+	}
+
+	result := make(map[string]float64)
+	for name, v := range m {
+		if !known[name] {
+			return nil, fmt.Errorf("%s: unknown check %s", ConfigFile, name)
+		}
+		w, err := parseWeight(name, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ConfigFile, err)
+		}
+		result[name] = w
+	}
+	return result, nil
+}
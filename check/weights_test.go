@@ -0,0 +1,50 @@
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWeights(t *testing.T) {
+	cases := []struct {
+		s       string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"", map[string]float64{}, false},
+		{"gofmt=0.3, go_vet=0.25", map[string]float64{"gofmt": 0.3, "go_vet": 0.25}, false},
+		{"gofmt", nil, true},
+		{"gofmt=high", nil, true},
+		{"gofmt=-1", nil, true},
+	}
+	for _, tt := range cases {
+		got, err := ParseWeights(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("[%q] ParseWeights error = %v, want error %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%q] ParseWeights = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestRepoConfigWeight(t *testing.T) {
+	defer func(w map[string]float64) { Weights = w }(Weights)
+	Weights = map[string]float64{"gofmt": 0.1, "go_vet": 0.2}
+	cfg := RepoConfig{Weights: map[string]float64{"gofmt": 0}}
+
+	cases := []struct {
+		name string
+		want float64
+	}{
+		{"gofmt", 0},
+		{"go_vet", 0.2},
+		{"gocyclo", 0.5},
+	}
+	for _, tt := range cases {
+		if got := cfg.weight(tt.name, 0.5); got != tt.want {
+			t.Errorf("[%s] weight = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// git runs a git command in the repository at dir
+func git(dir string, args ...string) error {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// gitStdout runs a git command in the repository at dir and returns its
+// output
+func gitStdout(dir string, args ...string) (string, error) {
+	var stderr strings.Builder
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// CheckAtCommit runs all checks against the repository at dir as it was
+// at the given commit, using a Checker with no logger
+func CheckAtCommit(ctx context.Context, dir, commitSHA string) ([]CheckResult, error) {
+	return Checker{}.CheckAtCommit(ctx, dir, commitSHA)
+}
+
+// CheckAtCommit runs all checks against the repository at dir as it was
+// at the given commit. The commit is checked out into a temporary detached
+// worktree, so the working tree in dir is left untouched. The worktree is
+// removed again before returning.
+func (c Checker) CheckAtCommit(ctx context.Context, dir, commitSHA string) ([]CheckResult, error) {
+	tmp, err := ioutil.TempDir("", "goreportcard-worktree")
+	if err != nil {
+		return nil, err
+	}
+	worktree := filepath.Join(tmp, filepath.Base(dir))
+
+	if err := git(dir, "worktree", "add", "--detach", worktree, commitSHA); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	// deferred so the worktree is cleaned up even if a check panics
+	defer func() {
+		if rmErr := git(dir, "worktree", "remove", "--force", worktree); rmErr != nil {
+			c.logger().Log("could not remove worktree", "path", worktree, "error", rmErr)
+		}
+		os.RemoveAll(tmp)
+		git(dir, "worktree", "prune")
+	}()
+
+	pkgs, skipped, err := c.LoadPackages(ctx, worktree)
+	if err != nil {
+		return nil, fmt.Errorf("could not get filenames: %v", err)
+	}
+	filenames := PackageFiles(pkgs)
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no .go files found at %s", commitSHA)
+	}
+
+	// the worktree is thrown away afterwards, so there is no need to
+	// revert the renamed files
+	if err := RenameFiles(skipped); err != nil {
+		c.logger().Log("could not remove files", "error", err)
+	}
+
+	return c.RunAll(WithPackages(ctx, pkgs), worktree, filenames), nil
+}
@@ -0,0 +1,78 @@
+package check
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func licenseResult(t *testing.T, results []CheckResult) CheckResult {
+	for _, r := range results {
+		if r.Name == "license" {
+			return r
+		}
+	}
+	t.Fatal("no license result")
+	return CheckResult{}
+}
+
+func TestCheckAtCommit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goreportcard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gitOutput(t, dir, "init", "-q")
+	write("a.go", "package a\n")
+	gitOutput(t, dir, "add", "-A")
+	gitOutput(t, dir, "commit", "-q", "-m", "first")
+	first := gitOutput(t, dir, "rev-parse", "HEAD")
+
+	write("LICENSE", "SPDX-License-Identifier: MIT\n")
+	gitOutput(t, dir, "add", "-A")
+	gitOutput(t, dir, "commit", "-q", "-m", "second")
+	second := gitOutput(t, dir, "rev-parse", "HEAD")
+
+	results, err := CheckAtCommit(context.Background(), dir, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := licenseResult(t, results).Percentage; p != 0 {
+		t.Errorf("CheckAtCommit(%q) license = %f, want 0", first, p)
+	}
+
+	results, err = CheckAtCommit(context.Background(), dir, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := licenseResult(t, results).Percentage; p != 1 {
+		t.Errorf("CheckAtCommit(%q) license = %f, want 1", second, p)
+	}
+
+	if wt := gitOutput(t, dir, "worktree", "list"); strings.Count(wt, "\n") != 0 {
+		t.Errorf("worktrees were not removed:\n%s", wt)
+	}
+	if st := gitOutput(t, dir, "status", "--porcelain"); st != "" {
+		t.Errorf("working tree was modified:\n%s", st)
+	}
+}
@@ -0,0 +1,117 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML used by the repo config file: a
+// mapping of keys to scalars, lists of scalars, or mappings of scalars,
+// one level deep. Lists can be written in block style ("- a") or flow
+// style ("[a, b]"). Values are returned as string, []string and
+// map[string]string.
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	var key string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = stripYAMLComment(line)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		line = strings.TrimSpace(line)
+
+		if !indented {
+			k, v, ok := splitYAMLPair(line)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key: value", i+1)
+			}
+			key = k
+			switch {
+			case v == "":
+				// a block list or mapping follows
+				doc[key] = nil
+			case strings.HasPrefix(v, "["):
+				if !strings.HasSuffix(v, "]") {
+					return nil, fmt.Errorf("line %d: unterminated list", i+1)
+				}
+				var list = []string{}
+				for _, item := range strings.Split(v[1:len(v)-1], ",") {
+					if item = unquoteYAML(strings.TrimSpace(item)); item != "" {
+						list = append(list, item)
+					}
+				}
+				doc[key] = list
+			default:
+				doc[key] = unquoteYAML(v)
+			}
+			continue
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+		switch v := doc[key].(type) {
+		case nil:
+			if strings.HasPrefix(line, "- ") || line == "-" {
+				doc[key] = []string{unquoteYAML(strings.TrimSpace(strings.TrimPrefix(line, "-")))}
+				continue
+			}
+			k, val, ok := splitYAMLPair(line)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected list item or key: value", i+1)
+			}
+			doc[key] = map[string]string{k: unquoteYAML(val)}
+		case []string:
+			if !strings.HasPrefix(line, "-") {
+				return nil, fmt.Errorf("line %d: expected list item", i+1)
+			}
+			doc[key] = append(v, unquoteYAML(strings.TrimSpace(strings.TrimPrefix(line, "-"))))
+		case map[string]string:
+			k, val, ok := splitYAMLPair(line)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key: value", i+1)
+			}
+			v[k] = unquoteYAML(val)
+		default:
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+	}
+	return doc, nil
+}
+
+// stripYAMLComment removes a # comment from the end of line, unless it
+// is quoted
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitYAMLPair splits "key: value" into its key and value
+func splitYAMLPair(line string) (key, value string, ok bool) {
+	i := strings.Index(line, ":")
+	if i <= 0 || (i+1 < len(line) && line[i+1] != ' ') {
+		return "", "", false
+	}
+	return unquoteYAML(strings.TrimSpace(line[:i])), strings.TrimSpace(line[i+1:]), true
+}
+
+// unquoteYAML removes the quotes around a quoted scalar
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package download
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+// GitLabHosts are the hosts of GitLab instances. Repos on them are
+// cloned like any other repo, but their files link to GitLab.
+var GitLabHosts = []string{"gitlab.com"}
+
+// host returns the host of an import path
+func host(path string) string {
+	return strings.SplitN(path, "/", 2)[0]
+}
+
+// IsGitLab reports whether the repo with the import path is on one of
+// the GitLabHosts
+func IsGitLab(path string) bool {
+	h := host(path)
+	for _, g := range GitLabHosts {
+		if strings.EqualFold(h, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultBranch returns the default branch of the git repo cloned in
+// dir, which is the branch HEAD of its origin points to, or master if it
+// is not known
+func DefaultBranch(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD").Output()
+	if err != nil {
+		return "master"
+	}
+	branch := strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/")
+	if branch == "" {
+		return "master"
+	}
+	return branch
+}
+
+// WebURL returns the HTTPS URL of the origin of the git repo cloned in
+// dir, such as https://github.com/uber-go/zap for a repo cloned from
+// git@github.com:uber-go/zap.git, or "" if it has no origin. For repos
+// with a vanity import path, it is where the files of the repo are.
+func WebURL(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return ""
+	}
+	return webURL(strings.TrimSpace(string(out)))
+}
+
+// webURL returns the HTTPS URL of a git remote URL, or "" for a remote
+// on the local file system
+func webURL(remote string) string {
+	if strings.HasPrefix(remote, "/") || strings.HasPrefix(remote, ".") || strings.HasPrefix(remote, "file://") || filepath.VolumeName(remote) != "" {
+		return ""
+	}
+	u := strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if i := strings.Index(u, ":"); i >= 0 {
+		// an scp-like address, such as git@github.com:foo/bar
+		u = u[:i] + "/" + u[i+1:]
+	}
+	u = trimUsername(u)
+	if u == "" {
+		return ""
+	}
+	return "https://" + u
+}
+
+// syncDefault checks out the default branch of the repo in dir, with the
+// arguments auth that authenticate git. For git repos, vcs assumes that
+// the default branch is master.
+func syncDefault(root *vcs.RepoRoot, dir string, update bool, auth []string) error {
+	if root.VCS.Cmd != "git" {
+		return root.VCS.TagSync(dir, "")
+	}
+	if update {
+		// the default branch may have changed since the repo was cloned
+		runGit(dir, auth, "remote", "set-head", "origin", "--auto")
+	}
+	branch := DefaultBranch(dir)
+	if runGit(dir, nil, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch) == nil {
+		// a shallow update only fetches the branch
+		return runGit(dir, nil, "checkout", "-B", branch, "origin/"+branch, "--")
+	}
+	return runGit(dir, nil, "checkout", branch)
+}
+
+// syncRef checks out ref in the repo in dir, or the default branch if ref
+// is empty. Branches are checked out at their head on the origin, without
+// a local branch, and the tags are fetched, as they are not all fetched
+// with the branches. Shallow clones only fetch ref, unless it is an
+// abbreviated commit, which cannot be fetched by itself.
+func syncRef(root *vcs.RepoRoot, dir, ref string, update bool, auth []string) error {
+	switch {
+	case ref == "":
+		return syncDefault(root, dir, update, auth)
+	case root.VCS.Cmd != "git":
+		return root.VCS.TagSync(dir, ref)
+	case shallow(root) && runGit(dir, auth, "fetch", "--depth=1", "origin", ref) == nil:
+		return runGit(dir, nil, "checkout", "--detach", "FETCH_HEAD", "--")
+	}
+	fetch := []string{"fetch", "--tags", "origin"}
+	if ok, _ := exists(filepath.Join(dir, ".git", "shallow")); ok {
+		fetch = []string{"fetch", "--unshallow", "--tags", "origin"}
+	}
+	if err := runGit(dir, auth, fetch...); err != nil {
+		return err
+	}
+	rev := ref
+	if runGit(dir, nil, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+ref) == nil {
+		rev = "origin/" + ref
+	}
+	return runGit(dir, nil, "checkout", "--detach", rev, "--")
+}
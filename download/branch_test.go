@@ -0,0 +1,174 @@
+package download
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+func TestIsGitLab(t *testing.T) {
+	defer func(hosts []string) { GitLabHosts = hosts }(GitLabHosts)
+	GitLabHosts = append(GitLabHosts, "git.example.com")
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"gitlab.com/foo/bar", true},
+		{"GitLab.com/foo/bar", true},
+		{"git.example.com/foo/group/bar", true},
+		{"github.com/foo/bar", false},
+		{"gitlab.com.example.org/foo/bar", false},
+	}
+	for _, c := range cases {
+		if got := IsGitLab(c.path); got != c.want {
+			t.Errorf("[%q] IsGitLab = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	dir := t.TempDir()
+	origin, clone := filepath.Join(dir, "origin"), filepath.Join(dir, "clone")
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com", "GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-b", "main", origin)
+	git("-C", origin, "commit", "--allow-empty", "-m", "first")
+	git("clone", origin, clone)
+
+	if got := DefaultBranch(clone); got != "main" {
+		t.Errorf("DefaultBranch of a clone = %q, want main", got)
+	}
+	if got := DefaultBranch(origin); got != "master" {
+		t.Errorf("DefaultBranch of a repo without origin = %q, want master", got)
+	}
+	git("-C", clone, "remote", "set-url", "origin", "git@git.example.com:foo/bar.git")
+	if got, want := WebURL(clone), "https://git.example.com/foo/bar"; got != want {
+		t.Errorf("WebURL of a clone = %q, want %q", got, want)
+	}
+	if got := WebURL(origin); got != "" {
+		t.Errorf("WebURL of a repo without origin = %q, want none", got)
+	}
+}
+
+func TestSyncRef(t *testing.T) {
+	dir := t.TempDir()
+	origin, clone := filepath.Join(dir, "origin"), filepath.Join(dir, "clone")
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com", "GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-b", "main", origin)
+	git("-C", origin, "commit", "--allow-empty", "-m", "first")
+	first := git("-C", origin, "rev-parse", "HEAD")
+	git("-C", origin, "tag", "v1.0.0")
+	git("-C", origin, "checkout", "-b", "develop")
+	git("-C", origin, "commit", "--allow-empty", "-m", "second")
+	git("clone", origin, clone)
+	// the branch moves after it was cloned
+	git("-C", origin, "commit", "--allow-empty", "-m", "third")
+	develop := git("-C", origin, "rev-parse", "HEAD")
+	git("-C", origin, "checkout", "main")
+
+	root := &vcs.RepoRoot{VCS: vcs.ByCmd("git"), Repo: origin, Root: "example.com/foo/bar"}
+	cases := []struct{ ref, want string }{
+		{"v1.0.0", first},
+		{"develop", develop},
+		{first[:7], first},
+	}
+	for _, c := range cases {
+		if err := syncRef(root, clone, c.ref, true, nil); err != nil {
+			t.Errorf("[%q] syncRef: %v", c.ref, err)
+			continue
+		}
+		if got := git("-C", clone, "rev-parse", "HEAD"); got != c.want {
+			t.Errorf("[%q] HEAD after syncRef = %s, want %s", c.ref, got, c.want)
+		}
+	}
+}
+
+func TestSplitRef(t *testing.T) {
+	cases := []struct{ path, repo, ref string }{
+		{"github.com/foo/bar", "github.com/foo/bar", ""},
+		{"github.com/foo/bar@v1.2.3", "github.com/foo/bar", "v1.2.3"},
+		{"https://github.com/foo/bar@feature/x", "https://github.com/foo/bar", "feature/x"},
+		{"git@github.com:foo/bar", "git@github.com:foo/bar", ""},
+		{"https://user@github.com/foo/bar", "https://user@github.com/foo/bar", ""},
+		{"user@github.com/foo/bar@develop", "user@github.com/foo/bar", "develop"},
+	}
+	for _, c := range cases {
+		if repo, ref := SplitRef(c.path); repo != c.repo || ref != c.ref {
+			t.Errorf("[%q] SplitRef = %q, %q, want %q, %q", c.path, repo, ref, c.repo, c.ref)
+		}
+	}
+	for _, ref := range []string{"--upload-pack=x", "a..b", "HEAD~1", "a b"} {
+		if err := checkRef(ref); err == nil {
+			t.Errorf("[%q] checkRef did not fail", ref)
+		}
+	}
+}
+
+func TestWebURL(t *testing.T) {
+	cases := []struct{ remote, want string }{
+		{"https://github.com/uber-go/zap.git", "https://github.com/uber-go/zap"},
+		{"https://github.com/uber-go/zap/", "https://github.com/uber-go/zap"},
+		{"git@github.com:uber-go/zap.git", "https://github.com/uber-go/zap"},
+		{"ssh://git@git.example.com/foo/bar", "https://git.example.com/foo/bar"},
+		{"https://user@bitbucket.org/foo/bar.git", "https://bitbucket.org/foo/bar"},
+		{"/srv/git/bar.git", ""},
+		{"file:///srv/git/bar.git", ""},
+	}
+	for _, c := range cases {
+		if got := webURL(c.remote); got != c.want {
+			t.Errorf("[%q] webURL = %q, want %q", c.remote, got, c.want)
+		}
+	}
+}
+
+func TestRepoSubdir(t *testing.T) {
+	cases := []struct{ path, root, want string }{
+		{"github.com/foo/bar", "github.com/foo/bar", ""},
+		{"github.com/foo/bar/", "github.com/foo/bar", ""},
+		{"github.com/foo/bar/services/api", "github.com/foo/bar", "services/api"},
+		{"git.example.com/foo/bar.git/services/api/", "git.example.com/foo/bar", "services/api"},
+		{"github.com/foo/bar/tree/main/services", "github.com/foo/bar", ""},
+		{"bitbucket.org/foo/bar/src/master/pkg", "bitbucket.org/foo/bar", ""},
+	}
+	for _, c := range cases {
+		if got, err := repoSubdir(c.path, c.root); err != nil || got != c.want {
+			t.Errorf("[%q] repoSubdir = %q, %v, want %q", c.path, got, err, c.want)
+		}
+	}
+	for _, path := range []string{"github.com/foo/bar/.git/config", "github.com/foo/bar/a//b", "github.com/foo/bar/a/../../x"} {
+		if _, err := repoSubdir(path, "github.com/foo/bar"); err == nil {
+			t.Errorf("[%q] repoSubdir did not fail", path)
+		}
+	}
+}
+
+func TestCheckSubdir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "services", "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSubdir(dir, "services/api"); err != nil {
+		t.Errorf("checkSubdir of a directory: %v", err)
+	}
+	if err := checkSubdir(dir, "services/web"); err == nil {
+		t.Error("checkSubdir of a missing directory did not fail")
+	}
+}
@@ -2,7 +2,8 @@ package download
 
 import (
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,78 +16,304 @@ import (
 // It is forgiving in terms of the exact path given: the path may have
 // a scheme or username, which will be trimmed.
 func Download(path, dest string) (root *vcs.RepoRoot, err error) {
-	return download(path, dest, true)
+	repo, err := DownloadRef(path, dest, "", "")
+	return repo.RepoRoot, err
 }
 
-func download(path, dest string, firstAttempt bool) (root *vcs.RepoRoot, err error) {
+// Repo is a repo that was downloaded
+type Repo struct {
+	*vcs.RepoRoot
+	// Private is whether the repo was cloned with an access token or
+	// over SSH
+	Private bool
+	// Token is the access token that the private repo was cloned with
+	// over HTTPS: the token passed to DownloadRef, or else the token of
+	// its host in Tokens
+	Token string
+	// Version is the version of the module zip that was downloaded
+	// instead of cloning the repo, if any
+	Version string
+	// Subdir is the directory of the repo, with forward slashes, that
+	// the path it was downloaded for points to, or "" for the whole repo
+	Subdir string
+}
+
+// ImportPath returns the import path of the directory of the repo that
+// was downloaded, such as github.com/foo/monorepo/services/api
+func (r Repo) ImportPath() string {
+	if r.Subdir == "" {
+		return r.Root
+	}
+	return r.Root + "/" + r.Subdir
+}
+
+// Ref returns the tag or commit of the Version of the repo, or "" if it
+// was cloned
+func (r Repo) Ref() string {
+	if r.Version == "" {
+		return ""
+	}
+	return versionRef(r.Version)
+}
+
+// DownloadRef downloads a repo like Download, with the Strategy, and
+// checks out ref, which is a branch, a tag or a commit, or the default
+// branch if ref is empty. If the repo cannot be cloned without
+// credentials, it is cloned over HTTPS with the access token, or else
+// with the token of its host in Tokens, and is Private. If path is a
+// directory of the repo, the whole repo is downloaded, and the directory
+// must be in it.
+func DownloadRef(path, dest, ref, token string) (Repo, error) {
+	if err := checkRef(ref); err != nil {
+		return Repo{}, err
+	}
+	return download(path, dest, ref, token, true)
+}
+
+func download(path, dest, ref, token string, firstAttempt bool) (repo Repo, err error) {
 	vcs.ShowCmd = true
 
 	path, err = Clean(path)
 	if err != nil {
-		return root, err
+		return repo, err
 	}
 
-	root, err = vcs.RepoRootForImportPath(path, true)
+	root, err := repoRoot(path)
+	if err != nil {
+		return repo, err
+	}
+	subdir, err := repoSubdir(path, root.Root)
 	if err != nil {
-		return root, err
+		return repo, err
+	}
+	auth := authArgs(root, token)
+	repo = Repo{RepoRoot: root, Private: auth != nil, Subdir: subdir}
+	if auth != nil && strings.HasPrefix(root.Repo, "https://") {
+		repo.Token = token
+		if repo.Token == "" {
+			repo.Token = Tokens[host(root.Root)]
+		}
 	}
 
 	localDirPath := filepath.Join(dest, root.Root, "..")
 
 	err = os.MkdirAll(localDirPath, 0777)
 	if err != nil {
-		return root, err
+		return repo, err
 	}
 
 	fullLocalPath := filepath.Join(dest, root.Root)
-	ex, err := exists(fullLocalPath)
+	if Strategy == StrategyModule && auth == nil {
+		// private repos are not on public proxies
+		repo.Version, err = downloadModule(root.Root, root.Repo, ref, fullLocalPath)
+		if err == nil {
+			slog.Info("downloaded module", "module", root.Root, "version", repo.Version)
+			if err = checkSubdir(fullLocalPath, subdir); err == nil {
+				return repo, nil
+			}
+			// nested modules are not in module zips
+			repo.Version = ""
+			if err := os.RemoveAll(fullLocalPath); err != nil {
+				return repo, err
+			}
+		}
+		slog.Info("could not download module, cloning repo", "module", root.Root, "error", err)
+	}
+	ex, err := exists(filepath.Join(fullLocalPath, "."+root.VCS.Cmd))
 	if err != nil {
-		return root, err
+		return repo, err
+	}
+	if !ex {
+		// a module zip is in the directory, or the repo was moved to
+		// another version control system
+		if err := os.RemoveAll(fullLocalPath); err != nil {
+			return repo, err
+		}
 	}
 	if ex {
-		log.Println("Update", root.Repo)
-		err = root.VCS.Download(fullLocalPath)
+		slog.Info("updating repo", "repo", root.Repo, "private", repo.Private)
+		err = update(root, fullLocalPath, auth)
 		if err != nil && firstAttempt {
 			// may have been rebased; we delete the directory, then try one more time:
-			log.Printf("Failed to download %q (%v), trying again...", root.Repo, err.Error())
+			slog.Warn("could not download repo, trying again", "repo", root.Repo, "error", err)
 			err = os.RemoveAll(fullLocalPath)
 			if err != nil {
-				log.Println("Failed to delete path:", fullLocalPath, err)
+				slog.Error("could not delete path", "path", fullLocalPath, "error", err)
 			}
-			return download(path, dest, false)
+			return download(path, dest, ref, token, false)
 		} else if err != nil {
-			return root, err
+			return repo, err
 		}
 	} else {
-		log.Println("Create", root.Repo)
+		slog.Info("cloning repo", "repo", root.Repo, "private", repo.Private)
 
-		err = root.VCS.Create(fullLocalPath, root.Repo)
+		err = clone(root, fullLocalPath, auth)
 		if err != nil {
-			return root, err
+			return repo, err
 		}
 	}
-	err = root.VCS.TagSync(fullLocalPath, "")
+	err = syncRef(root, fullLocalPath, ref, ex, auth)
 	if err != nil && firstAttempt {
 		// may have been rebased; we delete the directory, then try one more time:
-		log.Printf("Failed to update %q (%v), trying again...", root.Repo, err.Error())
+		slog.Warn("could not update repo, trying again", "repo", root.Repo, "error", err)
 		err = os.RemoveAll(fullLocalPath)
-		return download(path, dest, false)
+		return download(path, dest, ref, token, false)
+	}
+	if err != nil {
+		return repo, err
+	}
+	return repo, checkSubdir(fullLocalPath, subdir)
+}
+
+// repoSubdir returns the directory of the repo with the import path root
+// that the import path points to, or "" if it is the root. Directories
+// whose names start with a dot, such as .git, are not graded.
+func repoSubdir(importPath, root string) (string, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(importPath, root), ".git")
+	if rest == importPath || !strings.HasPrefix(rest, "/") {
+		return "", nil
+	}
+	subdir := strings.Trim(rest, "/")
+	switch strings.SplitN(subdir, "/", 2)[0] {
+	case "", "tree", "blob", "src", "-":
+		// the path is of the whole repo, or is a link to a page of
+		// the repo on its host, such as github.com/foo/bar/tree/main
+		return "", nil
+	}
+	for _, elem := range strings.Split(subdir, "/") {
+		if elem == "" || strings.HasPrefix(elem, ".") {
+			return "", fmt.Errorf("invalid directory %q of repo %s", subdir, root)
+		}
+	}
+	return subdir, nil
+}
+
+// checkSubdir returns an error if subdir is not a directory of the repo
+// downloaded into dir
+func checkSubdir(dir, subdir string) error {
+	if subdir == "" {
+		return nil
+	}
+	fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(subdir)))
+	if err != nil || !fi.IsDir() {
+		return fmt.Errorf("no directory %s in the repo", subdir)
 	}
-	return root, err
+	return nil
+}
+
+// shallow reports whether the repo of root is cloned with only the
+// commit that is graded
+func shallow(root *vcs.RepoRoot) bool {
+	return Strategy != StrategyClone && root.VCS.Cmd == "git"
+}
+
+// clone clones the repo of root into dir, with the arguments auth that
+// authenticate git. A shallow clone falls back to cloning the whole repo,
+// as some git servers cannot clone shallowly.
+func clone(root *vcs.RepoRoot, dir string, auth []string) error {
+	if shallow(root) {
+		err := runGit("", auth, "clone", "--depth=1", root.Repo, dir)
+		if err == nil {
+			return nil
+		}
+		slog.Info("could not clone repo shallowly, cloning it whole", "repo", root.Repo, "error", err)
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		return runGit("", auth, "clone", root.Repo, dir)
+	}
+	return root.VCS.Create(dir, root.Repo)
+}
+
+// update fetches the changes of the repo of root cloned in dir, with the
+// arguments auth that authenticate git
+func update(root *vcs.RepoRoot, dir string, auth []string) error {
+	if root.VCS.Cmd != "git" {
+		return root.VCS.Download(dir)
+	}
+	if shallow(root) {
+		// the changes are checked out from the origin by syncRef
+		return runGit(dir, auth, "fetch", "--depth=1", "origin")
+	}
+	// HEAD is detached if a ref was checked out, so git cannot pull
+	runGit(dir, nil, "checkout", DefaultBranch(dir))
+	if auth != nil {
+		return runGit(dir, auth, "pull", "--ff-only")
+	}
+	return root.VCS.Download(dir)
+}
+
+// SplitRef splits a path like github.com/foo/bar@v1.2.3 into the import
+// path of the repo and the ref to check out, which is empty if the path
+// has none. The user name of a path like git@github.com:foo/bar is not a
+// ref.
+func SplitRef(path string) (string, string) {
+	i := strings.LastIndex(path, "@")
+	if i < 0 || !strings.Contains(trimScheme(path[:i]), "/") {
+		return path, ""
+	}
+	return path[:i], path[i+1:]
+}
+
+// checkRef returns an error if ref cannot be the name of a branch, a tag
+// or a commit, so that it is not taken as an option of git
+func checkRef(ref string) error {
+	if strings.HasPrefix(ref, "-") || strings.Contains(ref, "..") || strings.ContainsAny(ref, " \t\n~^:?*[\\") {
+		return fmt.Errorf("invalid ref %q", ref)
+	}
+	return nil
 }
 
 // Clean trims any URL parts, like the scheme or username, that might be present
-// in a user-submitted URL
+// in a user-submitted URL. A path to a directory of a repo is cleaned to
+// the import path of the repo joined with the directory.
 func Clean(path string) (string, error) {
 	importPath := trimUsername(trimScheme(path))
-	root, err := vcs.RepoRootForImportPath(importPath, true)
+	root, err := repoRoot(importPath)
 	if err != nil {
 		return "", err
 	}
 	if root != nil && (root.Root == "" || root.Repo == "") {
 		return root.Root, errors.New("empty repo root")
 	}
-	return root.Root, err
+	subdir, err := repoSubdir(importPath, root.Root)
+	if err != nil {
+		return "", err
+	}
+	return Repo{RepoRoot: root, Subdir: subdir}.ImportPath(), nil
+}
+
+// Root returns the import path of the root of the repo that path, which
+// may be a directory of the repo, is in. Downloads of paths with the same
+// root share the checkout of the repo.
+func Root(path string) (string, error) {
+	root, err := repoRoot(trimUsername(trimScheme(path)))
+	if err != nil {
+		return "", err
+	}
+	return root.Root, nil
+}
+
+// repoRoot returns the root of the repo with the import path. Repos on
+// bitbucket.org are cloned over HTTPS with git, as vcs looks up their
+// version control system with an API that Bitbucket removed, and
+// Bitbucket only hosts git repos. Repos on the SSHHosts are cloned over
+// SSH.
+func repoRoot(path string) (*vcs.RepoRoot, error) {
+	if isSSH(path) {
+		return sshRepoRoot(path)
+	}
+	if !strings.HasPrefix(path, "bitbucket.org/") {
+		return vcs.RepoRootForImportPath(path, true)
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 || parts[1] == "" || strings.TrimSuffix(parts[2], ".git") == "" {
+		return nil, fmt.Errorf("invalid bitbucket.org import path %q", path)
+	}
+	root := strings.Join([]string{parts[0], parts[1], strings.TrimSuffix(parts[2], ".git")}, "/")
+	return &vcs.RepoRoot{VCS: vcs.ByCmd("git"), Repo: "https://" + root, Root: root}, nil
 }
 
 // trimScheme removes a scheme (e.g. https://) from the URL for more
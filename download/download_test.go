@@ -16,8 +16,8 @@ func TestRepoRootForImportPath(t *testing.T) {
 	}{
 		{"github.com/gojp/goreportcard", "github.com/gojp/goreportcard", "git"},
 		{"https://github.com/boltdb/bolt", "github.com/boltdb/bolt", "git"},
-		{"https://bitbucket.org/rickb777/go-talk", "bitbucket.org/rickb777/go-talk", "hg"},
-		{"ssh://hg@bitbucket.org/rickb777/go-talk", "bitbucket.org/rickb777/go-talk", "hg"},
+		{"https://bitbucket.org/rickb777/go-talk", "bitbucket.org/rickb777/go-talk", "git"},
+		{"ssh://git@bitbucket.org/rickb777/go-talk", "bitbucket.org/rickb777/go-talk", "git"},
 	}
 
 	for _, tt := range cases {
@@ -40,3 +40,27 @@ func TestRepoRootForImportPath(t *testing.T) {
 	// clean up the test
 	os.RemoveAll(testDownloadDir)
 }
+
+func TestBitbucketRepoRoot(t *testing.T) {
+	cases := []struct {
+		path, wantRoot, wantRepo string
+	}{
+		{"bitbucket.org/foo/bar", "bitbucket.org/foo/bar", "https://bitbucket.org/foo/bar"},
+		{"bitbucket.org/foo/bar.git", "bitbucket.org/foo/bar", "https://bitbucket.org/foo/bar"},
+		{"bitbucket.org/foo/bar/src/master/pkg", "bitbucket.org/foo/bar", "https://bitbucket.org/foo/bar"},
+	}
+	for _, tt := range cases {
+		root, err := repoRoot(tt.path)
+		if err != nil {
+			t.Fatalf("[%q] repoRoot: %v", tt.path, err)
+		}
+		if root.Root != tt.wantRoot || root.Repo != tt.wantRepo || root.VCS.Cmd != "git" {
+			t.Errorf("[%q] repoRoot = %q, %q, %q, want %q, %q, git", tt.path, root.Root, root.Repo, root.VCS.Cmd, tt.wantRoot, tt.wantRepo)
+		}
+	}
+	for _, path := range []string{"bitbucket.org/foo", "bitbucket.org//bar"} {
+		if _, err := repoRoot(path); err == nil {
+			t.Errorf("[%q] repoRoot did not fail", path)
+		}
+	}
+}
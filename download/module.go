@@ -0,0 +1,207 @@
+package download
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The strategies of downloading the code of a repo
+const (
+	// StrategyClone clones the whole history of a repo
+	StrategyClone = "clone"
+	// StrategyShallow clones only the commit that is graded of git
+	// repos, and the whole history of other repos
+	StrategyShallow = "shallow"
+	// StrategyModule downloads the module zip of a repo from
+	// ModuleProxy, and clones it shallowly if the proxy does not have it
+	StrategyModule = "module"
+)
+
+// Strategy is how the code of repos is downloaded
+var Strategy = StrategyClone
+
+// ParseStrategy parses the name of a strategy of downloading repos
+func ParseStrategy(s string) (string, error) {
+	switch s {
+	case StrategyClone, StrategyShallow, StrategyModule:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown download strategy %q, want %s, %s or %s", s, StrategyClone, StrategyShallow, StrategyModule)
+}
+
+// ModuleProxy is the Go module proxy that module zips are downloaded from
+var ModuleProxy = "https://proxy.golang.org"
+
+// maxModuleZipSize is the size of the largest module zip that proxies
+// serve
+const maxModuleZipSize = 500 << 20
+
+var moduleClient = &http.Client{Timeout: 2 * time.Minute}
+
+// EscapeModulePath escapes a module path for the proxy protocol, where
+// upper case letters are written as ! followed by the lower case letter
+func EscapeModulePath(path string) string {
+	var escaped []rune
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			escaped = append(escaped, '!', unicode.ToLower(r))
+			continue
+		}
+		escaped = append(escaped, r)
+	}
+	return string(escaped)
+}
+
+// proxyGet gets the path on the ModuleProxy
+func proxyGet(path string) (*http.Response, error) {
+	resp, err := moduleClient.Get(ModuleProxy + "/" + path)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: proxy returned %s", path, resp.Status)
+	}
+	return resp, nil
+}
+
+// moduleVersion returns the version of the module path that ref is, or
+// its latest version if ref is empty
+func moduleVersion(path, ref string) (string, error) {
+	query := EscapeModulePath(path) + "/@latest"
+	if ref != "" {
+		query = EscapeModulePath(path) + "/@v/" + EscapeModulePath(ref) + ".info"
+	}
+	resp, err := proxyGet(query)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var info struct{ Version string }
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("could not parse version of %s: %v", path, err)
+	}
+	if info.Version == "" {
+		return "", fmt.Errorf("no version of %s", path)
+	}
+	return info.Version, nil
+}
+
+// versionRef returns the tag or the commit of a module version, such as
+// v1.2.3 or the commit hash of a pseudo-version
+func versionRef(version string) string {
+	v := strings.TrimSuffix(version, "+incompatible")
+	parts := strings.Split(v, "-")
+	if n := len(parts); n >= 3 && len(parts[n-1]) == 12 && len(parts[n-2]) >= 14 {
+		if strings.Trim(parts[n-1], "0123456789abcdef") == "" {
+			return parts[n-1]
+		}
+	}
+	return v
+}
+
+// downloadModule replaces the code in dir with the module zip of module,
+// at the version of ref or at its latest version, and returns the
+// version. dir is made a git repo without commits, with repo as its
+// origin, so that git does not take it for a directory of the repo it is
+// in.
+func downloadModule(module, repo, ref, dir string) (string, error) {
+	version, err := moduleVersion(module, ref)
+	if err != nil {
+		return "", err
+	}
+	resp, err := proxyGet(EscapeModulePath(module) + "/@v/" + EscapeModulePath(version) + ".zip")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	f, err := ioutil.TempFile("", "goreportcard-module")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxModuleZipSize+1))
+	if err != nil {
+		return "", fmt.Errorf("could not download module zip: %v", err)
+	}
+	if n > maxModuleZipSize {
+		return "", fmt.Errorf("module zip of %s@%s is too large", module, version)
+	}
+
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), filepath.Base(dir)+".module")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := unzipModule(f, n, module+"@"+version+"/", tmp); err != nil {
+		return "", fmt.Errorf("could not unzip module: %v", err)
+	}
+	if err := runGit(tmp, nil, "init", "--quiet"); err != nil {
+		return "", err
+	}
+	if err := runGit(tmp, nil, "remote", "add", "origin", repo); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	return version, os.Rename(tmp, dir)
+}
+
+// unzipModule extracts the files of the module zip r of size bytes, whose
+// names start with prefix, into dir
+func unzipModule(r io.ReaderAt, size int64, prefix, dir string) error {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range z.File {
+		if !strings.HasPrefix(f.Name, prefix) {
+			return fmt.Errorf("file %q is not in %s", f.Name, prefix)
+		}
+		name := strings.TrimPrefix(f.Name, prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if clean := path.Clean(name); clean != name || strings.HasPrefix(clean, "../") || path.IsAbs(clean) {
+			return fmt.Errorf("invalid file name %q", f.Name)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := unzipFile(f, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipFile(f *zip.File, dest string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,168 @@
+package download
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+func moduleZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadModule(t *testing.T) {
+	good := moduleZip(t, map[string]string{
+		"github.com/!foo/bar@v1.2.3/go.mod":   "module github.com/Foo/bar\n",
+		"github.com/!foo/bar@v1.2.3/a/b.go":   "package a\n",
+		"github.com/!foo/bar@v1.2.3/LICENSE":  "MIT\n",
+		"github.com/Foo/bar@v1.2.3/README.md": "bar\n",
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/github.com/!foo/bar/@latest", "/github.com/!foo/bar/@v/v1.2.3.info":
+			w.Write([]byte(`{"Version":"v1.2.3"}`))
+		case "/github.com/!foo/bar/@v/v1.2.3.zip":
+			w.Write(good)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(p string) { ModuleProxy = p }(ModuleProxy)
+	ModuleProxy = srv.URL
+
+	dir := filepath.Join(t.TempDir(), "bar")
+	_, err := downloadModule("github.com/Foo/bar", "https://github.com/Foo/bar", "", dir)
+	if err == nil || !strings.Contains(err.Error(), "is not in") {
+		t.Fatalf("downloadModule of a zip with a file of another module = %v, want an error", err)
+	}
+
+	good = moduleZip(t, map[string]string{
+		"github.com/Foo/bar@v1.2.3/go.mod": "module github.com/Foo/bar\n",
+		"github.com/Foo/bar@v1.2.3/a/b.go": "package a\n",
+	})
+	version, err := downloadModule("github.com/Foo/bar", "https://github.com/Foo/bar", "", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "v1.2.3" {
+		t.Errorf("version = %q, want v1.2.3", version)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "a", "b.go")); err != nil || string(b) != "package a\n" {
+		t.Errorf("a/b.go = %q, %v, want the file of the zip", b, err)
+	}
+	if got, want := WebURL(dir), "https://github.com/Foo/bar"; got != want {
+		t.Errorf("WebURL = %q, want %q", got, want)
+	}
+	if err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Run(); err == nil {
+		t.Errorf("the directory of a module has a commit")
+	}
+
+	if _, err := downloadModule("github.com/Foo/bar", "https://github.com/Foo/bar", "develop", dir); err == nil {
+		t.Errorf("downloadModule of a ref the proxy does not have did not fail")
+	}
+}
+
+func TestUnzipModuleOutsideDir(t *testing.T) {
+	b := moduleZip(t, map[string]string{"example.com/m@v1.0.0/../../evil.go": "package evil\n"})
+	err := unzipModule(bytes.NewReader(b), int64(len(b)), "example.com/m@v1.0.0/", t.TempDir())
+	if err == nil {
+		t.Errorf("unzipModule of a file outside of its directory did not fail")
+	}
+}
+
+func TestVersionRef(t *testing.T) {
+	cases := []struct{ version, want string }{
+		{"v1.2.3", "v1.2.3"},
+		{"v2.0.0+incompatible", "v2.0.0"},
+		{"v1.2.3-rc.1", "v1.2.3-rc.1"},
+		{"v0.0.0-20191109021931-daa7c04131f5", "daa7c04131f5"},
+		{"v1.2.4-0.20191109021931-daa7c04131f5", "daa7c04131f5"},
+	}
+	for _, c := range cases {
+		if got := versionRef(c.version); got != c.want {
+			t.Errorf("[%q] versionRef = %q, want %q", c.version, got, c.want)
+		}
+	}
+}
+
+func TestShallowClone(t *testing.T) {
+	defer func(s string) { Strategy = s }(Strategy)
+	Strategy = StrategyShallow
+
+	dir := t.TempDir()
+	origin, clonePath := filepath.Join(dir, "origin"), filepath.Join(dir, "clone")
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com", "GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-b", "main", origin)
+	git("-C", origin, "commit", "--allow-empty", "-m", "first")
+	first := git("-C", origin, "rev-parse", "HEAD")
+	git("-C", origin, "tag", "v1.0.0")
+	git("-C", origin, "commit", "--allow-empty", "-m", "second")
+
+	root := &vcs.RepoRoot{VCS: vcs.ByCmd("git"), Repo: "file://" + origin, Root: "example.com/foo/bar"}
+	if err := clone(root, clonePath, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("-C", clonePath, "rev-list", "--count", "HEAD"); got != "1" {
+		t.Errorf("shallow clone has %s commits, want 1", got)
+	}
+
+	git("-C", origin, "commit", "--allow-empty", "-m", "third")
+	third := git("-C", origin, "rev-parse", "HEAD")
+	if err := update(root, clonePath, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncRef(root, clonePath, "", true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("-C", clonePath, "rev-parse", "HEAD"); got != third {
+		t.Errorf("HEAD after update = %s, want %s", got, third)
+	}
+	for _, ref := range []string{"v1.0.0", first[:7]} {
+		if err := syncRef(root, clonePath, ref, true, nil); err != nil {
+			t.Fatalf("[%q] syncRef: %v", ref, err)
+		}
+		if got := git("-C", clonePath, "rev-parse", "HEAD"); got != first {
+			t.Errorf("[%q] HEAD = %s, want %s", ref, got, first)
+		}
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	for _, s := range []string{StrategyClone, StrategyShallow, StrategyModule} {
+		if got, err := ParseStrategy(s); err != nil || got != s {
+			t.Errorf("[%q] ParseStrategy = %q, %v", s, got, err)
+		}
+	}
+	if _, err := ParseStrategy("rsync"); err == nil {
+		t.Errorf("ParseStrategy of an unknown strategy did not fail")
+	}
+}
@@ -0,0 +1,77 @@
+package download
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+// SSHHosts are the hosts whose repos are cloned over SSH with SSHKey, for
+// git servers that cannot be reached over HTTPS
+var SSHHosts []string
+
+// SSHKey is the path of the private key, such as a deploy key, with which
+// repos on the SSHHosts are cloned
+var SSHKey string
+
+// SSHKnownHosts is the path of the known hosts file with the host keys of
+// the SSHHosts. If it is empty, hosts are trusted the first time they are
+// connected to.
+var SSHKnownHosts string
+
+// isSSH reports whether the repo with the import path is on one of the
+// SSHHosts
+func isSSH(path string) bool {
+	h := host(path)
+	for _, s := range SSHHosts {
+		if strings.EqualFold(h, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sshRepoRoot returns the root of the repo with the import path on one of
+// the SSHHosts, without looking it up over HTTPS. The root ends at an
+// element with a .git suffix, at the whole path for GitLab, which has
+// subgroups, or else at the third element, as on GitHub.
+func sshRepoRoot(path string) (*vcs.RepoRoot, error) {
+	parts := strings.Split(path, "/")
+	n := len(parts)
+	for i, p := range parts {
+		if strings.HasSuffix(p, ".git") {
+			n = i + 1
+			break
+		}
+	}
+	if n == len(parts) && !IsGitLab(path) && n > 3 {
+		n = 3
+	}
+	parts[n-1] = strings.TrimSuffix(parts[n-1], ".git")
+	if n < 3 || parts[1] == "" || parts[n-1] == "" {
+		return nil, fmt.Errorf("invalid import path %q for a repo over SSH", path)
+	}
+	root := strings.Join(parts[:n], "/")
+	repo := "ssh://git@" + root + ".git"
+	return &vcs.RepoRoot{VCS: vcs.ByCmd("git"), Repo: repo, Root: root}, nil
+}
+
+// sshArgs returns the arguments of git that make it connect with SSHKey
+func sshArgs() []string {
+	ssh := []string{"ssh", "-o", "BatchMode=yes", "-o", "IdentitiesOnly=yes"}
+	if SSHKey != "" {
+		ssh = append(ssh, "-i", shellQuote(SSHKey))
+	}
+	if SSHKnownHosts != "" {
+		ssh = append(ssh, "-o", shellQuote("UserKnownHostsFile="+SSHKnownHosts))
+	} else {
+		ssh = append(ssh, "-o", "StrictHostKeyChecking=accept-new")
+	}
+	return []string{"-c", "core.sshCommand=" + strings.Join(ssh, " ")}
+}
+
+// shellQuote quotes s for sh, which runs the SSH command of git
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,57 @@
+package download
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSSHRepoRoot(t *testing.T) {
+	defer func(hosts, gitlab []string) { SSHHosts, GitLabHosts = hosts, gitlab }(SSHHosts, GitLabHosts)
+	SSHHosts = []string{"git.example.com", "gitlab.example.com"}
+	GitLabHosts = []string{"gitlab.example.com"}
+
+	cases := []struct {
+		path string
+		root string
+		repo string
+	}{
+		{"git.example.com/foo/bar", "git.example.com/foo/bar", "ssh://git@git.example.com/foo/bar.git"},
+		{"git.example.com/foo/bar/baz", "git.example.com/foo/bar", "ssh://git@git.example.com/foo/bar.git"},
+		{"git.example.com/foo/bar.git/baz", "git.example.com/foo/bar", "ssh://git@git.example.com/foo/bar.git"},
+		{"gitlab.example.com/group/sub/project", "gitlab.example.com/group/sub/project", "ssh://git@gitlab.example.com/group/sub/project.git"},
+	}
+	for _, c := range cases {
+		root, err := repoRoot(c.path)
+		if err != nil {
+			t.Errorf("[%q] repoRoot: %v", c.path, err)
+			continue
+		}
+		if root.Root != c.root || root.Repo != c.repo || root.VCS.Cmd != "git" {
+			t.Errorf("[%q] repoRoot = %q, %q, %q, want %q, %q, git", c.path, root.Root, root.Repo, root.VCS.Cmd, c.root, c.repo)
+		}
+		if got := authArgs(root, ""); !reflect.DeepEqual(got, sshArgs()) {
+			t.Errorf("[%q] authArgs = %q, want %q", c.path, got, sshArgs())
+		}
+	}
+	for _, path := range []string{"git.example.com/foo", "git.example.com//bar"} {
+		if _, err := repoRoot(path); err == nil {
+			t.Errorf("[%q] repoRoot did not fail", path)
+		}
+	}
+}
+
+func TestSSHArgs(t *testing.T) {
+	defer func(key, knownHosts string) { SSHKey, SSHKnownHosts = key, knownHosts }(SSHKey, SSHKnownHosts)
+	SSHKey = "/etc/goreportcard/deploy key"
+	SSHKnownHosts = ""
+	want := []string{"-c", "core.sshCommand=ssh -o BatchMode=yes -o IdentitiesOnly=yes -i '/etc/goreportcard/deploy key' -o StrictHostKeyChecking=accept-new"}
+	if got := sshArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sshArgs = %q, want %q", got, want)
+	}
+
+	SSHKnownHosts = "/etc/goreportcard/known_hosts"
+	want = []string{"-c", "core.sshCommand=ssh -o BatchMode=yes -o IdentitiesOnly=yes -i '/etc/goreportcard/deploy key' -o 'UserKnownHostsFile=/etc/goreportcard/known_hosts'"}
+	if got := sshArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sshArgs = %q, want %q", got, want)
+	}
+}
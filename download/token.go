@@ -0,0 +1,74 @@
+package download
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+// Tokens are the access tokens of the operator for cloning private repos,
+// by host, such as a GitHub or GitLab personal access token
+var Tokens = map[string]string{}
+
+// ParseTokens parses access tokens like github.com=TOKEN,gitlab.com=TOKEN
+func ParseTokens(s string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid access token %q, want host=token", kv)
+		}
+		tokens[parts[0]] = parts[1]
+	}
+	return tokens, nil
+}
+
+// authArgs returns the arguments of git that authenticate with token, or
+// with the token of the host of root in Tokens, or nil if the repo can be
+// cloned without them. Tokens are only sent over HTTPS. Repos on the
+// SSHHosts are cloned with SSHKey instead.
+func authArgs(root *vcs.RepoRoot, token string) []string {
+	if root.VCS.Cmd == "git" && strings.HasPrefix(root.Repo, "ssh://") && isSSH(root.Root) {
+		return sshArgs()
+	}
+	if root.VCS.Cmd != "git" || !strings.HasPrefix(root.Repo, "https://") {
+		return nil
+	}
+	if token == "" {
+		token = Tokens[host(root.Root)]
+	}
+	if token == "" || public(root.Repo) {
+		return nil
+	}
+	// GitLab takes any user name with a personal access token, but
+	// requires oauth2 for OAuth tokens
+	user := "x-access-token"
+	if IsGitLab(root.Root) {
+		user = "oauth2"
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte(user + ":" + token))
+	return []string{"-c", "http.extraHeader=Authorization: Basic " + basic}
+}
+
+// public reports whether the git repo at url can be read without
+// credentials
+func public(url string) bool {
+	return runGit("", nil, "ls-remote", "--quiet", url, "HEAD") == nil
+}
+
+// runGit runs git in dir, or in the current directory if dir is empty,
+// with the arguments auth before args. git does not prompt for
+// credentials, and the error leaves out auth, as it contains the token.
+func runGit(dir string, auth []string, args ...string) error {
+	cmd := exec.Command("git", append(auth, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
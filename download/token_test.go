@@ -0,0 +1,44 @@
+package download
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/vcs"
+)
+
+func TestParseTokens(t *testing.T) {
+	got, err := ParseTokens("github.com=abc, gitlab.example.com=d=ef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"github.com": "abc", "gitlab.example.com": "d=ef"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTokens = %v, want %v", got, want)
+	}
+	for _, s := range []string{"github.com", "=abc", "github.com="} {
+		if _, err := ParseTokens(s); err == nil {
+			t.Errorf("[%q] ParseTokens did not fail", s)
+		}
+	}
+}
+
+func TestAuthArgsWithoutToken(t *testing.T) {
+	defer func(tokens map[string]string) { Tokens = tokens }(Tokens)
+	Tokens = map[string]string{"github.com": "abc"}
+
+	cases := []struct {
+		name  string
+		root  *vcs.RepoRoot
+		token string
+	}{
+		{"no token for the host", &vcs.RepoRoot{VCS: vcs.ByCmd("git"), Repo: "https://gitlab.com/foo/bar", Root: "gitlab.com/foo/bar"}, ""},
+		{"not over HTTPS", &vcs.RepoRoot{VCS: vcs.ByCmd("git"), Repo: "git://github.com/foo/bar", Root: "github.com/foo/bar"}, "abc"},
+		{"not git", &vcs.RepoRoot{VCS: vcs.ByCmd("hg"), Repo: "https://hg.example.com/foo", Root: "hg.example.com/foo"}, "abc"},
+	}
+	for _, c := range cases {
+		if got := authArgs(c.root, c.token); got != nil {
+			t.Errorf("[%q] authArgs = %q, want none", c.name, got)
+		}
+	}
+}
@@ -1,14 +1,14 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"text/template"
 )
 
 // AboutHandler handles the about page
 func AboutHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("Serving about page")
+	slog.Debug("serving about page")
 
 	t := template.Must(template.New("about.html").Delims("[[", "]]").ParseFiles("templates/about.html"))
 	t.Execute(w, map[string]interface{}{
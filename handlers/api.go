@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gojp/goreportcard/download"
+)
+
+// APIPrefix is the path that version 1 of the JSON API is served under.
+// Its responses only change compatibly, unlike those of the pages.
+const APIPrefix = "/api/v1"
+
+// OpenAPIPath is the OpenAPI document that describes the API
+const OpenAPIPath = "assets/api/openapi.v1.json"
+
+// apiError is the body of the responses of the API to failed requests
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiBadge is the grade of a repo in the API
+type apiBadge struct {
+	Repo        string    `json:"repo"`
+	Grade       Grade     `json:"grade"`
+	Score       float64   `json:"score"`
+	BadgeURL    string    `json:"badge_url"`
+	ReportURL   string    `json:"report_url"`
+	LastRefresh time.Time `json:"last_refresh"`
+}
+
+// apiJob is the job that grades a repo in the API
+type apiJob struct {
+	Job       string `json:"job"`
+	StatusURL string `json:"status_url"`
+}
+
+// apiHighScores is the high scores of graded repos in the API
+type apiHighScores struct {
+	Count      int         `json:"count"`
+	HighScores []scoreItem `json:"high_scores"`
+	// Stats are the number of repos by their score, rounded down to a
+	// whole percentage
+	Stats []int `json:"stats"`
+}
+
+// writeAPI writes v as the JSON body of a response of the API with the
+// status code
+func writeAPI(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("could not marshal json", "error", err)
+		code, b = http.StatusInternalServerError, []byte(`{"error":"could not marshal response"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// allowGet writes an error for the requests to w whose method is not GET,
+// and reports whether the method is GET
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeAPI(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+		return false
+	}
+	return true
+}
+
+// APIReportHandler handles the request for the latest report of a repo,
+// as version 2 of the JSON report. Repos that were never graded are not
+// found.
+func APIReportHandler(w http.ResponseWriter, r *http.Request, repo string, dev bool) {
+	if !allowGet(w, r) {
+		return
+	}
+	key := repoKey(repoRef(r, repo))
+	resp, err := getReport(r, key)
+	if err != nil {
+		slog.Info("repo not in cache", "repo", key, "error", err)
+		writeAPI(w, http.StatusNotFound, apiError{"repository not graded"})
+		return
+	}
+	writeAPI(w, http.StatusOK, newReportV2(resp))
+}
+
+// APIBadgeHandler handles the request for the grade of a repo, and the
+// URL of its badge. A repo that was never graded is queued to be graded,
+// and the response is the job that grades it.
+func APIBadgeHandler(w http.ResponseWriter, r *http.Request, repo string, dev bool) {
+	if !allowGet(w, r) {
+		return
+	}
+	name, ref := repoRef(r, repo)
+	key := repoKey(name, ref)
+	resp, err := getReport(r, key)
+	if err == errPrivateReport {
+		writeAPI(w, http.StatusNotFound, apiError{"repository not graded"})
+		return
+	} else if err != nil {
+		slog.Info("repo not in cache", "repo", key, "error", err)
+		name, err := download.Clean(name)
+		if err != nil {
+			writeAPI(w, http.StatusBadRequest, apiError{"could not download repository: " + err.Error()})
+			return
+		}
+		key = repoKey(name, ref)
+		if limit, wait := rateLimit(r, key); limit != "" {
+			w.Header().Set("Retry-After", retryAfter(wait))
+			writeAPI(w, http.StatusTooManyRequests, apiError{"too many requests " + limit})
+			return
+		}
+		job, err := jobs.add(key, accessToken(r), false, "")
+		if err != nil {
+			slog.Error("could not queue repo", "repo", key, "error", err)
+			writeAPI(w, http.StatusServiceUnavailable, apiError{"could not grade repository: " + err.Error()})
+			return
+		}
+		w.Header().Set("Location", JobsPath+job.ID)
+		writeAPI(w, http.StatusAccepted, apiJob{Job: job.ID, StatusURL: JobsPath + job.ID})
+		return
+	}
+	// the grade is not stored for some repos, and the thresholds may
+	// have changed since
+	resp.Score = resp.Average * 100
+	writeAPI(w, http.StatusOK, apiBadge{
+		Repo:        key,
+		Grade:       grade(resp.Score),
+		Score:       resp.Score,
+		BadgeURL:    "https://" + *domain + "/badge/" + key,
+		ReportURL:   "https://" + *domain + "/report/" + key,
+		LastRefresh: resp.LastRefresh,
+	})
+}
+
+// APIHighScoresHandler handles the request for the repos with the highest
+// scores
+func APIHighScoresHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	scores, stats, count, err := loadHighScores()
+	if err != nil {
+		slog.Error("could not load high scores from bolt database", "error", err)
+		writeAPI(w, http.StatusInternalServerError, apiError{"could not load high scores"})
+		return
+	}
+	writeAPI(w, http.StatusOK, apiHighScores{Count: count, HighScores: scores, Stats: stats})
+}
+
+// OpenAPIHandler handles the request for the OpenAPI document of the API
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, OpenAPIPath)
+}
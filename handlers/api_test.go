@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestOpenAPIDescribesAPI(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("..", OpenAPIPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]schemaObject `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	for _, p := range []string{"/report/{repo}", "/badge/{repo}", "/high_scores", "/openapi.json"} {
+		if _, ok := doc.Paths[p]; !ok {
+			t.Errorf("OpenAPI document does not describe %s", p)
+		}
+	}
+
+	cases := []struct {
+		name string
+		typ  reflect.Type
+	}{
+		{"Error", reflect.TypeOf(apiError{})},
+		{"Badge", reflect.TypeOf(apiBadge{})},
+		{"Job", reflect.TypeOf(apiJob{})},
+		{"HighScores", reflect.TypeOf(apiHighScores{})},
+	}
+	for _, c := range cases {
+		names, _ := jsonFields(c.typ)
+		var properties []string
+		for name := range doc.Components.Schemas[c.name].Properties {
+			properties = append(properties, name)
+		}
+		sort.Strings(names)
+		sort.Strings(properties)
+		if !reflect.DeepEqual(properties, names) {
+			t.Errorf("[%q] schema properties = %q, want %q", c.name, properties, names)
+		}
+	}
+}
+
+func TestAPIReportHandler(t *testing.T) {
+	withTestDB(t, "github.com/foo/bar", "github.com/foo/bar@dev")
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/v1/report/github.com/foo/bar", 200},
+		{"GET", "/api/v1/report/github.com/foo/bar?ref=dev", 200},
+		{"GET", "/api/v1/report/github.com/foo/baz", 404},
+		{"POST", "/api/v1/report/github.com/foo/bar", 405},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, c.path, nil)
+		w := httptest.NewRecorder()
+		APIReportHandler(w, r, "github.com/foo/"+filepath.Base(r.URL.Path), false)
+		if w.Code != c.want {
+			t.Errorf("[%s %s] status = %d, want %d: %s", c.method, c.path, w.Code, c.want, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("[%s %s] Content-Type = %q, want application/json", c.method, c.path, ct)
+		}
+		if !json.Valid(w.Body.Bytes()) {
+			t.Errorf("[%s %s] body is not JSON: %s", c.method, c.path, w.Body)
+		}
+	}
+}
+
+func TestAPIBadgeHandler(t *testing.T) {
+	withTestJobs(t)
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(RepoBucket)).Put([]byte("github.com/foo/bar"), []byte(`{"average":0.95}`))
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(q *jobQueue) { jobs = q }(jobs)
+	jobs = stoppedJobQueue()
+	defer func(ip RateLimit) { IPRateLimit = ip }(IPRateLimit)
+	IPRateLimit = RateLimit{1, time.Hour}
+	ipLimiter.buckets, repoLimiter.buckets = make(map[string]*tokenBucket), make(map[string]*tokenBucket)
+
+	cases := []struct {
+		repo string
+		want int
+	}{
+		{"github.com/foo/bar", 200},
+		// never graded repos are queued, and only grading is limited
+		{"github.com/foo/baz", 202},
+		{"github.com/foo/bar", 200},
+		{"github.com/foo/qux", 429},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/api/v1/badge/"+c.repo, nil)
+		w := httptest.NewRecorder()
+		APIBadgeHandler(w, r, c.repo, false)
+		if w.Code != c.want {
+			t.Errorf("[%s] status = %d, want %d: %s", c.repo, w.Code, c.want, w.Body)
+			continue
+		}
+		switch w.Code {
+		case 200:
+			var badge apiBadge
+			if err := json.Unmarshal(w.Body.Bytes(), &badge); err != nil || badge.Grade != GradeAPlus {
+				t.Errorf("[%s] badge = %s, want grade A+", c.repo, w.Body)
+			}
+		case 202:
+			var job apiJob
+			if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil || job.Job == "" {
+				t.Fatalf("[%s] job = %s, want a job ID", c.repo, w.Body)
+			}
+			if loc := w.Header().Get("Location"); loc != JobsPath+job.Job {
+				t.Errorf("[%s] Location = %q, want %q", c.repo, loc, JobsPath+job.Job)
+			}
+			if queued, err := loadJob(job.Job); err != nil || queued.Key != c.repo {
+				t.Errorf("[%s] queued job = %+v, %v, want a job for the repo", c.repo, queued, err)
+			}
+		case 429:
+			if !json.Valid(w.Body.Bytes()) || w.Header().Get("Retry-After") == "" {
+				t.Errorf("[%s] 429 response = %s, want JSON with Retry-After", c.repo, w.Body)
+			}
+		}
+	}
+}
+
+func TestAPIHighScoresHandler(t *testing.T) {
+	withTestDB(t)
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(MetaBucket))
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte("scores"), []byte(`[{"repo":"github.com/foo/a","score":80,"files":3},{"repo":"github.com/foo/b","score":95,"files":1}]`)); err != nil {
+			return err
+		}
+		return b.Put([]byte("total_repos"), []byte("2"))
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	APIHighScoresHandler(w, httptest.NewRequest("GET", "/api/v1/high_scores", nil))
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got apiHighScores
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []scoreItem{{"github.com/foo/b", 95, 1}, {"github.com/foo/a", 80, 3}}
+	if got.Count != 2 || !reflect.DeepEqual(got.HighScores, want) || len(got.Stats) != 101 {
+		t.Errorf("APIHighScoresHandler = %+v, want 2 repos with high scores %+v", got, want)
+	}
+}
@@ -1,19 +1,19 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 )
 
 // AssetsHandler handles serving static files
 func AssetsHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("Serving " + r.URL.Path[1:])
+	slog.Debug("serving asset", "path", r.URL.Path[1:])
 
 	http.ServeFile(w, r, r.URL.Path[1:])
 }
 
 // FaviconHandler handles serving the favicon.ico
 func FaviconHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("Serving " + r.URL.Path[1:])
+	slog.Debug("serving asset", "path", r.URL.Path[1:])
 	http.ServeFile(w, r, "assets/favicon.ico")
 }
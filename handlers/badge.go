@@ -2,8 +2,9 @@ package handlers
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -23,24 +24,104 @@ const (
 	GradeF           = "F"
 )
 
+// GradeThreshold is the percentage that a score must exceed to get a
+// grade
+type GradeThreshold struct {
+	Grade Grade   `json:"grade"`
+	Min   float64 `json:"min"`
+}
+
+// GradeThresholds are the cutoffs of the grades, from the highest grade
+// down. Scores that exceed none of them get an F.
+var GradeThresholds = []GradeThreshold{
+	{GradeAPlus, 90},
+	{GradeA, 80},
+	{GradeB, 70},
+	{GradeC, 60},
+	{GradeD, 50},
+	{GradeE, 40},
+}
+
+// gradeOrder are the grades that can have a threshold, from the highest
+var gradeOrder = []Grade{GradeAPlus, GradeA, GradeB, GradeC, GradeD, GradeE}
+
+// ParseGrade parses the name of a grade, such as "A+" or "b"
+func ParseGrade(s string) (Grade, error) {
+	g := Grade(strings.ToUpper(strings.TrimSpace(s)))
+	if g != GradeF && gradeRank(g) == len(gradeOrder) {
+		return "", fmt.Errorf("unknown grade %q", s)
+	}
+	return g, nil
+}
+
+// AtLeast reports whether g is min or a higher grade
+func (g Grade) AtLeast(min Grade) bool {
+	return gradeRank(g) <= gradeRank(min)
+}
+
+// gradeRank returns the position of g from the highest grade, with F and
+// unknown grades last
+func gradeRank(g Grade) int {
+	for i, o := range gradeOrder {
+		if o == g {
+			return i
+		}
+	}
+	return len(gradeOrder)
+}
+
+// ParseGradeThresholds parses a comma separated list of grade cutoffs,
+// such as "A+=95,A=85,B=75,C=65,D=55,E=45". Higher grades must have
+// higher cutoffs, and grades that are left out are not given.
+func ParseGradeThresholds(s string) ([]GradeThreshold, error) {
+	cutoffs := make(map[Grade]float64)
+	for _, kv := range strings.Split(s, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("grade threshold %q is not grade=percentage", kv)
+		}
+		g := Grade(strings.ToUpper(strings.TrimSpace(parts[0])))
+		if gradeRank(g) == len(gradeOrder) {
+			return nil, fmt.Errorf("grade %q cannot have a threshold", parts[0])
+		}
+		if _, ok := cutoffs[g]; ok {
+			return nil, fmt.Errorf("grade %s has more than one threshold", g)
+		}
+		min, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || min < 0 || min >= 100 {
+			return nil, fmt.Errorf("threshold of %s must be a percentage from 0 up to 100", g)
+		}
+		cutoffs[g] = min
+	}
+	if len(cutoffs) == 0 {
+		return nil, fmt.Errorf("no grade thresholds in %q", s)
+	}
+
+	var thresholds []GradeThreshold
+	for _, g := range gradeOrder {
+		min, ok := cutoffs[g]
+		if !ok {
+			continue
+		}
+		if n := len(thresholds); n > 0 && min >= thresholds[n-1].Min {
+			return nil, fmt.Errorf("threshold of %s must be below the threshold of %s", g, thresholds[n-1].Grade)
+		}
+		thresholds = append(thresholds, GradeThreshold{g, min})
+	}
+	return thresholds, nil
+}
+
 // grade is a helper for getting the grade for a percentage
 func grade(percentage float64) Grade {
-	switch {
-	case percentage > 90:
-		return GradeAPlus
-	case percentage > 80:
-		return GradeA
-	case percentage > 70:
-		return GradeB
-	case percentage > 60:
-		return GradeC
-	case percentage > 50:
-		return GradeD
-	case percentage > 40:
-		return GradeE
-	default:
-		return GradeF
+	for _, t := range GradeThresholds {
+		if percentage > t.Min {
+			return t.Grade
+		}
 	}
+	return GradeF
 }
 
 func badgePath(grade Grade, style string, dev bool) string {
@@ -49,8 +130,7 @@ func badgePath(grade Grade, style string, dev bool) string {
 
 // BadgeHandler handles fetching the badge images
 func BadgeHandler(w http.ResponseWriter, r *http.Request, repo string, dev bool) {
-	name := fmt.Sprintf("%s", repo)
-	resp, err := newChecksResp(name, false)
+	name, ref := repoRef(r, repo)
 
 	// See: http://shields.io/#styles
 	style := r.URL.Query().Get("style")
@@ -58,12 +138,25 @@ func BadgeHandler(w http.ResponseWriter, r *http.Request, repo string, dev bool)
 		style = "flat"
 	}
 
+	key := repoKey(name, ref)
+	if g, ok := cachedGrade(key); ok {
+		w.Header().Set("Cache-control", "no-store, no-cache, must-revalidate")
+		http.ServeFile(w, r, badgePath(g, style, dev))
+		return
+	}
+
+	// only requests that grade the repo are limited
+	if _, err := getReport(r, key); err != nil && rateLimited(w, r, key) {
+		return
+	}
+	resp, err := newChecksResp(r.Context(), name, ref, "", false)
 	if err != nil {
-		log.Printf("ERROR: fetching badge for %s: %v", name, err)
+		slog.Error("could not fetch badge", "repo", name, "error", err)
 		url := "https://img.shields.io/badge/go%20report-error-lightgrey.svg?style=" + style
 		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 		return
 	}
+	cacheGrade(key, resp.Grade)
 
 	w.Header().Set("Cache-control", "no-store, no-cache, must-revalidate")
 	http.ServeFile(w, r, badgePath(resp.Grade, style, dev))
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrade(t *testing.T) {
+	cases := []struct {
+		percentage float64
+		want       Grade
+	}{
+		{100, GradeAPlus},
+		{90.5, GradeAPlus},
+		{90, GradeA},
+		{75, GradeB},
+		{41, GradeE},
+		{40, GradeF},
+		{0, GradeF},
+	}
+	for _, tt := range cases {
+		if got := grade(tt.percentage); got != tt.want {
+			t.Errorf("[%v] grade = %q, want %q", tt.percentage, got, tt.want)
+		}
+	}
+}
+
+func TestGradeCustomThresholds(t *testing.T) {
+	defer func(old []GradeThreshold) { GradeThresholds = old }(GradeThresholds)
+	GradeThresholds = []GradeThreshold{{GradeAPlus, 95}, {GradeB, 60}}
+
+	cases := []struct {
+		percentage float64
+		want       Grade
+	}{
+		{96, GradeAPlus},
+		{95, GradeB},
+		{61, GradeB},
+		{60, GradeF},
+	}
+	for _, tt := range cases {
+		if got := grade(tt.percentage); got != tt.want {
+			t.Errorf("[%v] grade = %q, want %q", tt.percentage, got, tt.want)
+		}
+	}
+}
+
+func TestParseGradeThresholds(t *testing.T) {
+	cases := []struct {
+		s       string
+		want    []GradeThreshold
+		wantErr bool
+	}{
+		{s: "A+=90,A=80,B=70,C=60,D=50,E=40", want: GradeThresholds},
+		{s: " b=70, a+=95 ", want: []GradeThreshold{{GradeAPlus, 95}, {GradeB, 70}}},
+		{s: "A=80,A+=80", wantErr: true},
+		{s: "A=80,A=70", wantErr: true},
+		{s: "F=10", wantErr: true},
+		{s: "A=100", wantErr: true},
+		{s: "A=-1", wantErr: true},
+		{s: "A", wantErr: true},
+		{s: "", wantErr: true},
+	}
+	for _, tt := range cases {
+		got, err := ParseGradeThresholds(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("[%q] error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%q] thresholds = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestGradeAtLeast(t *testing.T) {
+	cases := []struct {
+		grade, min string
+		want       bool
+	}{
+		{"A+", "b", true},
+		{"B", "B", true},
+		{"C", "B", false},
+		{"F", "E", false},
+		{"F", "f", true},
+	}
+	for _, tt := range cases {
+		min, err := ParseGrade(tt.min)
+		if err != nil {
+			t.Fatalf("[%q] ParseGrade: %v", tt.min, err)
+		}
+		if got := Grade(tt.grade).AtLeast(min); got != tt.want {
+			t.Errorf("[%q] AtLeast(%q) = %v, want %v", tt.grade, tt.min, got, tt.want)
+		}
+	}
+	for _, s := range []string{"B+", "G", ""} {
+		if _, err := ParseGrade(s); err == nil {
+			t.Errorf("[%q] ParseGrade succeeded, want an error", s)
+		}
+	}
+}
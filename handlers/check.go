@@ -4,10 +4,9 @@ import (
 	"container/heap"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/gojp/goreportcard/download"
@@ -25,116 +24,135 @@ const (
 	MetaBucket string = "meta"
 )
 
+// accessToken returns the access token of a request for a private repo,
+// from a bearer Authorization header or the token field of a POSTed form.
+// Tokens in the URL would end up in the logs of proxies, so the query is
+// ignored.
+func accessToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.PostFormValue("token")
+}
+
+// repoRef returns the import path of the repo at path and the ref of it
+// to grade, from a path like github.com/foo/bar@v1.2.3 or else from the
+// ref parameter of the request
+func repoRef(r *http.Request, path string) (repo, ref string) {
+	repo, ref = download.SplitRef(path)
+	if ref == "" {
+		ref = r.FormValue("ref")
+	}
+	return repo, ref
+}
+
 // CheckHandler handles the request for checking a repo
 func CheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	repo, err := download.Clean(r.FormValue("repo"))
+	path, ref := repoRef(r, r.FormValue("repo"))
+	repo, err := download.Clean(path)
 	if err != nil {
-		log.Println("ERROR: from download.Clean:", err)
+		slog.Warn("could not clean repo name", "repo", r.FormValue("repo"), "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(`Could not download the repository: ` + err.Error()))
 		return
 	}
+	key := repoKey(repo, ref)
 
-	log.Printf("Checking repo %q...", repo)
+	var minGrade Grade
+	if s := r.FormValue("min_grade"); s != "" {
+		minGrade, err = ParseGrade(s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`Invalid min_grade: ` + err.Error()))
+			return
+		}
+	}
+
+	slog.Info("checking repo", "repo", repo, "ref", ref)
 
 	forceRefresh := r.Method != "GET" // if this is a GET request, try to fetch from cached version in boltdb first
-	resp, err := newChecksResp(repo, forceRefresh)
-	if err != nil {
-		log.Println("ERROR: from newChecksResp:", err)
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`Could not download the repository.`))
+	if !forceRefresh {
+		if resp, err := getReport(r, key); err == nil {
+			cacheLookups.add(1, "report", "hit")
+			result := map[string]interface{}{"redirect": "/report/" + key}
+			if minGrade != "" {
+				// for automation that fails when the grade drops
+				resp.Grade = grade(resp.Average * 100)
+				result["grade"] = resp.Grade
+				result["score"] = resp.Average * 100
+				result["min_grade"] = minGrade
+				result["passed"] = resp.Grade.AtLeast(minGrade)
+			}
+			b, err := json.Marshal(result)
+			if err != nil {
+				slog.Error("could not marshal json", "repo", repo, "error", err)
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(b)
+			return
+		}
+	}
+	// only requests that grade the repo are limited
+	if rateLimited(w, r, key) {
 		return
 	}
 
-	respBytes, err := json.Marshal(resp)
+	// grading takes too long for proxies to wait for, so the repo is
+	// graded by a job whose status the client polls
+	job, err := jobs.add(key, accessToken(r), forceRefresh, minGrade)
 	if err != nil {
-		log.Println("ERROR: could not marshal json:", err)
-		http.Error(w, err.Error(), 500)
+		slog.Error("could not queue repo", "repo", repo, "error", err)
+		http.Error(w, "Could not grade the repository: "+err.Error(), http.StatusServiceUnavailable)
 		return
 	}
-
-	// write to boltdb
-	db, err := bolt.Open(DBPath, 0755, &bolt.Options{Timeout: 1 * time.Second})
+	b, err := json.Marshal(job.result())
 	if err != nil {
-		log.Println("Failed to open bolt database: ", err)
-		http.Error(w, err.Error(), 500)
-		return
+		slog.Error("could not marshal json", "repo", repo, "error", err)
 	}
-	defer db.Close()
+	w.Header().Set("Location", JobsPath+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(b)
+}
 
-	// is this a new repo? if so, increase the count in the high scores bucket later
-	isNewRepo := false
-	var oldRepoBytes []byte
-	err = db.View(func(tx *bolt.Tx) error {
+// saveResp saves the grade resp, marshalled to respBytes, of the repo
+// under key in the repo bucket and counts it in the metadata, unless the
+// repo was graded before and overwrite is false
+func saveResp(db *bolt.DB, key string, resp checksResp, respBytes []byte, overwrite bool) error {
+	return db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(RepoBucket))
 		if b == nil {
 			return fmt.Errorf("repo bucket not found")
 		}
-		oldRepoBytes = b.Get([]byte(repo))
-		return nil
-	})
-	if err != nil {
-		log.Println(err)
-	}
-
-	// get the old score and store it for stats updating
-	var oldScore *float64
-	if isNewRepo = oldRepoBytes == nil; !isNewRepo {
-		oldRepo := checksResp{}
-		err = json.Unmarshal(oldRepoBytes, &oldRepo)
-		if err != nil {
-			log.Println("ERROR: could not unmarshal json:", err)
-			http.Error(w, err.Error(), 500)
-			return
-		}
-		oldScore = &oldRepo.Average
-	}
-
-	// if this is a new repo, or the user force-refreshed, update the cache
-	if isNewRepo || forceRefresh {
-		err = db.Update(func(tx *bolt.Tx) error {
-			log.Printf("Saving repo %q to cache...", repo)
 
-			b := tx.Bucket([]byte(RepoBucket))
-			if b == nil {
-				return fmt.Errorf("repo bucket not found")
+		// get the old score and store it for stats updating
+		var oldScore *float64
+		oldRepoBytes := b.Get([]byte(key))
+		isNewRepo := oldRepoBytes == nil
+		if !isNewRepo {
+			if !overwrite {
+				return nil
 			}
-
-			// save repo to cache
-			err = b.Put([]byte(repo), respBytes)
-			if err != nil {
-				return err
+			oldRepo := checksResp{}
+			if err := json.Unmarshal(oldRepoBytes, &oldRepo); err != nil {
+				return fmt.Errorf("could not unmarshal json: %v", err)
 			}
-			return updateMetadata(tx, resp, repo, isNewRepo, oldScore)
-		})
-
-		if err != nil {
-			log.Println("Bolt writing error:", err)
+			oldScore = &oldRepo.Average
 		}
 
-	}
-
-	db.Update(func(tx *bolt.Tx) error {
-		// fetch meta-bucket
-		mb := tx.Bucket([]byte(MetaBucket))
-		return updateRecentlyViewed(mb, repo)
+		slog.Info("saving repo to cache", "repo", key)
+		if err := b.Put([]byte(key), respBytes); err != nil {
+			return err
+		}
+		return updateMetadata(tx, resp, key, isNewRepo, oldScore)
 	})
-
-	b, err := json.Marshal(map[string]string{"redirect": "/report/" + repo})
-	if err != nil {
-		log.Println("JSON marshal error:", err)
-	}
-	w.WriteHeader(http.StatusOK)
-	w.Write(b)
-	return
 }
 
 func updateHighScores(mb *bolt.Bucket, resp checksResp, repo string) error {
 	// check if we need to update the high score list
-	if resp.Files < 100 {
-		// only repos with >= 100 files are considered for the high score list
+	if resp.Files < 100 || resp.Private {
+		// only public repos with >= 100 files are considered for the high score list
 		return nil
 	}
 
@@ -207,7 +225,7 @@ func updateStats(mb *bolt.Bucket, resp checksResp, oldScore *float64) error {
 }
 
 func updateReposCount(mb *bolt.Bucket, resp checksResp, repo string) (err error) {
-	log.Printf("New repo %q, adding to repo count...", repo)
+	slog.Info("adding new repo to repo count", "repo", repo)
 	totalInt := 0
 	total := mb.Get([]byte("total_repos"))
 	if total != nil {
@@ -222,7 +240,7 @@ func updateReposCount(mb *bolt.Bucket, resp checksResp, repo string) (err error)
 		return fmt.Errorf("could not marshal total repos count: %v", err)
 	}
 	mb.Put([]byte("total_repos"), total)
-	log.Println("Repo count is now", totalInt)
+	slog.Info("updated repo count", "total_repos", totalInt)
 	return nil
 }
 
@@ -271,6 +289,10 @@ func updateMetadata(tx *bolt.Tx, resp checksResp, repo string, isNewRepo bool, o
 	if mb == nil {
 		return fmt.Errorf("high score bucket not found")
 	}
+	if resp.Ref != "" {
+		// only the default branches of repos are counted and ranked
+		return nil
+	}
 	// update total repos count
 	if isNewRepo {
 		err := updateReposCount(mb, resp, repo)
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestAccessToken(t *testing.T) {
+	cases := []struct {
+		header string
+		query  string
+		body   string
+		want   string
+	}{
+		{"Bearer abc", "", "", "abc"},
+		{"Bearer abc", "", "token=def", "abc"},
+		{"Basic abc", "", "token=def", "def"},
+		// tokens in the URL are ignored
+		{"", "token=def", "", ""},
+		{"", "", "", ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", "/checks?repo=github.com/foo/bar&"+c.query, strings.NewReader(c.body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+		if got := accessToken(r); got != c.want {
+			t.Errorf("[%q %q %q] accessToken = %q, want %q", c.header, c.query, c.body, got, c.want)
+		}
+	}
+}
+
+func TestGetReportOfPrivateRepo(t *testing.T) {
+	mem := &memStore{reports: map[string][]byte{}}
+	defer func(s Store) { DefaultStore = s }(DefaultStore)
+	DefaultStore = mem
+	b, err := json.Marshal(checksResp{Repo: "github.com/foo/private", Private: true, AccessHash: tokenHash("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem.reports["github.com/foo/private"] = b
+
+	for _, c := range []struct {
+		auth string
+		ok   bool
+	}{
+		{"", false},
+		{"Bearer other", false},
+		{"Bearer secret", true},
+	} {
+		r := httptest.NewRequest("GET", "/report/github.com/foo/private", nil)
+		if c.auth != "" {
+			r.Header.Set("Authorization", c.auth)
+		}
+		resp, err := getReport(r, "github.com/foo/private")
+		if c.ok && (err != nil || resp.AccessHash != "") {
+			t.Errorf("[%q] getReport = %+v, %v, want the report without its hash", c.auth, resp, err)
+		}
+		if !c.ok && err != errPrivateReport {
+			t.Errorf("[%q] getReport error = %v, want %v", c.auth, err, errPrivateReport)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	APIReportHandler(w, httptest.NewRequest("GET", "/api/v2/report/github.com/foo/private", nil), "github.com/foo/private", false)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("report of a private repo without a token = %d, want 404", w.Code)
+	}
+}
+
+func TestUpdateHighScoresSkipsPrivateRepos(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goreportcard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(MetaBucket))
+		if err != nil {
+			return err
+		}
+		resp := checksResp{Repo: "github.com/foo/private", Private: true, Files: 200, Average: 0.9}
+		if err := updateHighScores(b, resp, resp.Repo); err != nil {
+			return err
+		}
+		if scores := b.Get([]byte("scores")); scores != nil {
+			t.Errorf("high scores = %s, want none", scores)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepoRef(t *testing.T) {
+	cases := []struct {
+		url, path string
+		key       string
+	}{
+		{"/checks", "github.com/foo/bar", "github.com/foo/bar"},
+		{"/checks", "github.com/foo/bar@v1.2.3", "github.com/foo/bar@v1.2.3"},
+		{"/checks?ref=develop", "github.com/foo/bar", "github.com/foo/bar@develop"},
+		{"/checks?ref=develop", "github.com/foo/bar@v1.2.3", "github.com/foo/bar@v1.2.3"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", c.url, nil)
+		if got := repoKey(repoRef(r, c.path)); got != c.key {
+			t.Errorf("[%q %q] repoKey = %q, want %q", c.url, c.path, got, c.key)
+		}
+	}
+}
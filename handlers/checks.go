@@ -1,176 +1,477 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
+	"sync"
 	"time"
 
-	"github.com/boltdb/bolt"
 	humanize "github.com/dustin/go-humanize"
 	"github.com/gojp/goreportcard/check"
 	"github.com/gojp/goreportcard/download"
 )
 
+// Snippets attaches the source around every issue to the issue, so
+// that the report can show the code
+var Snippets = false
+
+// repoKey returns the key of the grades of ref of repo, such as
+// github.com/foo/bar@v1.2.3, or repo for its default branch
+func repoKey(repo, ref string) string {
+	if ref == "" {
+		return repo
+	}
+	return repo + "@" + ref
+}
+
 func dirName(repo string) string {
-	return fmt.Sprintf("repos/src/%s", repo)
+	return check.RepoDir(repo)
 }
 
+// getFromCache returns the report with the key, whether or not it is
+// private. Reports that are served to clients are read with getReport.
 func getFromCache(repo string) (checksResp, error) {
-	// try and fetch from boltdb
-	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
-	if err != nil {
-		return checksResp{}, fmt.Errorf("failed to open bolt database during GET: %v", err)
-	}
-	defer db.Close()
-
 	resp := checksResp{}
-	err = db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(RepoBucket))
-		if b == nil {
-			return errors.New("No repo bucket")
-		}
-		cached := b.Get([]byte(repo))
-		if cached == nil {
-			return fmt.Errorf("%q not found in cache", repo)
-		}
-
-		err = json.Unmarshal(cached, &resp)
-		if err != nil {
-			return fmt.Errorf("failed to parse JSON for %q in cache", repo)
-		}
-		return nil
-	})
-
+	cached, err := DefaultStore.Report(repo)
 	if err != nil {
 		return resp, err
 	}
+	if err := json.Unmarshal(cached, &resp); err != nil {
+		return resp, fmt.Errorf("failed to parse JSON for %q in cache", repo)
+	}
 
 	resp.LastRefresh = resp.LastRefresh.UTC()
 	resp.HumanizedLastRefresh = humanize.Time(resp.LastRefresh.UTC())
+	resp.Percentile = percentile(resp.Average * 100)
 
 	return resp, nil
 }
 
-type score struct {
-	Name          string              `json:"name"`
-	Description   string              `json:"description"`
-	FileSummaries []check.FileSummary `json:"file_summaries"`
-	Weight        float64             `json:"weight"`
-	Percentage    float64             `json:"percentage"`
-	Error         string              `json:"error"`
+// errPrivateReport is returned for a private report that is requested
+// without the access token it was graded with
+var errPrivateReport = errors.New("the report is private")
+
+// getReport returns the report with the key like getFromCache, unless it
+// is private and r does not carry the access token it was graded with
+func getReport(r *http.Request, key string) (checksResp, error) {
+	resp, err := getFromCache(key)
+	if err != nil {
+		return resp, err
+	}
+	if !resp.readableWith(accessToken(r)) {
+		return checksResp{}, errPrivateReport
+	}
+	resp.AccessHash = ""
+	return resp, nil
+}
+
+// tokenHash returns the hash of an access token that reports graded with
+// it keep, so that the token itself is not stored
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// readableWith reports whether the report can be served to a request
+// with the access token. Reports of repos cloned with a token of the
+// request are only served with the same token.
+func (resp checksResp) readableWith(token string) bool {
+	if resp.AccessHash == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(tokenHash(token)), []byte(resp.AccessHash)) == 1
 }
 
 type checksResp struct {
-	Checks               []score   `json:"checks"`
-	Average              float64   `json:"average"`
-	Grade                Grade     `json:"grade"`
-	Files                int       `json:"files"`
-	Issues               int       `json:"issues"`
-	Repo                 string    `json:"repo"`
+	Checks                    []check.CheckResult    `json:"checks"`
+	Average                   float64                `json:"average"`
+	Score                     float64                `json:"score"`
+	Percentile                *float64               `json:"percentile,omitempty"`
+	Grade                     Grade                  `json:"grade"`
+	GradeThresholds           []GradeThreshold       `json:"grade_thresholds"`
+	Files                     int                    `json:"files"`
+	Excluded                  []string               `json:"excluded,omitempty"`
+	Issues                    int                    `json:"issues"`
+	Suppressed                int                    `json:"suppressed,omitempty"`
+	Baselined                 int                    `json:"baselined,omitempty"`
+	Severities                map[string]int         `json:"severities,omitempty"`
+	Weights                   map[string]float64     `json:"weights,omitempty"`
+	Packages                  []packageScore         `json:"packages,omitempty"`
+	Modules                   []packageScore         `json:"modules,omitempty"`
+	Previous                  *previousRun           `json:"previous,omitempty"`
+	Repo                      string                 `json:"repo"`
+	Ref                       string                 `json:"ref,omitempty"`
+	Private                   bool                   `json:"private,omitempty"`
+	Commit                    string                 `json:"commit,omitempty"`
+	License                   string                 `json:"license,omitempty"`
+	Dependencies              *check.DependencyStats `json:"dependencies,omitempty"`
+	HumanizedDependenciesSize string                 `json:"humanized_dependencies_size,omitempty"`
+	Settings                  []string               `json:"settings,omitempty"`
+	Artifacts                 []string               `json:"artifacts,omitempty"`
+	// AccessHash is the tokenHash of the access token that the private
+	// repo was cloned with, of the request or of the server, if any.
+	// getReport clears it.
+	AccessHash           string    `json:"access_hash,omitempty"`
 	LastRefresh          time.Time `json:"last_refresh"`
 	HumanizedLastRefresh string    `json:"humanized_last_refresh"`
 }
 
-func newChecksResp(repo string, forceRefresh bool) (checksResp, error) {
+// newChecksResp grades ref of repo, or its default branch if ref is
+// empty, or returns the cached result unless forceRefresh is set. The
+// repo is cloned with the access token if it is private. Grading stops when ctx is done, for example
+// because the client went away. The progress of grading is published
+// to the clients of ProgressHandler.
+func newChecksResp(ctx context.Context, repo, ref, token string, forceRefresh bool) (checksResp, error) {
+	cached, cacheErr := getFromCache(repoKey(repo, ref))
+	if cacheErr == nil && !cached.readableWith(token) {
+		// neither the report nor its issues are compared against
+		cacheErr = errPrivateReport
+	}
 	if !forceRefresh {
-		resp, err := getFromCache(repo)
-		if err != nil {
+		if cacheErr != nil {
 			// just log the error and continue
-			log.Println(err)
+			slog.Info("repo not in cache", "repo", repo, "error", cacheErr)
+			cacheLookups.add(1, "report", "miss")
 		} else {
-			resp.Grade = grade(resp.Average * 100) // grade is not stored for some repos, yet
-			return resp, nil
+			cacheLookups.add(1, "report", "hit")
+			// the grade is not stored for some repos, yet, and the
+			// thresholds may have changed since
+			cached.Score = cached.Average * 100
+			cached.Grade = grade(cached.Score)
+			cached.GradeThresholds = GradeThresholds
+			for i := range cached.Packages {
+				cached.Packages[i].Grade = grade(cached.Packages[i].Percentage * 100)
+			}
+			for i := range cached.Modules {
+				cached.Modules[i].Grade = grade(cached.Modules[i].Percentage * 100)
+			}
+			return cached, nil
 		}
 	}
 
+	key := repoKey(repo, ref)
+	// the refs and directories of a repo are graded in its checkout
+	unlock := lockRepo(repo)
+	defer unlock()
+	graded := false
+	gradesInProgress.add(1)
+	gradeStarted := time.Now()
+	defer func() {
+		gradesInProgress.add(-1)
+		gradeDuration.observe(time.Since(gradeStarted).Seconds())
+		if graded {
+			gradesTotal.add(1, "success")
+			return
+		}
+		gradesTotal.add(1, "failure")
+		progress.publish(key, progressEvent{Stage: stageFailed, Message: "grading failed"})
+	}()
+
 	// fetch the repo and grade it
-	repoRoot, err := download.Download(repo, "repos/src")
+	progress.publish(key, progressEvent{Stage: stageCloning, Message: "cloning"})
+	downloaded, err := download.DownloadRef(repo, check.ReposDir, ref, token)
 	if err != nil {
+		cloneDuration.observe(time.Since(gradeStarted).Seconds(), "failure")
 		return checksResp{}, fmt.Errorf("could not clone repo: %v", err)
 	}
+	cloneDuration.observe(time.Since(gradeStarted).Seconds(), "success")
 
-	repo = repoRoot.Root
+	repo = downloaded.ImportPath()
 
+	started := time.Now()
+	logger := slog.Default().With("repo", repo)
 	dir := dirName(repo)
-	filenames, skipped, err := check.GoFiles(dir)
+	if r := downloaded.Ref(); r != "" {
+		// the files of a module zip are at its version
+		check.SetRef(dir, r)
+	} else {
+		check.SetRef(dir, ref)
+	}
+	checker := check.Checker{
+		Logger:   check.SlogLogger(logger),
+		Snippets: Snippets,
+		Progress: func(p check.Progress) {
+			progress.publish(key, checkEvent(p))
+		},
+	}
+	if FileCacheSize > 0 {
+		checker.Cache = boltFileCache{}
+	}
+	if StoreArtifacts && artifactStore != nil {
+		if checker.Artifacts, err = os.MkdirTemp("", "goreportcard-artifacts-"); err != nil {
+			logger.Warn("could not create artifacts directory", "error", err)
+		} else {
+			defer os.RemoveAll(checker.Artifacts)
+		}
+	}
+	commit, err := check.HeadCommit(dir)
+	if err != nil {
+		logger.Info("could not get commit", "error", err)
+	} else if cacheErr == nil {
+		checker.Previous = previousGrade(dir, cached, commit, logger)
+	}
+	progress.publish(key, progressEvent{Stage: stageLoading, Message: "loading packages"})
+	pkgs, skipped, err := checker.LoadPackages(ctx, dir)
 	if err != nil {
 		return checksResp{}, fmt.Errorf("could not get filenames: %v", err)
 	}
+	filenames := check.PackageFiles(pkgs)
 	if len(filenames) == 0 {
 		return checksResp{}, fmt.Errorf("no .go files found")
 	}
 
 	err = check.RenameFiles(skipped)
 	if err != nil {
-		log.Println("Could not remove files:", err)
+		logger.Warn("could not remove skipped files", "error", err)
 	}
 	defer check.RevertFiles(skipped)
 
-	checks := []check.Check{
-		check.GoFmt{Dir: dir, Filenames: filenames},
-		check.GoVet{Dir: dir, Filenames: filenames},
-		check.GoLint{Dir: dir, Filenames: filenames},
-		check.GoCyclo{Dir: dir, Filenames: filenames},
-		check.License{Dir: dir, Filenames: []string{}},
-		check.Misspell{Dir: dir, Filenames: filenames},
-		check.IneffAssign{Dir: dir, Filenames: filenames},
-		// check.ErrCheck{Dir: dir, Filenames: filenames}, // disable errcheck for now, too slow and not finalized
-	}
-
-	ch := make(chan score)
-	for _, c := range checks {
-		go func(c check.Check) {
-			p, summaries, err := c.Percentage()
-			errMsg := ""
-			if err != nil {
-				log.Printf("ERROR: (%s) %v", c.Name(), err)
-				errMsg = err.Error()
-			}
-			s := score{
-				Name:          c.Name(),
-				Description:   c.Description(),
-				FileSummaries: summaries,
-				Weight:        c.Weight(),
-				Percentage:    p,
-				Error:         errMsg,
-			}
-			ch <- s
-		}(c)
+	results := checker.RunAll(check.WithPackages(ctx, pkgs), dir, filenames)
+	if err := ctx.Err(); err != nil {
+		// the results are incomplete, so do not cache them
+		return checksResp{}, fmt.Errorf("grading stopped: %v", err)
 	}
 
 	resp := checksResp{
 		Repo:                 repo,
+		Ref:                  ref,
+		Private:              downloaded.Private,
+		Commit:               commit,
 		Files:                len(filenames),
+		Excluded:             repoFiles(dir, skipped),
 		LastRefresh:          time.Now().UTC(),
 		HumanizedLastRefresh: humanize.Time(time.Now().UTC()),
 	}
+	if downloaded.Token != "" {
+		resp.AccessHash = tokenHash(downloaded.Token)
+	}
 
-	var total, totalWeight float64
 	var issues = make(map[string]bool)
-	for i := 0; i < len(checks); i++ {
-		s := <-ch
+	resp.License, _, err = check.DetectLicense(dir)
+	if err != nil {
+		logger.Warn("could not detect license", "error", err)
+	}
+
+	if cfg, err := check.LoadRepoConfig(dir); err == nil {
+		resp.Settings = cfg.Settings()
+	}
+
+	deps, err := checker.DependencyStats(ctx, dir)
+	if err != nil {
+		logger.Warn("could not get dependency stats", "error", err)
+	} else if deps.Modules() > 0 {
+		resp.Dependencies = &deps
+		resp.HumanizedDependenciesSize = humanize.Bytes(uint64(deps.DownloadSize))
+	}
+
+	for _, s := range results {
+		checkDuration.observe(s.Duration.Seconds(), s.Name)
+		if s.Error != "" && s.Status != check.StatusSkipped {
+			checkErrors.add(1, s.Name)
+		}
 		resp.Checks = append(resp.Checks, s)
-		total += s.Percentage * s.Weight
-		totalWeight += s.Weight
+		resp.Suppressed += s.Suppressed
+		resp.Baselined += s.Baselined
+		for sev, n := range s.Severities {
+			if resp.Severities == nil {
+				resp.Severities = make(map[string]int)
+			}
+			resp.Severities[sev] += n
+		}
 		for _, fs := range s.FileSummaries {
 			issues[fs.Filename] = true
 		}
 	}
-	total /= totalWeight
+	total := average(results)
+	resp.Weights = weightShares(results)
+	for _, ps := range check.PackageScores(dir, pkgs, results) {
+		resp.Packages = append(resp.Packages, packageScore{PackageScore: ps, Grade: grade(ps.Percentage * 100)})
+	}
+	// the modules are only broken down in repos with several
+	if mods, err := check.FindModules(dir); err != nil {
+		logger.Warn("could not find modules", "error", err)
+	} else if len(mods) > 1 {
+		for _, ms := range check.ModuleScores(dir, mods, pkgs, results) {
+			resp.Modules = append(resp.Modules, packageScore{PackageScore: ms, Grade: grade(ms.Percentage * 100)})
+		}
+	}
 
-	sort.Sort(ByWeight(resp.Checks))
+	// checks of the same weight stay in the order they are run in
+	sort.Stable(ByWeight(resp.Checks))
 	resp.Average = total
 	resp.Issues = len(issues)
-	resp.Grade = grade(total * 100)
+	resp.Score = total * 100
+	resp.Percentile = percentile(resp.Score)
+	resp.Grade = grade(resp.Score)
+	resp.GradeThresholds = GradeThresholds
+	if cacheErr == nil {
+		resp.Previous = newPreviousRun(dir, cached, results)
+	}
+	logger.Info("graded repo", "grade", resp.Grade, "files", resp.Files, "duration", time.Since(started))
+	if checker.Artifacts != "" {
+		if resp.Artifacts, err = saveArtifacts(key, resp, checker.Artifacts); err != nil {
+			logger.Warn("could not store artifacts", "error", err)
+		}
+	}
+	graded = true
+	if err := recordHistory(resp); err != nil {
+		logger.Error("could not record history", "error", err)
+	}
+	progress.publish(key, progressEvent{Stage: stageDone, Message: "done"})
 
 	return resp, nil
 }
 
+// repoLock is held while a ref or directory of a repo is graded
+type repoLock struct {
+	sync.Mutex
+	// waiting counts the holder of the lock and the graders waiting for it
+	waiting int
+}
+
+var (
+	repoLocksMu sync.Mutex
+	repoLocks   = make(map[string]*repoLock)
+)
+
+// lockRepo waits until no other ref or directory of the repo is graded,
+// and returns the function that lets the next one be graded. Refs and
+// directories of a repo share its checkout, which grading checks out the
+// ref in and changes the files of.
+func lockRepo(repo string) (unlock func()) {
+	root, err := download.Root(repo)
+	if err != nil {
+		// the repo cannot be cloned either
+		root = repo
+	}
+	repoLocksMu.Lock()
+	l := repoLocks[root]
+	if l == nil {
+		l = &repoLock{}
+		repoLocks[root] = l
+	}
+	l.waiting++
+	repoLocksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		repoLocksMu.Lock()
+		if l.waiting--; l.waiting == 0 {
+			delete(repoLocks, root)
+		}
+		repoLocksMu.Unlock()
+	}
+}
+
+// repoFiles returns paths in the repo in dir relative to dir, with
+// forward slashes
+func repoFiles(dir string, paths []string) []string {
+	var rel []string
+	for _, p := range paths {
+		if r, err := filepath.Rel(dir, p); err == nil {
+			p = r
+		}
+		rel = append(rel, filepath.ToSlash(p))
+	}
+	return rel
+}
+
+// previousGrade returns the grade of an earlier commit of the repo in
+// dir, for grading the commit checked out now incrementally, or nil if
+// the repo must be graded from scratch
+func previousGrade(dir string, prev checksResp, commit string, logger *slog.Logger) *check.Previous {
+	if prev.Commit == "" {
+		return nil
+	}
+	changed, err := check.ChangedFiles(dir, prev.Commit, commit)
+	if err != nil {
+		// the earlier commit is gone after a force push
+		logger.Info("could not diff against previous grade", "commit", prev.Commit, "error", err)
+		return nil
+	}
+	logger.Debug("grading incrementally", "commit", prev.Commit, "changed", len(changed))
+	return &check.Previous{Results: prev.Checks, Changed: changed}
+}
+
+// previousRun is the grade before the latest grade of a repo, with the
+// changes of the checks since
+type previousRun struct {
+	Commit      string             `json:"commit,omitempty"`
+	Grade       Grade              `json:"grade"`
+	Score       float64            `json:"score"`
+	LastRefresh time.Time          `json:"last_refresh"`
+	Checks      []check.CheckDelta `json:"checks"`
+}
+
+// newPreviousRun returns the grade prev of the repo in dir, compared to
+// the results of grading it again
+func newPreviousRun(dir string, prev checksResp, results []check.CheckResult) *previousRun {
+	return &previousRun{
+		Commit:      prev.Commit,
+		Grade:       grade(prev.Average * 100),
+		Score:       prev.Average * 100,
+		LastRefresh: prev.LastRefresh,
+		Checks:      check.Deltas(dir, prev.Checks, results),
+	}
+}
+
+// weightShares returns the shares of the checks in the weighted average
+// of the results, by check name
+func weightShares(results []check.CheckResult) map[string]float64 {
+	var totalWeight float64
+	for _, s := range results {
+		totalWeight += s.Weight
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+	shares := make(map[string]float64, len(results))
+	for _, s := range results {
+		shares[s.Name] = s.Weight / totalWeight
+	}
+	return shares
+}
+
+// GradeResults returns the grade of the results of the checks run on a
+// repo, and its score out of 100
+func GradeResults(results []check.CheckResult) (Grade, float64) {
+	score := average(results) * 100
+	return grade(score), score
+}
+
+// average returns the weighted average percentage of the results
+func average(results []check.CheckResult) float64 {
+	var total, totalWeight float64
+	for _, s := range results {
+		total += s.Percentage * s.Weight
+		totalWeight += s.Weight
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return total / totalWeight
+}
+
+// packageScore is the score of a package or a module of a repo, with its
+// grade
+type packageScore struct {
+	check.PackageScore
+	Grade Grade `json:"grade"`
+}
+
 // ByWeight implements sorting for checks by weight descending
-type ByWeight []score
+type ByWeight []check.CheckResult
 
 func (a ByWeight) Len() int           { return len(a) }
 func (a ByWeight) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -1,6 +1,11 @@
 package handlers
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
 
 var dirNameTests = []struct {
 	url  string
@@ -16,3 +21,14 @@ func TestDirName(t *testing.T) {
 		}
 	}
 }
+
+func TestWeightShares(t *testing.T) {
+	results := []check.CheckResult{{Name: "gofmt", Weight: 0.75}, {Name: "go_vet", Weight: 0.25}, {Name: "misspell", Weight: 0}}
+	want := map[string]float64{"gofmt": 0.75, "go_vet": 0.25, "misspell": 0}
+	if got := weightShares(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("weightShares = %v, want %v", got, want)
+	}
+	if got := weightShares([]check.CheckResult{{Name: "misspell"}}); got != nil {
+		t.Errorf("weightShares without weights = %v, want nil", got)
+	}
+}
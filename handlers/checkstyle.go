@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+type checkstyleDoc struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// checkstyleSource returns the source of an issue found by the named
+// check, such as goreportcard.go_vet.printf
+func checkstyleSource(name string, e check.Error) string {
+	if e.RuleID == "" || e.RuleID == name {
+		return "goreportcard." + name
+	}
+	return "goreportcard." + name + "." + e.RuleID
+}
+
+// ToCheckstyle writes the issues in the results of the checks run on the
+// repo in dir to w as checkstyle XML, which code review bots and editor
+// plugins read. The issues of all checks are listed by file.
+func ToCheckstyle(dir string, results []check.CheckResult, w io.Writer) error {
+	byFile := make(map[string][]checkstyleError)
+	for _, r := range results {
+		for _, fs := range r.FileSummaries {
+			rel := check.RelFilename(dir, fs.Filename)
+			for _, e := range fs.Errors {
+				sev := e.Severity
+				if sev == "" {
+					sev = check.SeverityWarning
+				}
+				byFile[rel] = append(byFile[rel], checkstyleError{
+					Line:     e.LineNumber,
+					Column:   e.Column,
+					Severity: sev,
+					Message:  strings.TrimSpace(e.ErrorString),
+					Source:   checkstyleSource(r.Name, e),
+				})
+			}
+		}
+	}
+
+	doc := checkstyleDoc{Version: "5.0", Files: []checkstyleFile{}}
+	for name, errs := range byFile {
+		sort.SliceStable(errs, func(i, j int) bool {
+			if errs[i].Line != errs[j].Line {
+				return errs[i].Line < errs[j].Line
+			}
+			return errs[i].Column < errs[j].Column
+		})
+		doc.Files = append(doc.Files, checkstyleFile{Name: name, Errors: errs})
+	}
+	sort.Slice(doc.Files, func(i, j int) bool { return doc.Files[i].Name < doc.Files[j].Name })
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToCheckstyle(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	results := []check.CheckResult{
+		{Name: "go_vet", FileSummaries: []check.FileSummary{
+			{Filename: "bar/b.go", Errors: []check.Error{{LineNumber: 9, Column: 4, ErrorString: "printf: bad verb", RuleID: "printf", Severity: check.SeverityError}}},
+			{Filename: "bar/a.go", Errors: []check.Error{{LineNumber: 5, ErrorString: "unreachable code"}}},
+		}},
+		{Name: "misspell", FileSummaries: []check.FileSummary{
+			{Filename: "bar/b.go", Errors: []check.Error{{LineNumber: 2, ErrorString: `"teh" is a misspelling of "the"`, Severity: check.SeverityInfo}}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := ToCheckstyle(dir, results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var got checkstyleDoc
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("ToCheckstyle wrote invalid XML: %v", err)
+	}
+	want := []checkstyleFile{
+		{Name: "a.go", Errors: []checkstyleError{{Line: 5, Severity: "warning", Message: "unreachable code", Source: "goreportcard.go_vet"}}},
+		{Name: "b.go", Errors: []checkstyleError{
+			{Line: 2, Severity: "info", Message: `"teh" is a misspelling of "the"`, Source: "goreportcard.misspell"},
+			{Line: 9, Column: 4, Severity: "error", Message: "printf: bad verb", Source: "goreportcard.go_vet.printf"},
+		}},
+	}
+	if got.Version != "5.0" || !reflect.DeepEqual(got.Files, want) {
+		t.Errorf("ToCheckstyle = %+v, want version 5.0 with %+v", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+type codeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Severity    string              `json:"severity"`
+	Fingerprint string              `json:"fingerprint"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+type codeClimateLocation struct {
+	Path  string           `json:"path"`
+	Lines codeClimateLines `json:"lines"`
+}
+
+type codeClimateLines struct {
+	Begin int `json:"begin"`
+}
+
+// codeClimateCategories are the Code Climate categories of the issues of
+// checks, by check name. Checks that are not listed are in the category
+// of their report section, or else in "Style".
+var codeClimateCategories = map[string]string{
+	"go_vet":                "Bug Risk",
+	"staticcheck":           "Bug Risk",
+	"errcheck":              "Bug Risk",
+	"ineffassign":           "Bug Risk",
+	"shadow":                "Bug Risk",
+	"exhaustive":            "Bug Risk",
+	"library_exits":         "Bug Risk",
+	"unused":                "Bug Risk",
+	"cross_platform_build":  "Compatibility",
+	"go_mod_tidy":           "Compatibility",
+	"outdated_dependencies": "Compatibility",
+	"dependency_bloat":      "Compatibility",
+	"gocyclo":               "Complexity",
+	"gocognit":              "Complexity",
+	"nakedret":              "Complexity",
+	"globals":               "Complexity",
+	"dupl":                  "Duplication",
+	"doc_coverage":          "Clarity",
+	"godox":                 "Clarity",
+	"license":               "Clarity",
+	"missing_tests":         "Clarity",
+	"misspell":              "Clarity",
+	"vulnerabilities":       "Security",
+	"secrets":               "Security",
+}
+
+// codeClimateSectionCategories are the Code Climate categories of the
+// report sections of checks
+var codeClimateSectionCategories = map[string]string{
+	check.CategoryPerformance: "Performance",
+	check.CategorySecurity:    "Security",
+}
+
+// codeClimateSeverities are the Code Climate severities of the
+// severities of issues
+var codeClimateSeverities = map[string]string{
+	check.SeverityError:   "major",
+	check.SeverityWarning: "minor",
+	check.SeverityInfo:    "info",
+}
+
+// codeClimateCategory returns the Code Climate category of the issues of
+// r
+func codeClimateCategory(r check.CheckResult) string {
+	if c, ok := codeClimateCategories[r.Name]; ok {
+		return c
+	}
+	if c, ok := codeClimateSectionCategories[r.Category]; ok {
+		return c
+	}
+	return "Style"
+}
+
+// codeClimateFingerprint returns the fingerprint of the nth issue with
+// the same message reported by the named check in the file at rel. Like
+// the hashes of a Baseline, it leaves out the line, so an issue keeps its
+// fingerprint when the code around it moves.
+func codeClimateFingerprint(name, rel, msg string, n int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", name, rel, msg, n)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ToCodeClimate writes the issues in the results of the checks run on
+// the repo in dir to w in the Code Climate format, which the code quality
+// report of GitLab merge requests reads
+func ToCodeClimate(dir string, results []check.CheckResult, w io.Writer) error {
+	issues := []codeClimateIssue{}
+	for _, r := range results {
+		category := codeClimateCategory(r)
+		for _, fs := range r.FileSummaries {
+			rel := check.RelFilename(dir, fs.Filename)
+			seen := make(map[string]int)
+			for _, e := range fs.Errors {
+				msg := strings.TrimSpace(e.ErrorString)
+				name := sarifRuleID(r.Name, e)
+				sev := codeClimateSeverities[e.Severity]
+				if sev == "" {
+					sev = codeClimateSeverities[check.SeverityWarning]
+				}
+				line := e.LineNumber
+				if line < 1 {
+					line = 1
+				}
+				issues = append(issues, codeClimateIssue{
+					Type:        "issue",
+					CheckName:   name,
+					Description: msg,
+					Categories:  []string{category},
+					Severity:    sev,
+					Fingerprint: codeClimateFingerprint(name, rel, msg, seen[msg]),
+					Location:    codeClimateLocation{Path: rel, Lines: codeClimateLines{Begin: line}},
+				})
+				seen[msg]++
+			}
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToCodeClimate(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	results := []check.CheckResult{
+		{Name: "go_vet", FileSummaries: []check.FileSummary{
+			{Filename: "bar/a.go", Errors: []check.Error{
+				{LineNumber: 9, ErrorString: "printf: bad verb", RuleID: "printf", Severity: check.SeverityError},
+				{LineNumber: 12, ErrorString: "printf: bad verb", RuleID: "printf", Severity: check.SeverityError},
+			}},
+		}},
+		{Name: "gocyclo", FileSummaries: []check.FileSummary{
+			{Filename: "bar/b.go", Errors: []check.Error{{ErrorString: "cyclomatic complexity 20 of function f"}}},
+		}},
+		{Name: "gosec", Category: check.CategorySecurity, FileSummaries: []check.FileSummary{
+			{Filename: "bar/b.go", Errors: []check.Error{{LineNumber: 3, ErrorString: "G104: errors unhandled", Severity: check.SeverityInfo}}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := ToCodeClimate(dir, results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var got []codeClimateIssue
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("ToCodeClimate wrote invalid JSON: %v", err)
+	}
+	want := []struct {
+		checkName, category, severity, path string
+		line                                int
+	}{
+		{"go_vet/printf", "Bug Risk", "major", "a.go", 9},
+		{"go_vet/printf", "Bug Risk", "major", "a.go", 12},
+		{"gocyclo", "Complexity", "minor", "b.go", 1},
+		{"gosec", "Security", "info", "b.go", 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ToCodeClimate wrote %d issues, want %d", len(got), len(want))
+	}
+	seen := make(map[string]bool)
+	for i, w := range want {
+		g := got[i]
+		if g.Type != "issue" || g.CheckName != w.checkName || len(g.Categories) != 1 || g.Categories[0] != w.category ||
+			g.Severity != w.severity || g.Location.Path != w.path || g.Location.Lines.Begin != w.line {
+			t.Errorf("[%d] ToCodeClimate = %+v, want %+v", i, g, w)
+		}
+		if g.Fingerprint == "" || seen[g.Fingerprint] {
+			t.Errorf("[%d] fingerprint %q is empty or not unique", i, g.Fingerprint)
+		}
+		seen[g.Fingerprint] = true
+	}
+}
+
+func TestCodeClimateFingerprint(t *testing.T) {
+	a := codeClimateFingerprint("go_vet", "a.go", "unreachable code", 0)
+	if b := codeClimateFingerprint("go_vet", "a.go", "unreachable code", 0); a != b {
+		t.Errorf("fingerprints of the same issue differ: %q and %q", a, b)
+	}
+	if b := codeClimateFingerprint("go_vet", "b.go", "unreachable code", 0); a == b {
+		t.Errorf("fingerprints of issues in different files are both %q", a)
+	}
+}
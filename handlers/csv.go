@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// ToCSV writes the issues in the results of the checks run on the repo in
+// dir to w as CSV, with a header row and a row per issue, for pivoting in
+// spreadsheets and BI tools
+func ToCSV(dir string, results []check.CheckResult, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"filename", "line", "check", "severity", "message"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		for _, fs := range r.FileSummaries {
+			rel := check.RelFilename(dir, fs.Filename)
+			for _, e := range fs.Errors {
+				sev := e.Severity
+				if sev == "" {
+					sev = check.SeverityWarning
+				}
+				row := []string{rel, strconv.Itoa(e.LineNumber), sarifRuleID(r.Name, e), sev, strings.TrimSpace(e.ErrorString)}
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
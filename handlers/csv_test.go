@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToCSV(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	results := []check.CheckResult{
+		{Name: "go_vet", FileSummaries: []check.FileSummary{
+			{Filename: "bar/a.go", Errors: []check.Error{{LineNumber: 9, ErrorString: "printf: bad verb, \"%z\"", RuleID: "printf", Severity: check.SeverityError}}},
+		}},
+		{Name: "gocyclo", FileSummaries: []check.FileSummary{
+			{Filename: "bar/b.go", Errors: []check.Error{{LineNumber: 3, ErrorString: "cyclomatic complexity 20 of function f\n"}}},
+		}},
+		{Name: "misspell"},
+	}
+
+	var buf bytes.Buffer
+	if err := ToCSV(dir, results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ToCSV wrote invalid CSV: %v", err)
+	}
+	want := [][]string{
+		{"filename", "line", "check", "severity", "message"},
+		{"a.go", "9", "go_vet/printf", "error", "printf: bad verb, \"%z\""},
+		{"b.go", "3", "gocyclo", "warning", "cyclomatic complexity 20 of function f"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToCSV = %q, want %q", got, want)
+	}
+}
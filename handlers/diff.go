@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// diffRun is one of the two grades of a repo in a diffResp
+type diffRun struct {
+	Time   time.Time `json:"time"`
+	Commit string    `json:"commit,omitempty"`
+	Grade  Grade     `json:"grade"`
+	Score  float64   `json:"score"`
+}
+
+// diffResp is the issues that changed between two grades of a repo
+type diffResp struct {
+	Repo       string            `json:"repo"`
+	From       diffRun           `json:"from"`
+	To         diffRun           `json:"to"`
+	Introduced int               `json:"introduced"`
+	Resolved   int               `json:"resolved"`
+	Checks     []check.IssueDiff `json:"checks"`
+}
+
+func newDiffRun(e historyEntry) diffRun {
+	return diffRun{Time: e.Time, Commit: e.Commit, Grade: e.Grade, Score: e.Score}
+}
+
+// findRun returns the index of the latest grade in entries of the
+// commit, which may be abbreviated, or of the latest grade if the commit
+// is empty. It returns -1 if there is no such grade.
+func findRun(entries []historyEntry, commit string) int {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if commit == "" || entries[i].Commit != "" && strings.HasPrefix(entries[i].Commit, commit) {
+			return i
+		}
+	}
+	return -1
+}
+
+// newDiffResp returns the issues of repo that changed from the grade from
+// to the grade to
+func newDiffResp(repo string, from, to historyEntry) diffResp {
+	resp := diffResp{
+		Repo:   repo,
+		From:   newDiffRun(from),
+		To:     newDiffRun(to),
+		Checks: check.DiffIssues(dirName(repo), from.results(), to.results()),
+	}
+	for _, d := range resp.Checks {
+		for _, fs := range d.Introduced {
+			resp.Introduced += len(fs.Errors)
+		}
+		for _, fs := range d.Resolved {
+			resp.Resolved += len(fs.Errors)
+		}
+	}
+	return resp
+}
+
+// DiffHandler handles the request for the issues introduced and resolved
+// between two grades of a repo, given by their commits in the from and to
+// parameters. Without from, the grade before to is used, and without to
+// the latest grade.
+func DiffHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	if _, err := getReport(r, repo); err == errPrivateReport {
+		http.Error(w, "The repository has not been graded yet", http.StatusNotFound)
+		return
+	}
+	entries, err := getHistory(repo)
+	if err != nil {
+		slog.Error("could not get history", "repo", repo, "error", err)
+		http.Error(w, "Failed to load the history", http.StatusInternalServerError)
+		return
+	}
+	to := findRun(entries, r.FormValue("to"))
+	if to < 0 {
+		http.Error(w, "No grade of the repository found for the to commit", http.StatusNotFound)
+		return
+	}
+	from := to - 1
+	if c := r.FormValue("from"); c != "" {
+		from = findRun(entries, c)
+	}
+	if from < 0 {
+		http.Error(w, "No grade of the repository found for the from commit", http.StatusNotFound)
+		return
+	}
+
+	b, err := json.Marshal(newDiffResp(repo, entries[from], entries[to]))
+	if err != nil {
+		slog.Error("could not marshal json", "repo", repo, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
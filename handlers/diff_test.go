@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestFindRun(t *testing.T) {
+	entries := []historyEntry{{Commit: "0123abc"}, {Commit: "4567def"}, {Commit: "0123abc"}, {}}
+	cases := []struct {
+		commit string
+		want   int
+	}{
+		{"", 3},
+		{"4567", 1},
+		{"0123abc", 2},
+		{"89ab", -1},
+	}
+	for _, c := range cases {
+		if got := findRun(entries, c.commit); got != c.want {
+			t.Errorf("[%q] findRun = %d, want %d", c.commit, got, c.want)
+		}
+	}
+}
+
+func TestNewDiffResp(t *testing.T) {
+	repo := "github.com/foo/bar"
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	errs := func(msgs ...string) []check.Error {
+		var errs []check.Error
+		for i, msg := range msgs {
+			errs = append(errs, check.Error{LineNumber: i + 1, ErrorString: msg, Snippet: &check.Snippet{StartLine: i + 1, Lines: []string{"x"}}})
+		}
+		return errs
+	}
+	from := newHistoryEntry(checksResp{
+		Repo: repo, Commit: "a", Grade: GradeB, Score: 75, LastRefresh: start,
+		Checks: []check.CheckResult{
+			{Name: "go_vet", FileSummaries: []check.FileSummary{{Filename: "bar/a.go", Errors: errs("unreachable code", "bad printf")}}},
+		},
+	})
+	to := newHistoryEntry(checksResp{
+		Repo: repo, Commit: "b", Grade: GradeA, Score: 85, LastRefresh: start.Add(time.Hour),
+		Checks: []check.CheckResult{
+			{Name: "go_vet", FileSummaries: []check.FileSummary{{Filename: "bar/a.go", Errors: errs("copies lock", "unreachable code")}}},
+			{Name: "gofmt"},
+		},
+	})
+	if s := from.Checks[0].FileSummaries[0].Errors[0].Snippet; s != nil {
+		t.Errorf("newHistoryEntry kept the snippet %+v", s)
+	}
+
+	got := newDiffResp(repo, from, to)
+	want := diffResp{
+		Repo:       repo,
+		From:       diffRun{Time: start, Commit: "a", Grade: GradeB, Score: 75},
+		To:         diffRun{Time: start.Add(time.Hour), Commit: "b", Grade: GradeA, Score: 85},
+		Introduced: 1,
+		Resolved:   1,
+		Checks: []check.IssueDiff{{
+			Name:       "go_vet",
+			Introduced: []check.FileSummary{{Filename: "bar/a.go", Errors: []check.Error{{LineNumber: 1, ErrorString: "copies lock"}}}},
+			Resolved:   []check.FileSummary{{Filename: "bar/a.go", Errors: []check.Error{{LineNumber: 2, ErrorString: "bad printf"}}}},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newDiffResp = %+v, want %+v", got, want)
+	}
+}
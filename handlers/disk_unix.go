@@ -0,0 +1,15 @@
+//go:build !windows
+
+package handlers
+
+import "syscall"
+
+// freeDisk returns the bytes available to the server on the disk of the
+// path
+func freeDisk(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
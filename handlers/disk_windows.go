@@ -0,0 +1,7 @@
+package handlers
+
+// freeDisk returns errNoStatfs, as the free space of disks is not looked
+// up on Windows
+func freeDisk(path string) (uint64, error) {
+	return 0, errNoStatfs
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+)
+
+// explanation is how the grade of a repo was computed
+type explanation struct {
+	Repo            string             `json:"repo"`
+	Grade           Grade              `json:"grade"`
+	Score           float64            `json:"score"`
+	GradeThresholds []GradeThreshold   `json:"grade_thresholds"`
+	Formula         string             `json:"formula"`
+	TotalWeight     float64            `json:"total_weight"`
+	Checks          []checkExplanation `json:"checks"`
+	// Excluded are the files that no check was run on, such as generated
+	// files and files skipped by the repo config
+	Excluded []string `json:"excluded"`
+}
+
+// checkExplanation is how a check counted towards the grade of a repo.
+// Points and Penalty add up to Share times 100.
+type checkExplanation struct {
+	Name       string  `json:"name"`
+	Percentage float64 `json:"percentage"`
+	Weight     float64 `json:"weight"`
+	// Share is the weight of the check divided by the total weight
+	Share float64 `json:"share"`
+	// Points are the points out of 100 that the check adds to the score,
+	// and Penalty the points it loses
+	Points          float64 `json:"points"`
+	Penalty         float64 `json:"penalty"`
+	FilesWithIssues int     `json:"files_with_issues"`
+	Issues          int     `json:"issues"`
+	Suppressed      int     `json:"suppressed"`
+	Baselined       int     `json:"baselined"`
+	Status          string  `json:"status,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+const scoreFormula = "score = 100 * sum(percentage * weight) / sum(weight)"
+
+// explain returns how the grade in resp was computed, with the checks
+// that lose the most points first
+func explain(resp checksResp) explanation {
+	e := explanation{
+		Repo:            resp.Repo,
+		Grade:           resp.Grade,
+		Score:           resp.Score,
+		GradeThresholds: resp.GradeThresholds,
+		Formula:         scoreFormula,
+		Checks:          []checkExplanation{},
+		Excluded:        resp.Excluded,
+	}
+	if e.Excluded == nil {
+		e.Excluded = []string{}
+	}
+	for _, r := range resp.Checks {
+		e.TotalWeight += r.Weight
+	}
+	for _, r := range resp.Checks {
+		ce := checkExplanation{
+			Name:            r.Name,
+			Percentage:      r.Percentage,
+			Weight:          r.Weight,
+			FilesWithIssues: len(r.FileSummaries),
+			Suppressed:      r.Suppressed,
+			Baselined:       r.Baselined,
+			Status:          r.Status,
+			Error:           r.Error,
+		}
+		if e.TotalWeight > 0 {
+			ce.Share = r.Weight / e.TotalWeight
+		}
+		ce.Points = 100 * ce.Share * r.Percentage
+		ce.Penalty = 100*ce.Share - ce.Points
+		for _, fs := range r.FileSummaries {
+			ce.Issues += len(fs.Errors)
+		}
+		e.Checks = append(e.Checks, ce)
+	}
+	sort.SliceStable(e.Checks, func(i, j int) bool { return e.Checks[i].Penalty > e.Checks[j].Penalty })
+	return e
+}
+
+// ExplainHandler handles the request for how the grade of a repo was
+// computed
+func ExplainHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	resp, err := getReport(r, repo)
+	if err != nil {
+		slog.Info("repo not in cache", "repo", repo, "error", err)
+		http.Error(w, "The repository has not been graded yet", http.StatusNotFound)
+		return
+	}
+	resp.Score = resp.Average * 100
+	resp.Grade = grade(resp.Score)
+	resp.GradeThresholds = GradeThresholds
+
+	b, err := json.Marshal(explain(resp))
+	if err != nil {
+		slog.Error("could not marshal json", "repo", repo, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
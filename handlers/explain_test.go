@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestExplain(t *testing.T) {
+	resp := checksResp{
+		Repo: "github.com/foo/bar",
+		Checks: []check.CheckResult{
+			{Name: "gofmt", Percentage: 1, Weight: 0.5},
+			{Name: "go_vet", Percentage: 0.5, Weight: 0.25, Suppressed: 2, FileSummaries: []check.FileSummary{
+				{Filename: "a.go", Errors: []check.Error{{LineNumber: 1}, {LineNumber: 2}}},
+				{Filename: "b.go", Errors: []check.Error{{LineNumber: 3}}},
+			}},
+			{Name: "errcheck", Percentage: 0, Weight: 0.25, Error: "failed"},
+		},
+	}
+	e := explain(resp)
+	if e.TotalWeight != 1 {
+		t.Errorf("TotalWeight = %v, want 1", e.TotalWeight)
+	}
+	if e.Excluded == nil {
+		t.Errorf("Excluded = nil, want an empty list")
+	}
+	want := []checkExplanation{
+		{Name: "errcheck", Percentage: 0, Weight: 0.25, Share: 0.25, Points: 0, Penalty: 25, Error: "failed"},
+		{Name: "go_vet", Percentage: 0.5, Weight: 0.25, Share: 0.25, Points: 12.5, Penalty: 12.5, FilesWithIssues: 2, Issues: 3, Suppressed: 2},
+		{Name: "gofmt", Percentage: 1, Weight: 0.5, Share: 0.5, Points: 50, Penalty: 0},
+	}
+	if len(e.Checks) != len(want) {
+		t.Fatalf("got %d checks, want %d", len(e.Checks), len(want))
+	}
+	for i, got := range e.Checks {
+		w := want[i]
+		if math.Abs(got.Points-w.Points) > 1e-9 || math.Abs(got.Penalty-w.Penalty) > 1e-9 {
+			t.Errorf("[%q] points, penalty = %v, %v, want %v, %v", w.Name, got.Points, got.Penalty, w.Points, w.Penalty)
+		}
+		got.Points, got.Penalty = w.Points, w.Penalty
+		if got != w {
+			t.Errorf("[%q] explanation = %+v, want %+v", w.Name, got, w)
+		}
+	}
+}
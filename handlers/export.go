@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// exportCheck is a check in a static HTML report
+type exportCheck struct {
+	check.CheckResult
+	Percent int
+	Share   int
+	Color   string
+	// DescriptionHTML is the description of the check, which contains
+	// links
+	DescriptionHTML template.HTML
+}
+
+// exportReport is the data of a static HTML report
+type exportReport struct {
+	Repo      string
+	Grade     Grade
+	Score     float64
+	Generated string
+	Files     int
+	Issues    int
+	Checks    []exportCheck
+}
+
+// percentageColor returns the class of a percentage, as the report page
+// colors it
+func percentageColor(percent int) string {
+	switch {
+	case percent < 30:
+		return "danger"
+	case percent < 50:
+		return "warning"
+	case percent < 80:
+		return "info"
+	default:
+		return "success"
+	}
+}
+
+// exportRepo returns the name of the repo in dir for the title of a
+// static report
+func exportRepo(dir string) string {
+	if repo := strings.TrimPrefix(filepath.ToSlash(dir), check.ReposDir+"/"); repo != filepath.ToSlash(dir) {
+		return repo
+	}
+	return filepath.Base(dir)
+}
+
+var exportTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"snippetLine": func(s *check.Snippet, i int) int { return s.StartLine + i },
+	"lineURL":     func(fileURL string, line int) string { return check.LineURL(fileURL, line, 0) },
+}).Parse(exportHTML))
+
+// ToHTML writes the results of the checks run on the repo in dir to w as
+// a single HTML page with the issues of every file. The page has no
+// scripts and inlines its styles, so it can be kept as a CI artifact and
+// opened without the site.
+func ToHTML(dir string, results []check.CheckResult, w io.Writer) error {
+	grade, score := GradeResults(results)
+	report := exportReport{
+		Repo:      exportRepo(dir),
+		Grade:     grade,
+		Score:     score,
+		Generated: time.Now().UTC().Format(time.RFC1123),
+	}
+	var totalWeight float64
+	for _, r := range results {
+		totalWeight += r.Weight
+	}
+	for _, r := range results {
+		c := exportCheck{
+			CheckResult:     r,
+			Percent:         int(r.Percentage * 100),
+			DescriptionHTML: template.HTML(r.Description),
+		}
+		if totalWeight > 0 {
+			c.Share = int(r.Weight / totalWeight * 100)
+		}
+		c.Color = percentageColor(c.Percent)
+		for _, fs := range r.FileSummaries {
+			report.Issues += len(fs.Errors)
+		}
+		report.Checks = append(report.Checks, c)
+	}
+	report.Files = len(filesWithIssues(results))
+	if err := exportTemplate.Execute(w, report); err != nil {
+		return fmt.Errorf("could not render report: %v", err)
+	}
+	return nil
+}
+
+// filesWithIssues returns the names of the files in which the results
+// have issues
+func filesWithIssues(results []check.CheckResult) map[string]bool {
+	files := make(map[string]bool)
+	for _, r := range results {
+		for _, fs := range r.FileSummaries {
+			if fs.Filename != "" && len(fs.Errors) > 0 {
+				files[fs.Filename] = true
+			}
+		}
+	}
+	return files
+}
+
+const exportHTML = `<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Go Report Card | {{.Repo}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; color: #333; margin: 0; }
+header, main, footer { max-width: 960px; margin: 0 auto; padding: 1em; }
+header { border-bottom: 1px solid #eee; }
+h1 { font-size: 1.5em; margin: 0; }
+h2 { font-size: 1.3em; border-bottom: 1px solid #eee; padding-bottom: .3em; margin-top: 2em; }
+a { color: #3273dc; text-decoration: none; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .3em .6em; border-bottom: 1px solid #eee; }
+td.number { text-align: right; }
+.grade { font-size: 3em; font-weight: bold; }
+.summary { font-size: 1.2em; }
+.danger { color: #C61E1E; }
+.warning { color: #C6761E; }
+.success { color: #4CC61E; }
+.percentage { float: right; }
+.description, .weight, footer { color: #777; }
+.error-msg { color: #C61E1E; }
+.perfect { color: #4CC61E; }
+ul.files { list-style-type: none; padding-left: 0; }
+ul.errors { padding-left: 1.5em; }
+pre.snippet { background: #f5f5f5; padding: .5em; overflow-x: auto; }
+pre.snippet .current { background: #fff3c4; display: inline-block; width: 100%; }
+</style>
+</head>
+<body>
+<header>
+<h1>Go Report Card: {{.Repo}}</h1>
+</header>
+<main>
+<p class="summary"><span class="grade">{{.Grade}}</span> {{printf "%.1f" .Score}}% &middot; {{.Issues}} issues in {{.Files}} files</p>
+<table>
+<tr><th>Check</th><th>Score</th><th>Weight</th></tr>
+{{range .Checks}}<tr><td><a href="#{{.Name}}">{{.Name}}</a></td><td class="number {{.Color}}">{{.Percent}}%</td><td class="number">{{.Share}}%</td></tr>
+{{end}}</table>
+{{range .Checks}}
+<h2 id="{{.Name}}">{{.Name}}<span class="percentage {{.Color}}">{{.Percent}}%</span></h2>
+<p class="description">{{.DescriptionHTML}}</p>
+<p class="weight">Counts for {{.Share}}% of the grade</p>
+{{if .Suppressed}}<p>{{.Suppressed}} issues were suppressed with <code>//nolint</code> comments</p>
+{{end}}{{if .Baselined}}<p>{{.Baselined}} issues from before the baseline are not counted</p>
+{{end}}{{if .Error}}<p class="error-msg">An error occurred while running this check ({{.Error}})</p>
+{{else if not .FileSummaries}}<p class="perfect">No problems detected. Good job!</p>
+{{else}}<ul class="files">
+{{range .FileSummaries}}{{$file := .}}<li>{{if .FileURL}}<a href="{{.FileURL}}">{{.Filename}}</a>{{else}}{{.Filename}}{{end}}
+<ul class="errors">
+{{range .Errors}}<li>{{if .LineNumber}}{{if $file.FileURL}}<a href="{{lineURL $file.FileURL .LineNumber}}">Line {{.LineNumber}}</a>{{else}}Line {{.LineNumber}}{{end}}: {{end}}{{if .RuleID}}<strong>{{.RuleID}}</strong>{{if .Severity}} ({{.Severity}}){{end}}: {{end}}{{.ErrorString}}{{if .Snippet}}{{$e := .}}
+<pre class="snippet">{{range $i, $line := .Snippet.Lines}}{{$n := snippetLine $e.Snippet $i}}{{if eq $n $e.LineNumber}}<span class="current">{{$n}}: {{$line}}</span>{{else}}{{$n}}: {{$line}}{{end}}
+{{end}}</pre>{{end}}</li>
+{{end}}</ul>
+</li>
+{{end}}</ul>
+{{end}}{{end}}
+</main>
+<footer>Generated by Go Report Card on {{.Generated}}</footer>
+</body>
+</html>
+`
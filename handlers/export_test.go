@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToHTML(t *testing.T) {
+	results := []check.CheckResult{
+		{
+			Name:        "go_vet",
+			Description: `<a href="https://pkg.go.dev/cmd/vet">go vet</a> examines Go source code`,
+			Weight:      .5,
+			Percentage:  .5,
+			FileSummaries: []check.FileSummary{{
+				Filename: "a.go",
+				FileURL:  "https://github.com/foo/bar/blob/master/a.go",
+				Errors: []check.Error{{
+					LineNumber:  4,
+					ErrorString: "unreachable code after <return>",
+					Snippet:     &check.Snippet{StartLine: 3, Lines: []string{"return", "x++"}},
+				}},
+			}},
+		},
+		{Name: "gofmt", Weight: .5, Percentage: 1},
+		{Name: "misspell", Error: "exit status 2"},
+	}
+
+	var buf bytes.Buffer
+	if err := ToHTML("repos/src/github.com/foo/bar", results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<title>Go Report Card | github.com/foo/bar</title>",
+		`<span class="grade">B</span> 75.0%`,
+		"1 issues in 1 files",
+		`<a href="https://pkg.go.dev/cmd/vet">go vet</a>`,
+		`<a href="https://github.com/foo/bar/blob/master/a.go#L4">Line 4</a>: unreachable code after &lt;return&gt;`,
+		"3: return\n<span class=\"current\">4: x&#43;&#43;</span>",
+		"No problems detected. Good job!",
+		"An error occurred while running this check (exit status 2)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ToHTML output does not contain %q:\n%s", want, out)
+		}
+	}
+	for _, external := range []string{"<script", "<link", "/assets/"} {
+		if strings.Contains(out, external) {
+			t.Errorf("ToHTML output contains %q, want a self-contained page", external)
+		}
+	}
+}
+
+func TestExportRepo(t *testing.T) {
+	cases := []struct{ dir, want string }{
+		{"repos/src/github.com/foo/bar", "github.com/foo/bar"},
+		{"/home/foo/src/bar", "bar"},
+	}
+	for _, c := range cases {
+		if got := exportRepo(c.dir); got != c.want {
+			t.Errorf("[%q] exportRepo = %q, want %q", c.dir, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// FileBucket is the bucket in which the issues found in files are cached
+// in the bolt DB
+const FileBucket string = "files"
+
+// FileCacheSize is the maximum number of files whose issues are cached.
+// When there are more, the least recently used ones are evicted. If zero,
+// issues are not cached.
+var FileCacheSize = 100000
+
+// boltFileCache is a check.Cache in the FileBucket. Every value is stored
+// after the time it was last used, for evicting the least recently used
+// values.
+type boltFileCache struct{}
+
+// Load returns the values stored for keys, and marks them as used
+func (boltFileCache) Load(keys []string) (map[string][]byte, error) {
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database: %v", err)
+	}
+	defer db.Close()
+
+	values := make(map[string][]byte)
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(FileBucket))
+		if b == nil {
+			return errors.New("No file bucket")
+		}
+		now := usedAt(time.Now())
+		for _, k := range keys {
+			v := b.Get([]byte(k))
+			if len(v) < len(now) {
+				continue
+			}
+			values[k] = append([]byte(nil), v[len(now):]...)
+			if err := b.Put([]byte(k), append(now, values[k]...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	cacheLookups.add(float64(len(values)), "file", "hit")
+	cacheLookups.add(float64(len(keys)-len(values)), "file", "miss")
+	return values, err
+}
+
+// Store stores values, then evicts the least recently used values down
+// to nine tenths of FileCacheSize if there are more than FileCacheSize
+func (boltFileCache) Store(values map[string][]byte) error {
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open bolt database: %v", err)
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(FileBucket))
+		if b == nil {
+			return errors.New("No file bucket")
+		}
+		now := usedAt(time.Now())
+		for k, v := range values {
+			if err := b.Put([]byte(k), append(now, v...)); err != nil {
+				return err
+			}
+		}
+		return evictFiles(b, FileCacheSize)
+	})
+}
+
+// usedAt returns t as the prefix of a value in the FileBucket
+func usedAt(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+// evictFiles deletes the least recently used values in b down to nine
+// tenths of size, if there are more than size
+func evictFiles(b *bolt.Bucket, size int) error {
+	type entry struct {
+		key  []byte
+		used uint64
+	}
+	var entries []entry
+	err := b.ForEach(func(k, v []byte) error {
+		var used uint64
+		if len(v) >= 8 {
+			used = binary.BigEndian.Uint64(v)
+		}
+		entries = append(entries, entry{append([]byte(nil), k...), used})
+		return nil
+	})
+	if err != nil || len(entries) <= size {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used < entries[j].used })
+	for _, e := range entries[:len(entries)-size*9/10] {
+		if err := b.Delete(e.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestEvictFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goreportcard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(FileBucket))
+		if err != nil {
+			return err
+		}
+		// key i was last used i seconds after the start
+		start := time.Now()
+		for i := 0; i < 12; i++ {
+			if err := b.Put([]byte(fmt.Sprint(i)), usedAt(start.Add(time.Duration(i)*time.Second))); err != nil {
+				return err
+			}
+		}
+		return evictFiles(b, 10)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(FileBucket))
+		if n := b.Stats().KeyN; n != 9 {
+			t.Errorf("got %d files after eviction, want 9", n)
+		}
+		for i := 0; i < 12; i++ {
+			if kept := b.Get([]byte(fmt.Sprint(i))) != nil; kept != (i >= 3) {
+				t.Errorf("[%d] kept = %v, want %v", i, kept, i >= 3)
+			}
+		}
+		return nil
+	})
+}
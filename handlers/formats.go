@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// reportFormat writes the results of the checks run on the repo in dir
+// in a format other tools read
+type reportFormat struct {
+	contentType string
+	write       func(dir string, results []check.CheckResult, w io.Writer) error
+}
+
+// reportFormats are the formats of the report, by the name used in the
+// format parameter
+var reportFormats = map[string]reportFormat{
+	"json":        {"application/json", writeJSON},
+	"markdown":    {"text/markdown; charset=utf-8", writeMarkdown},
+	"md":          {"text/markdown; charset=utf-8", writeMarkdown},
+	"sarif":       {"application/sarif+json", ToSARIF},
+	"junit":       {"application/xml", ToJUnit},
+	"checkstyle":  {"application/xml", ToCheckstyle},
+	"codeclimate": {"application/json", ToCodeClimate},
+	"csv":         {"text/csv; charset=utf-8", ToCSV},
+	"html":        {"text/html; charset=utf-8", ToHTML},
+	"github":      {"text/plain; charset=utf-8", ToGitHubActions},
+}
+
+// writeMarkdown writes the Markdown summary of the results
+func writeMarkdown(dir string, results []check.CheckResult, w io.Writer) error {
+	return ToMarkdown(results, w)
+}
+
+var tagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags returns the text of the HTML descriptions of checks
+func stripTags(s string) string {
+	return html.UnescapeString(tagRegexp.ReplaceAllString(s, ""))
+}
+
+// Formats returns the names of the formats of the report, sorted
+func Formats() []string {
+	var names []string
+	for name := range reportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteFormat writes the results of the checks run on the repo in dir to
+// w in the named format
+func WriteFormat(format, dir string, results []check.CheckResult, w io.Writer) error {
+	f, ok := reportFormats[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, want one of %v", format, Formats())
+	}
+	return f.write(dir, results, w)
+}
+
+// FormatHandler handles the request for the latest report of a repo in
+// the named format
+func FormatHandler(w http.ResponseWriter, r *http.Request, repo, format string) {
+	f, ok := reportFormats[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown format %q, want one of %v", format, Formats()), http.StatusBadRequest)
+		return
+	}
+	resp, err := getReport(r, repo)
+	if err != nil {
+		slog.Info("repo not in cache", "repo", repo, "error", err)
+		http.Error(w, "The repository has not been graded yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", f.contentType)
+	switch {
+	case format == "json" && r.FormValue("v") == "1":
+		err = json.NewEncoder(w).Encode(resp.Checks)
+	case format == "json":
+		err = json.NewEncoder(w).Encode(newReportV2(resp))
+	default:
+		err = f.write(dirName(repo), resp.Checks, w)
+	}
+	if err != nil {
+		slog.Error("could not write report", "repo", repo, "format", format, "error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// githubCommands are the workflow commands of the severities of issues
+var githubCommands = map[string]string{
+	check.SeverityError:   "error",
+	check.SeverityWarning: "warning",
+	check.SeverityInfo:    "notice",
+}
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// ToGitHubActions writes the issues in the results of the checks run on
+// the repo in dir to w as GitHub Actions workflow commands, such as
+// ::error file=a.go,line=3::message, so that running the checks in a
+// workflow annotates the lines of the pull request diff
+func ToGitHubActions(dir string, results []check.CheckResult, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, r := range results {
+		for _, fs := range r.FileSummaries {
+			rel := check.RelFilename(dir, fs.Filename)
+			for _, e := range fs.Errors {
+				cmd := githubCommands[e.Severity]
+				if cmd == "" {
+					cmd = githubCommands[check.SeverityWarning]
+				}
+				var props []string
+				if rel != "" {
+					props = append(props, "file="+githubPropertyEscaper.Replace(rel))
+				}
+				if e.LineNumber > 0 {
+					props = append(props, fmt.Sprintf("line=%d", e.LineNumber))
+				}
+				if e.Column > 0 {
+					props = append(props, fmt.Sprintf("col=%d", e.Column))
+				}
+				props = append(props, "title="+githubPropertyEscaper.Replace(sarifRuleID(r.Name, e)))
+				fmt.Fprintf(bw, "::%s %s::%s\n", cmd, strings.Join(props, ","), githubDataEscaper.Replace(strings.TrimSpace(e.ErrorString)))
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToGitHubActions(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	results := []check.CheckResult{
+		{Name: "go_vet", FileSummaries: []check.FileSummary{
+			{Filename: "bar/a.go", Errors: []check.Error{
+				{LineNumber: 9, Column: 2, ErrorString: "printf: bad verb, 100%", RuleID: "printf", Severity: check.SeverityError},
+				{LineNumber: 12, ErrorString: "unreachable code\nafter return"},
+			}},
+		}},
+		{Name: "misspell", FileSummaries: []check.FileSummary{
+			{Filename: "bar/b,c.go", Errors: []check.Error{{LineNumber: 3, ErrorString: `"teh" is a misspelling of "the"`, Severity: check.SeverityInfo}}},
+		}},
+		{Name: "go_mod_tidy", FileSummaries: []check.FileSummary{
+			{Errors: []check.Error{{ErrorString: "go.mod is not tidy"}}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := ToGitHubActions(dir, results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "::error file=a.go,line=9,col=2,title=go_vet/printf::printf: bad verb, 100%25\n" +
+		"::warning file=a.go,line=12,title=go_vet::unreachable code%0Aafter return\n" +
+		"::notice file=b%2Cc.go,line=3,title=misspell::\"teh\" is a misspelling of \"the\"\n" +
+		"::warning title=go_mod_tidy::go.mod is not tidy\n"
+	if got := buf.String(); got != want {
+		t.Errorf("ToGitHubActions = %q, want %q", got, want)
+	}
+}
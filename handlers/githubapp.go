@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojp/goreportcard/download"
+)
+
+// GitHubAppID is the ID of the GitHub App that posts the grades of pushed
+// commits as commit statuses, with GitHubAppKey. No statuses are posted
+// if it is 0.
+var GitHubAppID int64
+
+// GitHubAppKey is the private key of the GitHub App
+var GitHubAppKey *rsa.PrivateKey
+
+// GitHubMinGrade is the lowest grade of a commit whose status is a
+// success. If it is empty, all graded commits succeed.
+var GitHubMinGrade Grade
+
+// githubAPI is the URL of the GitHub REST API
+var githubAPI = "https://api.github.com"
+
+// statusContext is the context of the commit statuses of reports, which
+// protected branches can require
+const statusContext = "goreportcard"
+
+// LoadGitHubAppKey reads the PEM encoded private key of a GitHub App
+func LoadGitHubAppKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// githubAppEnabled reports whether commit statuses are posted
+func githubAppEnabled() bool {
+	return GitHubAppID != 0 && GitHubAppKey != nil
+}
+
+// githubAppJWT returns the JSON web token that authenticates the GitHub
+// App at now, valid for nine minutes
+func githubAppJWT(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		// a minute early, in case the clock of GitHub is behind
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": GitHubAppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, GitHubAppKey, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// installationToken is an access token of an installation of the GitHub
+// App
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var (
+	installationTokensMu sync.Mutex
+	installationTokens   = make(map[int64]installationToken)
+)
+
+// tokenFor returns an access token of the installation, reusing the last
+// one until shortly before it expires
+func tokenFor(installation int64) (string, error) {
+	installationTokensMu.Lock()
+	defer installationTokensMu.Unlock()
+	if t, ok := installationTokens[installation]; ok && time.Until(t.ExpiresAt) > time.Minute {
+		return t.Token, nil
+	}
+
+	jwt, err := githubAppJWT(time.Now())
+	if err != nil {
+		return "", fmt.Errorf("could not sign app token: %v", err)
+	}
+	var t installationToken
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", githubAPI, installation)
+	if err := githubRequest("POST", url, "Bearer "+jwt, nil, &t); err != nil {
+		return "", fmt.Errorf("could not get installation token: %v", err)
+	}
+	installationTokens[installation] = t
+	return t.Token, nil
+}
+
+// commitStatus is a status of a commit in the GitHub API
+type commitStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// newCommitStatus returns the status of the commit of the report with the
+// key: a failure if its grade is lower than min, and a success otherwise.
+// The status links to the report unless it is private, as the link would
+// not be found without its token.
+func newCommitStatus(key string, resp checksResp, min Grade) commitStatus {
+	s := commitStatus{
+		State:       "success",
+		Description: fmt.Sprintf("Grade %s (%.1f%%)", resp.Grade, resp.Score),
+		Context:     reportContext(key),
+	}
+	if resp.readableWith("") {
+		s.TargetURL = "https://" + *domain + "/report/" + key
+	}
+	if min != "" && !resp.Grade.AtLeast(min) {
+		s.State = "failure"
+		s.Description += fmt.Sprintf(", below %s", min)
+	}
+	return s
+}
+
+// reportContext returns the context of the statuses of the report with the
+// key, which differs for the reports of the directories of a repo
+func reportContext(key string) string {
+	repo, _ := download.SplitRef(key)
+	parts := strings.SplitN(repo, "/", 4)
+	if len(parts) < 4 {
+		return statusContext
+	}
+	return statusContext + "/" + parts[3]
+}
+
+// postCommitStatus posts the status of the commit to the GitHub repo, the
+// full name of which is owner/name, as the installation of the GitHub App
+func postCommitStatus(installation int64, repo, commit string, status commitStatus) error {
+	token, err := tokenFor(installation)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/repos/%s/statuses/%s", githubAPI, repo, commit)
+	return githubRequest("POST", url, "token "+token, status, nil)
+}
+
+// githubRequest sends a request with the JSON of body to the GitHub API,
+// and decodes the JSON of the response into out, if it is not nil
+func githubRequest(method, url, auth string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", auth)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withGitHubApp sets up the GitHub App with a new key, and the GitHub API
+// at the URL of a test server with handler
+func withGitHubApp(t *testing.T, handler http.HandlerFunc) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	id, oldKey, api := GitHubAppID, GitHubAppKey, githubAPI
+	t.Cleanup(func() {
+		srv.Close()
+		GitHubAppID, GitHubAppKey, githubAPI = id, oldKey, api
+		installationTokens = make(map[int64]installationToken)
+	})
+	GitHubAppID, GitHubAppKey, githubAPI = 42, key, srv.URL
+}
+
+func TestGitHubAppJWT(t *testing.T) {
+	withGitHubApp(t, nil)
+	now := time.Unix(1700000000, 0)
+	jwt, err := githubAppJWT(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("githubAppJWT = %q, want 3 parts", jwt)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&GitHubAppKey.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		t.Errorf("signature of githubAppJWT does not verify: %v", err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]int64
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != 42 || claims["iat"] != now.Unix()-60 || claims["exp"] != now.Unix()+540 {
+		t.Errorf("claims of githubAppJWT = %v", claims)
+	}
+}
+
+func TestNewCommitStatus(t *testing.T) {
+	cases := []struct {
+		key   string
+		grade Grade
+		min   Grade
+		want  commitStatus
+	}{
+		{"github.com/foo/bar", GradeB, "", commitStatus{"success", "https://goreportcard.com/report/github.com/foo/bar", "Grade B (75.0%)", "goreportcard"}},
+		{"github.com/foo/bar", GradeB, GradeB, commitStatus{"success", "https://goreportcard.com/report/github.com/foo/bar", "Grade B (75.0%)", "goreportcard"}},
+		{"github.com/foo/bar@dev", GradeC, GradeB, commitStatus{"failure", "https://goreportcard.com/report/github.com/foo/bar@dev", "Grade C (75.0%), below B", "goreportcard"}},
+		{"github.com/foo/bar/services/api", GradeA, GradeB, commitStatus{"success", "https://goreportcard.com/report/github.com/foo/bar/services/api", "Grade A (75.0%)", "goreportcard/services/api"}},
+	}
+	for _, c := range cases {
+		got := newCommitStatus(c.key, checksResp{Grade: c.grade, Score: 75}, c.min)
+		if got != c.want {
+			t.Errorf("[%q] newCommitStatus = %+v, want %+v", c.key, got, c.want)
+		}
+	}
+}
+
+func TestPostJobStatus(t *testing.T) {
+	var tokens int
+	var statuses []commitStatus
+	withGitHubApp(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/app/installations/7/access_tokens" && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "):
+			tokens++
+			json.NewEncoder(w).Encode(installationToken{"tok", time.Now().Add(time.Hour)})
+		case r.URL.Path == "/repos/foo/bar/statuses/abc" && r.Header.Get("Authorization") == "token tok":
+			var s commitStatus
+			json.NewDecoder(r.Body).Decode(&s)
+			statuses = append(statuses, s)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "unexpected request", http.StatusNotFound)
+		}
+	})
+
+	job := regradeJob{key: "github.com/foo/bar", repo: "foo/bar", commit: "abc", installation: 7}
+	if err := postJobStatus(job, checksResp{Grade: GradeA, Score: 85}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := postJobStatus(job, checksResp{}, errors.New("clone failed")); err != nil {
+		t.Fatal(err)
+	}
+	if tokens != 1 {
+		t.Errorf("got %d installation tokens, want 1", tokens)
+	}
+	if len(statuses) != 2 || statuses[0].State != "success" || statuses[1].State != "error" {
+		t.Errorf("posted statuses %+v, want a success and an error", statuses)
+	}
+	if statuses[0].TargetURL != "https://goreportcard.com/report/github.com/foo/bar" {
+		t.Errorf("status of a public report links to %q, want the report", statuses[0].TargetURL)
+	}
+
+	// a private repo that is pushed to for the first time is graded with
+	// the token of the installation, so nobody can read its report
+	private := checksResp{Grade: GradeA, Score: 85, AccessHash: tokenHash("tok")}
+	if err := postJobStatus(job, private, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := statuses[len(statuses)-1]; s.State != "success" || s.TargetURL != "" {
+		t.Errorf("status of a private report = %+v, want a success without a link", s)
+	}
+
+	// the commit that was graded gets the status
+	if err := postJobStatus(job, checksResp{Commit: "def"}, nil); err == nil {
+		t.Errorf("postJobStatus of commit def succeeded, want an error")
+	}
+}
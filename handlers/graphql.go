@@ -0,0 +1,678 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+	"github.com/gojp/goreportcard/download"
+)
+
+// GraphQLSchema describes the data that the GraphQL API at /graphql
+// serves, in the GraphQL schema language
+const GraphQLSchema = `# A graded repo, by its import path, at its default branch or at ref
+type Query {
+  repo(path: String!, ref: String): Repo
+}
+
+type Repo {
+  path: String!
+  ref: String
+  commit: String
+  grade: String!
+  score: Float!
+  files: Int!
+  issues: Int!
+  lastRefresh: String!
+  # The checks of the latest grade, or only those with the names
+  checks(names: [String!]): [Check!]!
+  # The grades of the repo, oldest first, or only the last ones
+  runs(last: Int): [Run!]!
+}
+
+type Run {
+  time: String!
+  commit: String
+  grade: String!
+  score: Float!
+  checks(names: [String!]): [Check!]!
+}
+
+type Check {
+  name: String!
+  description: String
+  weight: Float!
+  percentage: Float!
+  error: String
+  issues: Int!
+  # The files with issues, or only those in the package with the
+  # directory, relative to the repo root, or with the path
+  files(package: String, path: String): [File!]!
+}
+
+type File {
+  filename: String!
+  fileURL: String
+  package: String!
+  errors(severity: String, ruleID: String): [Error!]!
+}
+
+type Error {
+  line: Int!
+  column: Int!
+  message: String!
+  ruleID: String
+  severity: String
+}
+`
+
+// maxGraphQLRequest is the size of the largest GraphQL request
+const maxGraphQLRequest = 1 << 20
+
+// graphQLRequest is the body of a GraphQL request
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	// token is the access token of the request, which private reports
+	// are only resolved with
+	token string
+}
+
+// graphQLError is an error in the response to a GraphQL request, at the
+// path of the field it happened in
+type graphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// graphQLResponse is the response to a GraphQL request
+type graphQLResponse struct {
+	Data   *gqlResult     `json:"data,omitempty"`
+	Errors []graphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLHandler handles the GraphQL API. A GET request without a query
+// gets the schema.
+func GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	switch r.Method {
+	case "GET", "HEAD":
+		req.Query, req.OperationName = r.FormValue("query"), r.FormValue("operationName")
+		if req.Query == "" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, GraphQLSchema)
+			return
+		}
+		if v := r.FormValue("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQLError(w, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	case "POST":
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxGraphQLRequest+1))
+		if err != nil || len(body) > maxGraphQLRequest {
+			writeGraphQLError(w, "could not read request")
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			req.Query = string(body)
+		} else if err := json.Unmarshal(body, &req); err != nil {
+			writeGraphQLError(w, "invalid request: "+err.Error())
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req.token = accessToken(r)
+	resp, err := execGraphQL(req)
+	if err != nil {
+		writeGraphQLError(w, err.Error())
+		return
+	}
+	writeAPI(w, http.StatusOK, resp)
+}
+
+// writeGraphQLError writes the response to a GraphQL request that could
+// not be executed
+func writeGraphQLError(w http.ResponseWriter, msg string) {
+	writeAPI(w, http.StatusBadRequest, graphQLResponse{Errors: []graphQLError{{Message: msg}}})
+}
+
+// execGraphQL executes the query of req. It returns an error if the query
+// could not be executed at all, while field errors are in the response.
+func execGraphQL(req graphQLRequest) (graphQLResponse, error) {
+	doc, err := parseGraphQL(req.Query)
+	if err != nil {
+		return graphQLResponse{}, fmt.Errorf("could not parse query: %v", err)
+	}
+	var op *gqlOperation
+	for i, o := range doc.operations {
+		if req.OperationName == "" && len(doc.operations) == 1 || o.name == req.OperationName {
+			op = &doc.operations[i]
+		}
+	}
+	if op == nil {
+		return graphQLResponse{}, fmt.Errorf("no operation %q in query", req.OperationName)
+	}
+
+	vars := make(map[string]interface{})
+	for _, v := range op.variables {
+		val, ok := req.Variables[v.name]
+		switch {
+		case ok && val != nil:
+			vars[v.name] = val
+		case v.required:
+			return graphQLResponse{}, fmt.Errorf("variable $%s is required", v.name)
+		default:
+			vars[v.name] = v.def.value
+		}
+	}
+
+	e := &gqlExec{doc: doc, vars: vars}
+	data := e.object(gqlQuery{token: req.token}, op.selection, nil, nil)
+	return graphQLResponse{Data: data, Errors: e.errors}, nil
+}
+
+// gqlObject is a value of an object type of GraphQLSchema
+type gqlObject interface {
+	typename() string
+	// resolve returns the value of the field with the arguments: a
+	// scalar, a gqlObject, a []gqlObject or nil
+	resolve(field string, args map[string]interface{}) (interface{}, error)
+}
+
+// gqlExec executes a query of a document
+type gqlExec struct {
+	doc    *gqlDocument
+	vars   map[string]interface{}
+	errors []graphQLError
+}
+
+// gqlResult is a JSON object whose fields keep the order of the query
+type gqlResult struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (r *gqlResult) set(key string, v interface{}) {
+	if old, ok := r.values[key]; ok {
+		// a field selected twice, such as in a fragment, is merged
+		if o, ok := old.(*gqlResult); ok {
+			if n, ok := v.(*gqlResult); ok {
+				for _, k := range n.keys {
+					o.set(k, n.values[k])
+				}
+				return
+			}
+		}
+	} else {
+		r.keys = append(r.keys, key)
+	}
+	r.values[key] = v
+}
+
+// MarshalJSON writes the fields of r in order
+func (r *gqlResult) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// fail records an error at the path
+func (e *gqlExec) fail(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, graphQLError{Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+// object returns the fields of obj in the selection set sel. spreads are
+// the fragments that sel is in, which may not be spread again.
+func (e *gqlExec) object(obj gqlObject, sel []gqlSelection, path []interface{}, spreads []string) *gqlResult {
+	r := &gqlResult{values: make(map[string]interface{})}
+	for _, s := range sel {
+		if s.fragment != "" {
+			frag, ok := e.doc.fragments[s.fragment]
+			if !ok || contains(spreads, s.fragment) {
+				e.fail(path, "unknown or recursive fragment %q", s.fragment)
+				continue
+			}
+			f := e.object(obj, frag, path, append(spreads[:len(spreads):len(spreads)], s.fragment))
+			for _, k := range f.keys {
+				r.set(k, f.values[k])
+			}
+			continue
+		}
+
+		fieldPath := append(path[:len(path):len(path)], s.alias)
+		if s.name == "__typename" {
+			r.set(s.alias, obj.typename())
+			continue
+		}
+		args, err := e.args(s.args)
+		if err != nil {
+			e.fail(fieldPath, "%v", err)
+			r.set(s.alias, nil)
+			continue
+		}
+		v, err := obj.resolve(s.name, args)
+		if err != nil {
+			e.fail(fieldPath, "%v", err)
+			r.set(s.alias, nil)
+			continue
+		}
+		r.set(s.alias, e.value(v, s, fieldPath, spreads))
+	}
+	return r
+}
+
+// value returns the JSON value of the field s whose value is v
+func (e *gqlExec) value(v interface{}, s gqlSelection, path []interface{}, spreads []string) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case gqlObject:
+		if len(s.selection) == 0 {
+			e.fail(path, "field %q must have a selection of subfields", s.name)
+			return nil
+		}
+		return e.object(v, s.selection, path, spreads)
+	case []gqlObject:
+		if len(s.selection) == 0 {
+			e.fail(path, "field %q must have a selection of subfields", s.name)
+			return nil
+		}
+		list := make([]interface{}, len(v))
+		for i, o := range v {
+			list[i] = e.value(o, s, append(path[:len(path):len(path)], i), spreads)
+		}
+		return list
+	}
+	if len(s.selection) > 0 {
+		e.fail(path, "field %q has no subfields", s.name)
+		return nil
+	}
+	return v
+}
+
+// args returns the arguments of a field, with the values of variables
+func (e *gqlExec) args(args map[string]gqlValue) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(args))
+	for name, a := range args {
+		if a.variable == "" {
+			values[name] = a.value
+			continue
+		}
+		v, ok := e.vars[a.variable]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", a.variable)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// onlyArgs returns an error if args has arguments other than names
+func onlyArgs(args map[string]interface{}, names ...string) error {
+	for a := range args {
+		if !contains(names, a) {
+			return fmt.Errorf("unknown argument %q", a)
+		}
+	}
+	return nil
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// stringArg returns the string argument, or "" if it is not set
+func stringArg(args map[string]interface{}, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a string", name)
+}
+
+// intArg returns the int argument, or def if it is not set
+func intArg(args map[string]interface{}, name string, def int) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		// variables are decoded from JSON as floats
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an int", name)
+}
+
+// stringsArg returns the list of strings argument, which may be a single
+// string, or nil if it is not set
+func stringsArg(args map[string]interface{}, name string) ([]string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, s := range v {
+			var ok bool
+			if list[i], ok = s.(string); !ok {
+				return nil, fmt.Errorf("argument %q must be a list of strings", name)
+			}
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("argument %q must be a list of strings", name)
+}
+
+// unknownField returns the error of a field that the type does not have
+func unknownField(typename, field string) error {
+	return fmt.Errorf("unknown field %q of type %s", field, typename)
+}
+
+// nullable returns nil for the empty string, which is null in responses
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// gqlQuery is the Query type
+type gqlQuery struct {
+	token string
+}
+
+func (gqlQuery) typename() string { return "Query" }
+
+func (q gqlQuery) resolve(field string, args map[string]interface{}) (interface{}, error) {
+	if field != "repo" {
+		return nil, unknownField(q.typename(), field)
+	}
+	if err := onlyArgs(args, "path", "ref"); err != nil {
+		return nil, err
+	}
+	repo, err := stringArg(args, "path")
+	if err != nil {
+		return nil, err
+	}
+	if repo == "" {
+		return nil, fmt.Errorf("argument %q is required", "path")
+	}
+	ref, err := stringArg(args, "ref")
+	if err != nil {
+		return nil, err
+	}
+	key := repoKey(repo, ref)
+	resp, err := getFromCache(key)
+	if err == nil && !resp.readableWith(q.token) {
+		err = errPrivateReport
+	}
+	if err != nil {
+		// repos that were never graded, or are private, are null
+		slog.Info("repo not in cache", "repo", key, "error", err)
+		return nil, nil
+	}
+	resp.AccessHash = ""
+	return gqlRepo{key: key, resp: resp}, nil
+}
+
+// gqlRepo is the Repo type
+type gqlRepo struct {
+	key  string
+	resp checksResp
+}
+
+func (gqlRepo) typename() string { return "Repo" }
+
+func (g gqlRepo) resolve(field string, args map[string]interface{}) (interface{}, error) {
+	repo, ref := download.SplitRef(g.key)
+	switch field {
+	case "path":
+		return repo, nil
+	case "ref":
+		return nullable(ref), nil
+	case "commit":
+		return nullable(g.resp.Commit), nil
+	case "grade":
+		// the grade is not stored for some repos
+		gr, _ := GradeResults(g.resp.Checks)
+		return gr, nil
+	case "score":
+		_, score := GradeResults(g.resp.Checks)
+		return score, nil
+	case "files":
+		return g.resp.Files, nil
+	case "issues":
+		return g.resp.Issues, nil
+	case "lastRefresh":
+		return g.resp.LastRefresh.Format(time.RFC3339), nil
+	case "checks":
+		return gqlChecks(dirName(repo), g.resp.Checks, args)
+	case "runs":
+		if err := onlyArgs(args, "last"); err != nil {
+			return nil, err
+		}
+		last, err := intArg(args, "last", 0)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := getHistory(g.key)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the history: %v", err)
+		}
+		if last > 0 && last < len(entries) {
+			entries = entries[len(entries)-last:]
+		}
+		runs := make([]gqlObject, len(entries))
+		for i, e := range entries {
+			runs[i] = gqlRun{dir: dirName(repo), entry: e}
+		}
+		return runs, nil
+	}
+	return nil, unknownField(g.typename(), field)
+}
+
+// gqlChecks returns the checks in results of the repo in dir, with the
+// names argument
+func gqlChecks(dir string, results []check.CheckResult, args map[string]interface{}) ([]gqlObject, error) {
+	if err := onlyArgs(args, "names"); err != nil {
+		return nil, err
+	}
+	names, err := stringsArg(args, "names")
+	if err != nil {
+		return nil, err
+	}
+	checks := []gqlObject{}
+	for _, r := range results {
+		if names == nil || contains(names, r.Name) {
+			checks = append(checks, gqlCheck{dir: dir, result: r})
+		}
+	}
+	return checks, nil
+}
+
+// gqlRun is the Run type
+type gqlRun struct {
+	dir   string
+	entry historyEntry
+}
+
+func (gqlRun) typename() string { return "Run" }
+
+func (g gqlRun) resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "time":
+		return g.entry.Time.Format(time.RFC3339), nil
+	case "commit":
+		return nullable(g.entry.Commit), nil
+	case "grade":
+		return g.entry.Grade, nil
+	case "score":
+		return g.entry.Score, nil
+	case "checks":
+		return gqlChecks(g.dir, g.entry.results(), args)
+	}
+	return nil, unknownField(g.typename(), field)
+}
+
+// gqlCheck is the Check type
+type gqlCheck struct {
+	dir    string
+	result check.CheckResult
+}
+
+func (gqlCheck) typename() string { return "Check" }
+
+func (g gqlCheck) resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return g.result.Name, nil
+	case "description":
+		return nullable(g.result.Description), nil
+	case "weight":
+		return g.result.Weight, nil
+	case "percentage":
+		return g.result.Percentage, nil
+	case "error":
+		return nullable(g.result.Error), nil
+	case "issues":
+		var n int
+		for _, fs := range g.result.FileSummaries {
+			n += len(fs.Errors)
+		}
+		return n, nil
+	case "files":
+		if err := onlyArgs(args, "package", "path"); err != nil {
+			return nil, err
+		}
+		pkg, err := stringArg(args, "package")
+		if err != nil {
+			return nil, err
+		}
+		fp, err := stringArg(args, "path")
+		if err != nil {
+			return nil, err
+		}
+		files := []gqlObject{}
+		for _, fs := range g.result.FileSummaries {
+			f := gqlFile{rel: check.RelFilename(g.dir, fs.Filename), summary: fs}
+			if pkg != "" && f.pkg() != strings.Trim(pkg, "/") || fp != "" && f.rel != fp {
+				continue
+			}
+			files = append(files, f)
+		}
+		return files, nil
+	}
+	return nil, unknownField(g.typename(), field)
+}
+
+// gqlFile is the File type
+type gqlFile struct {
+	// rel is the name of the file relative to the repo root
+	rel     string
+	summary check.FileSummary
+}
+
+func (gqlFile) typename() string { return "File" }
+
+// pkg returns the directory of the package of the file, relative to the
+// repo root
+func (g gqlFile) pkg() string {
+	return path.Dir(g.rel)
+}
+
+func (g gqlFile) resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "filename":
+		return g.summary.Filename, nil
+	case "fileURL":
+		return nullable(g.summary.FileURL), nil
+	case "package":
+		return g.pkg(), nil
+	case "errors":
+		if err := onlyArgs(args, "severity", "ruleID"); err != nil {
+			return nil, err
+		}
+		severity, err := stringArg(args, "severity")
+		if err != nil {
+			return nil, err
+		}
+		rule, err := stringArg(args, "ruleID")
+		if err != nil {
+			return nil, err
+		}
+		errs := []gqlObject{}
+		for _, e := range g.summary.Errors {
+			if severity != "" && e.Severity != severity || rule != "" && e.RuleID != rule {
+				continue
+			}
+			errs = append(errs, gqlIssue(e))
+		}
+		return errs, nil
+	}
+	return nil, unknownField(g.typename(), field)
+}
+
+// gqlIssue is the Error type
+type gqlIssue check.Error
+
+func (gqlIssue) typename() string { return "Error" }
+
+func (g gqlIssue) resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "line":
+		return g.LineNumber, nil
+	case "column":
+		return g.Column, nil
+	case "message":
+		return strings.TrimSpace(g.ErrorString), nil
+	case "ruleID":
+		return nullable(g.RuleID), nil
+	case "severity":
+		return nullable(g.Severity), nil
+	}
+	return nil, unknownField(g.typename(), field)
+}
@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// gqlDocument is a parsed GraphQL query document. Only the parts of the
+// language that queries of the GraphQL API need are supported: queries
+// with variables, aliases, arguments and fragments, but no mutations,
+// subscriptions or directives.
+type gqlDocument struct {
+	operations []gqlOperation
+	fragments  map[string][]gqlSelection
+}
+
+// gqlOperation is a query of a gqlDocument
+type gqlOperation struct {
+	name      string
+	variables []gqlVariable
+	selection []gqlSelection
+}
+
+// gqlVariable is the definition of a variable of a query
+type gqlVariable struct {
+	name     string
+	required bool
+	def      gqlValue
+}
+
+// gqlSelection is a field of a selection set, or a fragment spread if
+// fragment is set
+type gqlSelection struct {
+	alias, name string
+	args        map[string]gqlValue
+	selection   []gqlSelection
+	fragment    string
+}
+
+// gqlValue is an argument value: a constant, or a variable if variable is
+// set
+type gqlValue struct {
+	variable string
+	value    interface{}
+}
+
+// gqlToken is a token of the GraphQL language. kind is 'n' for names,
+// 'i' for ints, 'f' for floats, 's' for strings, 'p' for punctuators and
+// 0 at the end of the document.
+type gqlToken struct {
+	kind byte
+	text string
+	pos  int
+}
+
+// lexGraphQL splits src into tokens, without the ignored commas, white
+// space and comments
+func lexGraphQL(src string) ([]gqlToken, error) {
+	var toks []gqlToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			toks = append(toks, gqlToken{'p', "...", i})
+			i += 3
+		case strings.IndexByte("!$():=@[]{}|&", c) >= 0:
+			toks = append(toks, gqlToken{'p', string(c), i})
+			i++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, gqlToken{'n', src[i:j], i})
+			i = j
+		case c == '-' || c >= '0' && c <= '9':
+			j, kind := i+1, byte('i')
+			for j < len(src) && strings.IndexByte("0123456789.eE+-", src[j]) >= 0 {
+				if strings.IndexByte(".eE", src[j]) >= 0 {
+					kind = 'f'
+				}
+				j++
+			}
+			toks = append(toks, gqlToken{kind, src[i:j], i})
+			i = j
+		case c == '"':
+			if strings.HasPrefix(src[i:], `"""`) {
+				return nil, fmt.Errorf("block strings are not supported, at %d", i)
+			}
+			j := i + 1
+			for j < len(src) && src[j] != '"' && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) || src[j] != '"' {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, gqlToken{'s', src[i : j+1], i})
+			i = j + 1
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+	return append(toks, gqlToken{pos: len(src)}), nil
+}
+
+// gqlParser parses a GraphQL document from its tokens
+type gqlParser struct {
+	toks []gqlToken
+	i    int
+}
+
+// parseGraphQL parses the GraphQL document src
+func parseGraphQL(src string) (*gqlDocument, error) {
+	toks, err := lexGraphQL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &gqlParser{toks: toks}
+	doc := &gqlDocument{fragments: make(map[string][]gqlSelection)}
+	for p.peek().kind != 0 {
+		switch t := p.peek(); {
+		case t.kind == 'p' && t.text == "{":
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, gqlOperation{selection: sel})
+		case t.kind == 'n' && t.text == "query":
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case t.kind == 'n' && t.text == "fragment":
+			name, sel, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[name]; ok {
+				return nil, fmt.Errorf("fragment %q is defined twice", name)
+			}
+			doc.fragments[name] = sel
+		case t.kind == 'n' && (t.text == "mutation" || t.text == "subscription"):
+			return nil, fmt.Errorf("%ss are not supported", t.text)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document has no query")
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) peek() gqlToken {
+	return p.toks[p.i]
+}
+
+func (p *gqlParser) next() gqlToken {
+	t := p.toks[p.i]
+	if t.kind != 0 {
+		p.i++
+	}
+	return t
+}
+
+// skip consumes the punctuator text if it is next, and reports whether
+// it was
+func (p *gqlParser) skip(text string) bool {
+	if t := p.peek(); t.kind == 'p' && t.text == text {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *gqlParser) expect(text string) error {
+	if !p.skip(text) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *gqlParser) name() (string, error) {
+	if p.peek().kind != 'n' {
+		return "", p.unexpected()
+	}
+	return p.next().text, nil
+}
+
+func (p *gqlParser) unexpected() error {
+	t := p.peek()
+	if t.kind == 0 {
+		return fmt.Errorf("unexpected end of document")
+	}
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// operation parses a query with the query keyword
+func (p *gqlParser) operation() (gqlOperation, error) {
+	var op gqlOperation
+	p.next()
+	if p.peek().kind == 'n' {
+		op.name = p.next().text
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			v, err := p.variable()
+			if err != nil {
+				return op, err
+			}
+			op.variables = append(op.variables, v)
+		}
+	}
+	if p.peek().text == "@" {
+		return op, fmt.Errorf("directives are not supported, at %d", p.peek().pos)
+	}
+	sel, err := p.selectionSet()
+	op.selection = sel
+	return op, err
+}
+
+// variable parses the definition of a variable, of which only whether
+// its type is non-null is kept
+func (p *gqlParser) variable() (gqlVariable, error) {
+	var v gqlVariable
+	if err := p.expect("$"); err != nil {
+		return v, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return v, err
+	}
+	v.name = name
+	if err := p.expect(":"); err != nil {
+		return v, err
+	}
+	if err := p.typeRef(); err != nil {
+		return v, err
+	}
+	v.required = p.toks[p.i-1].text == "!"
+	if p.skip("=") {
+		if v.def, err = p.value(true); err != nil {
+			return v, err
+		}
+		v.required = false
+	}
+	return v, nil
+}
+
+// typeRef parses a type, like [String!]!
+func (p *gqlParser) typeRef() error {
+	if p.skip("[") {
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	p.skip("!")
+	return nil
+}
+
+// fragment parses a fragment definition
+func (p *gqlParser) fragment() (string, []gqlSelection, error) {
+	p.next()
+	name, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	if on, err := p.name(); err != nil || on != "on" {
+		return "", nil, fmt.Errorf("fragment %q has no type condition", name)
+	}
+	if _, err := p.name(); err != nil {
+		return "", nil, err
+	}
+	sel, err := p.selectionSet()
+	return name, sel, err
+}
+
+// selectionSet parses the fields between braces, with inline fragments
+// merged into them
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sel []gqlSelection
+	for !p.skip("}") {
+		if p.skip("...") {
+			// there is a single type with each set of fields, so type
+			// conditions always apply
+			if t := p.peek(); t.kind == 'n' && t.text != "on" {
+				sel = append(sel, gqlSelection{fragment: p.next().text})
+				continue
+			}
+			if p.peek().text == "on" {
+				p.next()
+				if _, err := p.name(); err != nil {
+					return nil, err
+				}
+			}
+			inline, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			sel = append(sel, inline...)
+			continue
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, f)
+	}
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return sel, nil
+}
+
+// field parses a field with its alias, arguments and selection set
+func (p *gqlParser) field() (gqlSelection, error) {
+	var f gqlSelection
+	name, err := p.name()
+	if err != nil {
+		return f, err
+	}
+	f.alias, f.name = name, name
+	if p.skip(":") {
+		if f.name, err = p.name(); err != nil {
+			return f, err
+		}
+	}
+	if p.skip("(") {
+		f.args = make(map[string]gqlValue)
+		for !p.skip(")") {
+			arg, err := p.name()
+			if err != nil {
+				return f, err
+			}
+			if err := p.expect(":"); err != nil {
+				return f, err
+			}
+			if f.args[arg], err = p.value(false); err != nil {
+				return f, err
+			}
+		}
+	}
+	if p.peek().text == "@" {
+		return f, fmt.Errorf("directives are not supported, at %d", p.peek().pos)
+	}
+	if p.peek().text == "{" {
+		f.selection, err = p.selectionSet()
+	}
+	return f, err
+}
+
+// value parses an argument value, which may not contain variables if it
+// is constant
+func (p *gqlParser) value(constant bool) (gqlValue, error) {
+	t := p.next()
+	switch {
+	case t.kind == 'p' && t.text == "$" && !constant:
+		name, err := p.name()
+		return gqlValue{variable: name}, err
+	case t.kind == 'i':
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return gqlValue{}, fmt.Errorf("invalid int %q at %d", t.text, t.pos)
+		}
+		return gqlValue{value: n}, nil
+	case t.kind == 'f':
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return gqlValue{}, fmt.Errorf("invalid float %q at %d", t.text, t.pos)
+		}
+		return gqlValue{value: f}, nil
+	case t.kind == 's':
+		// the escapes of GraphQL strings are those of JSON
+		var s string
+		if err := json.Unmarshal([]byte(t.text), &s); err != nil {
+			return gqlValue{}, fmt.Errorf("invalid string at %d", t.pos)
+		}
+		return gqlValue{value: s}, nil
+	case t.kind == 'n':
+		switch t.text {
+		case "true":
+			return gqlValue{value: true}, nil
+		case "false":
+			return gqlValue{value: false}, nil
+		case "null":
+			return gqlValue{}, nil
+		}
+		// enum values are passed as their names
+		return gqlValue{value: t.text}, nil
+	case t.kind == 'p' && t.text == "[":
+		list := []interface{}{}
+		for !p.skip("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return gqlValue{}, err
+			}
+			if v.variable != "" {
+				return gqlValue{}, fmt.Errorf("variables in lists are not supported, at %d", t.pos)
+			}
+			list = append(list, v.value)
+		}
+		return gqlValue{value: list}, nil
+	}
+	if t.kind != 0 {
+		p.i--
+	}
+	return gqlValue{}, p.unexpected()
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gojp/goreportcard/check"
+)
+
+var parseGraphQLErrorTests = []struct {
+	query string
+	want  string
+}{
+	{`{ repo(path: "x") { grade }`, "unexpected end of document"},
+	{`mutation { repo }`, "mutations are not supported"},
+	{`{ repo(path: "x") @include(if: true) { grade } }`, "directives are not supported"},
+	{`query Q($p: String! { repo }`, `unexpected "{"`},
+	{`{ }`, "empty selection set"},
+	{`{ repo(path: "x) }`, "unterminated string"},
+	{`fragment F on Repo { grade }`, "document has no query"},
+}
+
+func TestParseGraphQLErrors(t *testing.T) {
+	for _, tt := range parseGraphQLErrorTests {
+		_, err := parseGraphQL(tt.query)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%q] parseGraphQL error = %v, want %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+// withGraphQLDB changes to a directory with a database in which
+// github.com/foo/bar was graded three times
+func withGraphQLDB(t *testing.T) {
+	withTestDB(t)
+	issue := func(file string, line int, rule string) check.FileSummary {
+		return check.FileSummary{
+			Filename: file,
+			FileURL:  "https://github.com/foo/bar/blob/master/" + file,
+			Errors:   []check.Error{{LineNumber: line, ErrorString: "too complex\n", RuleID: rule, Severity: "warning"}},
+		}
+	}
+	var entries []historyEntry
+	var resp checksResp
+	for i := 0; i < 3; i++ {
+		resp = checksResp{
+			Repo:        "github.com/foo/bar",
+			Commit:      strings.Repeat(string(rune('a'+i)), 40),
+			LastRefresh: time.Date(2020, 1, 1+i, 0, 0, 0, 0, time.UTC),
+			Checks: []check.CheckResult{
+				{Name: "gocyclo", Weight: 1, Percentage: .5, FileSummaries: []check.FileSummary{issue("a.go", 10+i, "gocyclo"), issue("pkg/x/b.go", 20, "gocyclo")}},
+				{Name: "gofmt", Weight: 1, Percentage: 1, Description: "Gofmt formats Go programs."},
+			},
+		}
+		resp.Grade, resp.Score = GradeResults(resp.Checks)
+		entries = append(entries, newHistoryEntry(resp))
+	}
+
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := json.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket([]byte(RepoBucket)).Put([]byte("github.com/foo/bar"), b); err != nil {
+			return err
+		}
+		hb, err := tx.CreateBucket([]byte(HistoryBucket))
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := appendHistory(hb, "github.com/foo/bar", e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecGraphQL(t *testing.T) {
+	withGraphQLDB(t)
+	cases := []struct {
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		{
+			`{ repo(path: "github.com/foo/bar") { path ref grade commit } }`, nil,
+			`{"data":{"repo":{"path":"github.com/foo/bar","ref":null,"grade":"B","commit":"cccccccccccccccccccccccccccccccccccccccc"}}}`,
+		},
+		{
+			// only the gocyclo issues of one package over the last runs
+			`query Cyclo($repo: String!, $n: Int = 2) {
+				repo(path: $repo) {
+					runs(last: $n) {
+						time
+						checks(names: ["gocyclo"]) { name files(package: "pkg/x") { filename package errors { line message } } }
+					}
+				}
+			}`,
+			map[string]interface{}{"repo": "github.com/foo/bar"},
+			`{"data":{"repo":{"runs":[` +
+				`{"time":"2020-01-02T00:00:00Z","checks":[{"name":"gocyclo","files":[{"filename":"pkg/x/b.go","package":"pkg/x","errors":[{"line":20,"message":"too complex"}]}]}]},` +
+				`{"time":"2020-01-03T00:00:00Z","checks":[{"name":"gocyclo","files":[{"filename":"pkg/x/b.go","package":"pkg/x","errors":[{"line":20,"message":"too complex"}]}]}]}]}}}`,
+		},
+		{
+			`{ r: repo(path: "github.com/foo/bar") { ...F checks(names: "gofmt") { __typename d: description } } } fragment F on Repo { score }`, nil,
+			`{"data":{"r":{"score":75,"checks":[{"__typename":"Check","d":"Gofmt formats Go programs."}]}}}`,
+		},
+		{
+			`{ repo(path: "github.com/foo/baz") { grade } }`, nil,
+			`{"data":{"repo":null}}`,
+		},
+		{
+			`{ repo(path: "github.com/foo/bar") { owner checks } }`, nil,
+			`{"data":{"repo":{"owner":null,"checks":null}},"errors":[` +
+				`{"message":"unknown field \"owner\" of type Repo","path":["repo","owner"]},` +
+				`{"message":"field \"checks\" must have a selection of subfields","path":["repo","checks"]}]}`,
+		},
+	}
+	for _, c := range cases {
+		resp, err := execGraphQL(graphQLRequest{Query: c.query, Variables: c.vars})
+		if err != nil {
+			t.Errorf("[%q] execGraphQL error: %v", c.query, err)
+			continue
+		}
+		b, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != c.want {
+			t.Errorf("[%q] execGraphQL =\n%s\nwant\n%s", c.query, got, c.want)
+		}
+	}
+
+	if _, err := execGraphQL(graphQLRequest{Query: `query Q($repo: String!) { repo(path: $repo) { grade } }`}); err == nil {
+		t.Errorf("execGraphQL without a required variable succeeded")
+	}
+}
+
+func TestGraphQLSchemaResolves(t *testing.T) {
+	objects := map[string]gqlObject{
+		"Query": gqlQuery{},
+		"Repo":  gqlRepo{},
+		"Run":   gqlRun{},
+		"Check": gqlCheck{},
+		"File":  gqlFile{},
+		"Error": gqlIssue{},
+	}
+	typeRegexp := regexp.MustCompile(`(?s)type (\w+) \{(.*?)\n\}`)
+	fieldRegexp := regexp.MustCompile(`(?m)^  (\w+)`)
+	types := typeRegexp.FindAllStringSubmatch(GraphQLSchema, -1)
+	if len(types) != len(objects) {
+		t.Errorf("schema has %d types, want %d", len(types), len(objects))
+	}
+	withTestDB(t)
+	for _, typ := range types {
+		obj, ok := objects[typ[1]]
+		if !ok {
+			t.Errorf("type %s of the schema has no resolver", typ[1])
+			continue
+		}
+		for _, f := range fieldRegexp.FindAllStringSubmatch(typ[2], -1) {
+			if _, err := obj.resolve(f[1], nil); err != nil && strings.HasPrefix(err.Error(), "unknown field") {
+				t.Errorf("field %s.%s of the schema does not resolve: %v", typ[1], f[1], err)
+			}
+		}
+	}
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	withGraphQLDB(t)
+	cases := []struct {
+		method, url, contentType, body string
+		want                           int
+	}{
+		{"GET", "/graphql", "", "", 200},
+		{"GET", `/graphql?query={repo(path:"github.com/foo/bar"){grade}}`, "", "", 200},
+		{"POST", "/graphql", "application/json", `{"query":"query($p:String!){repo(path:$p){grade}}","variables":{"p":"github.com/foo/bar"}}`, 200},
+		{"POST", "/graphql", "application/graphql", `{repo(path:"github.com/foo/bar"){grade}}`, 200},
+		{"POST", "/graphql", "application/json", `{"query":"{"}`, 400},
+		{"DELETE", "/graphql", "", "", 405},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, strings.Replace(c.url, `"`, "%22", -1), strings.NewReader(c.body))
+		if c.contentType != "" {
+			r.Header.Set("Content-Type", c.contentType)
+		}
+		w := httptest.NewRecorder()
+		GraphQLHandler(w, r)
+		if w.Code != c.want {
+			t.Errorf("[%s %s %s] status = %d, want %d: %s", c.method, c.url, c.body, w.Code, c.want, w.Body)
+		}
+	}
+}
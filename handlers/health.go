@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// MinFreeDisk is the free space in bytes that the repos directory must
+// have for the server to be ready to grade repos, or 0 to not check it
+var MinFreeDisk uint64 = 1 << 30
+
+// ToolCheckInterval is how long /readyz remembers that the tools of the
+// checks are installed, as finding their versions starts a process for
+// every tool
+var ToolCheckInterval = 10 * time.Minute
+
+// errNoStatfs is returned by freeDisk where the free space of a disk is
+// not known, which is then not checked
+var errNoStatfs = errors.New("free disk space is not known on this platform")
+
+// healthCheck is the result of one of the checks of the health of the
+// server
+type healthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	// Version is the version of a tool, if it prints one
+	Version string `json:"version,omitempty"`
+}
+
+// healthStatus is the body of the responses of /healthz and /readyz
+type healthStatus struct {
+	// Status is ok, or unavailable if a check failed
+	Status string        `json:"status"`
+	Checks []healthCheck `json:"checks"`
+}
+
+var toolCache struct {
+	sync.Mutex
+	checked time.Time
+	checks  []healthCheck
+}
+
+// HealthzHandler handles the liveness probe of the server, which fails
+// if the datastore cannot be read
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, []healthCheck{checkDatastore()})
+}
+
+// ReadyzHandler handles the readiness probe of the server, which fails
+// if the datastore cannot be read, the repos directory is short of
+// MinFreeDisk, or a tool that the checks run is not installed
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	checks := []healthCheck{checkDatastore(), checkDisk()}
+	writeHealth(w, append(checks, checkTools(ctx)...))
+}
+
+// writeHealth writes the checks, with the status code 503 if any of them
+// failed
+func writeHealth(w http.ResponseWriter, checks []healthCheck) {
+	status, code := "ok", http.StatusOK
+	for _, c := range checks {
+		if !c.OK {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeAPI(w, code, healthStatus{Status: status, Checks: checks})
+}
+
+// checkDatastore checks that the reports in DefaultStore can be read
+func checkDatastore() healthCheck {
+	c := healthCheck{Name: "datastore"}
+	if err := DefaultStore.Ping(); err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	c.OK = true
+	return c
+}
+
+// checkDisk checks that the disk of the repos directory has at least
+// MinFreeDisk free
+func checkDisk() healthCheck {
+	c := healthCheck{Name: "disk"}
+	if MinFreeDisk == 0 {
+		c.OK = true
+		return c
+	}
+	free, err := freeDisk(check.ReposDir)
+	switch {
+	case err == errNoStatfs:
+		c.OK = true
+	case err != nil:
+		c.Error = err.Error()
+	case free < MinFreeDisk:
+		c.Error = fmt.Sprintf("%d MB free in %s, want at least %d MB", free>>20, check.ReposDir, MinFreeDisk>>20)
+	default:
+		c.OK = true
+	}
+	return c
+}
+
+// checkTools checks that the tools of the checks are installed. Once
+// they all are, the result is kept for ToolCheckInterval.
+func checkTools(ctx context.Context) []healthCheck {
+	toolCache.Lock()
+	defer toolCache.Unlock()
+	if toolCache.checks != nil && time.Since(toolCache.checked) < ToolCheckInterval {
+		return toolCache.checks
+	}
+
+	var checks []healthCheck
+	ok := true
+	for _, t := range check.Tools {
+		c := healthCheck{Name: "tool:" + t.Name}
+		version, err := check.ToolVersion(ctx, t)
+		if err != nil {
+			c.Error = err.Error()
+			ok = false
+		} else {
+			c.OK, c.Version = true, version
+		}
+		checks = append(checks, c)
+	}
+	if ok {
+		toolCache.checks, toolCache.checked = checks, time.Now()
+	}
+	return checks
+}
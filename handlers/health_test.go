@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestHealthz(t *testing.T) {
+	t.Chdir(t.TempDir())
+	w := httptest.NewRecorder()
+	HealthzHandler(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a database, /healthz code = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	withTestDB(t)
+	w = httptest.NewRecorder()
+	HealthzHandler(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/healthz code = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	withTestDB(t)
+	if err := os.MkdirAll(check.ReposDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(tools []check.Tool) { check.Tools, toolCache.checks = tools, nil }(check.Tools)
+	defer func(min uint64) { MinFreeDisk = min }(MinFreeDisk)
+	MinFreeDisk = 1
+
+	cases := []struct {
+		tools []check.Tool
+		code  int
+	}{
+		{[]check.Tool{{Name: "go", VersionArgs: []string{"version"}}}, http.StatusOK},
+		{[]check.Tool{{Name: "go"}, {Name: "goreportcard-missing-tool"}}, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		check.Tools = c.tools
+		toolCache.checks = nil
+		w := httptest.NewRecorder()
+		ReadyzHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+		if w.Code != c.code {
+			t.Errorf("[%v] /readyz code = %d, want %d: %s", c.tools, w.Code, c.code, w.Body)
+		}
+		var status healthStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(status.Checks); n != 2+len(c.tools) {
+			t.Errorf("[%v] /readyz has %d checks, want %d", c.tools, n, 2+len(c.tools))
+		}
+	}
+
+	check.Tools = nil
+	toolCache.checks = nil
+	MinFreeDisk = 1 << 62
+	w := httptest.NewRecorder()
+	ReadyzHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without free disk, /readyz code = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gojp/goreportcard/check"
+)
+
+// HistoryBucket is the bucket in which every grade of a repo is kept in
+// the bolt DB, in a bucket per repo
+const HistoryBucket string = "history"
+
+// historyEntry is a grade of a repo
+type historyEntry struct {
+	Time   time.Time      `json:"time"`
+	Commit string         `json:"commit,omitempty"`
+	Grade  Grade          `json:"grade"`
+	Score  float64        `json:"score"`
+	Checks []historyCheck `json:"checks"`
+}
+
+// historyCheck is the result of a check in a historyEntry
+type historyCheck struct {
+	Name       string  `json:"name"`
+	Percentage float64 `json:"percentage"`
+	Weight     float64 `json:"weight"`
+	// FileSummaries are the issues found by the check, without their
+	// snippets and related locations
+	FileSummaries []check.FileSummary `json:"file_summaries,omitempty"`
+}
+
+// newHistoryEntry returns the entry of the grade in resp
+func newHistoryEntry(resp checksResp) historyEntry {
+	e := historyEntry{
+		Time:   resp.LastRefresh,
+		Commit: resp.Commit,
+		Grade:  resp.Grade,
+		Score:  resp.Score,
+		Checks: []historyCheck{},
+	}
+	for _, r := range resp.Checks {
+		c := historyCheck{Name: r.Name, Percentage: r.Percentage, Weight: r.Weight}
+		for _, fs := range r.FileSummaries {
+			errs := make([]check.Error, len(fs.Errors))
+			for i, e := range fs.Errors {
+				e.Snippet, e.Related = nil, nil
+				errs[i] = e
+			}
+			c.FileSummaries = append(c.FileSummaries, check.FileSummary{Filename: fs.Filename, FileURL: fs.FileURL, Errors: errs})
+		}
+		e.Checks = append(e.Checks, c)
+	}
+	return e
+}
+
+// results returns the results of the checks of e
+func (e historyEntry) results() []check.CheckResult {
+	results := make([]check.CheckResult, len(e.Checks))
+	for i, c := range e.Checks {
+		results[i] = check.CheckResult{Name: c.Name, Percentage: c.Percentage, Weight: c.Weight, FileSummaries: c.FileSummaries}
+	}
+	return results
+}
+
+// historyKey returns the key of an entry at t, which sorts the entries of
+// a repo by time
+func historyKey(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+// appendHistory adds e to the history of repo in b
+func appendHistory(b *bolt.Bucket, repo string, e historyEntry) error {
+	rb, err := b.CreateBucketIfNotExists([]byte(repo))
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return rb.Put(historyKey(e.Time), v)
+}
+
+// readHistory returns the history of repo in b, oldest first
+func readHistory(b *bolt.Bucket, repo string) ([]historyEntry, error) {
+	entries := []historyEntry{}
+	rb := b.Bucket([]byte(repo))
+	if rb == nil {
+		return entries, nil
+	}
+	err := rb.ForEach(func(k, v []byte) error {
+		var e historyEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return fmt.Errorf("failed to parse history of %q: %v", repo, err)
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// recordHistory adds the grade in resp to the history of its repo
+func recordHistory(resp checksResp) error {
+	return DefaultStore.AppendHistory(repoKey(resp.Repo, resp.Ref), newHistoryEntry(resp))
+}
+
+// getHistory returns the history of repo, oldest first
+func getHistory(repo string) ([]historyEntry, error) {
+	return DefaultStore.History(repo)
+}
+
+// HistoryHandler handles the request for all grades of a repo
+func HistoryHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	if _, err := getReport(r, repo); err == errPrivateReport {
+		http.Error(w, "The repository has not been graded yet", http.StatusNotFound)
+		return
+	}
+	entries, err := getHistory(repo)
+	if err != nil {
+		slog.Error("could not get history", "repo", repo, "error", err)
+		http.Error(w, "Failed to load the history", http.StatusInternalServerError)
+		return
+	}
+	b, err := json.Marshal(map[string]interface{}{"repo": repo, "history": entries})
+	if err != nil {
+		slog.Error("could not marshal json", "repo", repo, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
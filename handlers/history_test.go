@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goreportcard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(HistoryBucket))
+		if err != nil {
+			return err
+		}
+		// added out of order, read by time
+		for _, i := range []int{1, 0, 2} {
+			resp := checksResp{
+				Repo:        "github.com/foo/bar",
+				Commit:      string(rune('a' + i)),
+				Grade:       GradeA,
+				Score:       float64(80 + i),
+				Checks:      []check.CheckResult{{Name: "gofmt", Percentage: 0.9, Weight: 0.3}},
+				LastRefresh: start.Add(time.Duration(i) * time.Hour),
+			}
+			if err := appendHistory(b, resp.Repo, newHistoryEntry(resp)); err != nil {
+				return err
+			}
+		}
+		return appendHistory(b, "github.com/foo/baz", historyEntry{Time: start})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []historyEntry
+	for i := 0; i < 3; i++ {
+		want = append(want, historyEntry{
+			Time:   start.Add(time.Duration(i) * time.Hour),
+			Commit: string(rune('a' + i)),
+			Grade:  GradeA,
+			Score:  float64(80 + i),
+			Checks: []historyCheck{{Name: "gofmt", Percentage: 0.9, Weight: 0.3}},
+		})
+	}
+
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(HistoryBucket))
+		got, err := readHistory(b, "github.com/foo/bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("readHistory = %+v, want %+v", got, want)
+		}
+		if got, _ := readHistory(b, "github.com/foo/none"); len(got) != 0 {
+			t.Errorf("readHistory of an unknown repo = %+v, want none", got)
+		}
+		return nil
+	})
+}
+
+func TestReportHandlerPages(t *testing.T) {
+	tmpl, err := ioutil.ReadFile(filepath.Join("..", "templates", "report.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTestDB(t, "github.com/foo/bar", "github.com/foo/bar/diff")
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte(HistoryBucket))
+		return err
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("templates", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join("templates", "report.html"), tmpl, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path, repo string
+		want       int
+		json       bool
+	}{
+		// the report of a subdirectory named like a page
+		{"/report/github.com/foo/bar/diff", "github.com/foo/bar/diff", 200, false},
+		{"/report/github.com/foo/bar/diff?page=explain", "github.com/foo/bar/diff", 200, true},
+		{"/report/github.com/foo/bar?page=history", "github.com/foo/bar", 200, true},
+		{"/report/github.com/foo/bar?page=nosuchpage", "github.com/foo/bar", 404, false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", c.path, nil)
+		w := httptest.NewRecorder()
+		ReportHandler(w, r, c.repo, false)
+		if w.Code != c.want {
+			t.Errorf("[%s] status = %d, want %d: %s", c.path, w.Code, c.want, w.Body)
+			continue
+		}
+		if isJSON := w.Header().Get("Content-Type") == "application/json"; isJSON != c.json {
+			t.Errorf("[%s] JSON = %v, want %v: %s", c.path, isJSON, c.json, w.Body)
+		}
+	}
+}
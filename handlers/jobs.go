@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gojp/goreportcard/download"
+)
+
+// JobBucket is the bucket of the jobs that grade repos, by their ID
+const JobBucket string = "jobs"
+
+// JobsPath is the path under which the status of jobs is served
+const JobsPath = "/checks/jobs/"
+
+// GradeWorkers is the number of repos that are graded at the same time
+var GradeWorkers = 2
+
+// JobRetention is how long finished jobs are kept for clients to read
+// their status
+var JobRetention = 24 * time.Hour
+
+// jobQueueSize is the number of jobs that can wait to be graded
+const jobQueueSize = 1000
+
+// errQueueFull is returned when a job is added to a full queue
+var errQueueFull = errors.New("too many repos waiting to be graded")
+
+// the states of jobs
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// gradeJob is a request to grade a repo, which is saved in JobBucket so
+// that it survives restarts of the server
+type gradeJob struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+	// Refresh grades the repo even if it was graded before
+	Refresh bool `json:"refresh,omitempty"`
+	// Private is set for jobs that clone the repo with an access token.
+	// The token is only kept in memory.
+	Private bool `json:"private,omitempty"`
+	// Installation is the installation of the GitHub App whose token
+	// clones the repo, for jobs that grade a push again
+	Installation int64     `json:"installation,omitempty"`
+	MinGrade     Grade     `json:"min_grade,omitempty"`
+	State        string    `json:"state"`
+	Error        string    `json:"error,omitempty"`
+	Grade        Grade     `json:"grade,omitempty"`
+	Score        float64   `json:"score,omitempty"`
+	Created      time.Time `json:"created"`
+	Updated      time.Time `json:"updated"`
+}
+
+// finished reports whether the job is done or failed
+func (j gradeJob) finished() bool {
+	return j.State == jobDone || j.State == jobFailed
+}
+
+// result returns the response to the requests for the status of the job
+func (j gradeJob) result() map[string]interface{} {
+	result := map[string]interface{}{
+		"job":        j.ID,
+		"state":      j.State,
+		"status_url": JobsPath + j.ID,
+	}
+	switch j.State {
+	case jobDone:
+		result["redirect"] = "/report/" + j.Key
+		if j.MinGrade != "" {
+			// for automation that fails when the grade drops
+			result["grade"] = j.Grade
+			result["score"] = j.Score
+			result["min_grade"] = j.MinGrade
+			result["passed"] = j.Grade.AtLeast(j.MinGrade)
+		}
+	case jobFailed:
+		result["error"] = j.Error
+	}
+	return result
+}
+
+// jobQueue grades the repos of jobs in the background, with GradeWorkers
+// workers
+type jobQueue struct {
+	mu sync.Mutex
+	// active are the IDs of the queued and running jobs of public repos
+	// by their report key, so that concurrent requests for a report share
+	// a job. Jobs with a token are not shared, as the token of one
+	// request must not grade the report for another.
+	active map[string]string
+	// tokens are the access tokens of the jobs of private repos
+	tokens map[string]string
+	// done are closed when the jobs with their IDs finish
+	done  map[string]chan struct{}
+	ids   chan string
+	start sync.Once
+}
+
+var jobs = newJobQueue()
+
+func newJobQueue() *jobQueue {
+	return &jobQueue{
+		active: make(map[string]string),
+		tokens: make(map[string]string),
+		done:   make(map[string]chan struct{}),
+		ids:    make(chan string, jobQueueSize),
+	}
+}
+
+// StartJobs queues the jobs that were waiting or running when the server
+// stopped again, and starts the workers that grade them
+func StartJobs() {
+	jobs.start.Do(jobs.run)
+}
+
+// newJobID returns a random ID for a job
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// add queues a job that grades the report with the key, cloning the repo
+// with token if it is not empty, or returns the job that already grades
+// it. Requests with a token always get a job of their own. A job that
+// grades the repo again is only shared while it is
+// queued, as a running job may grade an older commit, and queued jobs
+// that would return a report that was graded before grade it again.
+func (q *jobQueue) add(key, token string, refresh bool, minGrade Grade) (gradeJob, error) {
+	q.start.Do(q.run)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if id, ok := q.active[key]; ok && token == "" {
+		job, err := loadJob(id)
+		switch {
+		case err != nil:
+		case !refresh || job.Refresh && job.State == jobQueued:
+			return job, nil
+		case job.State == jobQueued:
+			job.Refresh = true
+			if err := saveJob(job); err == nil {
+				return job, nil
+			}
+		}
+	}
+
+	return q.queue(gradeJob{Key: key, Refresh: refresh, Private: token != "", MinGrade: minGrade}, token)
+}
+
+// addInstallation queues a job that grades the report with the key
+// again, cloning the repo with a token of the installation of the GitHub
+// App. Like the jobs of requests with a token, it is not shared.
+func (q *jobQueue) addInstallation(key string, installation int64) (gradeJob, error) {
+	q.start.Do(q.run)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.queue(gradeJob{Key: key, Refresh: true, Private: true, Installation: installation}, "")
+}
+
+// queue saves the job with a new ID and queues it. Jobs without a token
+// that are not Private are shared by the requests for their report.
+// q.mu must be held.
+func (q *jobQueue) queue(job gradeJob, token string) (gradeJob, error) {
+	now := time.Now().UTC()
+	job.ID, job.State, job.Created, job.Updated = newJobID(), jobQueued, now, now
+	if len(q.ids) == cap(q.ids) {
+		return gradeJob{}, errQueueFull
+	}
+	if err := saveJob(job); err != nil {
+		return gradeJob{}, err
+	}
+	switch {
+	case token != "":
+		q.tokens[job.ID] = token
+	case !job.Private:
+		q.active[job.Key] = job.ID
+	}
+	q.done[job.ID] = make(chan struct{})
+	q.ids <- job.ID
+	return job, nil
+}
+
+// wait returns the job with the ID once it finished
+func (q *jobQueue) wait(id string) gradeJob {
+	q.mu.Lock()
+	done := q.done[id]
+	q.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	job, err := loadJob(id)
+	if err != nil {
+		return gradeJob{ID: id, State: jobFailed, Error: err.Error()}
+	}
+	return job
+}
+
+// run queues the unfinished jobs in JobBucket, removes the jobs that
+// finished more than JobRetention ago, and starts the workers
+func (q *jobQueue) run() {
+	unfinished, err := recoverJobs()
+	if err != nil {
+		slog.Error("could not recover grading jobs", "error", err)
+	}
+	q.mu.Lock()
+	for _, job := range unfinished {
+		if len(q.ids) == cap(q.ids) {
+			job.fail("the server restarted with too many repos waiting to be graded")
+			continue
+		}
+		q.active[job.Key] = job.ID
+		q.done[job.ID] = make(chan struct{})
+		q.ids <- job.ID
+	}
+	q.mu.Unlock()
+	if len(unfinished) > 0 {
+		slog.Info("queued grading jobs again", "jobs", len(unfinished))
+	}
+	for i := 0; i < GradeWorkers; i++ {
+		go q.work()
+	}
+}
+
+func (q *jobQueue) work() {
+	for id := range q.ids {
+		job, err := loadJob(id)
+		if err != nil {
+			slog.Error("could not load grading job", "job", id, "error", err)
+			continue
+		}
+		q.mu.Lock()
+		token := q.tokens[id]
+		q.mu.Unlock()
+
+		job.State, job.Updated = jobRunning, time.Now().UTC()
+		if err := saveJob(job); err != nil {
+			slog.Error("could not save grading job", "job", id, "error", err)
+		}
+		if job.Installation != 0 {
+			token, err = tokenFor(job.Installation)
+		}
+		if err != nil {
+			slog.Error("could not get installation token", "repo", job.Key, "job", id, "error", err)
+			job.fail("could not get an access token of the GitHub App")
+		} else {
+			job.grade(token)
+		}
+
+		q.mu.Lock()
+		delete(q.tokens, id)
+		if q.active[job.Key] == id {
+			delete(q.active, job.Key)
+		}
+		if done := q.done[id]; done != nil {
+			close(done)
+			delete(q.done, id)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// grade grades the repo of the job and saves the report, and saves the
+// job as done or failed
+func (j gradeJob) grade(token string) {
+	repo, ref := download.SplitRef(j.Key)
+	resp, err := newChecksResp(context.Background(), repo, ref, token, j.Refresh)
+	if err != nil {
+		slog.Error("could not grade repo", "repo", j.Key, "job", j.ID, "error", err)
+		j.fail("could not download the repository")
+		return
+	}
+	if j.Installation != 0 {
+		// nobody has the token of the installation, so the clients who
+		// could read the report before still can
+		if cached, err := getFromCache(j.Key); err == nil && cached.AccessHash != "" {
+			resp.AccessHash = cached.AccessHash
+		}
+	}
+	// the report of a private repo replaces one that was graded with
+	// another token, which its clients could not read
+	if err := saveGrade(j.Key, resp, j.Refresh || j.Private); err != nil {
+		slog.Error("could not save grade", "repo", j.Key, "job", j.ID, "error", err)
+		j.fail("could not save the report")
+		return
+	}
+	j.State, j.Grade, j.Score, j.Updated = jobDone, resp.Grade, resp.Score, time.Now().UTC()
+	if err := saveJob(j); err != nil {
+		slog.Error("could not save grading job", "job", j.ID, "error", err)
+	}
+}
+
+// fail saves the job as failed with the message
+func (j gradeJob) fail(msg string) {
+	j.State, j.Error, j.Updated = jobFailed, msg, time.Now().UTC()
+	if err := saveJob(j); err != nil {
+		slog.Error("could not save grading job", "job", j.ID, "error", err)
+	}
+}
+
+// saveGrade saves resp as the report with the key, unless the repo was
+// graded before and overwrite is false, and lists public repos as
+// recently viewed
+func saveGrade(key string, resp checksResp, overwrite bool) error {
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("could not marshal json: %v", err)
+	}
+	if err := DefaultStore.SaveReport(key, resp, respBytes, overwrite); err != nil {
+		return err
+	}
+	if resp.Private {
+		return nil
+	}
+	if err := DefaultStore.AddRecentRepo(key); err != nil {
+		slog.Warn("could not update recently viewed repos", "repo", key, "error", err)
+	}
+	return nil
+}
+
+func saveJob(job gradeJob) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return fmt.Errorf("could not open bolt database: %v", err)
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		jb := tx.Bucket([]byte(JobBucket))
+		if jb == nil {
+			return fmt.Errorf("job bucket not found")
+		}
+		return jb.Put([]byte(job.ID), b)
+	})
+}
+
+func loadJob(id string) (gradeJob, error) {
+	var job gradeJob
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return job, fmt.Errorf("could not open bolt database: %v", err)
+	}
+	defer db.Close()
+	err = db.View(func(tx *bolt.Tx) error {
+		jb := tx.Bucket([]byte(JobBucket))
+		if jb == nil {
+			return fmt.Errorf("job bucket not found")
+		}
+		b := jb.Get([]byte(id))
+		if b == nil {
+			return fmt.Errorf("job %q not found", id)
+		}
+		return json.Unmarshal(b, &job)
+	})
+	return job, err
+}
+
+// recoverJobs removes the jobs in JobBucket that finished more than
+// JobRetention ago, and returns the jobs that did not finish, oldest
+// first. The jobs of private repos cannot be graded without their token,
+// so they fail, unless they get a token of the GitHub App.
+func recoverJobs() ([]gradeJob, error) {
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open bolt database: %v", err)
+	}
+	defer db.Close()
+
+	var unfinished []gradeJob
+	err = db.Update(func(tx *bolt.Tx) error {
+		jb := tx.Bucket([]byte(JobBucket))
+		if jb == nil {
+			return fmt.Errorf("job bucket not found")
+		}
+		var expired [][]byte
+		failed := make(map[string]gradeJob)
+		err := jb.ForEach(func(k, v []byte) error {
+			var job gradeJob
+			if err := json.Unmarshal(v, &job); err != nil {
+				slog.Warn("could not parse grading job", "job", string(k), "error", err)
+				expired = append(expired, k)
+				return nil
+			}
+			switch {
+			case job.finished():
+				if time.Since(job.Updated) > JobRetention {
+					expired = append(expired, k)
+				}
+			case job.Private && job.Installation == 0:
+				job.State, job.Error, job.Updated = jobFailed, "the server restarted before the repository was graded", time.Now().UTC()
+				failed[job.ID] = job
+			default:
+				job.State = jobQueued
+				unfinished = append(unfinished, job)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := jb.Delete(k); err != nil {
+				return err
+			}
+		}
+		for id, job := range failed {
+			b, err := json.Marshal(job)
+			if err != nil {
+				return err
+			}
+			if err := jb.Put([]byte(id), b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	sort.Slice(unfinished, func(i, j int) bool {
+		return unfinished[i].Created.Before(unfinished[j].Created)
+	})
+	return unfinished, err
+}
+
+// JobHandler handles the request for the status of a grading job. Once
+// the job is done, the response redirects to the report.
+func JobHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, JobsPath)
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		writeAPI(w, http.StatusNotFound, apiError{"job not found"})
+		return
+	}
+	job, err := loadJob(id)
+	if err != nil {
+		slog.Info("could not load grading job", "job", id, "error", err)
+		writeAPI(w, http.StatusNotFound, apiError{"job not found"})
+		return
+	}
+	if !job.finished() {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	writeAPI(w, http.StatusOK, job.result())
+}
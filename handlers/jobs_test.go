@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// withTestJobs is withTestDB with the job bucket, in which the jobs are
+// saved
+func withTestJobs(t *testing.T, saved ...gradeJob) {
+	withTestDB(t)
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte(JobBucket))
+		return err
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range saved {
+		if err := saveJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGradeJobResult(t *testing.T) {
+	job := gradeJob{ID: "abc", Key: "github.com/foo/bar@v1", State: jobRunning}
+	if got := job.result(); got["redirect"] != nil || got["status_url"] != JobsPath+"abc" {
+		t.Errorf("result of a running job = %v, want a status_url and no redirect", got)
+	}
+
+	job.State, job.Grade, job.Score, job.MinGrade = jobDone, GradeB, 75, GradeA
+	got := job.result()
+	if got["redirect"] != "/report/github.com/foo/bar@v1" || got["passed"] != false {
+		t.Errorf("result of a done job = %v, want a redirect to the report that did not pass", got)
+	}
+
+	job.State, job.Error = jobFailed, "could not download the repository"
+	if got := job.result(); got["error"] != job.Error || got["redirect"] != nil {
+		t.Errorf("result of a failed job = %v, want the error", got)
+	}
+}
+
+func TestRecoverJobs(t *testing.T) {
+	now := time.Now().UTC()
+	withTestJobs(t,
+		gradeJob{ID: "01", Key: "github.com/foo/old", State: jobDone, Updated: now.Add(-JobRetention - time.Hour)},
+		gradeJob{ID: "02", Key: "github.com/foo/recent", State: jobFailed, Updated: now},
+		gradeJob{ID: "03", Key: "github.com/foo/second", State: jobQueued, Created: now},
+		gradeJob{ID: "04", Key: "github.com/foo/first", State: jobRunning, Created: now.Add(-time.Minute)},
+		gradeJob{ID: "05", Key: "github.com/foo/private", State: jobQueued, Private: true, Created: now},
+	)
+
+	unfinished, err := recoverJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unfinished) != 2 || unfinished[0].ID != "04" || unfinished[1].ID != "03" {
+		t.Fatalf("recoverJobs = %v, want jobs 04 and 03", unfinished)
+	}
+	if unfinished[0].State != jobQueued {
+		t.Errorf("recovered running job is %s, want %s", unfinished[0].State, jobQueued)
+	}
+	if _, err := loadJob("01"); err == nil {
+		t.Errorf("expired job was kept")
+	}
+	if _, err := loadJob("02"); err != nil {
+		t.Errorf("recently finished job was removed: %v", err)
+	}
+	if job, err := loadJob("05"); err != nil || job.State != jobFailed {
+		t.Errorf("job of a private repo = %v, %v, want it failed", job, err)
+	}
+}
+
+func TestJobHandler(t *testing.T) {
+	withTestJobs(t, gradeJob{ID: "0123abcd", Key: "github.com/foo/bar", State: jobDone})
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{JobsPath + "0123abcd", http.StatusOK},
+		{JobsPath + "0123abce", http.StatusNotFound},
+		{JobsPath + "../repos", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		JobHandler(w, httptest.NewRequest("GET", c.path, nil))
+		if w.Code != c.code {
+			t.Errorf("[%s] code = %d, want %d", c.path, w.Code, c.code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	JobHandler(w, httptest.NewRequest("GET", JobsPath+"0123abcd", nil))
+	var result map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["state"] != jobDone || result["redirect"] != "/report/github.com/foo/bar" {
+		t.Errorf("status = %v, want the done job with a redirect to the report", result)
+	}
+}
+
+// stoppedJobQueue returns a job queue without workers, whose jobs stay
+// queued
+func stoppedJobQueue() *jobQueue {
+	q := newJobQueue()
+	q.start.Do(func() {})
+	return q
+}
+
+func TestJobQueueAdd(t *testing.T) {
+	withTestJobs(t)
+	q := stoppedJobQueue()
+
+	first, err := q.add("github.com/foo/bar", "", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := q.add("github.com/foo/bar", "", false, ""); job.ID != first.ID {
+		t.Errorf("second request got job %s, want the queued job %s", job.ID, first.ID)
+	}
+	// a regrade upgrades the queued job
+	if job, _ := q.add("github.com/foo/bar", "", true, ""); job.ID != first.ID || !job.Refresh {
+		t.Errorf("regrade got %+v, want the queued job graded again", job)
+	}
+
+	// a running job may grade an older commit
+	running, _ := loadJob(first.ID)
+	running.State = jobRunning
+	if err := saveJob(running); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := q.add("github.com/foo/bar", "", false, ""); job.ID != first.ID {
+		t.Errorf("request for the report got job %s, want the running job %s", job.ID, first.ID)
+	}
+	if job, _ := q.add("github.com/foo/bar", "", true, ""); job.ID == first.ID {
+		t.Errorf("regrade shares the running job")
+	}
+}
+
+func TestJobQueueAddPrivate(t *testing.T) {
+	withTestJobs(t)
+	q := stoppedJobQueue()
+
+	public, err := q.add("github.com/foo/bar", "", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := q.add("github.com/foo/bar", "secret", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if private.ID == public.ID || !private.Private {
+		t.Errorf("request with a token got %+v, want a private job of its own", private)
+	}
+	if job, _ := q.add("github.com/foo/bar", "other", false, ""); job.ID == private.ID {
+		t.Errorf("request with another token shares the private job")
+	}
+	if job, _ := q.add("github.com/foo/bar", "", false, ""); job.ID != public.ID {
+		t.Errorf("request without a token got job %s, want the public job %s", job.ID, public.ID)
+	}
+	app, err := q.addInstallation("github.com/foo/bar", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.ID == public.ID || !app.Private || app.Installation != 42 || !app.Refresh {
+		t.Errorf("push to the GitHub App got %+v, want a private job of its own", app)
+	}
+	if job, _ := q.add("github.com/foo/bar", "", true, ""); job.ID == app.ID {
+		t.Errorf("request without a token shares the job of the GitHub App")
+	}
+}
+
+func TestJobQueueWait(t *testing.T) {
+	withTestJobs(t)
+	q := stoppedJobQueue()
+	job, err := q.add("github.com/foo/bar", "", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan gradeJob)
+	go func() { got <- q.wait(job.ID) }()
+
+	job.State = jobDone
+	if err := saveJob(job); err != nil {
+		t.Fatal(err)
+	}
+	q.mu.Lock()
+	close(q.done[job.ID])
+	q.mu.Unlock()
+	if j := <-got; j.State != jobDone {
+		t.Errorf("wait = %+v, want the done job", j)
+	}
+}
+
+func TestLockRepo(t *testing.T) {
+	unlock := lockRepo("github.com/foo/monorepo/services/api")
+	locked := make(chan bool)
+	go func() {
+		unlock := lockRepo("github.com/foo/monorepo")
+		locked <- true
+		unlock()
+	}()
+	select {
+	case <-locked:
+		t.Fatal("another ref of the repo was graded at the same time")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	repoLocksMu.Lock()
+	defer repoLocksMu.Unlock()
+	if len(repoLocks) != 0 {
+		t.Errorf("repoLocks = %v, want none left", repoLocks)
+	}
+}
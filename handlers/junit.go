@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ToJUnit writes the results of the checks run on the repo in dir to w as
+// JUnit XML, for the test views of CI systems. Every check is a test
+// suite with a failing test case per file with issues, or a single
+// passing test case if it found none.
+func ToJUnit(dir string, results []check.CheckResult, w io.Writer) error {
+	grade, score := GradeResults(results)
+	doc := junitTestSuites{Name: fmt.Sprintf("Go Report Card: %s (%.1f%%)", grade, score)}
+	for _, r := range results {
+		suite := junitTestSuite{
+			Name:       r.Name,
+			Properties: []junitProperty{{"percentage", fmt.Sprintf("%d", int(r.Percentage*100))}},
+		}
+		switch {
+		case r.Error != "":
+			suite.Errors++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      r.Name,
+				Classname: r.Name,
+				Error:     &junitFailure{Message: "the check could not run", Text: r.Error},
+			})
+		case len(r.FileSummaries) == 0:
+			suite.Cases = append(suite.Cases, junitTestCase{Name: r.Name, Classname: r.Name})
+		}
+		for _, fs := range r.FileSummaries {
+			rel := check.RelFilename(dir, fs.Filename)
+			name := rel
+			if name == "" {
+				// issues that are not in a file, such as build failures
+				name = r.Name
+			}
+			var lines []string
+			for _, e := range fs.Errors {
+				lines = append(lines, fmt.Sprintf("%s:%d: %s", rel, e.LineNumber, strings.TrimSpace(e.ErrorString)))
+			}
+			suite.Failures++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      name,
+				Classname: r.Name,
+				Failure: &junitFailure{
+					Message: fmt.Sprintf("%d issues", len(fs.Errors)),
+					Text:    strings.Join(lines, "\n"),
+				},
+			})
+		}
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
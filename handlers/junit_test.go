@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToJUnit(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	results := []check.CheckResult{
+		{Name: "gofmt", Weight: 1, Percentage: 1, FileSummaries: []check.FileSummary{}},
+		{Name: "go_vet", Weight: 1, Percentage: 0.5, FileSummaries: []check.FileSummary{
+			{Filename: "bar/a.go", Errors: []check.Error{{LineNumber: 3, ErrorString: "unreachable code"}, {LineNumber: 7, ErrorString: "bad <verb>"}}},
+		}},
+		{Name: "errcheck", Error: "exit status 2"},
+	}
+
+	var buf bytes.Buffer
+	if err := ToJUnit(dir, results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Errorf("ToJUnit output does not start with the XML header:\n%s", out)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("ToJUnit wrote invalid XML: %v", err)
+	}
+	if doc.Tests != 3 || doc.Failures != 1 || doc.Errors != 1 || len(doc.Suites) != 3 {
+		t.Errorf("ToJUnit totals: %d tests, %d failures, %d errors in %d suites, want 3, 1, 1 in 3", doc.Tests, doc.Failures, doc.Errors, len(doc.Suites))
+	}
+	vet := doc.Suites[1]
+	if len(vet.Cases) != 1 || vet.Cases[0].Name != "a.go" || vet.Cases[0].Failure == nil {
+		t.Fatalf("ToJUnit go_vet cases = %+v, want a failing case for a.go", vet.Cases)
+	}
+	if got, want := vet.Cases[0].Failure.Text, "a.go:3: unreachable code\na.go:7: bad <verb>"; got != want {
+		t.Errorf("ToJUnit failure text = %q, want %q", got, want)
+	}
+	if c := doc.Suites[0].Cases; len(c) != 1 || c[0].Failure != nil || c[0].Error != nil {
+		t.Errorf("ToJUnit gofmt cases = %+v, want one passing case", c)
+	}
+	if c := doc.Suites[2].Cases; len(c) != 1 || c[0].Error == nil {
+		t.Errorf("ToJUnit errcheck cases = %+v, want one case with an error", c)
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// markdownMaxFiles is the number of files listed per check in a
+// Markdown report before the rest are summarized
+const markdownMaxFiles = 10
+
+// markdownTopFiles is the number of files with the most issues listed in
+// a Markdown report
+const markdownTopFiles = 5
+
+var markdownEscaper = strings.NewReplacer(
+	"|", `\|`,
+	"<", "&lt;",
+	">", "&gt;",
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// markdownSections are the titles of the tables of checks that have
+// a category
+var markdownSections = map[string]string{
+	check.CategoryPerformance: "Performance hints",
+	check.CategorySecurity:    "Security",
+}
+
+// mdLink returns a Markdown link to url, or just the text if there
+// is no url
+func mdLink(text, url string) string {
+	text = markdownEscaper.Replace(text)
+	if url == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
+
+// ToMarkdown writes a Markdown summary of the results to w, suitable for
+// posting as a pull request comment. It contains the overall grade, a
+// table with the score of each check, the files with the most issues, and
+// a collapsible section per check listing the files with issues.
+func ToMarkdown(results []check.CheckResult, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	avg := average(results)
+	fmt.Fprintf(bw, "## Go Report Card\n\n")
+	fmt.Fprintf(bw, "**Grade: %s** (%.1f%%)\n\n", grade(avg*100), avg*100)
+
+	// checks with a category are listed in their own table
+	var main []check.CheckResult
+	sections := make(map[string][]check.CheckResult)
+	for _, r := range results {
+		if _, ok := markdownSections[r.Category]; ok {
+			sections[r.Category] = append(sections[r.Category], r)
+		} else {
+			main = append(main, r)
+		}
+	}
+	writeMarkdownTable(bw, main)
+	for _, category := range []string{check.CategorySecurity, check.CategoryPerformance} {
+		if len(sections[category]) > 0 {
+			fmt.Fprintf(bw, "\n### %s\n\n", markdownSections[category])
+			writeMarkdownTable(bw, sections[category])
+		}
+	}
+	writeMarkdownTopFiles(bw, results)
+
+	for _, r := range results {
+		if r.Error == "" && len(r.FileSummaries) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(bw, "\n<details>\n<summary>%s (%d%%)</summary>\n\n", markdownEscaper.Replace(r.Name), int(r.Percentage*100))
+		if r.Error != "" {
+			fmt.Fprintf(bw, "An error occurred while running this check: %s\n", markdownEscaper.Replace(r.Error))
+		}
+
+		writeMarkdownIssues(bw, r.FileSummaries)
+		fmt.Fprintf(bw, "\n</details>\n")
+	}
+
+	return bw.Flush()
+}
+
+// ToPullRequestMarkdown writes a Markdown summary of the issues that a
+// pull request introduces to w, for the pull request comment: the grades
+// of the base and head branches, from the results of their checks, the
+// change of the score of each check, and the issues in diffs that are new
+// in the head branch. It ends with a link to the report at reportURL.
+func ToPullRequestMarkdown(base, head []check.CheckResult, diffs []check.IssueDiff, reportURL string, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	headAvg, baseAvg := average(head)*100, average(base)*100
+	fmt.Fprintf(bw, "## Go Report Card\n\n")
+	fmt.Fprintf(bw, "**Grade: %s** (%.1f%%), %s (%.1f%%) on the base branch\n\n", grade(headAvg), headAvg, grade(baseAvg), baseAvg)
+
+	var introduced, resolved int
+	for _, d := range diffs {
+		for _, fs := range d.Introduced {
+			introduced += len(fs.Errors)
+		}
+		for _, fs := range d.Resolved {
+			resolved += len(fs.Errors)
+		}
+	}
+	switch {
+	case introduced == 0 && resolved == 0:
+		fmt.Fprintf(bw, "This pull request introduces no new issues.\n\n")
+	case introduced == 0:
+		fmt.Fprintf(bw, "This pull request introduces no new issues, and resolves %d.\n\n", resolved)
+	case introduced == 1:
+		fmt.Fprintf(bw, "This pull request introduces 1 new issue, and resolves %d.\n\n", resolved)
+	default:
+		fmt.Fprintf(bw, "This pull request introduces %d new issues, and resolves %d.\n\n", introduced, resolved)
+	}
+
+	before := make(map[string]float64, len(base))
+	for _, r := range base {
+		before[r.Name] = r.Percentage
+	}
+	fmt.Fprintf(bw, "| Check | Score | Change |\n")
+	fmt.Fprintf(bw, "|-------|------:|-------:|\n")
+	for _, r := range head {
+		var change string
+		if p, ok := before[r.Name]; ok {
+			if d := int(r.Percentage*100) - int(p*100); d != 0 {
+				change = fmt.Sprintf("%+d%%", d)
+			}
+		}
+		fmt.Fprintf(bw, "| %s | %d%% | %s |\n", markdownEscaper.Replace(r.Name), int(r.Percentage*100), change)
+	}
+
+	for _, d := range diffs {
+		var n int
+		for _, fs := range d.Introduced {
+			n += len(fs.Errors)
+		}
+		if n == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n<details>\n<summary>%s (%d new)</summary>\n\n", markdownEscaper.Replace(d.Name), n)
+		writeMarkdownIssues(bw, d.Introduced)
+		fmt.Fprintf(bw, "\n</details>\n")
+	}
+
+	if reportURL != "" {
+		fmt.Fprintf(bw, "\n%s\n", mdLink("Full report", reportURL))
+	}
+	return bw.Flush()
+}
+
+// writeMarkdownIssues writes a list of the files in summaries and their
+// issues to w
+func writeMarkdownIssues(w io.Writer, summaries []check.FileSummary) {
+	for i, fs := range summaries {
+		if i == markdownMaxFiles {
+			fmt.Fprintf(w, "\n...and %d more\n", len(summaries)-markdownMaxFiles)
+			break
+		}
+		fmt.Fprintf(w, "- %s\n", mdLink(fs.Filename, fs.FileURL))
+		for _, e := range fs.Errors {
+			if e.LineNumber == 0 {
+				fmt.Fprintf(w, "  - %s\n", markdownEscaper.Replace(strings.TrimSpace(e.ErrorString)))
+				continue
+			}
+			var url string
+			if fs.FileURL != "" {
+				url = check.LineURL(fs.FileURL, e.LineNumber, 0)
+			}
+			fmt.Fprintf(w, "  - %s: %s\n", mdLink(fmt.Sprintf("Line %d", e.LineNumber), url), markdownEscaper.Replace(strings.TrimSpace(e.ErrorString)))
+		}
+	}
+}
+
+// markdownFile is a file of a repo with the issues found in it by all
+// checks
+type markdownFile struct {
+	name, url string
+	issues    int
+	checks    []string
+}
+
+// writeMarkdownTopFiles writes a table of the files with the most issues
+// to w
+func writeMarkdownTopFiles(w io.Writer, results []check.CheckResult) {
+	byName := make(map[string]*markdownFile)
+	var files []*markdownFile
+	for _, r := range results {
+		for _, fs := range r.FileSummaries {
+			if fs.Filename == "" || len(fs.Errors) == 0 {
+				continue
+			}
+			f, ok := byName[fs.Filename]
+			if !ok {
+				f = &markdownFile{name: fs.Filename}
+				byName[fs.Filename] = f
+				files = append(files, f)
+			}
+			if f.url == "" {
+				f.url = fs.FileURL
+			}
+			f.issues += len(fs.Errors)
+			f.checks = append(f.checks, r.Name)
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].issues > files[j].issues })
+	if len(files) > markdownTopFiles {
+		files = files[:markdownTopFiles]
+	}
+
+	fmt.Fprintf(w, "\n### Top offending files\n\n")
+	fmt.Fprintf(w, "| File | Issues | Checks |\n")
+	fmt.Fprintf(w, "|------|-------:|--------|\n")
+	for _, f := range files {
+		fmt.Fprintf(w, "| %s | %d | %s |\n", mdLink(f.name, f.url), f.issues, markdownEscaper.Replace(strings.Join(f.checks, ", ")))
+	}
+}
+
+// writeMarkdownTable writes a table with the score of each check to w
+func writeMarkdownTable(w io.Writer, results []check.CheckResult) {
+	fmt.Fprintf(w, "| Check | Score | Grade |\n")
+	fmt.Fprintf(w, "|-------|------:|:-----:|\n")
+	for _, r := range results {
+		fmt.Fprintf(w, "| %s | %d%% | %s |\n", markdownEscaper.Replace(r.Name), int(r.Percentage*100), grade(r.Percentage*100))
+	}
+}
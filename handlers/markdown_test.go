@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToMarkdown(t *testing.T) {
+	results := []check.CheckResult{
+		{
+			Name:       "gofmt",
+			Weight:     .5,
+			Percentage: .5,
+			FileSummaries: []check.FileSummary{{
+				Filename: "a.go",
+				FileURL:  "https://github.com/foo/bar/blob/master/a.go",
+				Errors:   []check.Error{{LineNumber: 3, ErrorString: "file is not gofmted"}},
+			}},
+		},
+		{Name: "golint", Weight: .5, Percentage: 1},
+		{Name: "prealloc", Percentage: .5, Category: check.CategoryPerformance},
+	}
+
+	var buf bytes.Buffer
+	if err := ToMarkdown(results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"**Grade: B** (75.0%)",
+		"| Check | Score | Grade |",
+		"| gofmt | 50% | E |",
+		"| golint | 100% | A+ |",
+		"### Performance hints\n\n| Check | Score | Grade |\n|-------|------:|:-----:|\n| prealloc | 50% | E |",
+		"<summary>gofmt (50%)</summary>",
+		"- [a.go](https://github.com/foo/bar/blob/master/a.go)",
+		"  - [Line 3](https://github.com/foo/bar/blob/master/a.go#L3): file is not gofmted",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ToMarkdown output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<summary>golint") {
+		t.Errorf("ToMarkdown output contains details for a check without issues:\n%s", out)
+	}
+}
+
+func TestToMarkdownTruncates(t *testing.T) {
+	r := check.CheckResult{Name: "golint", Weight: 1}
+	for i := 0; i < markdownMaxFiles+2; i++ {
+		r.FileSummaries = append(r.FileSummaries, check.FileSummary{Filename: fmt.Sprintf("%d.go", i)})
+	}
+
+	var buf bytes.Buffer
+	if err := ToMarkdown([]check.CheckResult{r}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "...and 2 more") || strings.Contains(out, "- 10.go") {
+		t.Errorf("ToMarkdown did not truncate the file list:\n%s", out)
+	}
+}
+
+func TestToMarkdownTopFiles(t *testing.T) {
+	results := []check.CheckResult{
+		{Name: "gofmt", Weight: 1, FileSummaries: []check.FileSummary{
+			{Filename: "a.go", Errors: []check.Error{{LineNumber: 1}}},
+			{Filename: "b.go", Errors: []check.Error{{LineNumber: 1}, {LineNumber: 2}}},
+		}},
+		{Name: "go_vet", Weight: 1, FileSummaries: []check.FileSummary{
+			{Filename: "a.go", FileURL: "https://github.com/foo/bar/blob/master/a.go", Errors: []check.Error{{LineNumber: 4}, {LineNumber: 5}}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := ToMarkdown(results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "### Top offending files\n\n| File | Issues | Checks |\n|------|-------:|--------|\n" +
+		"| [a.go](https://github.com/foo/bar/blob/master/a.go) | 3 | gofmt, go_vet |\n| b.go | 2 | gofmt |\n"
+	if out := buf.String(); !strings.Contains(out, want) {
+		t.Errorf("ToMarkdown output does not contain %q:\n%s", want, out)
+	}
+}
+
+func TestToPullRequestMarkdown(t *testing.T) {
+	issue := func(line int, msg string) check.FileSummary {
+		return check.FileSummary{
+			Filename: "a.go",
+			FileURL:  "https://github.com/foo/bar/blob/feature/a.go",
+			Errors:   []check.Error{{LineNumber: line, ErrorString: msg}},
+		}
+	}
+	base := []check.CheckResult{
+		{Name: "gofmt", Weight: .5, Percentage: 1},
+		{Name: "golint", Weight: .5, Percentage: .8, FileSummaries: []check.FileSummary{issue(5, "exported func F should have comment")}},
+	}
+	head := []check.CheckResult{
+		{Name: "gofmt", Weight: .5, Percentage: .5, FileSummaries: []check.FileSummary{issue(3, "file is not gofmted")}},
+		{Name: "golint", Weight: .5, Percentage: .8, FileSummaries: []check.FileSummary{issue(5, "exported func F should have comment")}},
+	}
+
+	var buf bytes.Buffer
+	diffs := check.DiffIssues("repos/src/github.com/foo/bar", base, head)
+	if err := ToPullRequestMarkdown(base, head, diffs, "https://goreportcard.com/report/github.com/foo/bar@feature", &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"**Grade: C** (65.0%), A (90.0%) on the base branch",
+		"This pull request introduces 1 new issue, and resolves 0.",
+		"| gofmt | 50% | -50% |",
+		"| golint | 80% |  |",
+		"<summary>gofmt (1 new)</summary>",
+		"  - [Line 3](https://github.com/foo/bar/blob/feature/a.go#L3): file is not gofmted",
+		"[Full report](https://goreportcard.com/report/github.com/foo/bar@feature)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ToPullRequestMarkdown output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "should have comment") {
+		t.Errorf("ToPullRequestMarkdown output contains an issue of the base branch:\n%s", out)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// metric is a family of Prometheus counters, gauges or histograms, with a
+// series per combination of the values of its labels
+type metric struct {
+	name, help, typ string
+	labels          []string
+	// buckets are the upper bounds of the buckets of a histogram
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+}
+
+// metricSeries is the value of a metric for the values of its labels
+type metricSeries struct {
+	labels []string
+	// value is the value of a counter or gauge, or the sum of the
+	// observations of a histogram
+	value float64
+	// counts are the observations of a histogram that are at most the
+	// upper bound of each bucket, and n all observations
+	counts []uint64
+	n      uint64
+}
+
+// registry are the metrics written by MetricsHandler, in order
+var registry []*metric
+
+// durationBuckets are the buckets of histograms of durations, in seconds
+var durationBuckets = []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 120, 300, 600}
+
+func newMetric(name, help, typ string, buckets []float64, labels ...string) *metric {
+	m := &metric{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: make(map[string]*metricSeries)}
+	registry = append(registry, m)
+	return m
+}
+
+var (
+	httpRequests     = newMetric("goreportcard_http_requests_total", "HTTP requests by handler and status code.", "counter", nil, "handler", "code")
+	httpDuration     = newMetric("goreportcard_http_request_duration_seconds", "Time to respond to HTTP requests by handler.", "histogram", durationBuckets, "handler")
+	gradesTotal      = newMetric("goreportcard_grades_total", "Repos graded, by whether grading succeeded.", "counter", nil, "result")
+	gradesInProgress = newMetric("goreportcard_grades_in_progress", "Repos that are being graded.", "gauge", nil)
+	gradeDuration    = newMetric("goreportcard_grade_duration_seconds", "Time to download and grade repos.", "histogram", durationBuckets)
+	checkDuration    = newMetric("goreportcard_check_duration_seconds", "Time each check took to run on a repo.", "histogram", durationBuckets, "check")
+	checkErrors      = newMetric("goreportcard_check_errors_total", "Checks that failed or were stopped on a repo.", "counter", nil, "check")
+	cloneDuration    = newMetric("goreportcard_clone_duration_seconds", "Time to download repos, by whether the download succeeded.", "histogram", durationBuckets, "result")
+	cacheLookups     = newMetric("goreportcard_cache_lookups_total", "Lookups in the report and file caches, by whether they were hits.", "counter", nil, "cache", "result")
+	regradeQueued    = newMetric("goreportcard_regrade_queue_depth", "Reports waiting to be graded again after webhooks.", "gauge", nil)
+	jobsQueued       = newMetric("goreportcard_job_queue_depth", "Grading jobs waiting for a worker.", "gauge", nil)
+	dbSize           = newMetric("goreportcard_db_size_bytes", "Size of the bolt database file.", "gauge", nil)
+	dbKeys           = newMetric("goreportcard_db_keys", "Keys in each bucket of the bolt database.", "gauge", nil, "bucket")
+)
+
+// seriesFor returns the series of m for the label values, creating it if
+// there is none. m.mu must be held.
+func (m *metric) seriesFor(values []string) *metricSeries {
+	if len(values) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s has labels %v, got values %v", m.name, m.labels, values))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &metricSeries{labels: append([]string(nil), values...), counts: make([]uint64, len(m.buckets))}
+		m.series[key] = s
+	}
+	return s
+}
+
+// add adds v to the counter or gauge of m with the label values
+func (m *metric) add(v float64, values ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seriesFor(values).value += v
+}
+
+// set sets the gauge of m with the label values to v
+func (m *metric) set(v float64, values ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seriesFor(values).value = v
+}
+
+// observe adds the observation v to the histogram of m with the label
+// values
+func (m *metric) observe(v float64, values ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.seriesFor(values)
+	for i, b := range m.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.value += v
+	s.n++
+}
+
+// write writes m in the Prometheus text format to w
+func (m *metric) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.labels) == 0 {
+		// metrics without labels are zero until they change
+		m.seriesFor(nil)
+	}
+	keys := make([]string, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+	for _, k := range keys {
+		s := m.series[k]
+		labels := formatLabels(m.labels, s.labels)
+		if m.typ != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, labels, formatValue(s.value))
+			continue
+		}
+		le := append(m.labels[:len(m.labels):len(m.labels)], "le")
+		for i, b := range m.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(le, append(s.labels[:len(s.labels):len(s.labels)], formatValue(b))), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatLabels(le, append(s.labels[:len(s.labels):len(s.labels)], "+Inf")), s.n)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, labels, formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, labels, s.n)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels returns the labels with their values, like {a="x",b="y"},
+// or "" if there are none
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, n := range names {
+		pairs[i] = n + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatValue returns v in the Prometheus text format
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// MetricsHandler handles the request for the metrics of the server, in
+// the Prometheus text format
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	updateStoreMetrics()
+	regradeQueued.set(float64(len(regrades.jobs)))
+	jobsQueued.set(float64(len(jobs.ids)))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	for _, m := range registry {
+		m.write(bw)
+	}
+	bw.Flush()
+}
+
+// updateStoreMetrics sets the metrics of the size of the bolt database
+func updateStoreMetrics() {
+	if fi, err := os.Stat(DBPath); err == nil {
+		dbSize.set(float64(fi.Size()))
+	}
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		slog.Warn("could not open bolt database for metrics", "error", err)
+		return
+	}
+	defer db.Close()
+	db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			dbKeys.set(float64(b.Stats().KeyN), string(name))
+			return nil
+		})
+	})
+}
+
+// statusRecorder records the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush flushes the response, for progress events
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Instrument returns fn, counting its requests and how long it takes to
+// respond to them in the metrics of the named handler
+func Instrument(name string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		fn(rec, r)
+		httpDuration.observe(time.Since(started).Seconds(), name)
+		httpRequests.add(1, name, strconv.Itoa(rec.code))
+	}
+}
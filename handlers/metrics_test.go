@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricWrite(t *testing.T) {
+	counter := &metric{name: "c_total", help: "A counter.", typ: "counter", labels: []string{"path"}, series: make(map[string]*metricSeries)}
+	counter.add(1, `a"b`)
+	counter.add(2, `a"b`)
+	counter.add(1, "x")
+	hist := &metric{name: "h_seconds", help: "A histogram.", typ: "histogram", buckets: []float64{.5, 1}, series: make(map[string]*metricSeries)}
+	hist.observe(.2)
+	hist.observe(.7)
+	hist.observe(3)
+	gauge := &metric{name: "g", help: "A gauge.", typ: "gauge", series: make(map[string]*metricSeries)}
+
+	var buf bytes.Buffer
+	counter.write(&buf)
+	hist.write(&buf)
+	gauge.write(&buf)
+	want := `# HELP c_total A counter.
+# TYPE c_total counter
+c_total{path="a\"b"} 3
+c_total{path="x"} 1
+# HELP h_seconds A histogram.
+# TYPE h_seconds histogram
+h_seconds_bucket{le="0.5"} 1
+h_seconds_bucket{le="1"} 2
+h_seconds_bucket{le="+Inf"} 3
+h_seconds_sum 3.9
+h_seconds_count 3
+# HELP g A gauge.
+# TYPE g gauge
+g 0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("metrics =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	h := Instrument("teapot", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	withTestDB(t, "github.com/foo/bar")
+	w := httptest.NewRecorder()
+	MetricsHandler(w, httptest.NewRequest("GET", "/metrics", nil))
+	out := w.Body.String()
+	for _, want := range []string{
+		`goreportcard_http_requests_total{handler="teapot",code="418"} 1`,
+		`goreportcard_http_request_duration_seconds_count{handler="teapot"} 1`,
+		`goreportcard_db_keys{bucket="repos"} 1`,
+		"goreportcard_regrade_queue_depth 0",
+		"# TYPE goreportcard_check_duration_seconds histogram",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, out)
+		}
+	}
+}
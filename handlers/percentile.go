@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// PercentileInterval is how often the scores of all graded repos are
+// read again for ranking repos against each other. If zero, repos are not
+// ranked.
+var PercentileInterval = time.Hour
+
+// percentileBuckets is the number of buckets that scores are counted in,
+// with a width of a tenth of a percent
+const percentileBuckets = 1001
+
+// scoreDistribution counts the scores of all graded repos
+type scoreDistribution struct {
+	// counts are the numbers of repos by score rounded down to a tenth
+	// of a percent
+	counts []int
+	total  int
+}
+
+// bucket returns the bucket of a score out of 100
+func (d scoreDistribution) bucket(score float64) int {
+	i := int(score * 10)
+	switch {
+	case i < 0:
+		return 0
+	case i >= percentileBuckets:
+		return percentileBuckets - 1
+	}
+	return i
+}
+
+// add counts a repo with the score out of 100
+func (d *scoreDistribution) add(score float64) {
+	d.counts[d.bucket(score)]++
+	d.total++
+}
+
+// percentile returns the percentage of graded repos with a lower score
+// than score, rounded to a tenth
+func (d scoreDistribution) percentile(score float64) float64 {
+	if d.total == 0 {
+		return 0
+	}
+	var below int
+	for _, n := range d.counts[:d.bucket(score)] {
+		below += n
+	}
+	return math.Round(1000*float64(below)/float64(d.total)) / 10
+}
+
+// newScoreDistribution counts the scores of the repos in b, the
+// RepoBucket
+func newScoreDistribution(b *bolt.Bucket) (scoreDistribution, error) {
+	d := scoreDistribution{counts: make([]int, percentileBuckets)}
+	err := b.ForEach(func(k, v []byte) error {
+		var repo struct {
+			Average float64 `json:"average"`
+		}
+		if err := json.Unmarshal(v, &repo); err != nil {
+			// a repo that cannot be read is not ranked
+			return nil
+		}
+		d.add(repo.Average * 100)
+		return nil
+	})
+	return d, err
+}
+
+// distribution is the latest scoreDistribution, which is nil until the
+// scores are read for the first time
+var distribution struct {
+	sync.RWMutex
+	d *scoreDistribution
+}
+
+// percentile returns the percentage of graded repos with a lower score
+// than score, or nil if the scores were not read yet
+func percentile(score float64) *float64 {
+	distribution.RLock()
+	defer distribution.RUnlock()
+	if distribution.d == nil {
+		return nil
+	}
+	p := distribution.d.percentile(score)
+	return &p
+}
+
+// updateDistribution reads the scores of all graded repos
+func updateDistribution() error {
+	d, err := DefaultStore.ScoreDistribution()
+	if err != nil {
+		return err
+	}
+
+	distribution.Lock()
+	distribution.d = &d
+	distribution.Unlock()
+	return nil
+}
+
+// StartPercentiles reads the scores of all graded repos now and every
+// PercentileInterval, for ranking repos against each other
+func StartPercentiles() {
+	update := func() {
+		started := time.Now()
+		if err := updateDistribution(); err != nil {
+			slog.Error("could not update percentiles", "error", err)
+			return
+		}
+		slog.Debug("updated percentiles", "duration", time.Since(started))
+	}
+	update()
+	go func() {
+		for range time.Tick(PercentileInterval) {
+			update()
+		}
+	}()
+}
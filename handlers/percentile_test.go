@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestScoreDistribution(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goreportcard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var d scoreDistribution
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(RepoBucket))
+		if err != nil {
+			return err
+		}
+		for i, avg := range []float64{0.5, 0.8, 0.8, 0.9, 1} {
+			if err := b.Put([]byte(fmt.Sprint(i)), []byte(fmt.Sprintf(`{"average": %v}`, avg))); err != nil {
+				return err
+			}
+		}
+		if err := b.Put([]byte("broken"), []byte("{")); err != nil {
+			return err
+		}
+		d, err = newScoreDistribution(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		score float64
+		want  float64
+	}{
+		{0, 0},
+		{50, 0},
+		{80, 20},
+		{85, 60},
+		{100, 80},
+		{120, 80},
+	}
+	for _, tt := range cases {
+		if got := d.percentile(tt.score); got != tt.want {
+			t.Errorf("[%v] percentile = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+}
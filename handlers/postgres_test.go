@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testPostgres opens the database of GOREPORTCARD_TEST_POSTGRES_URL with
+// empty tables, and skips the test if it is not set
+func testPostgres(t *testing.T) postgresStore {
+	url := os.Getenv("GOREPORTCARD_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("GOREPORTCARD_TEST_POSTGRES_URL is not set")
+	}
+	store, err := OpenPostgres(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.(postgresStore)
+	t.Cleanup(func() { s.db.Close() })
+	if _, err := s.db.Exec(`TRUNCATE reports, history, recent`); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPostgresReports(t *testing.T) {
+	s := testPostgres(t)
+	save := func(key string, resp checksResp, overwrite bool) {
+		b, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveReport(key, resp, b, overwrite); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("github.com/foo/bar", checksResp{Repo: "github.com/foo/bar", Files: 120, Average: 0.9, Grade: GradeAPlus}, false)
+	save("github.com/foo/bar", checksResp{Repo: "github.com/foo/bar", Files: 120, Average: 0.5}, false)
+	save("github.com/foo/bar@v1", checksResp{Repo: "github.com/foo/bar", Ref: "v1", Files: 120, Average: 0.7}, true)
+	save("github.com/foo/baz", checksResp{Repo: "github.com/foo/baz", Files: 10, Average: 0.8}, true)
+	save("github.com/foo/private", checksResp{Repo: "github.com/foo/private", Private: true, Files: 200, Average: 0.95}, true)
+
+	b, err := s.Report("github.com/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp checksResp
+	if err := json.Unmarshal(b, &resp); err != nil || resp.Average != 0.9 {
+		t.Errorf("report saved without overwrite = %+v, %v, want the first report", resp, err)
+	}
+	if _, err := s.Report("github.com/foo/none"); err == nil {
+		t.Errorf("Report of an unknown repo succeeded")
+	}
+
+	keys, err := s.ReportKeys("github.com/foo/bar")
+	if want := []string{"github.com/foo/bar", "github.com/foo/bar@v1"}; err != nil || !reflect.DeepEqual(keys, want) {
+		t.Errorf("ReportKeys = %q, %v, want %q", keys, err, want)
+	}
+
+	scores, stats, count, err := s.HighScores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []scoreItem{{Repo: "github.com/foo/bar", Score: 90, Files: 120}}; !reflect.DeepEqual(scores, want) {
+		t.Errorf("high scores = %+v, want %+v", scores, want)
+	}
+	if count != 3 || stats[90] != 1 || stats[80] != 1 || stats[95] != 1 {
+		t.Errorf("count = %d, stats = %v, want 3 repos scoring 80, 90 and 95", count, stats)
+	}
+
+	d, err := s.ScoreDistribution()
+	if err != nil || d.total != 4 {
+		t.Errorf("ScoreDistribution counted %d repos, %v, want 4", d.total, err)
+	}
+}
+
+func TestPostgresRecentRepos(t *testing.T) {
+	s := testPostgres(t)
+	for _, k := range []string{"a", "b", "c", "a", "d", "e", "f"} {
+		if err := s.AddRecentRepo(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.RecentRepos()
+	if want := []string{"f", "e", "d", "c", "b"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentRepos = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestPostgresHistory(t *testing.T) {
+	s := testPostgres(t)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []historyEntry{
+		{Time: start, Commit: "abc", Grade: GradeB, Score: 72, Checks: []historyCheck{{Name: "gofmt", Percentage: 0.8, Weight: 0.3}}},
+		{Time: start.Add(time.Hour), Commit: "def", Grade: GradeA, Score: 85, Checks: []historyCheck{}},
+	}
+	for _, e := range []historyEntry{want[1], want[0]} {
+		if err := s.AppendHistory("github.com/foo/bar", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.History("github.com/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		got[i].Time = got[i].Time.UTC()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("History = %+v, want %+v", got, want)
+	}
+	if got, _ := s.History("github.com/foo/none"); len(got) != 0 {
+		t.Errorf("History of an unknown repo = %+v, want none", got)
+	}
+}
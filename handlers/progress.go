@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/gojp/goreportcard/check"
+	"github.com/gojp/goreportcard/download"
+)
+
+// the stages of grading a repo
+const (
+	stageCloning  = "cloning"
+	stageLoading  = "loading"
+	stageChecking = "checking"
+	stageDone     = "done"
+	stageFailed   = "failed"
+)
+
+// progressEvent is a step of grading a repo, as streamed to the report
+// page
+type progressEvent struct {
+	Stage   string `json:"stage"`
+	Message string `json:"message"`
+	Done    int    `json:"done,omitempty"`
+	Total   int    `json:"total,omitempty"`
+}
+
+// checkEvent returns the event of a check that started or finished
+func checkEvent(p check.Progress) progressEvent {
+	msg := fmt.Sprintf("running %s", p.Check)
+	if p.Finished {
+		msg = fmt.Sprintf("%d/%d checks done", p.Done, p.Total)
+	}
+	return progressEvent{Stage: stageChecking, Message: msg, Done: p.Done, Total: p.Total}
+}
+
+// progressBroker passes the progress of grading repos to the clients
+// waiting for it
+type progressBroker struct {
+	mu sync.Mutex
+	// last is the latest event of the repos being graded, for clients
+	// that start waiting halfway
+	last map[string]progressEvent
+	subs map[string]map[chan progressEvent]bool
+}
+
+var progress = &progressBroker{
+	last: make(map[string]progressEvent),
+	subs: make(map[string]map[chan progressEvent]bool),
+}
+
+// publish sends e to the clients waiting for the progress of repo.
+// Clients that do not keep up miss events.
+func (b *progressBroker) publish(repo string, e progressEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e.Stage == stageDone || e.Stage == stageFailed {
+		delete(b.last, repo)
+	} else {
+		b.last[repo] = e
+	}
+	for ch := range b.subs[repo] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// subscribe returns a channel that receives the progress of grading
+// repo, starting with the latest event if it is being graded, and a
+// function to stop receiving
+func (b *progressBroker) subscribe(repo string) (<-chan progressEvent, func()) {
+	ch := make(chan progressEvent, 32)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e, ok := b.last[repo]; ok {
+		ch <- e
+	}
+	if b.subs[repo] == nil {
+		b.subs[repo] = make(map[chan progressEvent]bool)
+	}
+	b.subs[repo][ch] = true
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[repo], ch)
+		if len(b.subs[repo]) == 0 {
+			delete(b.subs, repo)
+		}
+	}
+}
+
+// ProgressHandler streams the progress of grading a repo, or the repo
+// of a grading job, as server-sent events, until grading is done or the
+// client goes away
+func ProgressHandler(w http.ResponseWriter, r *http.Request) {
+	var key string
+	if id := r.FormValue("job"); id != "" {
+		job, err := loadJob(id)
+		if err != nil {
+			http.Error(w, "Could not find the job", http.StatusNotFound)
+			return
+		}
+		key = job.Key
+	} else {
+		path, ref := repoRef(r, r.FormValue("repo"))
+		repo, err := download.Clean(path)
+		if err != nil {
+			http.Error(w, "Could not find the repository: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		key = repoKey(repo, ref)
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, stop := progress.subscribe(key)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				slog.Error("could not marshal progress", "repo", key, "error", err)
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+			if e.Stage == stageDone || e.Stage == stageFailed {
+				return
+			}
+		}
+	}
+}
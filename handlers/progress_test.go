@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestCheckEvent(t *testing.T) {
+	cases := []struct {
+		p    check.Progress
+		want string
+	}{
+		{check.Progress{Check: "go_vet", Done: 2, Total: 7}, "running go_vet"},
+		{check.Progress{Check: "go_vet", Finished: true, Done: 3, Total: 7}, "3/7 checks done"},
+	}
+	for _, tt := range cases {
+		if got := checkEvent(tt.p).Message; got != tt.want {
+			t.Errorf("[%s] message = %q, want %q", tt.p.Check, got, tt.want)
+		}
+	}
+}
+
+func TestProgressHandler(t *testing.T) {
+	repo := "github.com/gojp/goreportcard"
+	progress.publish(repo, progressEvent{Stage: stageCloning, Message: "cloning"})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/checks/progress?repo="+repo, nil)
+	served := make(chan bool)
+	go func() {
+		ProgressHandler(w, r)
+		close(served)
+	}()
+
+	// wait for the handler to subscribe before grading is done
+	for i := 0; ; i++ {
+		progress.mu.Lock()
+		n := len(progress.subs[repo])
+		progress.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("handler did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	progress.publish(repo, progressEvent{Stage: stageDone, Message: "done"})
+	<-served
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	want := `data: {"stage":"cloning","message":"cloning"}` + "\n\n" + `data: {"stage":"done","message":"done"}` + "\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+	"github.com/gojp/goreportcard/download"
+)
+
+// pullCommentMarker marks the pull request comments of the GitHub App, so
+// that a pull request has a single comment that is updated
+const pullCommentMarker = "<!-- goreportcard -->"
+
+// githubPullRequestEvent is the part of the payload of a pull_request
+// event of GitHub that pull requests are graded for
+type githubPullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head githubPullRequestRef `json:"head"`
+		Base githubPullRequestRef `json:"base"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+}
+
+// githubPullRequestRef is the head or base branch of a pull request
+type githubPullRequestRef struct {
+	Ref  string `json:"ref"`
+	SHA  string `json:"sha"`
+	Repo *struct {
+		FullName string `json:"full_name"`
+	} `json:"repo"`
+}
+
+// pullRequestWebhook handles the pull_request events of the GitHub App,
+// by grading the head branch of the pull request in the background and
+// commenting on the pull request with the issues it introduces
+func pullRequestWebhook(w http.ResponseWriter, body []byte) {
+	var ev githubPullRequestEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		http.Error(w, "Could not parse payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	job, ok := pullRequestJob(ev)
+	if !ok || job.installation == 0 || !githubAppEnabled() {
+		w.Write([]byte("Ignored pull request"))
+		return
+	}
+	slog.Info("grading pull request", "repo", job.repo, "pull", job.pull, "head", job.key)
+	if !regrades.add(job) {
+		http.Error(w, "Too many reports waiting to be graded", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"reports": []string{job.key}})
+}
+
+// pullRequestJob returns the job that grades the head branch of the pull
+// request of the event, in the repo it is in, which is a fork for pull
+// requests from forks. ok is false for events that do not change the code
+// of the pull request, or whose head repo was deleted.
+func pullRequestJob(ev githubPullRequestEvent) (job regradeJob, ok bool) {
+	switch ev.Action {
+	case "opened", "reopened", "synchronize":
+	default:
+		return regradeJob{}, false
+	}
+	head, base := ev.PullRequest.Head, ev.PullRequest.Base
+	if head.Repo == nil || head.Repo.FullName == "" || ev.Repository.FullName == "" || ev.Number == 0 {
+		return regradeJob{}, false
+	}
+	baseRef := base.Ref
+	if baseRef == ev.Repository.DefaultBranch {
+		baseRef = ""
+	}
+	return regradeJob{
+		key:          repoKey("github.com/"+head.Repo.FullName, head.Ref),
+		repo:         ev.Repository.FullName,
+		commit:       head.SHA,
+		installation: ev.Installation.ID,
+		pull:         ev.Number,
+		base:         repoKey("github.com/"+ev.Repository.FullName, baseRef),
+	}, true
+}
+
+// postPullComment posts the issues that the head branch of the pull
+// request of the job, graded as head, introduces to the base branch as
+// the comment of the pull request
+func postPullComment(job regradeJob, head checksResp) error {
+	baseRepo, baseRef := download.SplitRef(job.base)
+	base, err := newChecksResp(context.Background(), baseRepo, baseRef, "", false)
+	if err != nil {
+		return fmt.Errorf("could not grade base branch: %v", err)
+	}
+	if err := saveBaseResp(job.base, base); err != nil {
+		return err
+	}
+
+	headRepo, _ := download.SplitRef(job.key)
+	diffs := check.DiffIssues(dirName(headRepo), base.Checks, head.Checks)
+	var body bytes.Buffer
+	body.WriteString(pullCommentMarker + "\n")
+	if err := ToPullRequestMarkdown(base.Checks, head.Checks, diffs, "https://"+*domain+"/report/"+job.key, &body); err != nil {
+		return err
+	}
+
+	token, err := tokenFor(job.installation)
+	if err != nil {
+		return err
+	}
+	id, err := findPullComment(token, job.repo, job.pull)
+	if err != nil {
+		return err
+	}
+	comment := map[string]string{"body": body.String()}
+	if id != 0 {
+		url := fmt.Sprintf("%s/repos/%s/issues/comments/%d", githubAPI, job.repo, id)
+		return githubRequest("PATCH", url, "token "+token, comment, nil)
+	}
+	url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", githubAPI, job.repo, job.pull)
+	return githubRequest("POST", url, "token "+token, comment, nil)
+}
+
+// saveBaseResp saves the report of the base branch of a pull request,
+// unless it was saved before
+func saveBaseResp(key string, resp checksResp) error {
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return DefaultStore.SaveReport(key, resp, respBytes, false)
+}
+
+// findPullComment returns the ID of the comment of the GitHub App on the
+// pull request, or 0 if it has none
+func findPullComment(token, repo string, pull int) (int64, error) {
+	const perPage = 100
+	for page := 1; ; page++ {
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		url := fmt.Sprintf("%s/repos/%s/issues/%d/comments?per_page=%d&page=%d", githubAPI, repo, pull, perPage, page)
+		if err := githubRequest("GET", url, "token "+token, nil, &comments); err != nil {
+			return 0, fmt.Errorf("could not list comments: %v", err)
+		}
+		for _, c := range comments {
+			if strings.HasPrefix(c.Body, pullCommentMarker) {
+				return c.ID, nil
+			}
+		}
+		if len(comments) < perPage {
+			return 0, nil
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestPullRequestJob(t *testing.T) {
+	event := func(action, head, headRef, baseRef string) githubPullRequestEvent {
+		var ev githubPullRequestEvent
+		ev.Action, ev.Number, ev.Installation.ID = action, 12, 7
+		ev.Repository.FullName, ev.Repository.DefaultBranch = "foo/bar", "main"
+		ev.PullRequest.Head.Ref, ev.PullRequest.Head.SHA = headRef, "abc"
+		ev.PullRequest.Base.Ref = baseRef
+		if head != "" {
+			ev.PullRequest.Head.Repo = &struct {
+				FullName string `json:"full_name"`
+			}{head}
+		}
+		return ev
+	}
+	cases := []struct {
+		ev   githubPullRequestEvent
+		want regradeJob
+		ok   bool
+	}{
+		{event("opened", "foo/bar", "feature", "main"), regradeJob{"github.com/foo/bar@feature", "foo/bar", "abc", 7, 12, "github.com/foo/bar"}, true},
+		{event("synchronize", "fork/bar", "main", "release"), regradeJob{"github.com/fork/bar@main", "foo/bar", "abc", 7, 12, "github.com/foo/bar@release"}, true},
+		{event("closed", "foo/bar", "feature", "main"), regradeJob{}, false},
+		{event("opened", "", "feature", "main"), regradeJob{}, false},
+	}
+	for _, c := range cases {
+		got, ok := pullRequestJob(c.ev)
+		if got != c.want || ok != c.ok {
+			t.Errorf("[%s %q] pullRequestJob = %+v, %v, want %+v, %v", c.ev.Action, c.ev.PullRequest.Head.Ref, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestFindPullComment(t *testing.T) {
+	// the comment of the app is on the second page
+	withGitHubApp(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/foo/bar/issues/12/comments" || r.Header.Get("Authorization") != "token tok" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		page, _ := strconv.Atoi(r.FormValue("page"))
+		var comments []map[string]interface{}
+		for i := 0; page == 1 && i < 100; i++ {
+			comments = append(comments, map[string]interface{}{"id": i + 1, "body": "LGTM"})
+		}
+		if page == 2 {
+			comments = append(comments, map[string]interface{}{"id": 101, "body": pullCommentMarker + "\n## Go Report Card"})
+		}
+		json.NewEncoder(w).Encode(comments)
+	})
+
+	id, err := findPullComment("tok", "foo/bar", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 101 {
+		t.Errorf("findPullComment = %d, want 101", id)
+	}
+	if _, err := findPullComment("tok", "foo/other", 12); err == nil {
+		t.Errorf("findPullComment of foo/other succeeded, want an error")
+	}
+}
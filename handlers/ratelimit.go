@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit allows N requests per period Per, in bursts of up to N. The
+// zero RateLimit allows all requests.
+type RateLimit struct {
+	N   int
+	Per time.Duration
+}
+
+// IPRateLimit limits the requests that grade repos from each client IP
+var IPRateLimit = RateLimit{N: 60, Per: time.Hour}
+
+// RepoRateLimit limits the requests that grade each repo
+var RepoRateLimit = RateLimit{N: 10, Per: time.Hour}
+
+// RateLimitAllowlist are the networks of trusted clients, such as CI
+// runners, whose requests are not rate limited
+var RateLimitAllowlist []*net.IPNet
+
+// RealIPHeader is the header in which a reverse proxy in front of the
+// server passes the IP of clients, such as X-Forwarded-For. The IP of the
+// connection is used if it is empty.
+var RealIPHeader = ""
+
+// maxBuckets is the number of clients or repos whose buckets are kept
+// before the full ones are dropped
+const maxBuckets = 10000
+
+// ParseRateLimit parses a rate limit like 60/1h, or 0 for no limit
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return RateLimit{}, nil
+	}
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q is not like 60/1h", s)
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil || n < 0 {
+		return RateLimit{}, fmt.Errorf("invalid number of requests in %q", s)
+	}
+	per, err := time.ParseDuration(s[i+1:])
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid period in %q", s)
+	}
+	if n == 0 {
+		return RateLimit{}, nil
+	}
+	return RateLimit{N: n, Per: per}, nil
+}
+
+// String returns l in the format of ParseRateLimit
+func (l RateLimit) String() string {
+	if l.N == 0 {
+		return "0"
+	}
+	per := l.Per.String()
+	if strings.HasSuffix(per, "m0s") {
+		per = strings.TrimSuffix(per, "0s")
+	}
+	if strings.HasSuffix(per, "h0m") {
+		per = strings.TrimSuffix(per, "0m")
+	}
+	return fmt.Sprintf("%d/%s", l.N, per)
+}
+
+// ParseAllowlist parses a comma separated list of IPs and CIDR networks
+func ParseAllowlist(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", entry)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// tokenBucket holds the requests a client or repo has left
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits requests by key, with a token bucket per key
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+var (
+	ipLimiter   = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+	repoLimiter = &rateLimiter{buckets: make(map[string]*tokenBucket)}
+)
+
+// allow takes a token from the bucket of key at now under limit, and
+// reports whether there was one. If not, it returns how long until there
+// is one.
+func (rl *rateLimiter) allow(key string, limit RateLimit, now time.Time) (bool, time.Duration) {
+	if limit.N <= 0 {
+		return true, 0
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	capacity := float64(limit.N)
+	perToken := limit.Per / time.Duration(limit.N)
+	refill := func(b *tokenBucket) {
+		b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+		b.last = now
+	}
+	b, ok := rl.buckets[key]
+	if !ok {
+		if len(rl.buckets) >= maxBuckets {
+			// full buckets are the same as new ones
+			for k, other := range rl.buckets {
+				if refill(other); other.tokens >= capacity {
+					delete(rl.buckets, k)
+				}
+			}
+		}
+		b = &tokenBucket{tokens: capacity, last: now}
+		rl.buckets[key] = b
+	}
+	refill(b)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// clientIP returns the IP of the client of r, from RealIPHeader if it is
+// set
+func clientIP(r *http.Request) net.IP {
+	addr := r.RemoteAddr
+	if RealIPHeader != "" {
+		if h := r.Header.Get(RealIPHeader); h != "" {
+			// proxies append the IP they were connected from
+			parts := strings.Split(h, ",")
+			addr = strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(addr)
+}
+
+// allowlisted reports whether ip is in RateLimitAllowlist
+func allowlisted(ip net.IP) bool {
+	for _, n := range RateLimitAllowlist {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimit returns the time to wait before the request r may grade the
+// repo with the key, and which of IPRateLimit and RepoRateLimit it
+// exceeds. The limit is empty if r may grade the repo now.
+func rateLimit(r *http.Request, key string) (limit string, wait time.Duration) {
+	ip := clientIP(r)
+	if allowlisted(ip) {
+		return "", 0
+	}
+	now := time.Now()
+	ok, wait := ipLimiter.allow(ip.String(), IPRateLimit, now)
+	limit = "from this client"
+	if ok {
+		ok, wait = repoLimiter.allow(key, RepoRateLimit, now)
+		limit = "for this repository"
+	}
+	if ok {
+		return "", 0
+	}
+	slog.Warn("rate limited", "ip", ip, "repo", key, "limit", limit)
+	return limit, wait
+}
+
+// rateLimited reports whether the request r to grade the repo with the
+// key exceeds IPRateLimit or RepoRateLimit, and if so writes a 429
+// response to w
+func rateLimited(w http.ResponseWriter, r *http.Request, key string) bool {
+	limit, wait := rateLimit(r, key)
+	if limit == "" {
+		return false
+	}
+	w.Header().Set("Retry-After", retryAfter(wait))
+	w.WriteHeader(http.StatusTooManyRequests)
+	fmt.Fprintf(w, "Too many requests %s, try again in %v.", limit, wait.Round(time.Second))
+	return true
+}
+
+// retryAfter returns the Retry-After header for waiting wait
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var parseRateLimitTests = []struct {
+	s    string
+	want RateLimit
+	err  bool
+}{
+	{"60/1h", RateLimit{60, time.Hour}, false},
+	{"5/90s", RateLimit{5, 90 * time.Second}, false},
+	{"0", RateLimit{}, false},
+	{"", RateLimit{}, false},
+	{"0/1h", RateLimit{}, false},
+	{"60", RateLimit{}, true},
+	{"x/1h", RateLimit{}, true},
+	{"60/-1h", RateLimit{}, true},
+}
+
+func TestParseRateLimit(t *testing.T) {
+	for _, tt := range parseRateLimitTests {
+		got, err := ParseRateLimit(tt.s)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("[%q] ParseRateLimit = %v, %v, want %v, error %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+	for _, s := range []string{"60/1h", "10/1m", "5/1m30s", "3/10s", "0"} {
+		l, err := ParseRateLimit(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.String(); got != s {
+			t.Errorf("[%q] String = %q", s, got)
+		}
+	}
+}
+
+func TestParseAllowlist(t *testing.T) {
+	nets, err := ParseAllowlist("192.0.2.10, 198.51.100.0/24,2001:db8::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RateLimitAllowlist = nets
+	defer func() { RateLimitAllowlist = nil }()
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.0.2.10", true},
+		{"192.0.2.11", false},
+		{"198.51.100.200", true},
+		{"2001:db8::1", true},
+		{"2001:db8::2", false},
+	}
+	for _, c := range cases {
+		if got := allowlisted(net.ParseIP(c.ip)); got != c.want {
+			t.Errorf("[%q] allowlisted = %v, want %v", c.ip, got, c.want)
+		}
+	}
+	if _, err := ParseAllowlist("192.0.2.300"); err == nil {
+		t.Errorf("ParseAllowlist of an invalid IP succeeded")
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	rl := &rateLimiter{buckets: make(map[string]*tokenBucket)}
+	limit := RateLimit{3, time.Minute}
+	now := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.allow("a", limit, now); !ok {
+			t.Fatalf("request %d of a burst was limited", i+1)
+		}
+	}
+	ok, wait := rl.allow("a", limit, now)
+	if ok || wait != 20*time.Second {
+		t.Errorf("allow after the burst = %v, %v, want false, 20s", ok, wait)
+	}
+	if ok, _ := rl.allow("b", limit, now); !ok {
+		t.Errorf("request of another key was limited")
+	}
+	// a token is refilled every 20 seconds
+	if ok, _ := rl.allow("a", limit, now.Add(20*time.Second)); !ok {
+		t.Errorf("request after the refill was limited")
+	}
+	if ok, _ := rl.allow("a", RateLimit{}, now.Add(20*time.Second)); !ok {
+		t.Errorf("request without a limit was limited")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	defer func(h string) { RealIPHeader = h }(RealIPHeader)
+	r := httptest.NewRequest("POST", "/checks", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.5, 198.51.100.7")
+	if got := clientIP(r).String(); got != "192.0.2.1" {
+		t.Errorf("clientIP = %s, want the connection IP 192.0.2.1", got)
+	}
+	RealIPHeader = "X-Forwarded-For"
+	if got := clientIP(r).String(); got != "198.51.100.7" {
+		t.Errorf("clientIP = %s, want the IP added by the proxy 198.51.100.7", got)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	defer func(ip, repo RateLimit) { IPRateLimit, RepoRateLimit = ip, repo }(IPRateLimit, RepoRateLimit)
+	IPRateLimit, RepoRateLimit = RateLimit{2, time.Hour}, RateLimit{1, time.Hour}
+	ipLimiter.buckets, repoLimiter.buckets = make(map[string]*tokenBucket), make(map[string]*tokenBucket)
+
+	request := func(ip, key string) int {
+		r := httptest.NewRequest("POST", "/checks", nil)
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		if !rateLimited(w, r, key) {
+			return 200
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Errorf("[%s %s] 429 response without Retry-After", ip, key)
+		}
+		return w.Code
+	}
+	cases := []struct {
+		ip, key string
+		want    int
+	}{
+		{"192.0.2.1", "github.com/foo/a", 200},
+		{"192.0.2.1", "github.com/foo/a", 429},
+		{"192.0.2.1", "github.com/foo/b", 429},
+		{"192.0.2.2", "github.com/foo/b", 200},
+		{"192.0.2.2", "github.com/foo/c", 200},
+		{"192.0.2.2", "github.com/foo/d", 429},
+	}
+	for _, c := range cases {
+		if got := request(c.ip, c.key); got != c.want {
+			t.Errorf("[%s %s] status = %d, want %d", c.ip, c.key, got, c.want)
+		}
+	}
+
+	RateLimitAllowlist, _ = ParseAllowlist("192.0.2.0/24")
+	defer func() { RateLimitAllowlist = nil }()
+	if got := request("192.0.2.2", "github.com/foo/d"); got != 200 {
+		t.Errorf("allowlisted request status = %d, want 200", got)
+	}
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisTTL is how long reports and the grades of badges are cached in
+// Redis
+var RedisTTL = time.Hour
+
+// redisTimeout is how long a command to Redis may take, including
+// connecting
+const redisTimeout = 2 * time.Second
+
+// maxIdleRedisConns is the number of connections to Redis that are kept
+// open between commands
+const maxIdleRedisConns = 16
+
+// prefixes of the keys of the Redis cache
+const (
+	redisReportPrefix = "goreportcard:report:"
+	redisBadgePrefix  = "goreportcard:badge:"
+)
+
+// badgeCache caches the grades of badges, or is nil if there is no Redis
+// cache
+var badgeCache *redisClient
+
+// redisError is an error reply of Redis
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisClient sends commands to a Redis server in the RESP protocol
+type redisClient struct {
+	addr     string
+	tls      bool
+	password string
+	db       int
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// newRedisClient returns a client of the Redis server at rawurl, such as
+// redis://:password@host:6379/0, or rediss:// for TLS
+func newRedisClient(rawurl string) (*redisClient, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis URL %q is not redis:// or rediss://", rawurl)
+	}
+	c := &redisClient{addr: u.Host, tls: u.Scheme == "rediss", idle: make(chan *redisConn, maxIdleRedisConns)}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		c.db, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database %q in redis URL", db)
+		}
+	}
+	return c, nil
+}
+
+// dial opens a connection and authenticates it
+func (c *redisClient) dial() (*redisConn, error) {
+	d := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if c.tls {
+		host, _, _ := net.SplitHostPort(c.addr)
+		conn, err = tls.DialWithDialer(d, "tcp", c.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = d.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+	if c.password != "" {
+		if _, err := rc.do("AUTH", c.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// do sends a command and returns its reply: a string, an int64, nil or a
+// []interface{} of them
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	var conn *redisConn
+	select {
+	case conn = <-c.idle:
+	default:
+		var err error
+		if conn, err = c.dial(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := conn.do(args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// the connection is in an unknown state
+		conn.Close()
+		return nil, err
+	}
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+func (conn *redisConn) do(args ...string) (interface{}, error) {
+	conn.SetDeadline(time.Now().Add(redisTimeout))
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(conn.r)
+}
+
+// readRedisReply reads a reply in the RESP protocol
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		replies := make([]interface{}, n)
+		for i := range replies {
+			if replies[i], err = readRedisReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, fmt.Errorf("redis: invalid reply %q", line)
+}
+
+// get returns the value of key, and whether it was found
+func (c *redisClient) get(key string) (string, bool, error) {
+	reply, err := c.do("GET", key)
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	s, ok := reply.(string)
+	return s, ok, nil
+}
+
+// set sets key to value, which expires after ttl
+func (c *redisClient) set(key, value string, ttl time.Duration) error {
+	_, err := c.do("SET", key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// del deletes the keys
+func (c *redisClient) del(keys ...string) error {
+	_, err := c.do(append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// cachedStore is a Store whose reports are cached in Redis for RedisTTL.
+// Saving a report removes it, and the grade of its badge, from the cache.
+// The cache is skipped when Redis cannot be reached.
+type cachedStore struct {
+	Store
+	redis *redisClient
+}
+
+// CacheInRedis caches the reports of DefaultStore, and the grades of
+// badges, in the Redis server at url, such as redis://:password@host:6379/0
+func CacheInRedis(url string) error {
+	c, err := newRedisClient(url)
+	if err != nil {
+		return err
+	}
+	if _, err := c.do("PING"); err != nil {
+		return err
+	}
+	DefaultStore = cachedStore{Store: DefaultStore, redis: c}
+	badgeCache = c
+	return nil
+}
+
+func (s cachedStore) Report(key string) ([]byte, error) {
+	cached, ok, err := s.redis.get(redisReportPrefix + key)
+	if err != nil {
+		slog.Warn("could not read report from redis", "repo", key, "error", err)
+	}
+	if ok {
+		cacheLookups.add(1, "redis", "hit")
+		return []byte(cached), nil
+	}
+	cacheLookups.add(1, "redis", "miss")
+
+	report, err := s.Store.Report(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.set(redisReportPrefix+key, string(report), RedisTTL); err != nil {
+		slog.Warn("could not cache report in redis", "repo", key, "error", err)
+	}
+	return report, nil
+}
+
+func (s cachedStore) SaveReport(key string, resp checksResp, report []byte, overwrite bool) error {
+	if err := s.Store.SaveReport(key, resp, report, overwrite); err != nil {
+		return err
+	}
+	if err := s.redis.del(redisReportPrefix+key, redisBadgePrefix+key); err != nil {
+		slog.Warn("could not remove report from redis", "repo", key, "error", err)
+	}
+	return nil
+}
+
+// cachedGrade returns the grade of the badge of the report with the key,
+// if it is cached
+func cachedGrade(key string) (Grade, bool) {
+	if badgeCache == nil {
+		return "", false
+	}
+	s, ok, err := badgeCache.get(redisBadgePrefix + key)
+	if err != nil {
+		slog.Warn("could not read badge from redis", "repo", key, "error", err)
+	}
+	if !ok {
+		return "", false
+	}
+	// the grade is part of the path of the badge
+	g, err := ParseGrade(s)
+	return g, err == nil
+}
+
+// cacheGrade caches the grade of the badge of the report with the key
+func cacheGrade(key string, g Grade) {
+	if badgeCache == nil {
+		return
+	}
+	if err := badgeCache.set(redisBadgePrefix+key, string(g), RedisTTL); err != nil {
+		slog.Warn("could not cache badge in redis", "repo", key, "error", err)
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves GET, SET, DEL and PING of the RESP protocol from a map
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// startFakeRedis starts a fakeRedis and returns its URL
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	f := &fakeRedis{data: map[string]string{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, "redis://" + l.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range reply.([]interface{}) {
+			args = append(args, a.(string))
+		}
+		f.mu.Lock()
+		switch args[0] {
+		case "PING":
+			conn.Write([]byte("+PONG\r\n"))
+		case "GET":
+			if v, ok := f.data[args[1]]; ok {
+				conn.Write([]byte("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"))
+			} else {
+				conn.Write([]byte("$-1\r\n"))
+			}
+		case "SET":
+			f.data[args[1]] = args[2]
+			conn.Write([]byte("+OK\r\n"))
+		case "DEL":
+			n := 0
+			for _, k := range args[1:] {
+				if _, ok := f.data[k]; ok {
+					delete(f.data, k)
+					n++
+				}
+			}
+			conn.Write([]byte(":" + strconv.Itoa(n) + "\r\n"))
+		default:
+			conn.Write([]byte("-ERR unknown command\r\n"))
+		}
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.data[key]
+	return v, ok
+}
+
+// memStore is a Store of reports in memory, which counts the reads
+type memStore struct {
+	Store
+	reports map[string][]byte
+	reads   int
+}
+
+func (s *memStore) Report(key string) ([]byte, error) {
+	s.reads++
+	if r, ok := s.reports[key]; ok {
+		return r, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *memStore) SaveReport(key string, resp checksResp, report []byte, overwrite bool) error {
+	s.reports[key] = report
+	return nil
+}
+
+func TestNewRedisClient(t *testing.T) {
+	cases := []struct {
+		url      string
+		addr     string
+		tls      bool
+		password string
+		db       int
+		err      bool
+	}{
+		{url: "redis://localhost", addr: "localhost:6379"},
+		{url: "rediss://:secret@cache:6380/2", addr: "cache:6380", tls: true, password: "secret", db: 2},
+		{url: "http://localhost", err: true},
+		{url: "redis://localhost/x", err: true},
+	}
+	for _, tt := range cases {
+		c, err := newRedisClient(tt.url)
+		if tt.err {
+			if err == nil {
+				t.Errorf("newRedisClient(%q) succeeded, want an error", tt.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newRedisClient(%q) = %v", tt.url, err)
+			continue
+		}
+		if c.addr != tt.addr || c.tls != tt.tls || c.password != tt.password || c.db != tt.db {
+			t.Errorf("newRedisClient(%q) = %+v", tt.url, c)
+		}
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	f, url := startFakeRedis(t)
+	mem := &memStore{reports: map[string][]byte{"github.com/foo/bar": []byte(`{"grade":"B"}`)}}
+	defer func(s Store, c *redisClient) { DefaultStore, badgeCache = s, c }(DefaultStore, badgeCache)
+	DefaultStore = mem
+	if err := CacheInRedis(url); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		r, err := DefaultStore.Report("github.com/foo/bar")
+		if err != nil || string(r) != `{"grade":"B"}` {
+			t.Fatalf("Report = %s, %v", r, err)
+		}
+	}
+	if mem.reads != 1 {
+		t.Errorf("the store was read %d times, want once", mem.reads)
+	}
+	if _, err := DefaultStore.Report("github.com/foo/none"); err == nil {
+		t.Errorf("Report of an unknown repo succeeded")
+	}
+
+	if _, ok := cachedGrade("github.com/foo/bar"); ok {
+		t.Errorf("grade cached before it was set")
+	}
+	cacheGrade("github.com/foo/bar", GradeB)
+	if g, ok := cachedGrade("github.com/foo/bar"); !ok || g != GradeB {
+		t.Errorf("cachedGrade = %q, %v, want %q", g, ok, GradeB)
+	}
+
+	if err := DefaultStore.SaveReport("github.com/foo/bar", checksResp{}, []byte(`{"grade":"A"}`), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.get(redisReportPrefix + "github.com/foo/bar"); ok {
+		t.Errorf("report still cached after it was saved")
+	}
+	if _, ok := cachedGrade("github.com/foo/bar"); ok {
+		t.Errorf("grade still cached after the report was saved")
+	}
+	if r, _ := DefaultStore.Report("github.com/foo/bar"); string(r) != `{"grade":"A"}` {
+		t.Errorf("Report after saving = %s, want the new report", r)
+	}
+
+	f.mu.Lock()
+	f.data[redisBadgePrefix+"github.com/foo/evil"] = "../../etc/passwd"
+	f.mu.Unlock()
+	if g, ok := cachedGrade("github.com/foo/evil"); ok {
+		t.Errorf("cachedGrade of an invalid grade = %q, want none", g)
+	}
+}
+
+func TestRedisUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "redis://" + l.Addr().String()
+	l.Close()
+	if err := CacheInRedis(url); err == nil {
+		t.Errorf("CacheInRedis of a closed port succeeded")
+	}
+
+	// once running, the store is read when Redis is down
+	mem := &memStore{reports: map[string][]byte{"github.com/foo/bar": []byte(`{}`)}}
+	c, _ := newRedisClient(url)
+	s := cachedStore{Store: mem, redis: c}
+	start := time.Now()
+	if _, err := s.Report("github.com/foo/bar"); err != nil {
+		t.Errorf("Report with Redis down = %v", err)
+	}
+	if time.Since(start) > 2*redisTimeout {
+		t.Errorf("Report with Redis down took %v", time.Since(start))
+	}
+}
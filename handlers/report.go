@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"flag"
@@ -12,20 +12,42 @@ import (
 var domain = flag.String("domain", "goreportcard.com", "Domain used for your goreportcard installation")
 var googleAnalyticsKey = flag.String("google_analytics_key", "UA-58936835-1", "Google Analytics Account Id")
 
-// ReportHandler handles the report page
+// ReportHandler handles the report page. The page parameter selects
+// another page of the report, such as ?page=history, so that the path is
+// always the repo, even for subdirectories named like a page.
 func ReportHandler(w http.ResponseWriter, r *http.Request, repo string, dev bool) {
-	log.Printf("Displaying report: %q", repo)
+	if format := r.FormValue("format"); format != "" {
+		FormatHandler(w, r, repoKey(repoRef(r, repo)), format)
+		return
+	}
+	repo = repoKey(repoRef(r, repo))
+	switch page := r.FormValue("page"); page {
+	case "":
+	case "history":
+		HistoryHandler(w, r, repo)
+		return
+	case "explain":
+		ExplainHandler(w, r, repo)
+		return
+	case "diff":
+		DiffHandler(w, r, repo)
+		return
+	default:
+		http.Error(w, "Unknown page of the report", http.StatusNotFound)
+		return
+	}
+	slog.Info("displaying report", "repo", repo)
 	t := template.Must(template.New("report.html").Delims("[[", "]]").ParseFiles("templates/report.html"))
-	resp, err := getFromCache(repo)
+	resp, err := getReport(r, repo)
 	needToLoad := false
 	if err != nil {
-		log.Println("ERROR:", err) // log error, but continue
+		slog.Warn("could not get report from cache", "repo", repo, "error", err) // log error, but continue
 		needToLoad = true
 	}
 
 	respBytes, err := json.Marshal(resp)
 	if err != nil {
-		log.Println("ERROR: marshaling json: ", err)
+		slog.Error("could not marshal json", "repo", repo, "error", err)
 		http.Error(w, "Failed to load cache object", 500)
 		return
 	}
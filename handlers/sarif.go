@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// sarifVersion and sarifSchema are the version of SARIF written by
+// ToSARIF and its schema
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	Properties       *sarifRuleProps `json:"properties,omitempty"`
+}
+
+type sarifRuleProps struct {
+	Category string `json:"category"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	RuleIndex        int             `json:"ruleIndex"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               int                   `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+	ContextRegion    *sarifRegion          `json:"contextRegion,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	EndLine     int           `json:"endLine,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// sarifLevels are the SARIF levels of the severities of issues
+var sarifLevels = map[string]string{
+	check.SeverityError:   "error",
+	check.SeverityWarning: "warning",
+	check.SeverityInfo:    "note",
+}
+
+// sarifRuleID returns the id of the rule of an issue found by the named
+// check: the name, or the name and the rule the check reported
+func sarifRuleID(name string, e check.Error) string {
+	if e.RuleID == "" || e.RuleID == name {
+		return name
+	}
+	return name + "/" + e.RuleID
+}
+
+// sarifArtifact returns the location of the file at rel, relative to the
+// root of the repo
+func sarifArtifact(rel string) sarifArtifactLocation {
+	return sarifArtifactLocation{URI: rel, URIBaseID: "%SRCROOT%"}
+}
+
+// ToSARIF writes the issues in the results of the checks run on the repo
+// in dir to w as a SARIF log, for code scanning services and editors.
+// Every check and every rule reported by a check is a rule of the log.
+func ToSARIF(dir string, results []check.CheckResult, w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "Go Report Card",
+			InformationURI: "https://goreportcard.com",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	rules := make(map[string]int)
+	rule := func(r check.CheckResult, e check.Error) int {
+		id := sarifRuleID(r.Name, e)
+		if i, ok := rules[id]; ok {
+			return i
+		}
+		sr := sarifRule{ID: id, Name: id, ShortDescription: sarifMessage{Text: stripTags(r.Description)}}
+		if id != r.Name {
+			sr.ShortDescription.Text = e.RuleID + ", reported by " + r.Name
+		}
+		if sr.ShortDescription.Text == "" {
+			sr.ShortDescription.Text = id
+		}
+		if r.Category != "" {
+			sr.Properties = &sarifRuleProps{Category: r.Category}
+		}
+		rules[id] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sr)
+		return rules[id]
+	}
+
+	for _, r := range results {
+		for _, fs := range r.FileSummaries {
+			rel := check.RelFilename(dir, fs.Filename)
+			for _, e := range fs.Errors {
+				i := rule(r, e)
+				res := sarifResult{
+					RuleID:    run.Tool.Driver.Rules[i].ID,
+					RuleIndex: i,
+					Level:     sarifLevels[e.Severity],
+					Message:   sarifMessage{Text: strings.TrimSpace(e.ErrorString)},
+				}
+				if res.Level == "" {
+					res.Level = "warning"
+				}
+				if fs.Filename != "" {
+					res.Locations = []sarifLocation{{PhysicalLocation: sarifPhysical(rel, e)}}
+				}
+				for j, l := range e.Related {
+					res.RelatedLocations = append(res.RelatedLocations, sarifLocation{
+						ID: j + 1,
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifact(check.RelFilename(dir, l.Filename)),
+							Region:           &sarifRegion{StartLine: l.StartLine, EndLine: l.EndLine},
+						},
+					})
+				}
+				run.Results = append(run.Results, res)
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+// sarifPhysical returns the location of e in the file at rel, with the
+// snippet around it if it has one
+func sarifPhysical(rel string, e check.Error) sarifPhysicalLocation {
+	loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifact(rel)}
+	if e.LineNumber > 0 {
+		loc.Region = &sarifRegion{StartLine: e.LineNumber, StartColumn: e.Column}
+	}
+	if s := e.Snippet; s != nil && len(s.Lines) > 0 {
+		loc.ContextRegion = &sarifRegion{
+			StartLine: s.StartLine,
+			EndLine:   s.StartLine + len(s.Lines) - 1,
+			Snippet:   &sarifMessage{Text: strings.Join(s.Lines, "\n") + "\n"},
+		}
+	}
+	return loc
+}
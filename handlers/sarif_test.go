@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestToSARIF(t *testing.T) {
+	dir := "repos/src/github.com/foo/bar"
+	results := []check.CheckResult{
+		{
+			Name:        "go_vet",
+			Description: "<code>go vet</code> examines Go source code",
+			FileSummaries: []check.FileSummary{{
+				Filename: "bar/a.go",
+				Errors: []check.Error{
+					{LineNumber: 3, Column: 2, ErrorString: "unreachable code", Severity: check.SeverityError},
+					{LineNumber: 5, ErrorString: "printf: bad verb", RuleID: "printf", Snippet: &check.Snippet{StartLine: 4, Lines: []string{"a", "b"}}},
+				},
+			}},
+		},
+		{Name: "gofmt", FileSummaries: []check.FileSummary{}},
+		{Name: "gosec", Category: check.CategorySecurity, FileSummaries: []check.FileSummary{{
+			Filename: "bar/b.go",
+			Errors:   []check.Error{{LineNumber: 1, ErrorString: "weak crypto", RuleID: "G401", Severity: check.SeverityInfo}},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	if err := ToSARIF(dir, results, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("ToSARIF wrote invalid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("ToSARIF version %q with %d runs, want 2.1.0 with 1 run", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+
+	var ruleIDs []string
+	for _, r := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, r.ID)
+	}
+	if want := []string{"go_vet", "go_vet/printf", "gosec/G401"}; !reflect.DeepEqual(ruleIDs, want) {
+		t.Errorf("ToSARIF rules = %v, want %v", ruleIDs, want)
+	}
+	if got := run.Tool.Driver.Rules[0].ShortDescription.Text; got != "go vet examines Go source code" {
+		t.Errorf("ToSARIF rule description = %q, want the text of the check description", got)
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("ToSARIF wrote %d results, want 3", len(run.Results))
+	}
+	for i, want := range []struct {
+		rule, level string
+		line, col   int
+	}{
+		{"go_vet", "error", 3, 2},
+		{"go_vet/printf", "warning", 5, 0},
+		{"gosec/G401", "note", 1, 0},
+	} {
+		res := run.Results[i]
+		loc := res.Locations[0].PhysicalLocation
+		if res.RuleID != want.rule || res.Level != want.level || loc.Region.StartLine != want.line || loc.Region.StartColumn != want.col {
+			t.Errorf("[%d] result %s %s at %d:%d, want %s %s at %d:%d", i, res.RuleID, res.Level, loc.Region.StartLine, loc.Region.StartColumn, want.rule, want.level, want.line, want.col)
+		}
+		if run.Tool.Driver.Rules[res.RuleIndex].ID != res.RuleID {
+			t.Errorf("[%d] rule index %d is not rule %s", i, res.RuleIndex, res.RuleID)
+		}
+	}
+	if uri := run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "a.go" {
+		t.Errorf("ToSARIF uri = %q, want a.go", uri)
+	}
+	if ctx := run.Results[1].Locations[0].PhysicalLocation.ContextRegion; ctx == nil || ctx.StartLine != 4 || ctx.EndLine != 5 {
+		t.Errorf("ToSARIF context region = %+v, want lines 4 to 5", ctx)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+// SchemaVersion is the version of the JSON report, which is described by
+// the JSON Schema at SchemaURL. Version 1 is the list of the results of
+// the checks, which is still served with the v=1 parameter.
+const SchemaVersion = 2
+
+// SchemaURL is the path of the JSON Schema of version SchemaVersion of
+// the JSON report
+const SchemaURL = "/assets/schema/report.v2.json"
+
+// reportV2 is version 2 of the JSON report of a repo
+type reportV2 struct {
+	Schema        string    `json:"$schema"`
+	SchemaVersion int       `json:"schema_version"`
+	Repo          string    `json:"repo"`
+	Commit        string    `json:"commit,omitempty"`
+	Grade         Grade     `json:"grade"`
+	Score         float64   `json:"score"`
+	Files         int       `json:"files,omitempty"`
+	Issues        int       `json:"issues"`
+	LastRefresh   time.Time `json:"last_refresh"`
+	Checks        []checkV2 `json:"checks"`
+}
+
+// checkV2 is the result of a check in version 2 of the JSON report
+type checkV2 struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Category    string         `json:"category,omitempty"`
+	Weight      float64        `json:"weight"`
+	Percentage  float64        `json:"percentage"`
+	Error       string         `json:"error,omitempty"`
+	Status      string         `json:"status,omitempty"`
+	DurationMS  int64          `json:"duration_ms"`
+	Suppressed  int            `json:"suppressed,omitempty"`
+	Baselined   int            `json:"baselined,omitempty"`
+	Severities  map[string]int `json:"severities,omitempty"`
+	Issues      []issueV2      `json:"issues"`
+}
+
+// issueV2 is an issue found by a check in version 2 of the JSON report
+type issueV2 struct {
+	File     string `json:"file,omitempty"`
+	FileURL  string `json:"file_url,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	RuleID   string `json:"rule_id"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// newReportV2 returns version 2 of the JSON report of resp
+func newReportV2(resp checksResp) reportV2 {
+	report := reportV2{
+		Schema:        "https://" + *domain + SchemaURL,
+		SchemaVersion: SchemaVersion,
+		Repo:          resp.Repo,
+		Commit:        resp.Commit,
+		Files:         resp.Files,
+		LastRefresh:   resp.LastRefresh,
+		Checks:        []checkV2{},
+	}
+	// the grade is not stored for some repos, and the thresholds may
+	// have changed since
+	report.Grade, report.Score = GradeResults(resp.Checks)
+	for _, r := range resp.Checks {
+		c := checkV2{
+			Name:        r.Name,
+			Description: r.Description,
+			Category:    r.Category,
+			Weight:      r.Weight,
+			Percentage:  r.Percentage,
+			Error:       r.Error,
+			Status:      r.Status,
+			DurationMS:  r.Duration.Milliseconds(),
+			Suppressed:  r.Suppressed,
+			Baselined:   r.Baselined,
+			Severities:  r.Severities,
+			Issues:      []issueV2{},
+		}
+		for _, fs := range r.FileSummaries {
+			for _, e := range fs.Errors {
+				rule := e.RuleID
+				if rule == "" {
+					rule = r.Name
+				}
+				sev := e.Severity
+				if sev == "" {
+					sev = check.SeverityWarning
+				}
+				c.Issues = append(c.Issues, issueV2{
+					File:     fs.Filename,
+					FileURL:  fs.FileURL,
+					Line:     e.LineNumber,
+					Column:   e.Column,
+					RuleID:   rule,
+					Severity: sev,
+					Message:  strings.TrimSpace(e.ErrorString),
+				})
+			}
+		}
+		report.Issues += len(c.Issues)
+		report.Checks = append(report.Checks, c)
+	}
+	return report
+}
+
+// writeJSON writes version 2 of the JSON report of the results of the
+// checks run on the repo in dir, as graded now
+func writeJSON(dir string, results []check.CheckResult, w io.Writer) error {
+	resp := checksResp{
+		Checks:      results,
+		Repo:        exportRepo(dir),
+		LastRefresh: time.Now().UTC(),
+	}
+	resp.Commit, _ = check.HeadCommit(dir)
+	return json.NewEncoder(w).Encode(newReportV2(resp))
+}
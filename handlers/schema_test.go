@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+func TestNewReportV2(t *testing.T) {
+	resp := checksResp{
+		Repo:   "github.com/foo/bar",
+		Commit: "0123abc",
+		Files:  2,
+		Checks: []check.CheckResult{
+			{Name: "go_vet", Weight: 1, Percentage: .5, Duration: 1500 * time.Millisecond, FileSummaries: []check.FileSummary{
+				{Filename: "a.go", FileURL: "https://github.com/foo/bar/blob/master/a.go", Errors: []check.Error{
+					{LineNumber: 3, Column: 2, ErrorString: "printf: bad verb\n", RuleID: "printf", Severity: check.SeverityError},
+					{LineNumber: 7, ErrorString: "unreachable code"},
+				}},
+			}},
+			{Name: "gofmt", Weight: 1, Percentage: 1},
+		},
+	}
+
+	got := newReportV2(resp)
+	if got.SchemaVersion != SchemaVersion || got.Repo != resp.Repo || got.Commit != resp.Commit ||
+		got.Grade != GradeB || got.Score != 75 || got.Files != 2 || got.Issues != 2 {
+		t.Errorf("newReportV2 = %+v, want schema version %d of %s at 0123abc graded B (75%%) with 2 files and 2 issues", got, SchemaVersion, resp.Repo)
+	}
+	if len(got.Checks) != 2 || got.Checks[0].DurationMS != 1500 {
+		t.Fatalf("newReportV2 checks = %+v, want 2 checks of which go_vet took 1500ms", got.Checks)
+	}
+	want := []issueV2{
+		{File: "a.go", FileURL: "https://github.com/foo/bar/blob/master/a.go", Line: 3, Column: 2, RuleID: "printf", Severity: check.SeverityError, Message: "printf: bad verb"},
+		{File: "a.go", FileURL: "https://github.com/foo/bar/blob/master/a.go", Line: 7, RuleID: "go_vet", Severity: check.SeverityWarning, Message: "unreachable code"},
+	}
+	if !reflect.DeepEqual(got.Checks[0].Issues, want) {
+		t.Errorf("newReportV2 issues = %+v, want %+v", got.Checks[0].Issues, want)
+	}
+	if issues := got.Checks[1].Issues; issues == nil || len(issues) != 0 {
+		t.Errorf("newReportV2 issues of a clean check = %#v, want an empty list", issues)
+	}
+}
+
+// jsonFields returns the names of the JSON fields of the struct type t,
+// and the names of those that are always written
+func jsonFields(t reflect.Type) (names, required []string) {
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")
+		names = append(names, tag[0])
+		if len(tag) == 1 || tag[1] != "omitempty" {
+			required = append(required, tag[0])
+		}
+	}
+	return names, required
+}
+
+type schemaObject struct {
+	Required   []string                   `json:"required"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+func TestSchemaDescribesReportV2(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("..", strings.TrimPrefix(SchemaURL, "/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		schemaObject
+		Defs map[string]schemaObject `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("invalid JSON Schema: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		typ    reflect.Type
+		schema schemaObject
+	}{
+		{"report", reflect.TypeOf(reportV2{}), schema.schemaObject},
+		{"check", reflect.TypeOf(checkV2{}), schema.Defs["check"]},
+		{"issue", reflect.TypeOf(issueV2{}), schema.Defs["issue"]},
+	}
+	for _, c := range cases {
+		names, required := jsonFields(c.typ)
+		var properties []string
+		for name := range c.schema.Properties {
+			properties = append(properties, name)
+		}
+		sort.Strings(names)
+		sort.Strings(properties)
+		if !reflect.DeepEqual(properties, names) {
+			t.Errorf("[%q] schema properties = %q, want %q", c.name, properties, names)
+		}
+		always := make(map[string]bool)
+		for _, name := range required {
+			always[name] = true
+		}
+		for _, name := range c.schema.Required {
+			if !always[name] {
+				t.Errorf("[%q] schema requires %q, which can be left out", c.name, name)
+			}
+		}
+	}
+}
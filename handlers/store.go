@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Store keeps the reports of repos, the high scores, stats and recently
+// viewed repos derived from them, and the history of their grades
+type Store interface {
+	// Report returns the JSON of the report with the key
+	Report(key string) ([]byte, error)
+	// SaveReport saves resp, marshalled to report, as the report with
+	// the key, and counts it in the stats and high scores, unless the
+	// repo was graded before and overwrite is false
+	SaveReport(key string, resp checksResp, report []byte, overwrite bool) error
+	// ReportKeys returns the keys of the reports that start with prefix
+	ReportKeys(prefix string) ([]string, error)
+	// ScoreDistribution counts the scores of all reports
+	ScoreDistribution() (scoreDistribution, error)
+	// HighScores returns the repos with the highest scores, from the
+	// highest score down, the number of repos by their rounded score,
+	// and the number of graded repos
+	HighScores() ([]scoreItem, []int, int, error)
+	// RecentRepos returns the keys of the recently viewed repos, the
+	// latest first
+	RecentRepos() ([]string, error)
+	// AddRecentRepo adds the report with the key to the recently viewed
+	// repos
+	AddRecentRepo(key string) error
+	// AppendHistory adds e to the history of the report with the key
+	AppendHistory(key string, e historyEntry) error
+	// History returns the history of the report with the key, oldest
+	// first
+	History(key string) ([]historyEntry, error)
+	// Ping checks that the store can be read
+	Ping() error
+}
+
+// DefaultStore is the Store of the server, the bolt database at DBPath
+// unless another store is configured
+var DefaultStore Store = boltStore{}
+
+// boltStore is a Store in the buckets of the bolt database at DBPath
+type boltStore struct{}
+
+func openBolt() (*bolt.DB, error) {
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open bolt database: %v", err)
+	}
+	return db, nil
+}
+
+func (boltStore) Report(key string) ([]byte, error) {
+	db, err := openBolt()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var report []byte
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(RepoBucket))
+		if b == nil {
+			return errors.New("No repo bucket")
+		}
+		cached := b.Get([]byte(key))
+		if cached == nil {
+			return fmt.Errorf("%q not found in cache", key)
+		}
+		report = append([]byte(nil), cached...)
+		return nil
+	})
+	return report, err
+}
+
+func (boltStore) SaveReport(key string, resp checksResp, report []byte, overwrite bool) error {
+	db, err := openBolt()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return saveResp(db, key, resp, report, overwrite)
+}
+
+func (boltStore) ReportKeys(prefix string) ([]string, error) {
+	db, err := openBolt()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var keys []string
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(RepoBucket))
+		if b == nil {
+			return fmt.Errorf("repo bucket not found")
+		}
+		c := b.Cursor()
+		p := []byte(prefix)
+		for k, _ := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = c.Next() {
+			keys = append(keys, string(k))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func (boltStore) ScoreDistribution() (scoreDistribution, error) {
+	db, err := openBolt()
+	if err != nil {
+		return scoreDistribution{}, err
+	}
+	defer db.Close()
+
+	var d scoreDistribution
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(RepoBucket))
+		if b == nil {
+			return errors.New("No repo bucket")
+		}
+		d, err = newScoreDistribution(b)
+		return err
+	})
+	return d, err
+}
+
+func (boltStore) HighScores() ([]scoreItem, []int, int, error) {
+	db, err := openBolt()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer db.Close()
+
+	count, scores, stats := 0, &ScoreHeap{}, make([]int, 101, 101)
+	err = db.View(func(tx *bolt.Tx) error {
+		hsb := tx.Bucket([]byte(MetaBucket))
+		if hsb == nil {
+			return fmt.Errorf("high score bucket not found")
+		}
+		if scoreBytes := hsb.Get([]byte("scores")); scoreBytes != nil {
+			json.Unmarshal(scoreBytes, scores)
+		}
+		if statsBytes := hsb.Get([]byte("stats")); statsBytes != nil {
+			json.Unmarshal(statsBytes, &stats)
+		}
+		heap.Init(scores)
+
+		total := hsb.Get([]byte("total_repos"))
+		if total == nil {
+			return nil
+		}
+		return json.Unmarshal(total, &count)
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	sortedScores := make([]scoreItem, len(*scores))
+	for i := range sortedScores {
+		sortedScores[len(sortedScores)-i-1] = heap.Pop(scores).(scoreItem)
+	}
+	return sortedScores, stats, count, nil
+}
+
+func (boltStore) RecentRepos() ([]string, error) {
+	db, err := openBolt()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	recent := []recentItem{}
+	err = db.View(func(tx *bolt.Tx) error {
+		rb := tx.Bucket([]byte(MetaBucket))
+		if rb == nil {
+			return fmt.Errorf("meta bucket not found")
+		}
+		if b := rb.Get([]byte("recent")); b != nil {
+			json.Unmarshal(b, &recent)
+		}
+		return nil
+	})
+
+	repos := make([]string, len(recent))
+	for i, r := range recent {
+		repos[len(recent)-i-1] = r.Repo
+	}
+	return repos, err
+}
+
+func (boltStore) AddRecentRepo(key string) error {
+	db, err := openBolt()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		return updateRecentlyViewed(tx.Bucket([]byte(MetaBucket)), key)
+	})
+}
+
+func (boltStore) AppendHistory(key string, e historyEntry) error {
+	db, err := openBolt()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(HistoryBucket))
+		if b == nil {
+			return errors.New("No history bucket")
+		}
+		return appendHistory(b, key, e)
+	})
+}
+
+func (boltStore) History(key string) ([]historyEntry, error) {
+	db, err := openBolt()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var entries []historyEntry
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(HistoryBucket))
+		if b == nil {
+			return errors.New("No history bucket")
+		}
+		entries, err = readHistory(b, key)
+		return err
+	})
+	return entries, err
+}
+
+// Ping checks that the bolt database can be opened and has the bucket of
+// the repos
+func (boltStore) Ping() error {
+	db, err := bolt.Open(DBPath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(RepoBucket)) == nil {
+			return fmt.Errorf("bucket %s not found", RepoBucket)
+		}
+		return nil
+	})
+}
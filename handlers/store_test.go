@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestBoltStore(t *testing.T) {
+	withTestDB(t)
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte(MetaBucket))
+		return err
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := boltStore{}
+	for _, resp := range []checksResp{
+		{Repo: "github.com/foo/bar", Files: 120, Average: 0.9},
+		{Repo: "github.com/foo/baz", Files: 150, Average: 0.8},
+	} {
+		b, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveReport(resp.Repo, resp, b, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddRecentRepo(resp.Repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Ping(); err != nil {
+		t.Errorf("Ping = %v", err)
+	}
+	scores, stats, count, err := s.HighScores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 || scores[0].Repo != "github.com/foo/bar" || count != 2 || stats[80] != 1 {
+		t.Errorf("HighScores = %+v, %d repos, want bar first of 2 repos", scores, count)
+	}
+	recent, err := s.RecentRepos()
+	if want := []string{"github.com/foo/baz", "github.com/foo/bar"}; err != nil || !reflect.DeepEqual(recent, want) {
+		t.Errorf("RecentRepos = %q, %v, want %q", recent, err, want)
+	}
+	keys, err := s.ReportKeys("github.com/foo/ba")
+	if err != nil || len(keys) != 2 {
+		t.Errorf("ReportKeys = %q, %v, want both repos", keys, err)
+	}
+}
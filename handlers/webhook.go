@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gojp/goreportcard/download"
+)
+
+// GitHubWebhookSecret is the secret that GitHub signs the payloads of
+// webhooks with. Webhooks are not accepted if it is empty.
+var GitHubWebhookSecret = ""
+
+// maxWebhookPayload is the size of the largest payload that GitHub sends
+// to webhooks
+const maxWebhookPayload = 25 << 20
+
+// regradeQueueSize is the number of reports that can wait to be graded
+// again after pushes
+const regradeQueueSize = 100
+
+// githubPushEvent is the part of the payload of a push event of GitHub
+// that reports are graded again for
+type githubPushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	// Installation is set for the events of a GitHub App
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+}
+
+// GitHubWebhookHandler handles the push events of GitHub webhooks, by
+// grading the reports of the pushed branch again, so that their badges
+// stay current. Only repos that were graded before are graded, unless the
+// GitHub App is installed on the repo, and grading happens after the
+// response is sent. With the GitHub App, the grades are posted as the
+// statuses of the pushed commits, and pull requests get a comment with the
+// issues they introduce.
+func GitHubWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if GitHubWebhookSecret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookPayload+1))
+	if err != nil {
+		http.Error(w, "Could not read payload", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebhookPayload {
+		http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !validGitHubSignature(GitHubWebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		slog.Warn("invalid webhook signature", "delivery", r.Header.Get("X-GitHub-Delivery"))
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "ping":
+		w.Write([]byte("pong"))
+		return
+	case "pull_request":
+		pullRequestWebhook(w, body)
+		return
+	case "push":
+	default:
+		fmt.Fprintf(w, "Ignored %s event", event)
+		return
+	}
+
+	var push githubPushEvent
+	if err := json.Unmarshal(body, &push); err != nil {
+		http.Error(w, "Could not parse payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	repo, ref, ok := pushedRef(push)
+	if !ok {
+		w.Write([]byte("Ignored push"))
+		return
+	}
+
+	app := push.Installation.ID != 0 && githubAppEnabled()
+	keys, err := storedReports(repo, ref, app)
+	if err != nil {
+		slog.Error("could not find reports of pushed repo", "repo", repo, "error", err)
+		http.Error(w, "Could not read reports", http.StatusInternalServerError)
+		return
+	}
+	if app && len(keys) == 0 {
+		keys = []string{repoKey(repo, ref)}
+	}
+	var queued []string
+	for _, key := range keys {
+		job := regradeJob{key: key}
+		if app {
+			job.repo, job.commit, job.installation = push.Repository.FullName, push.After, push.Installation.ID
+		}
+		if regrades.add(job) {
+			queued = append(queued, key)
+		}
+	}
+	slog.Info("grading pushed repo again", "repo", repo, "ref", ref, "reports", len(keys), "queued", len(queued))
+	if len(queued) < len(keys) {
+		http.Error(w, "Too many reports waiting to be graded", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"reports": keys})
+}
+
+// validGitHubSignature reports whether signature, the X-Hub-Signature-256
+// header of a webhook, is the HMAC of body with secret
+func validGitHubSignature(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// pushedRef returns the import path of the repo of a push event, and the
+// ref that was pushed: "" for the default branch, or a branch or tag. ok
+// is false for pushes that delete a ref, or that are not of a branch or
+// tag.
+func pushedRef(push githubPushEvent) (repo, ref string, ok bool) {
+	if push.Deleted || push.Repository.FullName == "" {
+		return "", "", false
+	}
+	switch {
+	case strings.HasPrefix(push.Ref, "refs/heads/"):
+		ref = strings.TrimPrefix(push.Ref, "refs/heads/")
+		if ref == push.Repository.DefaultBranch {
+			ref = ""
+		}
+	case strings.HasPrefix(push.Ref, "refs/tags/"):
+		ref = strings.TrimPrefix(push.Ref, "refs/tags/")
+	default:
+		return "", "", false
+	}
+	return "github.com/" + push.Repository.FullName, ref, true
+}
+
+// storedReports returns the keys of the reports at ref of the repo, and
+// of the directories of the repo, in the repo bucket. Reports graded with
+// the access token of a request are left out unless private is set, as
+// their repos can only be cloned with a token of the GitHub App. Reports
+// graded with the tokens of the server are kept.
+func storedReports(repo, ref string, private bool) ([]string, error) {
+	stored, err := DefaultStore.ReportKeys(repo)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, k := range stored {
+		path, kref := download.SplitRef(k)
+		if kref != ref || path != repo && !strings.HasPrefix(path, repo+"/") {
+			continue
+		}
+		if !private {
+			if resp, err := getFromCache(k); err == nil && resp.AccessHash != "" && !readableWithServerToken(resp) {
+				continue
+			}
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// readableWithServerToken reports whether the private report was graded
+// with one of the access tokens of the server, which grade it again
+func readableWithServerToken(resp checksResp) bool {
+	for _, t := range download.Tokens {
+		if resp.readableWith(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// regradeJob is a report that is graded again
+type regradeJob struct {
+	key string
+	// repo, commit and installation are set to post the grade as the
+	// status of the commit, as the installation of the GitHub App
+	repo         string
+	commit       string
+	installation int64
+	// pull is the number of the pull request that the issues introduced by
+	// the report, compared to the report of base, are commented on
+	pull int
+	base string
+}
+
+// id returns the ID of the job in the queue, which differs for the jobs
+// of pull requests
+func (j regradeJob) id() string {
+	if j.pull == 0 {
+		return j.key
+	}
+	return fmt.Sprintf("%s#%d", j.key, j.pull)
+}
+
+// regradeQueue grades reports again in grading jobs, one at a time, and
+// posts their grades to GitHub
+type regradeQueue struct {
+	mu sync.Mutex
+	// pending are the IDs of the jobs that are waiting
+	pending map[string]bool
+	jobs    chan regradeJob
+	start   sync.Once
+}
+
+var regrades = &regradeQueue{
+	pending: make(map[string]bool),
+	jobs:    make(chan regradeJob, regradeQueueSize),
+}
+
+// add queues the report of the job to be graded again, and reports
+// whether it was queued. A report that is already waiting is not queued
+// again, and neither are reports when the queue is full.
+func (q *regradeQueue) add(job regradeJob) bool {
+	q.start.Do(func() { go q.run() })
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[job.id()] {
+		return true
+	}
+	select {
+	case q.jobs <- job:
+		q.pending[job.id()] = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *regradeQueue) run() {
+	for job := range q.jobs {
+		// a push while the report is graded queues it again
+		q.mu.Lock()
+		delete(q.pending, job.id())
+		q.mu.Unlock()
+		resp, err := regrade(job)
+		if err != nil {
+			slog.Error("could not grade pushed repo again", "repo", job.key, "error", err)
+		}
+		if job.installation == 0 {
+			continue
+		}
+		if err := postJobStatus(job, resp, err); err != nil {
+			slog.Error("could not post commit status", "repo", job.key, "error", err)
+		}
+		if job.pull == 0 || err != nil {
+			continue
+		}
+		if err := postPullComment(job, resp); err != nil {
+			slog.Error("could not comment on pull request", "repo", job.repo, "pull", job.pull, "error", err)
+		}
+	}
+}
+
+// postJobStatus posts the grade of the job, or the error that grading it
+// failed with, as the status of its commit
+func postJobStatus(job regradeJob, resp checksResp, gradeErr error) error {
+	if gradeErr != nil {
+		if job.commit == "" {
+			return nil
+		}
+		return postCommitStatus(job.installation, job.repo, job.commit, commitStatus{
+			State:       "error",
+			Description: "Could not grade the commit",
+			Context:     reportContext(job.key),
+		})
+	}
+	commit := resp.Commit
+	if commit == "" {
+		commit = job.commit
+	}
+	return postCommitStatus(job.installation, job.repo, commit, newCommitStatus(job.key, resp, GitHubMinGrade))
+}
+
+// regrade grades the report of the job again in a grading job, and
+// returns the report once the job saved it. With the GitHub App, the repo
+// is cloned with a token of its installation, so that private repos can
+// be graded. Otherwise requests for the report that come in while the
+// job is queued share it.
+func regrade(job regradeJob) (checksResp, error) {
+	var (
+		gj  gradeJob
+		err error
+	)
+	if job.installation != 0 {
+		gj, err = jobs.addInstallation(job.key, job.installation)
+	} else {
+		gj, err = jobs.add(job.key, "", true, "")
+	}
+	if err != nil {
+		return checksResp{}, err
+	}
+	if gj = jobs.wait(gj.ID); gj.State != jobDone {
+		return checksResp{}, errors.New(gj.Error)
+	}
+	return getFromCache(job.key)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gojp/goreportcard/download"
+)
+
+func TestValidGitHubSignature(t *testing.T) {
+	// the example of the GitHub docs
+	secret, body := "It's a Secret to Everybody", []byte("Hello, World!")
+	cases := []struct {
+		signature string
+		want      bool
+	}{
+		{"sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", true},
+		{"sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e18", false},
+		{"757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", false},
+		{"sha256=not hex", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := validGitHubSignature(secret, body, c.signature); got != c.want {
+			t.Errorf("[%q] validGitHubSignature = %v, want %v", c.signature, got, c.want)
+		}
+	}
+}
+
+func TestPushedRef(t *testing.T) {
+	push := func(ref string, deleted bool) githubPushEvent {
+		var p githubPushEvent
+		p.Ref, p.Deleted = ref, deleted
+		p.Repository.FullName, p.Repository.DefaultBranch = "foo/bar", "main"
+		return p
+	}
+	cases := []struct {
+		push      githubPushEvent
+		repo, ref string
+		ok        bool
+	}{
+		{push("refs/heads/main", false), "github.com/foo/bar", "", true},
+		{push("refs/heads/feature/x", false), "github.com/foo/bar", "feature/x", true},
+		{push("refs/tags/v1.2.3", false), "github.com/foo/bar", "v1.2.3", true},
+		{push("refs/heads/develop", true), "", "", false},
+		{push("refs/notes/commits", false), "", "", false},
+	}
+	for _, c := range cases {
+		repo, ref, ok := pushedRef(c.push)
+		if repo != c.repo || ref != c.ref || ok != c.ok {
+			t.Errorf("[%q] pushedRef = %q, %q, %v, want %q, %q, %v", c.push.Ref, repo, ref, ok, c.repo, c.ref, c.ok)
+		}
+	}
+}
+
+// withTestDB changes to a directory in which DBPath is a database with
+// the repo bucket and keys
+func withTestDB(t *testing.T, keys ...string) {
+	t.Chdir(t.TempDir())
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(RepoBucket))
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Put([]byte(k), []byte("{}")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoredReports(t *testing.T) {
+	withTestDB(t,
+		"github.com/foo/bar",
+		"github.com/foo/bar@develop",
+		"github.com/foo/bar/services/api",
+		"github.com/foo/bar/services/api@develop",
+		"github.com/foo/barbaz",
+		"github.com/foo/other",
+	)
+	db, err := bolt.Open(DBPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(tokens map[string]string) { download.Tokens = tokens }(download.Tokens)
+	download.Tokens = map[string]string{"github.com": "server"}
+	err = db.Update(func(tx *bolt.Tx) error {
+		// graded with the token of a request and of the server
+		for key, token := range map[string]string{"github.com/foo/bar/internal": "secret", "github.com/foo/bar/ops": "server"} {
+			b, _ := json.Marshal(checksResp{Repo: key, AccessHash: tokenHash(token)})
+			if err := tx.Bucket([]byte(RepoBucket)).Put([]byte(key), b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		ref     string
+		private bool
+		want    []string
+	}{
+		{"", false, []string{"github.com/foo/bar", "github.com/foo/bar/ops", "github.com/foo/bar/services/api"}},
+		{"", true, []string{"github.com/foo/bar", "github.com/foo/bar/internal", "github.com/foo/bar/ops", "github.com/foo/bar/services/api"}},
+		{"develop", false, []string{"github.com/foo/bar/services/api@develop", "github.com/foo/bar@develop"}},
+		{"v1.2.3", false, nil},
+	}
+	for _, c := range cases {
+		got, err := storedReports("github.com/foo/bar", c.ref, c.private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("[%q, %t] storedReports = %q, want %q", c.ref, c.private, got, c.want)
+		}
+	}
+}
+
+func TestGitHubWebhookHandler(t *testing.T) {
+	defer func(secret string) { GitHubWebhookSecret = secret }(GitHubWebhookSecret)
+	GitHubWebhookSecret = "secret"
+	withTestDB(t)
+
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(GitHubWebhookSecret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	push := `{"ref":"refs/heads/main","repository":{"full_name":"foo/new","default_branch":"main"}}`
+	cases := []struct {
+		event, body, signature string
+		want                   int
+	}{
+		{"push", push, "", 401},
+		{"push", push, sign(push + " "), 401},
+		{"ping", `{"zen":"hi"}`, sign(`{"zen":"hi"}`), 200},
+		{"issues", `{}`, sign(`{}`), 200},
+		{"push", `{`, sign(`{`), 400},
+		// repos that were never graded are not graded
+		{"push", push, sign(push), 202},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", "/webhook/github", strings.NewReader(c.body))
+		r.Header.Set("X-GitHub-Event", c.event)
+		if c.signature != "" {
+			r.Header.Set("X-Hub-Signature-256", c.signature)
+		}
+		w := httptest.NewRecorder()
+		GitHubWebhookHandler(w, r)
+		if w.Code != c.want {
+			t.Errorf("[%s %q] status = %d, want %d: %s", c.event, c.body, w.Code, c.want, w.Body)
+		}
+	}
+}
@@ -1,4 +1,11 @@
 #!/bin/sh
 
-go get github.com/alecthomas/gometalinter
+go install github.com/alecthomas/gometalinter@latest
 gometalinter --install --update
+go install github.com/mgechev/revive@latest
+go install github.com/securego/gosec/cmd/gosec@latest
+go install github.com/uudashr/gocognit/cmd/gocognit@latest
+go install golang.org/x/vuln/cmd/govulncheck@latest
+go install github.com/nishanths/exhaustive/cmd/exhaustive@latest
+go install golang.org/x/tools/go/analysis/passes/fieldalignment/cmd/fieldalignment@latest
+go install golang.org/x/tools/go/analysis/passes/shadow/cmd/shadow@latest
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"path/filepath"
+
+	"github.com/gojp/goreportcard/check"
+)
+
+var dir = flag.String("dir", ".", "root of the repo to write the baseline for")
+
+func main() {
+	flag.Parse()
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	checker := check.Checker{Logger: check.StdLogger(), NoBaseline: true}
+	filenames, skipped, err := checker.GoFiles(ctx, root)
+	if err != nil {
+		log.Fatal("could not get filenames: ", err)
+	}
+	if err := check.RenameFiles(skipped); err != nil {
+		log.Println("Could not remove files:", err)
+	}
+	results := checker.RunAll(ctx, root, filenames)
+	if err := check.RevertFiles(skipped); err != nil {
+		log.Println("Could not revert files:", err)
+	}
+
+	for _, r := range results {
+		if r.Error != "" {
+			log.Printf("WARNING: %s failed, its issues are not in the baseline: %s", r.Name, r.Error)
+		}
+	}
+	b := check.NewBaseline(root, results)
+	if err := check.WriteBaseline(root, b); err != nil {
+		log.Fatal("could not write baseline: ", err)
+	}
+	log.Printf("Wrote %d issues to %s", len(b.Issues), filepath.Join(root, check.BaselineFile))
+}
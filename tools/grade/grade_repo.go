@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gojp/goreportcard/check"
+	"github.com/gojp/goreportcard/handlers"
+)
+
+var (
+	dir      = flag.String("dir", ".", "root of the repo to grade")
+	minGrade = flag.String("min_grade", "", "if set, exit with status 1 when the grade is below this grade, such as B")
+	format   = flag.String("format", "", fmt.Sprintf("if set, write the report to stdout in this format instead of the grade: one of %v", handlers.Formats()))
+)
+
+func main() {
+	flag.Parse()
+	var min handlers.Grade
+	if *minGrade != "" {
+		var err error
+		if min, err = handlers.ParseGrade(*minGrade); err != nil {
+			log.Fatal("invalid -min_grade: ", err)
+		}
+	}
+	if *format != "" && !contains(handlers.Formats(), *format) {
+		log.Fatalf("invalid -format %q, want one of %v", *format, handlers.Formats())
+	}
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	checker := check.Checker{Logger: check.StdLogger()}
+	filenames, skipped, err := checker.GoFiles(ctx, root)
+	if err != nil {
+		log.Fatal("could not get filenames: ", err)
+	}
+	if err := check.RenameFiles(skipped); err != nil {
+		log.Println("Could not remove files:", err)
+	}
+	results := checker.RunAll(ctx, root, filenames)
+	if err := check.RevertFiles(skipped); err != nil {
+		log.Println("Could not revert files:", err)
+	}
+
+	grade, score := handlers.GradeResults(results)
+	if *format != "" {
+		if err := handlers.WriteFormat(*format, root, results, os.Stdout); err != nil {
+			log.Fatal("could not write report: ", err)
+		}
+	} else {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("%-20s failed: %s\n", r.Name, r.Error)
+				continue
+			}
+			fmt.Printf("%-20s %3d%%\n", r.Name, int(r.Percentage*100))
+		}
+		fmt.Printf("Grade: %s (%.1f%%)\n", grade, score)
+	}
+
+	if min != "" && !grade.AtLeast(min) {
+		fmt.Fprintf(os.Stderr, "The grade %s is below %s\n", grade, min)
+		os.Exit(1)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}